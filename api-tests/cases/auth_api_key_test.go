@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createdAPIKey thông tin API key trả về khi tạo (key đầy đủ chỉ có trong response này)
+type createdAPIKey struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// TestAPIKeyAuthentication kiểm tra xác thực bằng API key của service account và giới hạn theo scopes
+func TestAPIKeyAuthentication(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	// Service account có quyền RBAC rộng hơn scopes của key
+	resp, body, err := adminClient.POST("/service-account/insert-one", map[string]interface{}{
+		"name":                fmt.Sprintf("test-sa-%d", time.Now().UnixNano()),
+		"ownerOrganizationId": rootOrgID,
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Tạo service account phải thành công. Body: %s", string(body))
+	var accountResult struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &accountResult))
+	accountID := accountResult.Data.ID
+
+	roleID, err := fixtures.CreateRoleWithPermissions(adminToken, rootOrgID, []string{"Organization.Read", "Role.Read", "ApiKey.Insert"}, 0)
+	require.NoError(t, err)
+	require.NoError(t, fixtures.AssignRole(adminToken, accountID, roleID))
+
+	// createKey tạo API key bằng admin và trả về key đầy đủ
+	createKey := func(scopes []string) createdAPIKey {
+		resp, body, err := adminClient.POST(fmt.Sprintf("/service-account/%s/create-api-key", accountID), map[string]interface{}{
+			"name":   fmt.Sprintf("test-key-%d", time.Now().UnixNano()),
+			"scopes": scopes,
+		})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Tạo API key phải thành công. Body: %s", string(body))
+		var result struct {
+			Data createdAPIKey `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &result))
+		require.NotEmpty(t, result.Data.Key, "Key đầy đủ phải được trả về khi tạo")
+		return result.Data
+	}
+
+	// keyClient tạo client xác thực bằng API key
+	keyClient := func(rawKey string) *utils.HTTPClient {
+		c := utils.NewHTTPClient(baseURL, 10)
+		c.SetAPIKey(rawKey)
+		c.SetActiveRoleID(roleID)
+		return c
+	}
+
+	readKey := createKey([]string{"Organization.Read"})
+
+	t.Run("✅ API key dùng được permission trong scopes", func(t *testing.T) {
+		resp, body, err := keyClient(readKey.Key).GET("/organization/find")
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 API key không dùng được permission ngoài scopes", func(t *testing.T) {
+		// Service account có Role.Read nhưng key không được cấp scope này
+		resp, body, err := keyClient(readKey.Key).GET("/role/find")
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusForbidden, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 API key không tạo được API key mới", func(t *testing.T) {
+		manageKey := createKey([]string{"Organization.Read", "ApiKey.Insert"})
+		resp, body, err := keyClient(manageKey.Key).POST(fmt.Sprintf("/service-account/%s/create-api-key", accountID), map[string]interface{}{
+			"name": "escalated-key",
+		})
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusForbidden, resp.StatusCode, "Key mới không được vượt quyền key đang gọi. Body: %s", string(body))
+	})
+
+	t.Run("🚫 API key sai bị từ chối", func(t *testing.T) {
+		resp, _, err := keyClient("mck_invalidprefix_invalidsecret").GET("/organization/find")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("🚫 API key đã thu hồi bị từ chối", func(t *testing.T) {
+		resp, body, err := adminClient.POST(fmt.Sprintf("/service-account/api-keys/%s/revoke", readKey.ID), nil)
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Thu hồi key phải thành công. Body: %s", string(body))
+
+		resp, _, err = keyClient(readKey.Key).GET("/organization/find")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
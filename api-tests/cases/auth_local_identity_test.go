@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocalIdentityProvider kiểm tra đăng nhập bằng token dev của provider local
+// Cần server bật LOCAL_IDENTITY_ENABLED=true và TEST_LOCAL_IDENTITY_SECRET trùng LOCAL_IDENTITY_SECRET
+func TestLocalIdentityProvider(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	waitForHealth(baseURL, 10, 1*time.Second, t)
+
+	secret := utils.GetTestLocalIdentitySecret()
+	if secret == "" {
+		t.Skip("⚠️ TEST_LOCAL_IDENTITY_SECRET không được set, bỏ qua test provider local")
+	}
+
+	client := utils.NewHTTPClient(baseURL, 10)
+	subject := fmt.Sprintf("local_%d@example.com", time.Now().UnixNano())
+
+	// login đăng nhập bằng token dev và trả về status code + user ID
+	login := func(idToken string) (int, string) {
+		resp, body, err := client.POST("/auth/login/local", map[string]interface{}{
+			"idToken": idToken,
+			"hwid":    "test_local_identity",
+		})
+		require.NoError(t, err)
+		var result struct {
+			Data struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal(body, &result))
+		}
+		return resp.StatusCode, result.Data.ID
+	}
+
+	// sign ký token dev hợp lệ cho danh tính
+	sign := func(claims utils.LocalIdentityClaims, ttl time.Duration) string {
+		token, err := utils.SignLocalIdentityToken(secret, claims, ttl)
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("✅ Token dev hợp lệ đăng nhập được và cùng subject trả về cùng user", func(t *testing.T) {
+		claims := utils.LocalIdentityClaims{Subject: subject, Email: subject, EmailVerified: true, Name: "Local Tester"}
+
+		status, firstID := login(sign(claims, time.Hour))
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, firstID)
+
+		status, secondID := login(sign(claims, time.Hour))
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, firstID, secondID, "Cùng subject phải trả về cùng user")
+	})
+
+	t.Run("🚫 Token ký bằng secret khác bị từ chối", func(t *testing.T) {
+		token, err := utils.SignLocalIdentityToken(secret+"-wrong", utils.LocalIdentityClaims{Subject: subject}, time.Hour)
+		require.NoError(t, err)
+		status, _ := login(token)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("🚫 Token hết hạn bị từ chối", func(t *testing.T) {
+		status, _ := login(sign(utils.LocalIdentityClaims{Subject: subject}, -time.Minute))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("🚫 Token sai issuer bị từ chối", func(t *testing.T) {
+		status, _ := login(sign(utils.LocalIdentityClaims{Issuer: "someone-else", Subject: subject}, time.Hour))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("🚫 Server không còn endpoint phát hành token dev", func(t *testing.T) {
+		resp, body, err := client.POST("/auth/local/token", map[string]interface{}{"subject": subject})
+		require.NoError(t, err)
+		assert.NotEqualf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 Danh tính local không tự liên kết vào tài khoản có sẵn", func(t *testing.T) {
+		otherSubject := fmt.Sprintf("local_other_%d", time.Now().UnixNano())
+		// Email đã thuộc user local khác (subject khác) → conflict
+		status, _ := login(sign(utils.LocalIdentityClaims{Subject: otherSubject, Email: subject, EmailVerified: true}, time.Hour))
+		assert.Equal(t, http.StatusConflict, status)
+
+		// Email của user Firebase (admin) → không được liên kết dù email_verified = true
+		_, adminEmail, _, _, err := utils.SetupTestWithAdminUser(t, baseURL)
+		if err != nil || adminEmail == "" {
+			t.Skipf("⚠️ Không có user Firebase để kiểm tra liên kết: %v", err)
+		}
+		status, _ = login(sign(utils.LocalIdentityClaims{Subject: otherSubject, Email: adminEmail, EmailVerified: true}, time.Hour))
+		assert.Equal(t, http.StatusConflict, status)
+	})
+}
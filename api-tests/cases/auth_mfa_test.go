@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMFAStepUpAndLockout kiểm tra đăng ký TOTP, step-up bằng mã TOTP và khóa tạm thời khi nhập sai nhiều lần
+// Dùng user mới của provider local để không khóa tài khoản admin dùng chung:
+// cần server bật LOCAL_IDENTITY_ENABLED=true và TEST_LOCAL_IDENTITY_SECRET trùng LOCAL_IDENTITY_SECRET
+func TestMFAStepUpAndLockout(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	waitForHealth(baseURL, 10, 1*time.Second, t)
+
+	secret := utils.GetTestLocalIdentitySecret()
+	if secret == "" {
+		t.Skip("⚠️ TEST_LOCAL_IDENTITY_SECRET không được set, bỏ qua test xác thực 2 lớp")
+	}
+
+	subject := fmt.Sprintf("mfa_%d@example.com", time.Now().UnixNano())
+	idToken, err := utils.SignLocalIdentityToken(secret, utils.LocalIdentityClaims{Subject: subject, Name: "MFA Tester"}, time.Hour)
+	require.NoError(t, err)
+
+	client := utils.NewHTTPClient(baseURL, 10)
+	resp, body, err := client.POST("/auth/login/local", map[string]interface{}{
+		"idToken": idToken,
+		"hwid":    fmt.Sprintf("test_mfa_%d", time.Now().UnixNano()),
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Đăng nhập phải thành công. Body: %s", string(body))
+	session := parseSessionTokens(t, body)
+	client.SetToken(session.Token)
+
+	// totp tính mã TOTP của secret tại thời điểm t
+	totp := func(totpSecret string, at time.Time) string {
+		code, err := utils.GenerateTOTPCode(totpSecret, at)
+		require.NoError(t, err)
+		return code
+	}
+
+	var totpSecret string
+	t.Run("🔐 Đăng ký và xác nhận TOTP", func(t *testing.T) {
+		resp, body, err := client.POST("/auth/mfa/totp/enroll", nil)
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Phiên vừa đăng nhập phải đăng ký được TOTP. Body: %s", string(body))
+		var enroll struct {
+			Data struct {
+				Secret     string `json:"secret"`
+				OtpauthURL string `json:"otpauthUrl"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &enroll))
+		require.NotEmpty(t, enroll.Data.Secret)
+		assert.Contains(t, enroll.Data.OtpauthURL, "otpauth://totp/")
+		totpSecret = enroll.Data.Secret
+
+		resp, body, err = client.POST("/auth/mfa/totp/confirm", map[string]interface{}{"code": totp(totpSecret, time.Now())})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Mã TOTP đúng phải bật được MFA. Body: %s", string(body))
+		var confirm struct {
+			Data struct {
+				RecoveryCodes []string `json:"recoveryCodes"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &confirm))
+		assert.NotEmpty(t, confirm.Data.RecoveryCodes, "Phải trả về recovery code")
+	})
+	require.NotEmpty(t, totpSecret, "Cần đăng ký TOTP thành công để tiếp tục")
+
+	// Mã của bước hiện tại đã dùng để xác nhận; server chấp nhận lệch 1 bước nên dùng mã của bước tiếp theo
+	stepUpCode := totp(totpSecret, time.Now().Add(30*time.Second))
+
+	t.Run("✅ Step-up bằng mã TOTP trả về access token mới có claim MFA", func(t *testing.T) {
+		resp, body, err := client.POST("/auth/mfa/verify", map[string]interface{}{"code": stepUpCode})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Mã TOTP đúng phải step-up được. Body: %s", string(body))
+		var result struct {
+			Data struct {
+				Token        string `json:"token"`
+				MfaAt        int64  `json:"mfaAt"`
+				MfaExpiresAt int64  `json:"mfaExpiresAt"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &result))
+		require.NotEmpty(t, result.Data.Token)
+		assert.Greater(t, result.Data.MfaAt, int64(0))
+		assert.Greater(t, result.Data.MfaExpiresAt, result.Data.MfaAt)
+
+		// Access token cũ bị thu hồi, token mới dùng được
+		resp, _, err = client.GET("/auth/profile")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Access token cũ phải bị thu hồi sau step-up")
+		client.SetToken(result.Data.Token)
+		resp, _, err = client.GET("/auth/profile")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Access token mới phải dùng được")
+	})
+
+	t.Run("🚫 Mã TOTP đã dùng không step-up lại được", func(t *testing.T) {
+		resp, _, err := client.POST("/auth/mfa/verify", map[string]interface{}{"code": stepUpCode})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("🔒 Nhập sai nhiều lần bị khóa tạm thời", func(t *testing.T) {
+		// Mã của một giờ trước: đúng định dạng nhưng nằm ngoài cửa sổ chấp nhận
+		wrongCode := totp(totpSecret, time.Now().Add(-time.Hour))
+
+		locked := false
+		for i := 0; i < 10 && !locked; i++ {
+			resp, body, err := client.POST("/auth/mfa/verify", map[string]interface{}{"code": wrongCode})
+			require.NoError(t, err)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				locked = true
+				assert.NotEmpty(t, resp.Header.Get("Retry-After"), "Phải có header Retry-After")
+				break
+			}
+			require.Equalf(t, http.StatusBadRequest, resp.StatusCode, "Mã sai phải trả về 400 trước khi bị khóa. Body: %s", string(body))
+		}
+		require.True(t, locked, "Phải bị khóa sau MFA_MAX_FAILED_ATTEMPTS lần nhập sai")
+
+		// Đang bị khóa thì mã đúng cũng bị từ chối, kể cả ở route khác dùng chung bộ đếm
+		resp, _, err := client.POST("/auth/mfa/verify", map[string]interface{}{"code": totp(totpSecret, time.Now())})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "Mã đúng cũng bị từ chối khi đang khóa")
+
+		resp, _, err = client.POST("/auth/mfa/disable", map[string]interface{}{"code": totp(totpSecret, time.Now())})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "Tắt MFA dùng chung bộ đếm nên cũng bị khóa")
+	})
+}
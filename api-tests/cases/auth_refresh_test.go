@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionTokens cặp token nhận được khi đăng nhập / refresh
+type sessionTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// parseSessionTokens lấy access token và refresh token từ response đăng nhập / refresh
+func parseSessionTokens(t *testing.T, body []byte) sessionTokens {
+	var result struct {
+		Data sessionTokens `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &result), "Phải parse được JSON response")
+	require.NotEmpty(t, result.Data.Token, "Phải có access token trong response")
+	require.NotEmpty(t, result.Data.RefreshToken, "Phải có refresh token trong response")
+	return result.Data
+}
+
+// TestRefreshTokenRotation kiểm tra rotation của refresh token và phát hiện refresh token bị dùng lại
+func TestRefreshTokenRotation(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	waitForHealth(baseURL, 10, 1*time.Second, t)
+	firebaseIDToken := getTestFirebaseIDToken(t)
+
+	client := utils.NewHTTPClient(baseURL, 10)
+	// Thiết bị riêng cho test này để không ảnh hưởng phiên của các test khác
+	hwid := fmt.Sprintf("test_refresh_%d", time.Now().UnixNano())
+
+	resp, body, err := client.POST("/auth/login/firebase", map[string]interface{}{
+		"idToken": firebaseIDToken,
+		"hwid":    hwid,
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Đăng nhập phải thành công. Body: %s", string(body))
+	first := parseSessionTokens(t, body)
+
+	// profileStatus gọi /auth/profile bằng access token và trả về status code
+	profileStatus := func(token string) int {
+		c := utils.NewHTTPClient(baseURL, 10)
+		c.SetToken(token)
+		resp, _, err := c.GET("/auth/profile")
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	var second sessionTokens
+	t.Run("🔄 Refresh trả về cặp token mới và thu hồi cặp cũ", func(t *testing.T) {
+		resp, body, err := client.POST("/auth/refresh", map[string]interface{}{"refreshToken": first.RefreshToken})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Refresh phải thành công. Body: %s", string(body))
+		second = parseSessionTokens(t, body)
+
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken, "Refresh token phải được rotate")
+		assert.Equal(t, http.StatusOK, profileStatus(second.Token), "Access token mới phải dùng được")
+		assert.Equal(t, http.StatusUnauthorized, profileStatus(first.Token), "Access token cũ phải bị thu hồi")
+	})
+
+	t.Run("🚫 Access token không dùng được như refresh token", func(t *testing.T) {
+		resp, _, err := client.POST("/auth/refresh", map[string]interface{}{"refreshToken": second.Token})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Access token không được đổi lấy token mới")
+	})
+
+	t.Run("🚨 Dùng lại refresh token cũ thu hồi toàn bộ phiên của thiết bị", func(t *testing.T) {
+		resp, _, err := client.POST("/auth/refresh", map[string]interface{}{"refreshToken": first.RefreshToken})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Refresh token cũ phải bị từ chối")
+
+		// Phiên hiện tại của thiết bị (cặp token thứ 2) cũng bị thu hồi
+		resp, _, err = client.POST("/auth/refresh", map[string]interface{}{"refreshToken": second.RefreshToken})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Refresh token hiện tại phải bị thu hồi sau khi phát hiện dùng lại")
+		assert.Equal(t, http.StatusUnauthorized, profileStatus(second.Token), "Access token hiện tại phải bị thu hồi sau khi phát hiện dùng lại")
+	})
+}
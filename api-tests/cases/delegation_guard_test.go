@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delegationError response lỗi DELEGATION_DENIED khi cấp quyền / gán role vượt quá quyền của người thực hiện
+type delegationError struct {
+	Details struct {
+		ErrorCode  string `json:"errorCode"`
+		Violations []struct {
+			PermissionName string `json:"permissionName"`
+			Scope          int    `json:"scope"`
+			Reason         string `json:"reason"`
+		} `json:"violations"`
+	} `json:"details"`
+}
+
+// parseDelegationError kiểm tra response là 403 DELEGATION_DENIED và trả về danh sách vi phạm
+func parseDelegationError(t *testing.T, resp *http.Response, body []byte) delegationError {
+	require.Equalf(t, http.StatusForbidden, resp.StatusCode, "Cấp quyền vượt quá quyền của mình phải bị từ chối. Body: %s", string(body))
+	var result delegationError
+	require.NoError(t, json.Unmarshal(body, &result), "Phải parse được JSON response")
+	assert.Equal(t, "DELEGATION_DENIED", result.Details.ErrorCode)
+	require.NotEmpty(t, result.Details.Violations, "Phải liệt kê các permission vi phạm")
+	return result
+}
+
+// TestDelegationGuard kiểm tra người có RolePermission.Update / UserRole.Insert chỉ cấp được quyền mình đang có,
+// với scope không rộng hơn của mình, và chỉ gán được role mà mình có đủ quyền
+func TestDelegationGuard(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, _, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	// Người thực hiện chỉ có quyền quản lý phân quyền với scope 0 tại tổ chức gốc
+	delegatorID, _, delegator, err := fixtures.CreateUserWithPermissions(adminToken, rootOrgID, []string{
+		"RolePermission.Read", "RolePermission.Update", "UserRole.Read", "UserRole.Insert", "Role.Read",
+	}, 0)
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user với quyền phân quyền (server cần bật local identity provider): %v", err)
+	}
+
+	permissionIDs, err := fixtures.FindPermissionIDs(adminToken, "RolePermission.Read", "User.Delete")
+	require.NoError(t, err)
+	heldPermissionID, missingPermissionID := permissionIDs[0], permissionIDs[1]
+
+	targetRoleID, err := fixtures.CreateTestRole(adminToken, fmt.Sprintf("DelegationTarget_%d", time.Now().UnixNano()), "Role nhận quyền trong test chống leo thang quyền", rootOrgID)
+	require.NoError(t, err)
+
+	// updateRole thay toàn bộ permission của role đích bằng tài khoản của người thực hiện
+	updateRole := func(permissions ...map[string]interface{}) (*http.Response, []byte) {
+		resp, body, err := delegator.PUT("/role-permission/update-role", map[string]interface{}{
+			"roleId":      targetRoleID,
+			"permissions": permissions,
+		})
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	t.Run("✅ Cấp permission mình đang có với cùng scope", func(t *testing.T) {
+		resp, body := updateRole(map[string]interface{}{"permissionId": heldPermissionID, "scope": 0})
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 Cấp permission mình không có", func(t *testing.T) {
+		resp, body := updateRole(
+			map[string]interface{}{"permissionId": heldPermissionID, "scope": 0},
+			map[string]interface{}{"permissionId": missingPermissionID, "scope": 0},
+		)
+		result := parseDelegationError(t, resp, body)
+		require.Len(t, result.Details.Violations, 1, "Chỉ permission không có mới bị liệt kê")
+		assert.Equal(t, "User.Delete", result.Details.Violations[0].PermissionName)
+		assert.Equal(t, "permission_not_held", result.Details.Violations[0].Reason)
+	})
+
+	t.Run("🚫 Cấp scope rộng hơn scope của mình", func(t *testing.T) {
+		resp, body := updateRole(map[string]interface{}{"permissionId": heldPermissionID, "scope": 1})
+		result := parseDelegationError(t, resp, body)
+		assert.Equal(t, "RolePermission.Read", result.Details.Violations[0].PermissionName)
+		assert.Equal(t, "scope_exceeded", result.Details.Violations[0].Reason)
+	})
+
+	t.Run("🚫 Gán role có permission mình không có", func(t *testing.T) {
+		privilegedRoleID, err := fixtures.CreateRoleWithPermissions(adminToken, rootOrgID, []string{"User.Delete"}, 0)
+		require.NoError(t, err)
+
+		resp, body, err := delegator.POST("/user-role/insert-one", map[string]interface{}{
+			"userId": delegatorID,
+			"roleId": privilegedRoleID,
+		})
+		require.NoError(t, err)
+		result := parseDelegationError(t, resp, body)
+		assert.Equal(t, "User.Delete", result.Details.Violations[0].PermissionName)
+	})
+
+	t.Run("✅ Gán role chỉ gồm permission mình đang có", func(t *testing.T) {
+		resp, body, err := delegator.POST("/user-role/insert-one", map[string]interface{}{
+			"userId": delegatorID,
+			"roleId": targetRoleID,
+		})
+		require.NoError(t, err)
+		assert.Containsf(t, []int{http.StatusOK, http.StatusCreated}, resp.StatusCode, "Body: %s", string(body))
+	})
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOAuthPKCECodeExchange kiểm tra luồng authorization_code + PKCE (S256) của client public
+func TestOAuthPKCECodeExchange(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	redirectURI := "http://localhost:3000/oauth/callback"
+	resp, body, err := adminClient.POST("/oauth-client/insert-one", map[string]interface{}{
+		"name":                fmt.Sprintf("test-public-client-%d", time.Now().UnixNano()),
+		"ownerOrganizationId": rootOrgID,
+		"isPublic":            true,
+		"redirectUris":        []string{redirectURI},
+		"grantTypes":          []string{"authorization_code"},
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Tạo OAuth client phải thành công. Body: %s", string(body))
+	var clientResult struct {
+		Data struct {
+			ClientID     string `json:"clientId"`
+			ClientSecret string `json:"clientSecret"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &clientResult))
+	clientID := clientResult.Data.ClientID
+	require.NotEmpty(t, clientID)
+	assert.Empty(t, clientResult.Data.ClientSecret, "Client public không có secret")
+
+	verifier := fmt.Sprintf("test-verifier-%d-0123456789abcdefghijklmnopqrstuvwxyz", time.Now().UnixNano())
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	// authorize xin authorization code cho admin, trả về status code và code
+	authorize := func(codeChallenge string) (int, string) {
+		resp, body, err := adminClient.POST("/oauth/authorize", map[string]interface{}{
+			"clientId":            clientID,
+			"redirectUri":         redirectURI,
+			"responseType":        "code",
+			"scope":               "openid profile email",
+			"state":               "test-state",
+			"codeChallenge":       codeChallenge,
+			"codeChallengeMethod": "S256",
+		})
+		require.NoError(t, err)
+		var result struct {
+			Data struct {
+				Code        string `json:"code"`
+				State       string `json:"state"`
+				RedirectURL string `json:"redirectUrl"`
+			} `json:"data"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal(body, &result))
+			assert.Equal(t, "test-state", result.Data.State)
+			assert.True(t, strings.HasPrefix(result.Data.RedirectURL, redirectURI), "redirectUrl phải dựa trên redirectUri đã đăng ký")
+		}
+		return resp.StatusCode, result.Data.Code
+	}
+
+	// exchange đổi code lấy token tại token endpoint (public, không cần đăng nhập)
+	exchange := func(code, codeVerifier string) (int, map[string]interface{}) {
+		c := utils.NewHTTPClient(baseURL, 10)
+		resp, body, err := c.POST("/oauth/token", map[string]interface{}{
+			"grant_type":    "authorization_code",
+			"code":          code,
+			"redirect_uri":  redirectURI,
+			"code_verifier": codeVerifier,
+			"client_id":     clientID,
+		})
+		require.NoError(t, err)
+		var result map[string]interface{}
+		require.NoErrorf(t, json.Unmarshal(body, &result), "Body: %s", string(body))
+		return resp.StatusCode, result
+	}
+
+	t.Run("🚫 Client public bắt buộc PKCE", func(t *testing.T) {
+		status, _ := authorize("")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("🚫 code_verifier sai bị từ chối", func(t *testing.T) {
+		status, code := authorize(challenge)
+		require.Equal(t, http.StatusOK, status)
+
+		status, result := exchange(code, verifier+"-wrong")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", result["error"])
+	})
+
+	t.Run("🚫 Thiếu code_verifier bị từ chối", func(t *testing.T) {
+		status, code := authorize(challenge)
+		require.Equal(t, http.StatusOK, status)
+
+		status, result := exchange(code, "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", result["error"])
+	})
+
+	t.Run("✅ code_verifier đúng đổi được token, code chỉ dùng một lần", func(t *testing.T) {
+		status, code := authorize(challenge)
+		require.Equal(t, http.StatusOK, status)
+
+		status, result := exchange(code, verifier)
+		require.Equalf(t, http.StatusOK, status, "Result: %v", result)
+		assert.Equal(t, "Bearer", result["token_type"])
+		assert.NotEmpty(t, result["access_token"])
+		assert.NotEmpty(t, result["id_token"], "Scope openid phải trả về ID token")
+
+		status, result = exchange(code, verifier)
+		assert.Equal(t, http.StatusBadRequest, status, "Code đã dùng không được đổi lại")
+		assert.Equal(t, "invalid_grant", result["error"])
+	})
+}
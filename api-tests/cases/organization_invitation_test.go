@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrganizationInvitationAccept kiểm tra user đã đăng nhập chấp nhận lời mời: được gán role của lời mời
+// dù bản thân chưa có quyền gán role đó (quyền đã kiểm tra với người mời), và link chỉ dùng được một lần
+func TestOrganizationInvitationAccept(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	// Role của lời mời có quyền mà người nhận chưa có
+	roleID, err := fixtures.CreateRoleWithPermissions(adminToken, rootOrgID, []string{"User.Read", "Role.Read"}, 0)
+	require.NoError(t, err)
+
+	// Người nhận đăng nhập trước bằng provider local (chưa có role nào)
+	email := fmt.Sprintf("invitee_%d@example.com", time.Now().UnixNano())
+	_, inviteeToken, err := fixtures.CreateTestUserDirect(email, "Invitee")
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user người nhận (server cần bật local identity provider): %v", err)
+	}
+	invitee := utils.NewHTTPClient(baseURL, 10)
+	invitee.SetToken(inviteeToken)
+
+	resp, body, err := adminClient.POST(fmt.Sprintf("/organization/%s/invitations", rootOrgID), map[string]interface{}{
+		"email":   email,
+		"roleIds": []string{roleID},
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Admin phải tạo được lời mời. Body: %s", string(body))
+	var created struct {
+		Data struct {
+			InviteLink string `json:"inviteLink"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	link, err := url.Parse(created.Data.InviteLink)
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token, "Link mời phải chứa token")
+
+	t.Run("✅ Người nhận chấp nhận lời mời và được gán role", func(t *testing.T) {
+		resp, body, err := invitee.POST("/invitation/accept", map[string]interface{}{"token": token})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Chấp nhận lời mời phải thành công. Body: %s", string(body))
+		var accepted struct {
+			Data struct {
+				Status string `json:"status"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &accepted))
+		assert.Equal(t, "accepted", accepted.Data.Status)
+
+		resp, body, err = invitee.GET("/auth/roles")
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+		var roles struct {
+			Data []struct {
+				RoleID string `json:"roleId"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &roles))
+		roleIDs := make([]string, 0, len(roles.Data))
+		for _, role := range roles.Data {
+			roleIDs = append(roleIDs, role.RoleID)
+		}
+		assert.Contains(t, roleIDs, roleID, "Role của lời mời phải được gán cho người nhận")
+	})
+
+	t.Run("🚫 Link đã dùng không chấp nhận lại được", func(t *testing.T) {
+		resp, body, err := invitee.POST("/invitation/accept", map[string]interface{}{"token": token})
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusBadRequest, resp.StatusCode, "Body: %s", string(body))
+	})
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrganizationShareRoutePermissions kiểm tra route /organization-share chỉ cần quyền OrganizationShare.*
+// (middleware của các route prefix "/organization" không được áp dụng chồng lên)
+func TestOrganizationShareRoutePermissions(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, _, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	_, _, client, err := fixtures.CreateUserWithPermissions(adminToken, rootOrgID, []string{
+		"OrganizationShare.Insert", "OrganizationShare.Read", "OrganizationShare.Update",
+		"OrganizationShare.Delete", "OrganizationShare.Create",
+	}, 0)
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user với quyền OrganizationShare.* (server cần bật local identity provider): %v", err)
+	}
+
+	t.Run("✅ Đọc danh sách share chỉ với OrganizationShare.Read", func(t *testing.T) {
+		resp, body, err := client.GET("/organization-share/find")
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("✅ Tạo share không bị chặn bởi quyền Organization.*", func(t *testing.T) {
+		// Payload rỗng: request phải qua được middleware và bị handler từ chối vì dữ liệu không hợp lệ
+		resp, body, err := client.POST("/organization-share", map[string]interface{}{})
+		require.NoError(t, err)
+		assert.NotEqualf(t, http.StatusForbidden, resp.StatusCode, "Body: %s", string(body))
+		assert.NotEqualf(t, http.StatusUnauthorized, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 Route /organization vẫn yêu cầu quyền Organization.*", func(t *testing.T) {
+		resp, body, err := client.GET("/organization/find")
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusForbidden, resp.StatusCode, "Body: %s", string(body))
+	})
+}
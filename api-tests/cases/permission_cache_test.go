@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheStats thống kê một cache trong bộ nhớ (GET /system/cache-stats)
+type cacheStats struct {
+	Name       string `json:"name"`
+	Size       int    `json:"size"`
+	MaxSize    int    `json:"maxSize"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+}
+
+// getCacheStats lấy thống kê của cache theo tên
+func getCacheStats(t *testing.T, client *utils.HTTPClient, name string) cacheStats {
+	resp, body, err := client.GET("/system/cache-stats")
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	var result struct {
+		Data struct {
+			Caches []cacheStats `json:"caches"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &result), "Phải parse được JSON response")
+	for _, stats := range result.Data.Caches {
+		if stats.Name == name {
+			return stats
+		}
+	}
+	t.Fatalf("❌ Không có cache '%s' trong thống kê", name)
+	return cacheStats{}
+}
+
+// TestPermissionCacheInvalidation kiểm tra thay đổi quyền của role và role của user có hiệu lực ngay ở request tiếp theo
+// (cache quyền của user bị xóa khi có thay đổi, không phải chờ hết TTL)
+func TestPermissionCacheInvalidation(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	userID, roleID, user, err := fixtures.CreateUserWithPermissions(adminToken, rootOrgID, []string{"Role.Read"}, 0)
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user với quyền (server cần bật local identity provider): %v", err)
+	}
+
+	permissionIDs, err := fixtures.FindPermissionIDs(adminToken, "Role.Read", "User.Read")
+	require.NoError(t, err)
+	roleReadID, userReadID := permissionIDs[0], permissionIDs[1]
+
+	// findRoles gọi API cần Role.Read bằng tài khoản của user
+	findRoles := func() int {
+		resp, _, err := user.GET("/role/find")
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	// setRolePermissions thay toàn bộ permission của role bằng tài khoản admin
+	setRolePermissions := func(permissionID string) {
+		resp, body, err := adminClient.PUT("/role-permission/update-role", map[string]interface{}{
+			"roleId":      roleID,
+			"permissions": []map[string]interface{}{{"permissionId": permissionID, "scope": 0}},
+		})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Admin phải cập nhật được quyền của role. Body: %s", string(body))
+	}
+
+	// Gọi nhiều lần để quyền của user nằm trong cache
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, findRoles(), "User có Role.Read phải xem được role")
+	}
+
+	t.Run("🚫 Gỡ permission khỏi role có hiệu lực ngay", func(t *testing.T) {
+		setRolePermissions(userReadID)
+		assert.Equal(t, http.StatusForbidden, findRoles(), "Quyền đã gỡ không được còn trong cache")
+	})
+
+	t.Run("✅ Cấp lại permission cho role có hiệu lực ngay", func(t *testing.T) {
+		setRolePermissions(roleReadID)
+		assert.Equal(t, http.StatusOK, findRoles(), "Quyền vừa cấp phải dùng được ngay")
+	})
+
+	t.Run("🚫 Gỡ role khỏi user có hiệu lực ngay", func(t *testing.T) {
+		otherRoleID, err := fixtures.CreateRoleWithPermissions(adminToken, rootOrgID, []string{"User.Read"}, 0)
+		require.NoError(t, err)
+		resp, body, err := adminClient.PUT("/user-role/update-user-roles", map[string]interface{}{
+			"userId":  userID,
+			"roleIds": []string{otherRoleID},
+		})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Admin phải cập nhật được role của user. Body: %s", string(body))
+
+		assert.NotEqual(t, http.StatusOK, findRoles(), "Role đã gỡ không được còn trong cache")
+	})
+}
+
+// TestPermissionCacheStats kiểm tra cấu hình cache quyền, các request đồng thời của user mới cùng nhận quyền đã load
+// và request sau đó lấy quyền từ cache
+func TestPermissionCacheStats(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	_, _, user, err := fixtures.CreateUserWithPermissions(adminToken, rootOrgID, []string{"Role.Read"}, 0)
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user với quyền (server cần bật local identity provider): %v", err)
+	}
+
+	before := getCacheStats(t, adminClient, "user_permissions")
+	assert.Equal(t, 10000, before.MaxSize, "Cache quyền giới hạn 10000 entry (LRU)")
+	assert.Equal(t, int64(300), before.TTLSeconds, "Entry của cache quyền sống 5 phút")
+
+	// Các request đồng thời của user mới (cache chưa có quyền của user) dùng chung một lần load (singleflight)
+	const concurrent = 10
+	var wg sync.WaitGroup
+	statuses := make([]int, concurrent)
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, _, err := user.GET("/role/find")
+			if err == nil {
+				statuses[i] = resp.StatusCode
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, status := range statuses {
+		assert.Equal(t, http.StatusOK, status, "Mọi request đồng thời phải nhận cùng quyền")
+	}
+
+	// Quyền của user đã được cache: các request tiếp theo không load lại
+	loaded := getCacheStats(t, adminClient, "user_permissions")
+	for i := 0; i < 3; i++ {
+		resp, _, err := user.GET("/role/find")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	after := getCacheStats(t, adminClient, "user_permissions")
+	assert.Equal(t, loaded.Misses, after.Misses, "Quyền đã có trong cache không được load lại")
+	assert.GreaterOrEqual(t, after.Hits-loaded.Hits, uint64(3), "Request sau lần load đầu phải lấy quyền từ cache")
+}
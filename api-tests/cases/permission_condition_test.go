@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"ff_be_auth_tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorDetails phần details của response lỗi (errorCode và các vi phạm nếu có)
+type errorDetails struct {
+	Details struct {
+		ErrorCode  string `json:"errorCode"`
+		Violations []struct {
+			PermissionName string `json:"permissionName"`
+			Reason         string `json:"reason"`
+		} `json:"violations"`
+	} `json:"details"`
+}
+
+// parseErrorDetails parse details của response lỗi
+func parseErrorDetails(t *testing.T, body []byte) errorDetails {
+	var result errorDetails
+	require.NoErrorf(t, json.Unmarshal(body, &result), "Phải parse được JSON response. Body: %s", string(body))
+	return result
+}
+
+// TestPermissionConditions kiểm tra permission kèm điều kiện (ABAC): chạy thử điều kiện, bản ghi khớp được truy cập,
+// bản ghi không khớp bị từ chối, cập nhật không được đưa bản ghi ra ngoài điều kiện, giải thích quyền theo document
+// và không được cấp permission với điều kiện rộng hơn điều kiện mình có
+func TestPermissionConditions(t *testing.T) {
+	baseURL := "http://localhost:8080/api/v1"
+	fixtures, _, adminToken, adminClient, err := utils.SetupTestWithAdminUser(t, baseURL)
+	if err != nil {
+		t.Skipf("⚠️ Không thể setup admin user: %v", err)
+	}
+
+	rootOrgID, err := fixtures.GetRootOrganizationID(adminToken)
+	require.NoError(t, err)
+
+	// Điều kiện theo describe: chỉ role có describe = marker
+	marker := fmt.Sprintf("cond_%d", time.Now().UnixNano())
+	conditions := map[string]interface{}{"describe": marker}
+	matchedRoleID, err := fixtures.CreateTestRole(adminToken, fmt.Sprintf("CondMatched_%d", time.Now().UnixNano()), marker, rootOrgID)
+	require.NoError(t, err)
+	otherRoleID, err := fixtures.CreateTestRole(adminToken, fmt.Sprintf("CondOther_%d", time.Now().UnixNano()), "other", rootOrgID)
+	require.NoError(t, err)
+
+	_, userRoleID, user, err := fixtures.CreateUserWithPermissions(adminToken, rootOrgID, []string{"RolePermission.Read", "RolePermission.Update"}, 0)
+	if err != nil {
+		t.Skipf("⚠️ Không thể tạo user với quyền (server cần bật local identity provider): %v", err)
+	}
+	permissionIDs, err := fixtures.FindPermissionIDs(adminToken, "RolePermission.Read", "RolePermission.Update", "Role.Read", "Role.Update")
+	require.NoError(t, err)
+	rolePermReadID, rolePermUpdateID, roleReadID, roleUpdateID := permissionIDs[0], permissionIDs[1], permissionIDs[2], permissionIDs[3]
+
+	// User có Role.Read, Role.Update kèm điều kiện
+	resp, body, err := adminClient.PUT("/role-permission/update-role", map[string]interface{}{
+		"roleId": userRoleID,
+		"permissions": []map[string]interface{}{
+			{"permissionId": rolePermReadID, "scope": 0},
+			{"permissionId": rolePermUpdateID, "scope": 0},
+			{"permissionId": roleReadID, "scope": 0, "conditions": conditions},
+			{"permissionId": roleUpdateID, "scope": 0, "conditions": conditions},
+		},
+	})
+	require.NoError(t, err)
+	require.Equalf(t, http.StatusOK, resp.StatusCode, "Admin phải gán được permission kèm điều kiện. Body: %s", string(body))
+
+	t.Run("🧪 Chạy thử điều kiện trên document mẫu", func(t *testing.T) {
+		resp, body, err := user.POST("/role-permission/validate-conditions", map[string]interface{}{
+			"conditions": map[string]interface{}{"describe": marker, "isTemplate": map[string]interface{}{"$ne": true}},
+			"documents":  []map[string]interface{}{{"describe": marker}},
+		})
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusBadRequest, resp.StatusCode, "Toán tử ngoài whitelist phải bị từ chối. Body: %s", string(body))
+		assert.Equal(t, "INVALID_PERMISSION_CONDITIONS", parseErrorDetails(t, body).Details.ErrorCode)
+
+		resp, body, err = user.POST("/role-permission/validate-conditions", map[string]interface{}{
+			"conditions": conditions,
+			"documents":  []map[string]interface{}{{"describe": marker}, {"describe": "other"}},
+		})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+		var result struct {
+			Data struct {
+				Valid   bool `json:"valid"`
+				Results []struct {
+					Matched bool     `json:"matched"`
+					Failed  []string `json:"failed"`
+				} `json:"results"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &result))
+		assert.True(t, result.Data.Valid)
+		require.Len(t, result.Data.Results, 2)
+		assert.True(t, result.Data.Results[0].Matched, "Document khớp điều kiện")
+		assert.False(t, result.Data.Results[1].Matched, "Document không khớp điều kiện")
+		assert.Equal(t, []string{"describe"}, result.Data.Results[1].Failed)
+	})
+
+	t.Run("✅ Bản ghi khớp điều kiện được truy cập", func(t *testing.T) {
+		resp, body, err := user.GET("/role/find-by-id/" + matchedRoleID)
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+	})
+
+	t.Run("🚫 Bản ghi không khớp điều kiện bị từ chối", func(t *testing.T) {
+		resp, body, err := user.GET("/role/find-by-id/" + otherRoleID)
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusForbidden, resp.StatusCode, "Body: %s", string(body))
+		assert.Equal(t, "PERMISSION_CONDITION_DENIED", parseErrorDetails(t, body).Details.ErrorCode)
+	})
+
+	t.Run("🚫 Cập nhật không được đưa bản ghi ra ngoài điều kiện", func(t *testing.T) {
+		resp, body, err := user.PUT("/role/update-by-id/"+matchedRoleID, map[string]interface{}{"describe": "other"})
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusForbidden, resp.StatusCode, "Body: %s", string(body))
+		assert.Equal(t, "PERMISSION_CONDITION_DENIED", parseErrorDetails(t, body).Details.ErrorCode)
+
+		resp, body, err = user.PUT("/role/update-by-id/"+matchedRoleID, map[string]interface{}{"name": fmt.Sprintf("CondRenamed_%d", time.Now().UnixNano())})
+		require.NoError(t, err)
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Cập nhật field ngoài điều kiện phải thành công. Body: %s", string(body))
+	})
+
+	// explain gọi /auth/effective-permissions ở chế độ giải thích Role.Read cho một document role
+	explain := func(documentID string) (bool, map[string]bool) {
+		query := url.Values{"permission": {"Role.Read"}, "documentId": {documentID}}
+		resp, body, err := user.GET("/auth/effective-permissions?" + query.Encode())
+		require.NoError(t, err)
+		require.Equalf(t, http.StatusOK, resp.StatusCode, "Body: %s", string(body))
+		var result struct {
+			Data struct {
+				Allowed bool `json:"allowed"`
+				Checks  []struct {
+					Check  string `json:"check"`
+					Passed bool   `json:"passed"`
+				} `json:"checks"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &result))
+		checks := make(map[string]bool, len(result.Data.Checks))
+		for _, check := range result.Data.Checks {
+			checks[check.Check] = check.Passed
+		}
+		return result.Data.Allowed, checks
+	}
+
+	t.Run("🔍 Giải thích quyền theo điều kiện của document", func(t *testing.T) {
+		allowed, checks := explain(matchedRoleID)
+		assert.True(t, allowed, "Document khớp điều kiện phải được phép")
+		assert.True(t, checks["condition"], "Phải có check condition đạt")
+
+		allowed, _ = explain(otherRoleID)
+		assert.False(t, allowed, "Document không khớp điều kiện không được phép")
+	})
+
+	t.Run("🚫 Không cấp được permission với điều kiện rộng hơn điều kiện mình có", func(t *testing.T) {
+		targetRoleID, err := fixtures.CreateTestRole(adminToken, fmt.Sprintf("CondTarget_%d", time.Now().UnixNano()), "Role nhận quyền kèm điều kiện", rootOrgID)
+		require.NoError(t, err)
+		grant := func(grantConditions map[string]interface{}) (*http.Response, []byte) {
+			item := map[string]interface{}{"permissionId": roleReadID, "scope": 0}
+			if grantConditions != nil {
+				item["conditions"] = grantConditions
+			}
+			resp, body, err := user.PUT("/role-permission/update-role", map[string]interface{}{
+				"roleId":      targetRoleID,
+				"permissions": []map[string]interface{}{item},
+			})
+			require.NoError(t, err)
+			return resp, body
+		}
+
+		for name, broader := range map[string]map[string]interface{}{
+			"không điều kiện":    nil,
+			"điều kiện rộng hơn": {"describe": map[string]interface{}{"$in": []string{marker, "other"}}},
+		} {
+			resp, body := grant(broader)
+			require.Equalf(t, http.StatusForbidden, resp.StatusCode, "Cấp %s phải bị từ chối. Body: %s", name, string(body))
+			result := parseErrorDetails(t, body)
+			assert.Equal(t, "DELEGATION_DENIED", result.Details.ErrorCode)
+			require.NotEmpty(t, result.Details.Violations)
+			assert.Equal(t, "conditions_exceeded", result.Details.Violations[0].Reason)
+		}
+
+		resp, body := grant(conditions)
+		assert.Equalf(t, http.StatusOK, resp.StatusCode, "Cấp cùng điều kiện mình có phải thành công. Body: %s", string(body))
+	})
+}
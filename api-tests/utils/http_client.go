@@ -15,6 +15,7 @@ type HTTPClient struct {
 	baseURL          string
 	token            string
 	activeRoleID     string // Header X-Active-Role-ID cho organization context
+	apiKey           string // Header X-API-Key cho service account
 }
 
 // NewHTTPClient tạo mới một HTTP client
@@ -38,6 +39,11 @@ func (c *HTTPClient) SetActiveRoleID(roleID string) {
 	c.activeRoleID = roleID
 }
 
+// SetAPIKey thiết lập API key (header X-API-Key) để xác thực như service account
+func (c *HTTPClient) SetAPIKey(apiKey string) {
+	c.apiKey = apiKey
+}
+
 // GetToken lấy token hiện tại
 func (c *HTTPClient) GetToken() string {
 	return c.token
@@ -69,6 +75,9 @@ func (c *HTTPClient) Request(method, path string, body interface{}) (*http.Respo
 	if c.token != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
 	// Thêm header X-Active-Role-ID cho organization context
 	if c.activeRoleID != "" {
 		req.Header.Set("X-Active-Role-ID", c.activeRoleID)
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// localIdentityIssuer là claim "iss" mà provider local của server chấp nhận
+const localIdentityIssuer = "meta_commerce-local"
+
+// GetTestLocalIdentitySecret lấy secret của provider local từ environment variable TEST_LOCAL_IDENTITY_SECRET
+// Phải trùng với LOCAL_IDENTITY_SECRET của server (server cần bật LOCAL_IDENTITY_ENABLED=true)
+func GetTestLocalIdentitySecret() string {
+	return os.Getenv("TEST_LOCAL_IDENTITY_SECRET")
+}
+
+// LocalIdentityClaims thông tin danh tính đưa vào token dev của provider local
+type LocalIdentityClaims struct {
+	Issuer        string // Rỗng = "meta_commerce-local"
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Phone         string
+	Name          string
+}
+
+// SignLocalIdentityToken ký token dev HS256 theo định dạng provider local của server
+// ttl <= 0 tạo token đã hết hạn (dùng để test token hết hạn)
+func SignLocalIdentityToken(secret string, claims LocalIdentityClaims, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("TEST_LOCAL_IDENTITY_SECRET environment variable không được set")
+	}
+	issuer := claims.Issuer
+	if issuer == "" {
+		issuer = localIdentityIssuer
+	}
+
+	now := time.Now()
+	payload := map[string]interface{}{
+		"iss": issuer,
+		"sub": claims.Subject,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	if claims.Email != "" {
+		payload["email"] = claims.Email
+		payload["email_verified"] = claims.EmailVerified
+	}
+	if claims.Phone != "" {
+		payload["phone_number"] = claims.Phone
+	}
+	if claims.Name != "" {
+		payload["name"] = claims.Name
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -76,14 +76,46 @@ func (tf *TestFixtures) CreateTestUser(firebaseIDToken string) (email, firebaseU
 	return email, firebaseUID, token, nil
 }
 
-// CreateTestUserDirect tạo user trực tiếp trong database (bypass Firebase) - CHỈ DÙNG CHO TEST
-// Tạo user với email và FirebaseUID giả để test nhanh hơn
-// Lưu ý: User này sẽ không thể login qua Firebase, chỉ dùng để test database operations
-// ⚠️ KHÔNG KHUYẾN NGHỊ: Hệ thống yêu cầu Firebase authentication, không thể bypass
+// CreateTestUserDirect tạo user qua identity provider "local" (bypass Firebase) - CHỈ DÙNG CHO TEST
+// Token dev được ký tại đây bằng TEST_LOCAL_IDENTITY_SECRET (server cần bật LOCAL_IDENTITY_ENABLED với cùng secret)
+// Subject của danh tính local là email nên gọi lại với cùng email sẽ trả về cùng user
 func (tf *TestFixtures) CreateTestUserDirect(email, name string) (userID, token string, err error) {
-	// Hệ thống yêu cầu Firebase authentication, không thể tạo user trực tiếp
-	// Sử dụng CreateTestUser() với Firebase ID token thay thế
-	return "", "", fmt.Errorf("không thể tạo user trực tiếp - cần Firebase authentication. Sử dụng CreateTestUser() với Firebase ID token")
+	idToken, err := SignLocalIdentityToken(GetTestLocalIdentitySecret(), LocalIdentityClaims{
+		Subject:       email,
+		Email:         email,
+		EmailVerified: true,
+		Name:          name,
+	}, time.Hour)
+	if err != nil {
+		return "", "", fmt.Errorf("lỗi ký local token: %v", err)
+	}
+
+	loginPayload := map[string]interface{}{
+		"idToken": idToken,
+		"hwid":    "test_device_123",
+	}
+	resp, body, err := tf.client.POST("/auth/login/local", loginPayload)
+	if err != nil {
+		return "", "", fmt.Errorf("lỗi đăng nhập local: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("đăng nhập local thất bại: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var loginResult struct {
+		Data struct {
+			ID    string `json:"id"`
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(body, &loginResult); err != nil {
+		return "", "", fmt.Errorf("lỗi parse response: %v", err)
+	}
+	if loginResult.Data.Token == "" {
+		return "", "", fmt.Errorf("không có token trong response")
+	}
+
+	return loginResult.Data.ID, loginResult.Data.Token, nil
 }
 
 // GetRootOrganizationID lấy Organization Root ID
@@ -661,3 +693,108 @@ func (tf *TestFixtures) assignPermissionsToRole(token, roleID string, permission
 		tf.client.POST("/role-permission/insert-one", payload)
 	}
 }
+
+// FindPermissionIDs lấy ID của các permission theo tên, lỗi nếu thiếu permission nào
+func (tf *TestFixtures) FindPermissionIDs(token string, names ...string) ([]string, error) {
+	tf.client.SetToken(token)
+
+	permissionIDs := make([]string, 0, len(names))
+	for _, permName := range names {
+		filter := fmt.Sprintf(`{"name":"%s"}`, permName)
+		resp, body, err := tf.client.GET(fmt.Sprintf("/permission/find?filter=%s", url.QueryEscape(filter)))
+		if err != nil {
+			return nil, fmt.Errorf("lỗi tìm permission %s: %v", permName, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("tìm permission %s thất bại: %d - %s", permName, resp.StatusCode, string(body))
+		}
+
+		var result struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		if err = json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("lỗi parse response: %v", err)
+		}
+		if len(result.Data) == 0 || result.Data[0].ID == "" {
+			return nil, fmt.Errorf("không tìm thấy permission %s", permName)
+		}
+		permissionIDs = append(permissionIDs, result.Data[0].ID)
+	}
+
+	return permissionIDs, nil
+}
+
+// CreateRoleWithPermissions tạo role trong organizationID và gán đúng các permission được chỉ định với scope
+func (tf *TestFixtures) CreateRoleWithPermissions(adminToken, organizationID string, permissionNames []string, scope byte) (string, error) {
+	permissionIDs, err := tf.FindPermissionIDs(adminToken, permissionNames...)
+	if err != nil {
+		return "", err
+	}
+
+	roleID, err := tf.CreateTestRole(adminToken, fmt.Sprintf("TestRole_%d", time.Now().UnixNano()), "Role test với permissions giới hạn", organizationID)
+	if err != nil {
+		return "", err
+	}
+
+	permissions := make([]map[string]interface{}, 0, len(permissionIDs))
+	for _, permID := range permissionIDs {
+		permissions = append(permissions, map[string]interface{}{
+			"permissionId": permID,
+			"scope":        scope,
+		})
+	}
+	tf.client.SetToken(adminToken)
+	resp, body, err := tf.client.PUT("/role-permission/update-role", map[string]interface{}{
+		"roleId":      roleID,
+		"permissions": permissions,
+	})
+	if err != nil {
+		return "", fmt.Errorf("lỗi gán permissions cho role: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gán permissions cho role thất bại: %d - %s", resp.StatusCode, string(body))
+	}
+	return roleID, nil
+}
+
+// AssignRole gán role cho user (hoặc service account)
+func (tf *TestFixtures) AssignRole(adminToken, userID, roleID string) error {
+	tf.client.SetToken(adminToken)
+	resp, body, err := tf.client.POST("/user-role/insert-one", map[string]interface{}{
+		"userId": userID,
+		"roleId": roleID,
+	})
+	if err != nil {
+		return fmt.Errorf("lỗi gán role cho user: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("gán role cho user thất bại: %d - %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// CreateUserWithPermissions tạo user mới (qua identity provider local) chỉ có đúng các permission được chỉ định
+// Tạo role riêng trong organizationID, gán permissions với scope, gán role cho user
+// Trả về userID, roleID và client đã set token + active role của user mới
+func (tf *TestFixtures) CreateUserWithPermissions(adminToken, organizationID string, permissionNames []string, scope byte) (userID, roleID string, client *HTTPClient, err error) {
+	roleID, err = tf.CreateRoleWithPermissions(adminToken, organizationID, permissionNames, scope)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	email := fmt.Sprintf("test_user_%d@example.com", time.Now().UnixNano())
+	userID, userToken, err := tf.CreateTestUserDirect(email, "Test User")
+	if err != nil {
+		return "", "", nil, err
+	}
+	if err = tf.AssignRole(adminToken, userID, roleID); err != nil {
+		return "", "", nil, err
+	}
+
+	client = NewHTTPClient(tf.baseURL, 10)
+	client.SetToken(userToken)
+	client.SetActiveRoleID(roleID)
+	return userID, roleID, client, nil
+}
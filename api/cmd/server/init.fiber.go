@@ -180,6 +180,7 @@ func InitFiberApp() *fiber.App {
 			"X-Request-ID",
 			"X-Requested-With",
 			"X-Active-Role-ID", // Header cho role context (quan trọng)
+			"X-API-Key",        // API key của service account
 		},
 		AllowCredentials: global.MongoDB_ServerConfig.CORS_AllowCredentials,
 		ExposeHeaders:    []string{"Content-Length", "Content-Range", "X-Request-ID"},
@@ -7,6 +7,7 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/database"
 	"meta_commerce/core/global"
+	"meta_commerce/core/identity"
 	"meta_commerce/core/utility"
 	"os"
 	"path/filepath"
@@ -18,11 +19,12 @@ import (
 
 // Hàm khởi tạo các biến toàn cục
 func InitGlobal() {
-	initColNames()         // Khởi tạo tên các collection trong database
-	initValidator()        // Khởi tạo validator
-	initConfig()           // Khởi tạo cấu hình server
-	initDatabase_MongoDB() // Khởi tạo kết nối database
-	initFirebase()         // Khởi tạo Firebase
+	initColNames()          // Khởi tạo tên các collection trong database
+	initValidator()         // Khởi tạo validator
+	initConfig()            // Khởi tạo cấu hình server
+	initDatabase_MongoDB()  // Khởi tạo kết nối database
+	initFirebase()          // Khởi tạo Firebase
+	initIdentityProviders() // Đăng ký các identity provider (firebase, oidc, local)
 }
 
 // Hàm khởi tạo tên các collection trong database
@@ -33,6 +35,18 @@ func initColNames() {
 	global.MongoDB_ColNames.RolePermissions = "auth_role_permissions"
 	global.MongoDB_ColNames.UserRoles = "auth_user_roles"
 	global.MongoDB_ColNames.Organizations = "auth_organizations"
+	global.MongoDB_ColNames.RevokedTokens = "auth_revoked_tokens"
+	global.MongoDB_ColNames.ServiceAccounts = "auth_service_accounts"
+	global.MongoDB_ColNames.ApiKeys = "auth_api_keys"
+	global.MongoDB_ColNames.OAuthClients = "auth_oauth_clients"
+	global.MongoDB_ColNames.OAuthKeys = "auth_oauth_signing_keys"
+	global.MongoDB_ColNames.OAuthCodes = "auth_oauth_codes"
+	global.MongoDB_ColNames.RoleElevations = "auth_role_elevation_requests"
+	global.MongoDB_ColNames.Invitations = "auth_organization_invitations"
+	global.MongoDB_ColNames.UserMFA = "auth_user_mfa"
+	global.MongoDB_ColNames.AuthLogs = "auth_logs"
+	global.MongoDB_ColNames.LoginAttempts = "auth_login_attempts"
+	global.MongoDB_ColNames.LoginEvents = "auth_login_events"
 	global.MongoDB_ColNames.Agents = "agents"
 	global.MongoDB_ColNames.AccessTokens = "access_tokens"
 	global.MongoDB_ColNames.FbPages = "fb_pages"
@@ -98,6 +112,18 @@ func initDatabase_MongoDB() {
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.UserRoles), models.UserRole{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RolePermissions), models.RolePermission{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.Organizations), models.Organization{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RevokedTokens), models.RevokedToken{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.ServiceAccounts), models.ServiceAccount{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.ApiKeys), models.APIKey{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.OAuthClients), models.OAuthClient{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.OAuthKeys), models.OAuthSigningKey{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.OAuthCodes), models.OAuthAuthorizationCode{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.RoleElevations), models.RoleElevationRequest{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.Invitations), models.OrganizationInvitation{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.UserMFA), models.UserMFA{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.AuthLogs), models.AuthLog{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.LoginAttempts), models.LoginAttempt{})
+	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.LoginEvents), models.LoginEvent{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.Agents), models.Agent{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.AccessTokens), models.AccessToken{})
 	database.CreateIndexes(context.TODO(), global.MongoDB_Session.Database(dbName).Collection(global.MongoDB_ColNames.FbPages), models.FbPage{})
@@ -186,3 +212,37 @@ func initFirebase() {
 
 	logrus.Info("Firebase initialized successfully")
 }
+
+// initIdentityProviders đăng ký các identity provider theo cấu hình
+// Firebase luôn được đăng ký (lỗi verify sẽ trả về nếu Firebase chưa khởi tạo),
+// OIDC chỉ được đăng ký khi có cấu hình, local chỉ khi bật cờ LOCAL_IDENTITY_ENABLED
+func initIdentityProviders() {
+	cfg := global.MongoDB_ServerConfig
+
+	providers := []identity.Provider{identity.NewFirebaseProvider()}
+	if cfg.OIDCIssuerURL != "" {
+		if cfg.OIDCClientID == "" {
+			logrus.Warn("OIDC_CLIENT_ID chưa được cấu hình, bỏ qua OIDC provider")
+		} else {
+			providers = append(providers, identity.NewOIDCProvider(cfg.OIDCProviderName, cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCJwksURL))
+		}
+	}
+	// Provider local chỉ bật khi có cờ LOCAL_IDENTITY_ENABLED tường minh - chỉ có secret là chưa đủ
+	if cfg.LocalIdentityEnabled {
+		if cfg.LocalIdentitySecret == "" {
+			logrus.Warn("LOCAL_IDENTITY_SECRET chưa được cấu hình, bỏ qua local identity provider")
+		} else {
+			logrus.Warn("Local identity provider đang bật - chỉ dùng cho dev/test, KHÔNG dùng ở production")
+			providers = append(providers, identity.NewLocalProvider(cfg.LocalIdentitySecret))
+		}
+	} else if cfg.LocalIdentitySecret != "" {
+		logrus.Warn("LOCAL_IDENTITY_SECRET được cấu hình nhưng LOCAL_IDENTITY_ENABLED=false, local identity provider không được bật")
+	}
+
+	for _, p := range providers {
+		if err := identity.Register(p); err != nil {
+			logrus.Errorf("Failed to register identity provider: %v", err)
+		}
+	}
+	logrus.Infof("Identity providers: %v", identity.Names())
+}
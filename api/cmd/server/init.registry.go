@@ -23,7 +23,7 @@ func InitRegistry() {
 // InitCollections khởi tạo và đăng ký các collections MongoDB
 func InitCollections(client *mongo.Client, cfg *config.Configuration) error {
 	db := client.Database(cfg.MongoDB_DBName_Auth)
-	colNames := []string{"auth_users", "auth_permissions", "auth_roles", "auth_role_permissions", "auth_user_roles", "auth_organizations",
+	colNames := []string{"auth_users", "auth_permissions", "auth_roles", "auth_role_permissions", "auth_user_roles", "auth_organizations", "auth_revoked_tokens", "auth_service_accounts", "auth_api_keys", "auth_oauth_clients", "auth_oauth_signing_keys", "auth_oauth_codes", "auth_role_elevation_requests", "auth_organization_invitations", "auth_user_mfa", "auth_logs", "auth_login_attempts", "auth_login_events",
 		"agents", "access_tokens", "fb_pages", "fb_conversations", "fb_messages", "fb_message_items", "fb_posts", "fb_customers", "pc_orders", "customers", "pc_pos_customers", "pc_pos_shops", "pc_pos_warehouses", "pc_pos_products", "pc_pos_variations", "pc_pos_categories", "pc_pos_orders",
 		"notification_senders", "notification_channels", "notification_templates", "notification_routing_rules", "notification_queue", "notification_history"}
 
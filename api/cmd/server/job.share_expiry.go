@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+)
+
+// EventTypeOrganizationShareExpired là eventType notification khi share hết hạn
+const EventTypeOrganizationShareExpired = "organization_share_expired"
+
+// runShareExpiryJob định kỳ đánh dấu expired cho share quá expiresAt và gửi notification event
+func runShareExpiryJob(ctx context.Context, interval time.Duration) {
+	log := logger.GetAppLogger()
+	shareService, err := services.NewOrganizationShareService()
+	if err != nil {
+		log.WithError(err).Error("Failed to create organization share service, share expiry job disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expireShares(ctx, shareService)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireShares chạy một lượt: đánh dấu share hết hạn và gửi notification cho từng share
+func expireShares(ctx context.Context, shareService *services.OrganizationShareService) {
+	log := logger.GetAppLogger()
+	shares, err := shareService.ExpireShares(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to expire organization shares")
+		return
+	}
+
+	for _, share := range shares {
+		payload := map[string]interface{}{
+			"shareId":             share.ID.Hex(),
+			"ownerOrganizationId": share.OwnerOrganizationID.Hex(),
+			"toOrgId":             share.ToOrgID.Hex(),
+			"permissionNames":     share.PermissionNames,
+			"documentFilter":      share.DocumentFilter,
+			"note":                share.Note,
+			"expiresAt":           *share.ExpiresAt,
+		}
+		if _, err := notification.Trigger(ctx, EventTypeOrganizationShareExpired, payload); err != nil {
+			log.WithError(err).WithField("shareId", share.ID.Hex()).Warn("Failed to trigger share expired notification")
+		}
+	}
+	if len(shares) > 0 {
+		log.WithField("count", len(shares)).Info("Organization shares expired")
+	}
+}
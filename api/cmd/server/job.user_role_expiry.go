@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventTypeUserRoleExpired là eventType notification khi role assignment có thời hạn hết hạn và bị gỡ
+const EventTypeUserRoleExpired = "user_role_expired"
+
+// runUserRoleExpiryJob định kỳ gỡ role assignment quá validUntil, đánh dấu yêu cầu nâng quyền expired và gửi notification event
+func runUserRoleExpiryJob(ctx context.Context, interval time.Duration) {
+	log := logger.GetAppLogger()
+	userRoleService, err := services.NewUserRoleService()
+	if err != nil {
+		log.WithError(err).Error("Failed to create user role service, user role expiry job disabled")
+		return
+	}
+	roleElevationService, err := services.NewRoleElevationService()
+	if err != nil {
+		log.WithError(err).Error("Failed to create role elevation service, user role expiry job disabled")
+		return
+	}
+	userService, err := services.NewUserService()
+	if err != nil {
+		log.WithError(err).Error("Failed to create user service, user role expiry job disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastRun := utility.CurrentTimeInMilli()
+	for {
+		now := utility.CurrentTimeInMilli()
+		// Assignment tới validFrom không phát sinh thay đổi dữ liệu → báo để cache quyền được làm mới
+		if err := userRoleService.RefreshActivatedUserRoles(ctx, lastRun, now); err != nil {
+			log.WithError(err).Warn("Failed to refresh permissions of activated user roles")
+		}
+		lastRun = now
+		expireUserRoles(ctx, userRoleService, roleElevationService, userService)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireUserRoles chạy một lượt: gỡ role assignment hết hạn và gửi notification cho từng assignment
+func expireUserRoles(ctx context.Context, userRoleService *services.UserRoleService, roleElevationService *services.RoleElevationService, userService *services.UserService) {
+	log := logger.GetAppLogger()
+	userRoles, err := userRoleService.ExpireUserRoles(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to expire user roles")
+		return
+	}
+
+	requestIDs := make([]primitive.ObjectID, 0)
+	for _, userRole := range userRoles {
+		if userRole.ElevationRequestID != nil {
+			requestIDs = append(requestIDs, *userRole.ElevationRequestID)
+		}
+	}
+	if err := roleElevationService.MarkExpired(ctx, requestIDs); err != nil {
+		log.WithError(err).Warn("Failed to mark role elevation requests expired")
+	}
+
+	for _, userRole := range userRoles {
+		payload := map[string]interface{}{
+			"userRoleId": userRole.ID.Hex(),
+			"userId":     userRole.UserID.Hex(),
+			"roleId":     userRole.RoleID.Hex(),
+			"validUntil": *userRole.ValidUntil,
+		}
+		if userRole.ElevationRequestID != nil {
+			payload["requestId"] = userRole.ElevationRequestID.Hex()
+		}
+		if _, err := notification.Trigger(ctx, EventTypeUserRoleExpired, payload); err != nil {
+			log.WithError(err).WithField("userRoleId", userRole.ID.Hex()).Warn("Failed to trigger user role expired notification")
+		}
+		// Báo cho chính user (service account không có email nên bỏ qua)
+		if user, err := userService.FindOneById(ctx, userRole.UserID); err == nil && user.Email != "" {
+			if _, err := notification.TriggerForRecipient(ctx, EventTypeUserRoleExpired, user.Email, payload); err != nil {
+				log.WithError(err).WithField("userRoleId", userRole.ID.Hex()).Warn("Failed to send user role expired notification to user")
+			}
+		}
+	}
+	if len(userRoles) > 0 {
+		log.WithField("count", len(userRoles)).Info("User roles expired")
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 
+	"meta_commerce/core/api/services"
 	"meta_commerce/core/global"
 	"meta_commerce/core/logger"
 	"meta_commerce/core/notification"
@@ -167,6 +169,30 @@ func main() {
 		log.Info("Notification Processor started successfully")
 	}
 
+	// Theo dõi thay đổi quyền qua MongoDB change stream (đồng bộ cache quyền giữa nhiều instance)
+	if global.MongoDB_ServerConfig.PermissionCacheWatch {
+		watchCtx, watchCancel := context.WithCancel(context.Background())
+		defer watchCancel()
+
+		go services.WatchPermissionChanges(watchCtx)
+	}
+
+	// Job đánh dấu share hết hạn và gửi notification
+	if interval := global.MongoDB_ServerConfig.ShareExpiryCheckInterval; interval > 0 {
+		jobCtx, jobCancel := context.WithCancel(context.Background())
+		defer jobCancel()
+
+		go runShareExpiryJob(jobCtx, time.Duration(interval)*time.Second)
+	}
+
+	// Job gỡ role assignment có thời hạn khi hết hạn và gửi notification
+	if interval := global.MongoDB_ServerConfig.UserRoleExpiryCheckInterval; interval > 0 {
+		jobCtx, jobCancel := context.WithCancel(context.Background())
+		defer jobCancel()
+
+		go runUserRoleExpiryJob(jobCtx, time.Duration(interval)*time.Second)
+	}
+
 	// Chạy Fiber server trên main thread
 	main_thread()
 }
@@ -15,6 +15,9 @@ type Configuration struct {
 	InitMode               bool   `env:"INITMODE" envDefault:"false"`               // Chế độ khởi tạo
 	Address                string `env:"ADDRESS" envDefault:":8080"`                // Địa chỉ server
 	JwtSecret              string `env:"JWT_SECRET,required"`                       // Bí mật JWT
+	JwtAccessTTL           int    `env:"JWT_ACCESS_TTL" envDefault:"900"`           // Thời gian sống của access token (giây)
+	JwtRefreshTTL          int    `env:"JWT_REFRESH_TTL" envDefault:"2592000"`      // Thời gian sống của refresh token (giây)
+	ImpersonationTokenTTL  int    `env:"IMPERSONATION_TOKEN_TTL" envDefault:"900"`  // Thời gian sống của token "login as" (giây)
 	MongoDB_ConnectionURI  string `env:"MONGODB_CONNECTION_URI,required"`           // URL kết nối cơ sở dữ liệu
 	MongoDB_DBName_Auth    string `env:"MONGODB_DBNAME_AUTH,required"`              // Tên cơ sở dữ liệu xác thực
 	MongoDB_DBName_Staging string `env:"MONGODB_DBNAME_STAGING,required"`           // Tên cơ sở dữ liệu staging
@@ -24,11 +27,52 @@ type Configuration struct {
 	RateLimit_Max          int    `env:"RATE_LIMIT_MAX" envDefault:"100"`           // Số request tối đa trong window (0 = disable rate limit)
 	RateLimit_Window       int    `env:"RATE_LIMIT_WINDOW" envDefault:"60"`         // Thời gian window (giây)
 	RateLimit_Enabled      bool   `env:"RATE_LIMIT_ENABLED" envDefault:"true"`      // Bật/tắt rate limiting
+	// Permission cache
+	PermissionCacheWatch bool `env:"PERMISSION_CACHE_WATCH" envDefault:"false"` // Theo dõi change stream để đồng bộ cache quyền giữa nhiều instance (cần replica set)
+	// Organization lifecycle
+	OrganizationPurgeRetentionDays int `env:"ORGANIZATION_PURGE_RETENTION_DAYS" envDefault:"30"`  // Số ngày tối thiểu từ khi lưu trữ tổ chức đến khi được xóa vĩnh viễn (purge)
+	ShareExpiryCheckInterval       int `env:"SHARE_EXPIRY_CHECK_INTERVAL" envDefault:"300"`       // Chu kỳ (giây) job đánh dấu share hết hạn và gửi notification (0 = tắt)
+	OrganizationInvitationTTLHours int `env:"ORGANIZATION_INVITATION_TTL_HOURS" envDefault:"168"` // Thời hạn mặc định (giờ) của link mời vào tổ chức
+	// Role assignment có thời hạn / nâng quyền tạm thời (JIT)
+	RoleElevationMaxHours       int `env:"ROLE_ELEVATION_MAX_HOURS" envDefault:"72"`        // Số giờ tối đa của một yêu cầu nâng quyền tạm thời
+	UserRoleExpiryCheckInterval int `env:"USER_ROLE_EXPIRY_CHECK_INTERVAL" envDefault:"60"` // Chu kỳ (giây) job gỡ role assignment hết hạn và gửi notification (0 = tắt)
+	// Xác thực 2 lớp (TOTP) và step-up cho thao tác nhạy cảm
+	MfaIssuer            string `env:"MFA_ISSUER" envDefault:"Meta Commerce"`     // Tên hiển thị trong ứng dụng authenticator
+	MfaEncryptionKey     string `env:"MFA_ENCRYPTION_KEY"`                        // Key AES-256 (base64, 32 byte) mã hóa secret TOTP (rỗng = dẫn xuất từ JWT_SECRET)
+	MfaStepUpPermissions string `env:"MFA_STEP_UP_PERMISSIONS" envDefault:""`     // Các permission cần token có claim MFA còn mới (phân cách bởi dấu phẩy, rỗng = không bắt buộc step-up)
+	MfaStepUpMaxAge      int    `env:"MFA_STEP_UP_MAX_AGE" envDefault:"300"`      // Số giây claim MFA được coi là còn mới
+	MfaMaxFailedAttempts int    `env:"MFA_MAX_FAILED_ATTEMPTS" envDefault:"5"`    // Số lần nhập sai mã xác thực 2 lớp liên tiếp của một user trước khi bị khóa (0 = tắt), thời gian khóa theo LOGIN_LOCKOUT_*
+	MfaEnrollMaxLoginAge int    `env:"MFA_ENROLL_MAX_LOGIN_AGE" envDefault:"600"` // Số giây tối đa từ lần đăng nhập của phiên đến khi đăng ký TOTP (quá thì phải đăng nhập lại, 0 = không kiểm tra)
+	// Audit trail (bản ghi auth_logs cho mọi thao tác ghi và thay đổi phân quyền)
+	AuditLogEnabled bool `env:"AUDIT_LOG_ENABLED" envDefault:"true"` // Bật ghi audit trail vào database
+	// Các collection không ghi audit (phân cách bởi dấu phẩy), mặc định bỏ qua notification và dữ liệu đồng bộ từ Facebook/Pancake (ghi số lượng lớn)
+	AuditLogExcludeCollections string `env:"AUDIT_LOG_EXCLUDE_COLLECTIONS" envDefault:"notification_queue,notification_history,fb_conversations,fb_messages,fb_message_items,fb_posts,fb_customers,pc_orders,pc_pos_customers,pc_pos_products,pc_pos_variations,pc_pos_categories,pc_pos_orders"`
+	AuditLogRetentionDays      int    `env:"AUDIT_LOG_RETENTION_DAYS" envDefault:"365"` // Số ngày giữ bản ghi audit (0 = giữ vĩnh viễn)
+	// Chống dò đăng nhập (brute-force) và lịch sử đăng nhập
+	LoginMaxFailedAttempts      int `env:"LOGIN_MAX_FAILED_ATTEMPTS" envDefault:"5"`         // Số lần thất bại liên tiếp của một danh tính đã xác thực trước khi bị khóa (0 = tắt), provider dạng token chỉ đếm theo IP
+	LoginMaxFailedAttemptsPerIP int `env:"LOGIN_MAX_FAILED_ATTEMPTS_PER_IP" envDefault:"20"` // Số lần thất bại liên tiếp từ một IP trước khi bị khóa (0 = tắt)
+	LoginFailureWindow          int `env:"LOGIN_FAILURE_WINDOW" envDefault:"900"`            // Số giây không có lần thất bại nào thì bộ đếm bắt đầu lại
+	LoginLockoutBaseSeconds     int `env:"LOGIN_LOCKOUT_BASE_SECONDS" envDefault:"60"`       // Thời gian khóa lần đầu (giây), mỗi lần khóa tiếp theo gấp đôi
+	LoginLockoutMaxSeconds      int `env:"LOGIN_LOCKOUT_MAX_SECONDS" envDefault:"3600"`      // Thời gian khóa tối đa (giây)
+	LoginEventRetentionDays     int `env:"LOGIN_EVENT_RETENTION_DAYS" envDefault:"90"`       // Số ngày giữ lịch sử đăng nhập (0 = giữ vĩnh viễn)
 	// Firebase Configuration
 	FirebaseProjectID       string `env:"FIREBASE_PROJECT_ID"`       // Firebase Project ID
 	FirebaseCredentialsPath string `env:"FIREBASE_CREDENTIALS_PATH"` // Đường dẫn đến service account JSON
 	FirebaseAPIKey          string `env:"FIREBASE_API_KEY"`          // Firebase Web API Key (cho frontend)
 	FirebaseAdminUID        string `env:"FIREBASE_ADMIN_UID"`        // Firebase UID của user admin (tự động tạo admin user trong init)
+	// Identity Providers (ngoài Firebase)
+	OIDCProviderName     string `env:"OIDC_PROVIDER_NAME" envDefault:"oidc"`      // Tên provider OIDC (dùng trong route /auth/login/:provider)
+	OIDCIssuerURL        string `env:"OIDC_ISSUER_URL"`                           // Issuer URL của OIDC provider (rỗng = tắt)
+	OIDCClientID         string `env:"OIDC_CLIENT_ID"`                            // Client ID - giá trị claim "aud" bắt buộc của id_token
+	OIDCJwksURL          string `env:"OIDC_JWKS_URL"`                             // JWKS URL (rỗng = lấy từ {issuer}/.well-known/openid-configuration)
+	LocalIdentityEnabled bool   `env:"LOCAL_IDENTITY_ENABLED" envDefault:"false"` // Bật provider "local" cho dev/test (cần thêm LOCAL_IDENTITY_SECRET, KHÔNG bật ở production)
+	LocalIdentitySecret  string `env:"LOCAL_IDENTITY_SECRET"`                     // Secret ký token dev của provider "local"
+	// OAuth2/OIDC Issuer (hệ thống phát hành token cho các dịch vụ khác)
+	OAuthIssuerURL       string `env:"OAUTH_ISSUER_URL" envDefault:"http://localhost:8080/api/v1"` // Issuer URL (claim "iss"), discovery tại {issuer}/.well-known/openid-configuration
+	OAuthAccessTokenTTL  int    `env:"OAUTH_ACCESS_TOKEN_TTL" envDefault:"3600"`                   // Thời hạn (giây) access token RS256
+	OAuthIDTokenTTL      int    `env:"OAUTH_ID_TOKEN_TTL" envDefault:"3600"`                       // Thời hạn (giây) ID token
+	OAuthCodeTTL         int    `env:"OAUTH_CODE_TTL" envDefault:"300"`                            // Thời hạn (giây) authorization code
+	OAuthKeyRotationDays int    `env:"OAUTH_KEY_ROTATION_DAYS" envDefault:"90"`                    // Tự động xoay vòng khóa ký sau số ngày này (0 = chỉ xoay vòng thủ công)
 	// Frontend URL
 	FrontendURL string `env:"FRONTEND_URL" envDefault:"http://localhost:3000"` // URL frontend
 	// TLS/HTTPS Configuration
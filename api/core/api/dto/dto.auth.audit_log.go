@@ -0,0 +1,13 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AuditLogFilter điều kiện lọc audit trail (GET /audit-log)
+type AuditLogFilter struct {
+	Collection string             // Tên collection
+	DocumentID primitive.ObjectID // ID document bị thay đổi
+	ActorID    primitive.ObjectID // ID người thực hiện
+	Action     string             // Hành động (insert, update, delete...)
+	From       int64              // Từ thời điểm (ms, bao gồm)
+	To         int64              // Đến thời điểm (ms, bao gồm)
+}
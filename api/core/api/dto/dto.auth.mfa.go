@@ -0,0 +1,31 @@
+package dto
+
+// MFACodeInput đầu vào chứa mã TOTP 6 chữ số (xác nhận đăng ký, tạo lại recovery code)
+type MFACodeInput struct {
+	Code string `json:"code" validate:"required"` // Mã TOTP từ ứng dụng authenticator - BẮT BUỘC
+}
+
+// MFAVerifyInput đầu vào xác thực 2 lớp: mã TOTP hoặc một recovery code chưa dùng
+type MFAVerifyInput struct {
+	Code         string `json:"code,omitempty"`         // Mã TOTP 6 chữ số - Optional nếu có recoveryCode
+	RecoveryCode string `json:"recoveryCode,omitempty"` // Recovery code (mỗi code chỉ dùng được một lần) - Optional nếu có code
+}
+
+// MFAEnrollOutput kết quả bắt đầu đăng ký TOTP
+type MFAEnrollOutput struct {
+	Secret     string `json:"secret"`     // Secret base32 (nhập tay vào ứng dụng authenticator)
+	OtpauthURL string `json:"otpauthUrl"` // URI otpauth:// để hiển thị mã QR
+}
+
+// MFARecoveryCodesOutput danh sách recovery code (chỉ trả về một lần)
+type MFARecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFAStepUpOutput access token mới của phiên, có claim MFA
+type MFAStepUpOutput struct {
+	Token          string `json:"token"`          // Access token mới (thay access token đang dùng)
+	TokenExpiresAt int64  `json:"tokenExpiresAt"` // Thời điểm hết hạn access token (Unix giây)
+	MfaAt          int64  `json:"mfaAt"`          // Thời điểm xác thực (Unix giây)
+	MfaExpiresAt   int64  `json:"mfaExpiresAt"`   // Claim MFA hết "mới" sau thời điểm này (Unix giây) - cần xác thực lại
+}
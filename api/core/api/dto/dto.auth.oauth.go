@@ -0,0 +1,90 @@
+package dto
+
+// OAuthClientCreateInput dùng cho đăng ký OAuth client (tầng transport)
+// Lưu ý: Backend parse trực tiếp vào Model, nhưng DTO này dùng để Frontend biết cấu trúc cần gửi
+type OAuthClientCreateInput struct {
+	OwnerOrganizationID string   `json:"ownerOrganizationId,omitempty"` // Tổ chức sở hữu - Optional (mặc định là tổ chức của role đang làm việc)
+	Name                string   `json:"name" validate:"required"`      // Tên ứng dụng - BẮT BUỘC
+	Describe            string   `json:"describe,omitempty"`            // Mô tả - Optional
+	IsPublic            bool     `json:"isPublic,omitempty"`            // true = client public (không có secret, bắt buộc PKCE) - Optional
+	RedirectURIs        []string `json:"redirectUris,omitempty"`        // Redirect URI hợp lệ - BẮT BUỘC nếu dùng authorization_code
+	GrantTypes          []string `json:"grantTypes"`                    // authorization_code, client_credentials - BẮT BUỘC
+	Scopes              []string `json:"scopes,omitempty"`              // Scope bổ sung ngoài openid/profile/email - Optional
+	ServiceAccountID    string   `json:"serviceAccountId,omitempty"`    // Service account làm principal cho client_credentials - BẮT BUỘC nếu dùng client_credentials
+	RoleID              string   `json:"roleId,omitempty"`              // Role của service account đưa vào claims - Optional
+}
+
+// OAuthClientUpdateInput dùng cho cập nhật OAuth client (tầng transport)
+// clientId, secret và tổ chức sở hữu không thể đổi qua API này
+type OAuthClientUpdateInput struct {
+	Name             *string  `json:"name,omitempty"`             // Tên ứng dụng - Optional
+	Describe         *string  `json:"describe,omitempty"`         // Mô tả - Optional
+	RedirectURIs     []string `json:"redirectUris,omitempty"`     // Thay toàn bộ danh sách redirect URI - Optional
+	GrantTypes       []string `json:"grantTypes,omitempty"`       // Thay toàn bộ danh sách grant type - Optional
+	Scopes           []string `json:"scopes,omitempty"`           // Thay toàn bộ danh sách scope - Optional
+	ServiceAccountID *string  `json:"serviceAccountId,omitempty"` // "" = gỡ service account - Optional
+	RoleID           *string  `json:"roleId,omitempty"`           // "" = gỡ role - Optional
+	IsDisabled       *bool    `json:"isDisabled,omitempty"`       // true = từ chối cấp token - Optional
+}
+
+// OAuthAuthorizeInput đầu vào cấp authorization code cho user đang đăng nhập (POST /oauth/authorize)
+// Frontend hiển thị màn hình đồng ý rồi gọi endpoint này thay user; role đang làm việc lấy từ header X-Active-Role-ID
+type OAuthAuthorizeInput struct {
+	ClientID            string `json:"clientId" validate:"required"`     // client_id
+	RedirectURI         string `json:"redirectUri" validate:"required"`  // Phải khớp chính xác một redirect URI đã đăng ký
+	ResponseType        string `json:"responseType" validate:"required"` // Chỉ hỗ trợ "code"
+	Scope               string `json:"scope"`                            // Scope yêu cầu, cách nhau bởi dấu cách (vd: "openid profile email")
+	State               string `json:"state"`                            // Trả lại nguyên vẹn cho client
+	Nonce               string `json:"nonce"`                            // Đưa vào ID token
+	CodeChallenge       string `json:"codeChallenge"`                    // PKCE - BẮT BUỘC với client public
+	CodeChallengeMethod string `json:"codeChallengeMethod"`              // PKCE - chỉ hỗ trợ S256
+}
+
+// OAuthAuthorizeOutput kết quả cấp authorization code
+type OAuthAuthorizeOutput struct {
+	Code        string `json:"code"`        // Authorization code (dùng một lần)
+	State       string `json:"state"`       // state của yêu cầu
+	RedirectURL string `json:"redirectUrl"` // redirectUri kèm code và state - frontend chuyển hướng tới URL này
+}
+
+// OAuthTokenInput đầu vào của token endpoint (POST /oauth/token)
+// Nhận application/x-www-form-urlencoded theo RFC 6749 (handler tự điền từ form hoặc JSON)
+type OAuthTokenInput struct {
+	GrantType    string `json:"grant_type"`    // authorization_code | client_credentials
+	Code         string `json:"code"`          // authorization_code: code nhận được
+	RedirectURI  string `json:"redirect_uri"`  // authorization_code: phải trùng redirect_uri lúc authorize
+	CodeVerifier string `json:"code_verifier"` // authorization_code: PKCE verifier
+	ClientID     string `json:"client_id"`     // client_id (hoặc qua HTTP Basic)
+	ClientSecret string `json:"client_secret"` // client_secret (hoặc qua HTTP Basic), rỗng với client public
+	Scope        string `json:"scope"`         // client_credentials: scope yêu cầu
+}
+
+// OAuthTokenOutput response của token endpoint theo RFC 6749
+type OAuthTokenOutput struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthErrorOutput lỗi của token endpoint theo RFC 6749 mục 5.2
+type OAuthErrorOutput struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthJSONWebKey là một khóa công khai trong JWKS (RFC 7517)
+type OAuthJSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// OAuthJWKS là bộ khóa công khai công bố tại /oauth/jwks
+type OAuthJWKS struct {
+	Keys []OAuthJSONWebKey `json:"keys"`
+}
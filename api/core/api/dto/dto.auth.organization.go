@@ -17,3 +17,8 @@ type OrganizationUpdateInput struct {
 	ParentID string `json:"parentId,omitempty"` // ID tổ chức cha (dạng string ObjectID)
 	IsActive *bool  `json:"isActive,omitempty"` // Trạng thái hoạt động (dùng pointer để phân biệt false và không cập nhật)
 }
+
+// OrganizationMoveInput dữ liệu đầu vào khi di chuyển tổ chức sang tổ chức cha khác (tầng transport)
+type OrganizationMoveInput struct {
+	NewParentID string `json:"newParentId" validate:"required"` // ID tổ chức cha mới (bắt buộc, dạng string ObjectID)
+}
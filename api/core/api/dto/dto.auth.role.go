@@ -7,7 +7,11 @@ type RoleCreateInput struct {
 	Name                string `json:"name" validate:"required"`      // Tên của vai trò - BẮT BUỘC
 	Describe            string `json:"describe" validate:"required"`  // Mô tả vai trò - BẮT BUỘC
 	OwnerOrganizationID string `json:"ownerOrganizationId,omitempty"` // Tổ chức sở hữu dữ liệu (phân quyền) - Optional, nếu không có → dùng context
+	IsTemplate          bool   `json:"isTemplate,omitempty"`          // Role mẫu (chỉ tạo ở tổ chức hệ thống/tập đoàn) - Optional
+	ParentRoleID        string `json:"parentRoleId,omitempty"`        // Role cha để kế thừa quyền (cùng tổ chức hoặc tổ chức cấp trên) - Optional
+	TemplateID          string `json:"templateId,omitempty"`          // Template để kế thừa quyền - Optional, nên dùng POST /role/:id/instantiate
 	// Lưu ý: Nếu có ownerOrganizationId trong request, backend sẽ validate quyền với organization đó
+	// Lưu ý: Gán parentRoleId/templateId = cấp cho role toàn bộ quyền của role cha/template, backend kiểm tra người thực hiện có thể cấp các quyền này
 }
 
 // RoleUpdateInput dùng cho cập nhật vai trò (tầng transport)
@@ -17,5 +21,14 @@ type RoleUpdateInput struct {
 	Name                string `json:"name"`                          // Tên của vai trò - Optional
 	Describe            string `json:"describe"`                      // Mô tả vai trò - Optional
 	OwnerOrganizationID string `json:"ownerOrganizationId,omitempty"` // Tổ chức sở hữu dữ liệu (phân quyền) - Optional, có thể update với validation quyền
+	IsTemplate          *bool  `json:"isTemplate,omitempty"`          // Role mẫu - Optional, không bỏ được khi còn role khởi tạo từ template
+	ParentRoleID        string `json:"parentRoleId,omitempty"`        // Role cha - Optional, gửi null để gỡ liên kết
 	// Lưu ý: Nếu update ownerOrganizationId, backend sẽ validate quyền với organization mới và document hiện tại
+	// Lưu ý: Tách role khỏi template dùng POST /role/:id/detach-template
+}
+
+// RoleInstantiateInput dùng cho khởi tạo role từ template cho các tổ chức (tầng transport)
+type RoleInstantiateInput struct {
+	OrganizationIDs []string `json:"organizationIds" validate:"required,min=1,max=100"` // Các tổ chức con của tổ chức sở hữu template - BẮT BUỘC
+	Name            string   `json:"name,omitempty"`                                    // Tên role - Optional, mặc định là tên template
 }
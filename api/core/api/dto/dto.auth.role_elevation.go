@@ -0,0 +1,13 @@
+package dto
+
+// RoleElevationRequestInput đầu vào khi user xin nâng quyền tạm thời (POST /elevation-request)
+type RoleElevationRequestInput struct {
+	RoleID        string `json:"roleId" validate:"required"`        // Role cần xin - BẮT BUỘC
+	DurationHours int    `json:"durationHours" validate:"required"` // Số giờ giữ role kể từ lúc duyệt (tối đa ROLE_ELEVATION_MAX_HOURS) - BẮT BUỘC
+	Justification string `json:"justification" validate:"required"` // Lý do xin quyền - BẮT BUỘC
+}
+
+// RoleElevationDecisionInput đầu vào khi duyệt/từ chối yêu cầu nâng quyền
+type RoleElevationDecisionInput struct {
+	Note string `json:"note,omitempty"` // Ghi chú của người duyệt - Optional
+}
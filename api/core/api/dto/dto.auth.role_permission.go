@@ -2,15 +2,17 @@ package dto
 
 // RolePermissionCreateInput đại diện cho dữ liệu đầu vào khi tạo quyền vai trò
 type RolePermissionCreateInput struct {
-	RoleID       string `json:"roleId" validate:"required"`       // ID của vai trò
-	PermissionID string `json:"permissionId" validate:"required"` // ID của quyền
-	Scope        byte   `json:"scope"`                            // Phạm vi của quyền (0: Chỉ tổ chức role thuộc về - default, 1: Tổ chức đó và tất cả các tổ chức con)
+	RoleID       string                 `json:"roleId" validate:"required"`       // ID của vai trò
+	PermissionID string                 `json:"permissionId" validate:"required"` // ID của quyền
+	Scope        byte                   `json:"scope" validate:"lte=2"`           // Phạm vi của quyền (0: Chỉ tổ chức role thuộc về - default, 1: Tổ chức đó và tất cả các tổ chức con, 2: Chỉ bản ghi của mình)
+	Conditions   map[string]interface{} `json:"conditions,omitempty"`             // Điều kiện trên document (rỗng = không giới hạn)
 }
 
 // RolePermissionUpdateItem đại diện cho một permission trong danh sách cập nhật
 type RolePermissionUpdateItem struct {
-	PermissionID string `json:"permissionId" validate:"required"` // ID của quyền
-	Scope        byte   `json:"scope"`                            // Phạm vi của quyền (0: Chỉ tổ chức role thuộc về - default, 1: Tổ chức đó và tất cả các tổ chức con)
+	PermissionID string                 `json:"permissionId" validate:"required"` // ID của quyền
+	Scope        byte                   `json:"scope" validate:"lte=2"`           // Phạm vi của quyền (0: Chỉ tổ chức role thuộc về - default, 1: Tổ chức đó và tất cả các tổ chức con, 2: Chỉ bản ghi của mình)
+	Conditions   map[string]interface{} `json:"conditions,omitempty"`             // Điều kiện trên document (rỗng = không giới hạn)
 }
 
 // RolePermissionUpdateInput dữ liệu đầu vào khi cập nhật quyền của vai trò
@@ -18,3 +20,9 @@ type RolePermissionUpdateInput struct {
 	RoleId      string                     `json:"roleId" validate:"required"`      // ID của vai trò
 	Permissions []RolePermissionUpdateItem `json:"permissions" validate:"required"` // Danh sách quyền với scope
 }
+
+// RolePermissionConditionDryRunInput dữ liệu đầu vào khi chạy thử điều kiện của role permission trên document mẫu
+type RolePermissionConditionDryRunInput struct {
+	Conditions map[string]interface{}   `json:"conditions"`                                  // Điều kiện cần kiểm tra
+	Documents  []map[string]interface{} `json:"documents" validate:"required,min=1,max=100"` // Document mẫu (tên field theo MongoDB)
+}
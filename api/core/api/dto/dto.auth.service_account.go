@@ -0,0 +1,31 @@
+package dto
+
+// ServiceAccountCreateInput dùng cho tạo service account (tầng transport)
+// Lưu ý: Backend parse trực tiếp vào Model, nhưng DTO này dùng để Frontend biết cấu trúc cần gửi
+type ServiceAccountCreateInput struct {
+	OwnerOrganizationID string `json:"ownerOrganizationId,omitempty"` // Tổ chức sở hữu - Optional (mặc định là tổ chức của role đang làm việc)
+	Name                string `json:"name" validate:"required"`      // Tên service account - BẮT BUỘC
+	Describe            string `json:"describe,omitempty"`            // Mô tả mục đích sử dụng - Optional
+	AgentID             string `json:"agentId,omitempty"`             // Trợ lý (bot) sử dụng service account - Optional
+}
+
+// ServiceAccountUpdateInput dùng cho cập nhật service account (tầng transport)
+type ServiceAccountUpdateInput struct {
+	Name       string `json:"name,omitempty"`       // Tên service account - Optional
+	Describe   string `json:"describe,omitempty"`   // Mô tả - Optional
+	IsDisabled *bool  `json:"isDisabled,omitempty"` // true = vô hiệu hóa mọi API key - Optional
+}
+
+// APIKeyCreateInput dùng cho tạo API key cho service account (tầng transport)
+type APIKeyCreateInput struct {
+	ServiceAccountID string   `json:"serviceAccountId,omitempty"` // Service account sở hữu key - lấy từ path :id
+	Name             string   `json:"name" validate:"required"`   // Tên gợi nhớ của key - BẮT BUỘC
+	Scopes           []string `json:"scopes,omitempty"`           // [] hoặc null = mọi permission của service account, ["Order.Read"] = chỉ các permission này - Optional
+	AllowedIPs       []string `json:"allowedIps,omitempty"`       // [] hoặc null = mọi IP, ["1.2.3.4", "10.0.0.0/8"] - Optional
+	ExpiresAt        *int64   `json:"expiresAt,omitempty"`        // Thời điểm hết hạn (milliseconds), null = không hết hạn - Optional
+}
+
+// APIKeyRotateInput dùng cho xoay vòng API key (tầng transport)
+type APIKeyRotateInput struct {
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds,omitempty"` // Số giây key cũ còn hiệu lực sau khi xoay vòng, 0 = thu hồi ngay - Optional
+}
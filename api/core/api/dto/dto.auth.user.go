@@ -37,6 +37,41 @@ type UnBlockUserInput struct {
 
 // FirebaseLoginInput đầu vào đăng nhập bằng Firebase ID token
 type FirebaseLoginInput struct {
-	IDToken string `json:"idToken" validate:"required"` // Firebase ID token
-	Hwid    string `json:"hwid" validate:"required"`    // Device hardware ID
+	IDToken         string `json:"idToken" validate:"required"` // Firebase ID token
+	Hwid            string `json:"hwid" validate:"required"`    // Device hardware ID
+	InvitationToken string `json:"invitationToken,omitempty"`   // Token trong link mời vào tổ chức - Optional (chấp nhận lời mời khi đăng nhập)
+	IP              string `json:"-"`                           // IP của request (handler tự điền)
+	UserAgent       string `json:"-"`                           // User-Agent của request (handler tự điền)
+}
+
+// IdentityLoginInput đầu vào đăng nhập bằng token của identity provider (POST /auth/login/:provider)
+type IdentityLoginInput struct {
+	IDToken         string `json:"idToken" validate:"required"` // Token do provider cấp (Firebase ID token, OIDC id_token, token dev của provider local)
+	Hwid            string `json:"hwid" validate:"required"`    // Device hardware ID
+	InvitationToken string `json:"invitationToken,omitempty"`   // Token trong link mời vào tổ chức - Optional (chấp nhận lời mời khi đăng nhập)
+	IP              string `json:"-"`                           // IP của request (handler tự điền)
+	UserAgent       string `json:"-"`                           // User-Agent của request (handler tự điền)
+}
+
+// RefreshTokenInput đầu vào lấy access token mới bằng refresh token
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken" validate:"required"` // Refresh token nhận được khi đăng nhập/refresh
+	IP           string `json:"-"`                                // IP của request (handler tự điền)
+	UserAgent    string `json:"-"`                                // User-Agent của request (handler tự điền)
+}
+
+// ImpersonateInput đầu vào đăng nhập thay user (POST /admin/user/:id/impersonate)
+type ImpersonateInput struct {
+	Reason string `json:"reason" validate:"required"` // Lý do (ticket hỗ trợ...) - ghi vào audit log và thông báo cho user
+	RoleID string `json:"roleId"`                     // Role của user muốn xem (gửi lại trong X-Active-Role-ID) - Optional, phải là role đã gán cho user
+}
+
+// ImpersonateOutput token đăng nhập thay user
+// Token chỉ là access token ngắn hạn, không có refresh token và không tạo phiên (thiết bị) mới cho user
+type ImpersonateOutput struct {
+	AccessToken        string `json:"accessToken"`        // Dùng như access token thường (Authorization: Bearer)
+	ExpiresAt          int64  `json:"expiresAt"`          // Thời điểm hết hạn (Unix giây)
+	ImpersonatedUserID string `json:"impersonatedUserId"` // User đang được xem
+	ImpersonatorID     string `json:"impersonatorId"`     // Quản trị viên thực hiện
+	RoleID             string `json:"roleId,omitempty"`   // Role đề xuất cho X-Active-Role-ID
 }
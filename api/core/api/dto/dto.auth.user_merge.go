@@ -0,0 +1,37 @@
+package dto
+
+// DuplicateUserInfo thông tin tóm tắt của một user trong nhóm nghi trùng
+type DuplicateUserInfo struct {
+	ID            string `json:"id"`                    // User ID
+	Name          string `json:"name"`                  // Tên
+	Email         string `json:"email,omitempty"`       // Email
+	Phone         string `json:"phone,omitempty"`       // Số điện thoại
+	FirebaseUID   string `json:"firebaseUid,omitempty"` // Firebase UID
+	EmailVerified bool   `json:"emailVerified"`         // Email đã xác thực
+	PhoneVerified bool   `json:"phoneVerified"`         // Số điện thoại đã xác thực
+	RoleCount     int64  `json:"roleCount"`             // Số role assignment
+	CreatedAt     int64  `json:"createdAt"`             // Thời gian tạo
+}
+
+// DuplicateUserGroup nhóm user có cùng email hoặc số điện thoại đã xác thực (sau khi chuẩn hóa)
+type DuplicateUserGroup struct {
+	MatchType  string              `json:"matchType"`  // "email" hoặc "phone"
+	MatchValue string              `json:"matchValue"` // Giá trị đã chuẩn hóa (email chữ thường, số điện thoại dạng 84xxxxxxxxx)
+	Users      []DuplicateUserInfo `json:"users"`      // Các user trong nhóm (cũ nhất trước)
+}
+
+// UserMergeInput đầu vào gộp tài khoản: sourceUserId được gộp vào targetUserId rồi bị xóa
+type UserMergeInput struct {
+	SourceUserID string `json:"sourceUserId" validate:"required"` // User bị gộp (sẽ bị xóa) - BẮT BUỘC
+	TargetUserID string `json:"targetUserId" validate:"required"` // User được giữ lại - BẮT BUỘC
+}
+
+// UserMergeResult kết quả gộp tài khoản
+type UserMergeResult struct {
+	TargetUserID string `json:"targetUserId"` // User được giữ lại
+	SourceUserID string `json:"sourceUserId"` // User đã bị xóa
+	UserRoles    int64  `json:"userRoles"`    // Số role assignment chuyển sang user được giữ lại
+	Agents       int64  `json:"agents"`       // Số agent được cập nhật assignedUsers
+	AccessTokens int64  `json:"accessTokens"` // Số access token được cập nhật assignedUsers
+	References   int64  `json:"references"`   // Số bản ghi createdBy/invitedBy/... được trỏ sang user được giữ lại
+}
@@ -2,8 +2,10 @@ package dto
 
 // UserRoleCreateInput đại diện cho dữ liệu đầu vào khi tạo vai trò người dùng
 type UserRoleCreateInput struct {
-	UserID string `json:"userId" validate:"required"` // ID của người dùng (bắt buộc)
-	RoleID string `json:"roleId" validate:"required"` // ID của vai trò (bắt buộc)
+	UserID     string `json:"userId" validate:"required"` // ID của người dùng (bắt buộc)
+	RoleID     string `json:"roleId" validate:"required"` // ID của vai trò (bắt buộc)
+	ValidFrom  *int64 `json:"validFrom,omitempty"`        // Bắt đầu có hiệu lực (milliseconds) - Optional
+	ValidUntil *int64 `json:"validUntil,omitempty"`       // Hết hiệu lực (milliseconds), bỏ trống = vĩnh viễn - Optional
 }
 
 // UserRoleUpdateInput đại diện cho dữ liệu đầu vào khi cập nhật vai trò người dùng
@@ -0,0 +1,14 @@
+package dto
+
+// OrganizationInvitationCreateInput dùng cho tạo lời mời vào tổ chức (POST /organization/:id/invitations)
+type OrganizationInvitationCreateInput struct {
+	Email     string   `json:"email,omitempty"`                   // Email người được mời - BẮT BUỘC nếu không có phone
+	Phone     string   `json:"phone,omitempty"`                   // Số điện thoại người được mời - BẮT BUỘC nếu không có email
+	RoleIDs   []string `json:"roleIds" validate:"required,min=1"` // Các role thuộc tổ chức sẽ được gán khi chấp nhận - BẮT BUỘC
+	ExpiresAt *int64   `json:"expiresAt,omitempty"`               // Thời điểm link hết hạn (milliseconds) - Optional (mặc định ORGANIZATION_INVITATION_TTL_HOURS)
+}
+
+// OrganizationInvitationAcceptInput dùng cho user đã đăng nhập chấp nhận lời mời (POST /invitation/accept)
+type OrganizationInvitationAcceptInput struct {
+	Token string `json:"token" validate:"required"` // Token trong link mời
+}
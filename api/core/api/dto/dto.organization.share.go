@@ -4,20 +4,32 @@ package dto
 // Đây là contract/interface cho Frontend - định nghĩa cấu trúc dữ liệu cần gửi khi tạo share
 // Lưu ý: Backend parse trực tiếp vào Model, nhưng DTO này dùng để Frontend biết cấu trúc cần gửi
 type OrganizationShareCreateInput struct {
-	OwnerOrganizationID string   `json:"ownerOrganizationId" validate:"required"` // Tổ chức sở hữu dữ liệu (phân quyền) - Organization share data - BẮT BUỘC
-	ToOrgID             string   `json:"toOrgId" validate:"required"`             // Organization nhận data - BẮT BUỘC
-	PermissionNames     []string `json:"permissionNames,omitempty"`              // [] hoặc null = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể - Optional
+	OwnerOrganizationID     string                 `json:"ownerOrganizationId" validate:"required"` // Tổ chức sở hữu dữ liệu (phân quyền) - Organization share data - BẮT BUỘC
+	ToOrgID                 string                 `json:"toOrgId" validate:"required"`             // Organization nhận data - BẮT BUỘC
+	PermissionNames         []string               `json:"permissionNames,omitempty"`               // [] hoặc null = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể - Optional
+	DocumentFilter          map[string]interface{} `json:"documentFilter,omitempty"`                // null = tất cả document, {"pageId": "123"} = chỉ document khớp (tên field đơn giản, giá trị string/number/bool) - Optional
+	Note                    string                 `json:"note,omitempty"`                          // Lý do / ghi chú - Optional
+	ExpiresAt               *int64                 `json:"expiresAt,omitempty"`                     // Thời điểm hết hạn (milliseconds), null = không hết hạn - Optional
+	IncludeOwnerSubtree     bool                   `json:"includeOwnerSubtree,omitempty"`           // true = share cả dữ liệu của tổ chức con của ownerOrganizationId - Optional
+	IncludeRecipientSubtree bool                   `json:"includeRecipientSubtree,omitempty"`       // true = tổ chức con của toOrgId cũng nhận share - Optional
 
-	// Lưu ý: KHÔNG cần gửi createdAt, createdBy - Backend tự động set
+	// Lưu ý: KHÔNG cần gửi status, createdAt, createdBy - Backend tự động set
 }
 
 // OrganizationShareUpdateInput dùng cho cập nhật organization share (tầng transport)
 // Đây là contract/interface cho Frontend - định nghĩa cấu trúc dữ liệu cần gửi khi cập nhật share
 // Lưu ý: Backend parse trực tiếp vào Model, nhưng DTO này dùng để Frontend biết cấu trúc cần gửi
-// Lưu ý: OrganizationShare thường không cần update, nhưng nếu có thì chỉ update PermissionNames
+// Lưu ý: OrganizationShare thường không cần update, nhưng nếu có thì chỉ update PermissionNames, IncludeOwnerSubtree, IncludeRecipientSubtree
 type OrganizationShareUpdateInput struct {
-	PermissionNames []string `json:"permissionNames,omitempty"` // [] hoặc null = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể - Optional
+	PermissionNames         []string `json:"permissionNames,omitempty"`         // [] hoặc null = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể - Optional
+	IncludeOwnerSubtree     *bool    `json:"includeOwnerSubtree,omitempty"`     // Share cả dữ liệu của tổ chức con của ownerOrganizationId - Optional
+	IncludeRecipientSubtree *bool    `json:"includeRecipientSubtree,omitempty"` // Tổ chức con của toOrgId cũng nhận share - Optional
 
 	// Lưu ý: KHÔNG thể update ownerOrganizationId, toOrgId - Backend sẽ tự động xóa các fields này nếu có trong request (bảo mật)
 	// Lưu ý: KHÔNG thể update createdAt, createdBy - Backend sẽ tự động xóa các fields này nếu có trong request
 }
+
+// OrganizationShareRevokeInput dùng cho thu hồi organization share (tầng transport)
+type OrganizationShareRevokeInput struct {
+	Note string `json:"note,omitempty"` // Lý do thu hồi - Optional (ghi đè note của share nếu có)
+}
@@ -0,0 +1,8 @@
+package dto
+
+// RecordAssignInput dùng cho giao bản ghi cho user (PUT /<collection>/assign)
+// Áp dụng cho collection có createdBy/assignedTo (phân quyền scope 2 - chỉ bản ghi của mình)
+type RecordAssignInput struct {
+	IDs        []string `json:"ids" validate:"required,min=1,max=100"` // ID các bản ghi cần giao - BẮT BUỘC
+	AssignedTo string   `json:"assignedTo"`                            // User được giao, rỗng = bỏ giao - Optional
+}
@@ -1,16 +1,24 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"meta_commerce/core/api/dto"
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// EventTypeUserImpersonated là eventType notification khi quản trị viên đăng nhập thay user
+const EventTypeUserImpersonated = "user_impersonated"
+
 // AdminHandler xử lý các route liên quan đến quản trị viên cho Fiber
 // Kế thừa từ FiberBaseHandler để có các chức năng CRUD cơ bản
 type AdminHandler struct {
@@ -19,6 +27,7 @@ type AdminHandler struct {
 	PermissionCRUD *services.PermissionService
 	RoleCRUD       *services.RoleService
 	AdminService   *services.AdminService
+	LoginGuard     *services.LoginGuardService
 }
 
 // NewAdminHandler tạo một instance mới của FiberAdminHandler
@@ -54,6 +63,12 @@ func NewAdminHandler() (*AdminHandler, error) {
 	}
 	handler.AdminService = adminService
 
+	loginGuard, err := services.NewLoginGuardService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login guard service: %v", err)
+	}
+	handler.LoginGuard = loginGuard
+
 	// Gán UserCRUD cho BaseHandler
 	handler.BaseService = nil
 	return handler, nil
@@ -252,3 +267,169 @@ func (h *AdminHandler) HandleSyncAdministratorPermissions(c fiber.Ctx) error {
 	h.HandleResponse(c, map[string]string{"message": "Đã đồng bộ quyền cho Administrator thành công"}, nil)
 	return nil
 }
+
+// HandleGetUserSessions lấy danh sách thiết bị đang đăng nhập của một người dùng (dành cho bộ phận hỗ trợ)
+// YÊU CẦU QUYỀN User.Session
+// @Summary Danh sách phiên đăng nhập của người dùng
+// @Param id path string true "User ID"
+// @Success 200 {array} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/user/{id}/sessions [get]
+func (h *AdminHandler) HandleGetUserSessions(c fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	sessions, err := h.UserCRUD.ListSessions(c.Context(), userID)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	h.HandleResponse(c, sessionsToResponse(sessions, ""), nil)
+	return nil
+}
+
+// HandleGetUserLoginHistory lấy lịch sử đăng nhập (thành công, thất bại, bị khóa) của một người dùng, mới nhất trước
+// Lần thất bại được gắn vào user theo danh tính (provider:subject) đọc từ token, kể cả khi token không hợp lệ
+// YÊU CẦU QUYỀN User.Session
+// @Summary Lịch sử đăng nhập của người dùng
+// @Param id path string true "User ID"
+// @Param page query int false "Trang"
+// @Param limit query int false "Số bản ghi mỗi trang"
+// @Success 200 {object} models.PaginateResult[models.LoginEvent]
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/user/{id}/login-history [get]
+func (h *AdminHandler) HandleGetUserLoginHistory(c fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	page, limit := h.ParsePagination(c)
+	result, err := h.LoginGuard.ListByUser(c.Context(), userID, page, limit)
+	h.HandleResponse(c, result, err)
+	return nil
+}
+
+// HandleRevokeUserSession đăng xuất một thiết bị của người dùng
+// YÊU CẦU QUYỀN User.Session
+// @Summary Đăng xuất một thiết bị của người dùng
+// @Param id path string true "User ID"
+// @Param hwid path string true "Hardware ID của thiết bị"
+// @Success 200 {object} nil
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/user/{id}/sessions/{hwid} [delete]
+func (h *AdminHandler) HandleRevokeUserSession(c fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	err = h.UserCRUD.RevokeSession(c.Context(), userID, c.Params("hwid"), "admin_session_revoke")
+	h.HandleResponse(c, nil, err)
+	return nil
+}
+
+// HandleRevokeAllUserSessions đăng xuất người dùng khỏi tất cả thiết bị
+// YÊU CẦU QUYỀN User.Session
+// @Summary Đăng xuất người dùng khỏi tất cả thiết bị
+// @Param id path string true "User ID"
+// @Success 200 {object} nil
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/user/{id}/sessions/revoke-all [post]
+func (h *AdminHandler) HandleRevokeAllUserSessions(c fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	err = h.UserCRUD.RevokeAllSessions(c.Context(), userID, "admin_session_revoke")
+	h.HandleResponse(c, nil, err)
+	return nil
+}
+
+// HandleImpersonateUser phát hành token "login as" để bộ phận hỗ trợ xem hệ thống đúng như user đang thấy
+// YÊU CẦU QUYỀN User.Impersonate
+// Mọi request dùng token này đều được ghi audit kèm impersonator_id; user được thông báo qua event user_impersonated
+// @Summary Đăng nhập thay người dùng
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.ImpersonateOutput
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/user/{id}/impersonate [post]
+func (h *AdminHandler) HandleImpersonateUser(c fiber.Ctx) error {
+	targetID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	var input dto.ImpersonateInput
+	if err := h.ParseRequestBody(c, &input); err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+		return nil
+	}
+
+	// Không cho impersonation lồng nhau và không cho service account đăng nhập thay user
+	if c.Locals("impersonator_id") != nil || c.Locals("service_account") != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Chỉ quản trị viên đăng nhập trực tiếp mới được đăng nhập thay người dùng", common.StatusForbidden, nil))
+		return nil
+	}
+	impersonatorIDStr, _ := c.Locals("user_id").(string)
+	impersonatorID, err := primitive.ObjectIDFromHex(impersonatorIDStr)
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, err))
+		return nil
+	}
+
+	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), impersonatorID, "User.Impersonate")
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	result, target, err := h.AdminService.Impersonate(c.Context(), impersonatorID, targetID, input, allowedOrgIDs)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	logger.LogAction("impersonation_start", c, map[string]interface{}{
+		"impersonated_user_id": targetID.Hex(),
+		"reason":               input.Reason,
+		"role_id":              input.RoleID,
+		"expires_at":           result.ExpiresAt,
+	})
+
+	payload := map[string]interface{}{
+		"userId":         targetID.Hex(),
+		"userEmail":      target.Email,
+		"userName":       target.Name,
+		"impersonatorId": impersonatorID.Hex(),
+		"reason":         input.Reason,
+		"expiresAt":      result.ExpiresAt,
+		"startedAt":      time.Now().Unix(),
+	}
+	if impersonator, ok := c.Locals("user").(models.User); ok {
+		payload["impersonatorName"] = impersonator.Name
+		payload["impersonatorEmail"] = impersonator.Email
+	}
+	// Gửi notification ở background để không làm chậm/thất bại request khi kênh gửi lỗi
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := notification.TriggerForRecipient(ctx, EventTypeUserImpersonated, email, payload); err != nil {
+			logrus.WithError(err).WithField("user_id", payload["userId"]).Warn("Failed to trigger user impersonated notification")
+		}
+	}(target.Email)
+
+	h.HandleResponse(c, result, nil)
+	return nil
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"meta_commerce/core/api/dto"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditLogHandler xử lý các route đọc audit trail (auth_logs)
+type AuditLogHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	AuditLogService *services.AuditLogService
+}
+
+// NewAuditLogHandler tạo một instance mới của AuditLogHandler
+func NewAuditLogHandler() (*AuditLogHandler, error) {
+	auditLogService, err := services.NewAuditLogService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log service: %v", err)
+	}
+
+	return &AuditLogHandler{
+		BaseHandler:     &BaseHandler[interface{}, interface{}, interface{}]{},
+		AuditLogService: auditLogService,
+	}, nil
+}
+
+// allowedOrganizations trả về các tổ chức người gọi được xem audit log (theo scope của AuditLog.Read)
+func (h *AuditLogHandler) allowedOrganizations(c fiber.Ctx) ([]primitive.ObjectID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, err)
+	}
+	orgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, "AuditLog.Read")
+	if err != nil {
+		return nil, err
+	}
+	if len(orgIDs) == 0 {
+		return nil, common.NewError(common.ErrCodeAuth, "Bạn không có quyền xem audit log của tổ chức nào", common.StatusForbidden, nil)
+	}
+	return orgIDs, nil
+}
+
+// parseFilter đọc điều kiện lọc từ query string
+func (h *AuditLogHandler) parseFilter(c fiber.Ctx) (dto.AuditLogFilter, error) {
+	filter := dto.AuditLogFilter{
+		Collection: c.Query("collection"),
+		Action:     c.Query("action"),
+	}
+	for name, target := range map[string]*primitive.ObjectID{"documentId": &filter.DocumentID, "actorId": &filter.ActorID} {
+		if value := c.Query(name); value != "" {
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				return filter, common.NewError(common.ErrCodeValidationFormat, name+" không hợp lệ", common.StatusBadRequest, err)
+			}
+			*target = id
+		}
+	}
+	for name, target := range map[string]*int64{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, common.NewError(common.ErrCodeValidationFormat, name+" phải là thời điểm dạng millisecond", common.StatusBadRequest, err)
+			}
+			*target = ms
+		}
+	}
+	return filter, nil
+}
+
+// HandleList trả về audit log (mới nhất trước) của các tổ chức trong phạm vi quyền, có phân trang
+// YÊU CẦU QUYỀN AuditLog.Read
+// @Summary Danh sách audit log
+// @Param collection query string false "Tên collection"
+// @Param documentId query string false "ID document bị thay đổi"
+// @Param actorId query string false "ID người thực hiện"
+// @Param action query string false "Hành động (insert, update, delete, ...)"
+// @Param from query int false "Từ thời điểm (ms)"
+// @Param to query int false "Đến thời điểm (ms)"
+// @Param page query int false "Trang (mặc định 1)"
+// @Param limit query int false "Số bản ghi mỗi trang (mặc định 10)"
+// @Router /audit-log [get]
+func (h *AuditLogHandler) HandleList(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgIDs, err := h.allowedOrganizations(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		filter, err := h.parseFilter(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		page, limit := h.ParsePagination(c)
+		data, err := h.AuditLogService.Query(c.Context(), orgIDs, filter, page, limit)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleGetByID trả về một bản ghi audit log (phải thuộc tổ chức trong phạm vi quyền)
+// YÊU CẦU QUYỀN AuditLog.Read
+// @Router /audit-log/{id} [get]
+func (h *AuditLogHandler) HandleGetByID(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		orgIDs, err := h.allowedOrganizations(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		entry, err := h.AuditLogService.FindOneById(c.Context(), id)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		for _, orgID := range orgIDs {
+			if orgID == entry.OwnerOrganizationID {
+				h.HandleResponse(c, entry, nil)
+				return nil
+			}
+		}
+		h.HandleResponse(c, nil, common.ErrNotFound)
+		return nil
+	})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// documentOwnershipResolver lấy thông tin tổ chức sở hữu của một document (BaseHandler implement)
+type documentOwnershipResolver interface {
+	resolveDocumentOwnership(ctx context.Context, id primitive.ObjectID) (*services.DocumentOwnership, error)
+}
+
+var (
+	permissionResourcesMu sync.RWMutex
+	permissionResources   = make(map[string]documentOwnershipResolver)
+)
+
+// RegisterPermissionResource đăng ký handler CRUD ứng với permission prefix (vd: "FbPage")
+// để chế độ explain tìm được document theo permission name ("FbPage.Read" → collection của FbPageHandler)
+func RegisterPermissionResource(permissionPrefix string, h interface{}) {
+	resolver, ok := h.(documentOwnershipResolver)
+	if !ok {
+		return
+	}
+	permissionResourcesMu.Lock()
+	defer permissionResourcesMu.Unlock()
+	permissionResources[permissionPrefix] = resolver
+}
+
+// resolveDocumentOwnership lấy tổ chức sở hữu của document theo ID
+func (h *BaseHandler[T, CreateInput, UpdateInput]) resolveDocumentOwnership(ctx context.Context, id primitive.ObjectID) (*services.DocumentOwnership, error) {
+	ownership := &services.DocumentOwnership{DocumentID: id, HasOrganizationID: h.hasOrganizationIDField()}
+	if h.BaseService == nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Resource không hỗ trợ tra cứu document", common.StatusBadRequest, nil)
+	}
+
+	doc, err := h.BaseService.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ownership.OwnerOrganizationID = h.getOrganizationIDFromModel(doc)
+	if ownership.Fields, err = services.DocumentToConditionMap(doc); err != nil {
+		return nil, err
+	}
+	if h.hasRecordOwnershipFields() {
+		ownership.HasRecordOwnership = true
+		ownership.CreatedBy, ownership.AssignedTo = h.getRecordOwnershipFromModel(doc)
+	}
+	return ownership, nil
+}
+
+// AccessHandler xử lý các route giải thích quyền hiệu lực và phạm vi dữ liệu
+type AccessHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	effectiveAccessService *services.EffectiveAccessService
+}
+
+// NewAccessHandler tạo một instance mới của AccessHandler
+func NewAccessHandler() (*AccessHandler, error) {
+	effectiveAccessService, err := services.NewEffectiveAccessService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create effective access service: %v", err)
+	}
+
+	return &AccessHandler{
+		BaseHandler:            &BaseHandler[interface{}, interface{}, interface{}]{},
+		effectiveAccessService: effectiveAccessService,
+	}, nil
+}
+
+// HandleGetMyEffectivePermissions trả về quyền hiệu lực và phạm vi dữ liệu của user hiện tại
+// @Summary Quyền hiệu lực của tôi
+// @Description Mỗi permission kèm scope, role nguồn và các tổ chức cụ thể (gồm tổ chức được share).
+// @Description Truyền permission (và documentId) để giải thích quyết định cho phép/từ chối.
+// @Param roleId query string false "Role context (mặc định lấy từ header X-Active-Role-ID, rỗng = tất cả role)"
+// @Param permission query string false "Tên permission cần giải thích (bật chế độ explain)"
+// @Param documentId query string false "ID document cần giải thích (chế độ explain)"
+// @Success 200 {object} services.EffectiveAccess
+// @Router /auth/effective-permissions [get]
+func (h *AccessHandler) HandleGetMyEffectivePermissions(c fiber.Ctx) error {
+	userIDStr, ok := c.Locals("user_id").(string)
+	if !ok || userIDStr == "" {
+		h.HandleResponse(c, nil, common.ErrTokenMissing)
+		return nil
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		h.HandleResponse(c, nil, common.ErrTokenInvalid)
+		return nil
+	}
+
+	roleIDStr := c.Query("roleId")
+	if roleIDStr == "" {
+		roleIDStr = c.Get("X-Active-Role-ID")
+	}
+
+	data, err := h.effectiveAccess(c, userID, roleIDStr, true)
+	h.HandleResponse(c, data, err)
+	return nil
+}
+
+// HandleGetUserEffectivePermissions trả về quyền hiệu lực và phạm vi dữ liệu của một user bất kỳ
+// YÊU CẦU QUYỀN User.EffectivePermission
+// @Summary Quyền hiệu lực của người dùng
+// @Param id path string true "User ID"
+// @Param roleId query string false "Role context (rỗng = tất cả role)"
+// @Param permission query string false "Tên permission cần giải thích (bật chế độ explain)"
+// @Param documentId query string false "ID document cần giải thích (chế độ explain)"
+// @Success 200 {object} services.EffectiveAccess
+// @Router /admin/user/{id}/effective-permissions [get]
+func (h *AccessHandler) HandleGetUserEffectivePermissions(c fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+		return nil
+	}
+
+	data, err := h.effectiveAccess(c, userID, c.Query("roleId"), false)
+	h.HandleResponse(c, data, err)
+	return nil
+}
+
+// effectiveAccess trả về quyền hiệu lực, hoặc kết quả explain nếu có query permission
+// redactDocument = true (user tự giải thích quyền của mình): chỉ tra cứu document khi user có permission,
+// và khi bị từ chối chỉ trả về allowed = false, không lộ document có tồn tại hay thuộc tổ chức nào
+func (h *AccessHandler) effectiveAccess(c fiber.Ctx, userID primitive.ObjectID, roleIDStr string, redactDocument bool) (interface{}, error) {
+	var activeRoleID *primitive.ObjectID
+	if roleIDStr != "" {
+		roleID, err := primitive.ObjectIDFromHex(roleIDStr)
+		if err != nil {
+			return nil, common.NewError(common.ErrCodeValidationFormat, "Role ID không hợp lệ", common.StatusBadRequest, err)
+		}
+		activeRoleID = &roleID
+	}
+
+	permissionName := c.Query("permission")
+	if permissionName == "" {
+		return h.effectiveAccessService.GetEffectiveAccess(c.Context(), userID, activeRoleID)
+	}
+
+	// Chế độ explain
+	documentIDStr := c.Query("documentId")
+	if documentIDStr == "" {
+		return h.effectiveAccessService.Explain(c.Context(), userID, activeRoleID, permissionName, nil)
+	}
+	documentID, err := primitive.ObjectIDFromHex(documentIDStr)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, "Document ID không hợp lệ", common.StatusBadRequest, err)
+	}
+
+	// Permission "FbPage.Read" → resource "FbPage"
+	resourceName := permissionName
+	if idx := strings.LastIndex(permissionName, "."); idx > 0 {
+		resourceName = permissionName[:idx]
+	}
+	permissionResourcesMu.RLock()
+	resolver, ok := permissionResources[resourceName]
+	permissionResourcesMu.RUnlock()
+	if !ok {
+		return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Không xác định được collection cho permission %s", permissionName), common.StatusBadRequest, nil)
+	}
+
+	if redactDocument {
+		// Kiểm tra permission trước khi đọc document
+		decision, err := h.effectiveAccessService.Explain(c.Context(), userID, activeRoleID, permissionName, nil)
+		if err != nil || !decision.Allowed {
+			return decision, err
+		}
+	}
+
+	document, err := resolver.resolveDocumentOwnership(c.Context(), documentID)
+	if err != nil {
+		if redactDocument && errors.Is(err, common.ErrNotFound) {
+			return documentDeniedDecision(permissionName), nil
+		}
+		return nil, err
+	}
+
+	decision, err := h.effectiveAccessService.Explain(c.Context(), userID, activeRoleID, permissionName, document)
+	if err == nil && redactDocument && !decision.Allowed {
+		return documentDeniedDecision(permissionName), nil
+	}
+	return decision, err
+}
+
+// documentDeniedDecision là kết quả từ chối truy cập document không kèm chi tiết (tổ chức sở hữu, document có tồn tại hay không)
+func documentDeniedDecision(permissionName string) *services.AccessDecision {
+	return &services.AccessDecision{
+		Permission: permissionName,
+		Allowed:    false,
+		Checks: []services.AccessCheck{
+			{Check: "document", Passed: false, Reason: "Không có quyền truy cập document này"},
+		},
+	}
+}
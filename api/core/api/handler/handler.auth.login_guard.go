@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypeSecurityNewLogin là eventType notification khi user đăng nhập từ thiết bị mới hoặc dải mạng lạ
+// Gửi cho email của user (cảnh báo bảo mật) và các route của eventType
+const EventTypeSecurityNewLogin = "security.new_login"
+
+// guardedLogin bọc một lần đăng nhập bằng bộ đếm thất bại và lịch sử đăng nhập:
+//   - Danh tính (đã xác thực) hoặc IP đang bị khóa: từ chối với 429 (header Retry-After), không gọi login
+//   - Provider dạng token chưa biết danh tính trước khi verify nên chỉ bị khóa theo IP
+//   - login thất bại (trừ lỗi server): tăng bộ đếm, khóa khi đủ số lần
+//   - login thành công: xóa bộ đếm của danh tính, cảnh báo nếu là thiết bị mới hoặc dải mạng lạ
+func (h *UserHandler) guardedLogin(c fiber.Ctx, info services.LoginRequestInfo, login func(ctx context.Context) (*models.User, error)) (*models.User, error) {
+	ctx := context.Background()
+
+	lockedUntil, err := h.loginGuard.LockedUntil(ctx, info)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check login lockout")
+	}
+	if lockedUntil > 0 {
+		h.loginGuard.RecordLocked(ctx, info)
+		c.Set("Retry-After", strconv.FormatInt(services.LoginRetryAfter(lockedUntil), 10))
+		logger.LogAction("login_locked", c, map[string]interface{}{
+			"provider":     info.Provider,
+			"identity":     info.IdentityKey,
+			"locked_until": lockedUntil,
+		})
+		return nil, services.LoginLockedError(lockedUntil)
+	}
+
+	user, err := login(ctx)
+	if err != nil {
+		if isLoginFailure(err) {
+			h.loginGuard.RecordFailure(ctx, info, err.Error())
+		}
+		return nil, err
+	}
+
+	event := h.loginGuard.RecordSuccess(ctx, info, user)
+	if event.NewDevice || event.UnusualLocation {
+		logger.LogAction("login_anomaly", c, map[string]interface{}{
+			"user_id":          user.ID.Hex(),
+			"new_device":       event.NewDevice,
+			"unusual_location": event.UnusualLocation,
+		})
+		h.notifyNewLogin(*user, *event)
+	}
+	return user, nil
+}
+
+// isLoginFailure lỗi đăng nhập có được tính vào bộ đếm thất bại không
+// Lỗi server (5xx, lỗi kết nối database...) không phải lỗi của người đăng nhập nên không bị tính
+func isLoginFailure(err error) bool {
+	var customErr *common.Error
+	if !errors.As(err, &customErr) {
+		return false
+	}
+	return customErr.StatusCode < common.StatusInternalServerError
+}
+
+// notifyNewLogin gửi cảnh báo đăng nhập từ thiết bị mới / dải mạng lạ cho user và các route của eventType
+// Chạy nền, lỗi gửi notification chỉ được log
+func (h *UserHandler) notifyNewLogin(user models.User, event models.LoginEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		payload := map[string]interface{}{
+			"userId":          user.ID.Hex(),
+			"userName":        user.Name,
+			"userEmail":       user.Email,
+			"provider":        event.Provider,
+			"ip":              event.IP,
+			"userAgent":       event.UserAgent,
+			"hwid":            event.Hwid,
+			"newDevice":       event.NewDevice,
+			"unusualLocation": event.UnusualLocation,
+			"loginAt":         event.CreatedAt,
+		}
+		log := logrus.WithField("user_id", user.ID.Hex())
+		if user.Email == "" {
+			if _, err := notification.Trigger(ctx, EventTypeSecurityNewLogin, payload); err != nil {
+				log.WithError(err).Warn("Failed to trigger security.new_login notification")
+			}
+			return
+		}
+		if _, err := notification.TriggerForRecipient(ctx, EventTypeSecurityNewLogin, user.Email, payload); err != nil {
+			log.WithError(err).Warn("Failed to send security.new_login notification")
+		}
+	}()
+}
@@ -0,0 +1,363 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypeMFAChanged là eventType notification khi user bật/tắt xác thực 2 lớp hoặc tạo lại recovery code
+// Gửi cho email của user (cảnh báo bảo mật) và các route của eventType
+const EventTypeMFAChanged = "mfa_changed"
+
+// MFAHandler xử lý các route xác thực 2 lớp (TOTP) của user đang đăng nhập
+type MFAHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	MFAService  *services.MFAService
+	userService *services.UserService
+	loginGuard  *services.LoginGuardService
+}
+
+// NewMFAHandler tạo một instance mới của MFAHandler
+func NewMFAHandler() (*MFAHandler, error) {
+	mfaService, err := services.NewMFAService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa service: %v", err)
+	}
+
+	userService, err := services.NewUserService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user service: %v", err)
+	}
+
+	loginGuard, err := services.NewLoginGuardService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login guard service: %v", err)
+	}
+
+	return &MFAHandler{
+		BaseHandler: &BaseHandler[interface{}, interface{}, interface{}]{},
+		MFAService:  mfaService,
+		userService: userService,
+		loginGuard:  loginGuard,
+	}, nil
+}
+
+// currentUser lấy user đang đăng nhập; service account và token "login as" không dùng được MFA
+func (h *MFAHandler) currentUser(c fiber.Ctx) (models.User, *models.JwtToken, error) {
+	if c.Locals("service_account") != nil {
+		return models.User{}, nil, common.NewError(common.ErrCodeAuth, "Service account không dùng được xác thực 2 lớp", common.StatusForbidden, nil)
+	}
+	if c.Locals("impersonator_id") != nil {
+		return models.User{}, nil, common.NewError(common.ErrCodeAuth, "Không thể thay đổi xác thực 2 lớp khi đang đăng nhập thay người dùng", common.StatusForbidden, nil)
+	}
+	user, ok := c.Locals("user").(models.User)
+	claims, claimsOk := c.Locals("token_claims").(*models.JwtToken)
+	if !ok || !claimsOk {
+		return models.User{}, nil, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, nil)
+	}
+	return user, claims, nil
+}
+
+// requireRecentLogin yêu cầu phiên hiện tại vừa đăng nhập (xác thực lớp 1) trong MFA_ENROLL_MAX_LOGIN_AGE giây
+// Thời điểm đăng nhập của phiên giữ nguyên khi refresh, nên token bị lộ / phiên cũ không tự đăng ký TOTP được
+func (h *MFAHandler) requireRecentLogin(user models.User, claims *models.JwtToken) error {
+	maxAge := global.MongoDB_ServerConfig.MfaEnrollMaxLoginAge
+	if maxAge <= 0 {
+		return nil
+	}
+	for _, session := range user.Tokens {
+		if session.Hwid != claims.Hwid || session.AccessJti != claims.Id {
+			continue
+		}
+		if time.Since(time.UnixMilli(session.CreatedAt)) <= time.Duration(maxAge)*time.Second {
+			return nil
+		}
+		break
+	}
+	return common.NewError(
+		common.ErrCodeAuthMFA,
+		"Vui lòng đăng nhập lại trước khi đăng ký xác thực 2 lớp",
+		common.StatusForbidden,
+		map[string]interface{}{
+			"errorCode": "REAUTHENTICATION_REQUIRED",
+			"maxAge":    maxAge,
+		},
+	)
+}
+
+// guardedVerify bọc một thao tác cần mã xác thực 2 lớp bằng bộ đếm nhập sai theo user:
+//   - User đang bị khóa: từ chối với 429 (header Retry-After), không gọi verify
+//   - Mã sai: tăng bộ đếm (khóa khi đủ MFA_MAX_FAILED_ATTEMPTS lần), ghi lịch sử đăng nhập và log hành động
+//   - Mã đúng: xóa bộ đếm
+func (h *MFAHandler) guardedVerify(c fiber.Ctx, user models.User, claims *models.JwtToken, action string, recoveryCode bool, verify func() error) error {
+	ctx := context.Background()
+	info := services.LoginRequestInfo{
+		Provider:  services.LoginProviderMFA,
+		IP:        c.IP(),
+		UserAgent: c.Get("User-Agent"),
+		Hwid:      claims.Hwid,
+	}
+
+	lockedUntil, err := h.loginGuard.MFALockedUntil(ctx, user.ID)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check mfa lockout")
+	}
+	if lockedUntil > 0 {
+		h.loginGuard.RecordMFALocked(ctx, user.ID, info)
+		c.Set("Retry-After", strconv.FormatInt(services.LoginRetryAfter(lockedUntil), 10))
+		logger.LogAction("mfa_locked", c, map[string]interface{}{
+			"action":       action,
+			"locked_until": lockedUntil,
+		})
+		return services.MFALockedError(lockedUntil)
+	}
+
+	if err := verify(); err != nil {
+		if errors.Is(err, services.ErrMFACodeInvalid) {
+			h.loginGuard.RecordMFAFailure(ctx, user.ID, info, action+": "+err.Error())
+			logger.LogAction("mfa_verify_failed", c, map[string]interface{}{
+				"action":        action,
+				"recovery_code": recoveryCode,
+			})
+		}
+		return err
+	}
+
+	h.loginGuard.RecordMFASuccess(ctx, user.ID)
+	return nil
+}
+
+// HandleGetStatus trả về trạng thái xác thực 2 lớp của user hiện tại
+// @Summary Trạng thái xác thực 2 lớp
+// @Success 200 {object} models.UserMFA
+// @Router /auth/mfa [get]
+func (h *MFAHandler) HandleGetStatus(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, _, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		status, err := h.MFAService.GetStatus(c.Context(), user.ID)
+		h.HandleResponse(c, status, err)
+		return nil
+	})
+}
+
+// HandleEnroll bắt đầu đăng ký TOTP, trả về secret và URI otpauth:// để quét QR
+// Phiên phải vừa đăng nhập trong MFA_ENROLL_MAX_LOGIN_AGE giây (403 REAUTHENTICATION_REQUIRED nếu quá)
+// @Summary Đăng ký TOTP
+// @Success 200 {object} dto.MFAEnrollOutput
+// @Router /auth/mfa/totp/enroll [post]
+func (h *MFAHandler) HandleEnroll(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, claims, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if err := h.requireRecentLogin(user, claims); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		output, err := h.MFAService.Enroll(c.Context(), user)
+		h.HandleResponse(c, output, err)
+		return nil
+	})
+}
+
+// HandleConfirm xác nhận đăng ký bằng mã TOTP đầu tiên, bật MFA và trả về recovery code (chỉ hiển thị một lần)
+// @Summary Xác nhận đăng ký TOTP
+// @Param body body dto.MFACodeInput true "Mã TOTP"
+// @Success 200 {object} dto.MFARecoveryCodesOutput
+// @Router /auth/mfa/totp/confirm [post]
+func (h *MFAHandler) HandleConfirm(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, _, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.MFACodeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		codes, err := h.MFAService.Confirm(c.Context(), user.ID, input.Code)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("mfa_enable", c, map[string]interface{}{})
+		h.notify(user, "enabled")
+		h.HandleResponse(c, dto.MFARecoveryCodesOutput{RecoveryCodes: codes}, nil)
+		return nil
+	})
+}
+
+// HandleVerify xác thực TOTP (step-up) cho phiên hiện tại
+// Trả về access token mới có claim MFA, dùng cho các thao tác thuộc MFA_STEP_UP_PERMISSIONS trong MFA_STEP_UP_MAX_AGE giây
+// Nhập sai MFA_MAX_FAILED_ATTEMPTS lần liên tiếp thì bị khóa tạm thời (429), dùng chung bộ đếm với tạo lại recovery code và tắt MFA
+// @Summary Xác thực 2 lớp (step-up)
+// @Param body body dto.MFAVerifyInput true "Mã TOTP hoặc recovery code"
+// @Success 200 {object} dto.MFAStepUpOutput
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) HandleVerify(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, claims, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.MFAVerifyInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		err = h.guardedVerify(c, user, claims, "verify", input.Code == "", func() error {
+			return h.MFAService.Verify(c.Context(), user.ID, input)
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		mfaAt := time.Now().Unix()
+		session, err := h.userService.MarkSessionMFA(c.Context(), user.ID, claims, mfaAt)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("mfa_verify", c, map[string]interface{}{
+			"recovery_code": input.Code == "",
+			"hwid":          session.Hwid,
+		})
+		h.HandleResponse(c, dto.MFAStepUpOutput{
+			Token:          session.JwtToken,
+			TokenExpiresAt: session.AccessExpiresAt,
+			MfaAt:          mfaAt,
+			MfaExpiresAt:   mfaAt + int64(global.MongoDB_ServerConfig.MfaStepUpMaxAge),
+		}, nil)
+		return nil
+	})
+}
+
+// HandleRegenerateRecoveryCodes tạo bộ recovery code mới (bộ cũ hết hiệu lực)
+// @Summary Tạo lại recovery code
+// @Param body body dto.MFACodeInput true "Mã TOTP"
+// @Success 200 {object} dto.MFARecoveryCodesOutput
+// @Router /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) HandleRegenerateRecoveryCodes(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, claims, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.MFACodeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		var codes []string
+		err = h.guardedVerify(c, user, claims, "recovery_codes_regenerate", false, func() error {
+			var err error
+			codes, err = h.MFAService.RegenerateRecoveryCodes(c.Context(), user.ID, input.Code)
+			return err
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("mfa_recovery_codes_regenerate", c, map[string]interface{}{})
+		h.notify(user, "recovery_codes_regenerated")
+		h.HandleResponse(c, dto.MFARecoveryCodesOutput{RecoveryCodes: codes}, nil)
+		return nil
+	})
+}
+
+// HandleDisable tắt xác thực 2 lớp sau khi xác thực mã TOTP hoặc recovery code
+// @Summary Tắt xác thực 2 lớp
+// @Param body body dto.MFAVerifyInput true "Mã TOTP hoặc recovery code"
+// @Router /auth/mfa/disable [post]
+func (h *MFAHandler) HandleDisable(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		user, claims, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.MFAVerifyInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		err = h.guardedVerify(c, user, claims, "disable", input.Code == "", func() error {
+			return h.MFAService.Disable(c.Context(), user.ID, input)
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("mfa_disable", c, map[string]interface{}{
+			"recovery_code": input.Code == "",
+		})
+		h.notify(user, "disabled")
+		h.HandleResponse(c, nil, nil)
+		return nil
+	})
+}
+
+// notify gửi cảnh báo thay đổi xác thực 2 lớp cho user ở background
+func (h *MFAHandler) notify(user models.User, action string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		payload := map[string]interface{}{
+			"userId":    user.ID.Hex(),
+			"userName":  user.Name,
+			"userEmail": user.Email,
+			"action":    action,
+			"changedAt": time.Now().UnixMilli(),
+		}
+		log := logrus.WithField("user_id", user.ID.Hex())
+		if user.Email == "" {
+			if _, err := notification.Trigger(ctx, EventTypeMFAChanged, payload); err != nil {
+				log.WithError(err).Warn("Failed to trigger mfa_changed notification")
+			}
+			return
+		}
+		if _, err := notification.TriggerForRecipient(ctx, EventTypeMFAChanged, user.Email, payload); err != nil {
+			log.WithError(err).Warn("Failed to send mfa_changed notification")
+		}
+	}()
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthHandler xử lý các endpoint OAuth2/OIDC khi hệ thống đóng vai trò issuer cho các service khác
+type OAuthHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	oauthService *services.OAuthService
+	keyService   *services.OAuthKeyService
+}
+
+// NewOAuthHandler tạo mới OAuthHandler
+func NewOAuthHandler() (*OAuthHandler, error) {
+	oauthService, err := services.NewOAuthService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth service: %v", err)
+	}
+	keyService, err := services.NewOAuthKeyService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth key service: %v", err)
+	}
+
+	return &OAuthHandler{
+		BaseHandler:  &BaseHandler[interface{}, interface{}, interface{}]{},
+		oauthService: oauthService,
+		keyService:   keyService,
+	}, nil
+}
+
+// HandleDiscovery trả về OpenID Provider Metadata
+// @Router /.well-known/openid-configuration [get]
+func (h *OAuthHandler) HandleDiscovery(c fiber.Ctx) error {
+	return c.Status(common.StatusOK).JSON(h.oauthService.Discovery())
+}
+
+// HandleJWKS trả về bộ khóa công khai (active + retired còn hiệu lực) để verify token
+// @Router /oauth/jwks [get]
+func (h *OAuthHandler) HandleJWKS(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		jwks, err := h.oauthService.JWKS(c.Context())
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		c.Set("Cache-Control", "public, max-age=300")
+		return c.Status(common.StatusOK).JSON(jwks)
+	})
+}
+
+// HandleAuthorize cấp authorization code cho user đang đăng nhập
+// Role đang làm việc (X-Active-Role-ID) được đưa vào claim role_id/org_id của token
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) HandleAuthorize(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input dto.OAuthAuthorizeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+
+		// Service account không có phiên đăng nhập tương tác - dùng client_credentials thay thế
+		if c.Locals("service_account") != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Service account không thể dùng authorization_code", common.StatusForbidden, nil))
+			return nil
+		}
+		// Token "login as" chỉ dùng để xem, không được cấp quyền của user cho ứng dụng khác
+		if c.Locals("impersonator_id") != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Không thể cấp quyền cho ứng dụng khi đang đăng nhập thay", common.StatusForbidden, nil))
+			return nil
+		}
+		userIDStr, _ := c.Locals("user_id").(string)
+		userID, err := primitive.ObjectIDFromHex(userIDStr)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Không xác định được người dùng", common.StatusUnauthorized, err))
+			return nil
+		}
+
+		var activeRoleID *primitive.ObjectID
+		if roleIDStr := c.Get("X-Active-Role-ID"); roleIDStr != "" {
+			roleID, err := primitive.ObjectIDFromHex(roleIDStr)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "X-Active-Role-ID không đúng định dạng", common.StatusBadRequest, err))
+				return nil
+			}
+			activeRoleID = &roleID
+		}
+
+		result, err := h.oauthService.Authorize(c.Context(), userID, activeRoleID, input)
+		h.HandleResponse(c, result, err)
+		return nil
+	})
+}
+
+// HandleToken là token endpoint (RFC 6749): authorization_code + PKCE và client_credentials
+// Nhận form-urlencoded (chuẩn) hoặc JSON; client xác thực bằng HTTP Basic hoặc client_id/client_secret trong body
+// Lỗi trả về theo định dạng {error, error_description} thay vì định dạng response chung
+// @Router /oauth/token [post]
+func (h *OAuthHandler) HandleToken(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input dto.OAuthTokenInput
+		if strings.HasPrefix(c.Get("Content-Type"), fiber.MIMEApplicationJSON) {
+			if err := c.Bind().JSON(&input); err != nil {
+				return h.writeOAuthError(c, &services.OAuthError{Code: "invalid_request", Description: "Malformed JSON body", Status: common.StatusBadRequest})
+			}
+		} else {
+			input = dto.OAuthTokenInput{
+				GrantType:    c.FormValue("grant_type"),
+				Code:         c.FormValue("code"),
+				RedirectURI:  c.FormValue("redirect_uri"),
+				CodeVerifier: c.FormValue("code_verifier"),
+				ClientID:     c.FormValue("client_id"),
+				ClientSecret: c.FormValue("client_secret"),
+				Scope:        c.FormValue("scope"),
+			}
+		}
+
+		if clientID, clientSecret, ok := parseBasicClientAuth(c.Get("Authorization")); ok {
+			if input.ClientID != "" && input.ClientID != clientID {
+				return h.writeOAuthError(c, &services.OAuthError{Code: "invalid_request", Description: "client_id does not match Authorization header", Status: common.StatusBadRequest})
+			}
+			input.ClientID, input.ClientSecret = clientID, clientSecret
+		}
+
+		result, err := h.oauthService.Token(c.Context(), input)
+		if err != nil {
+			return h.writeOAuthError(c, err)
+		}
+		c.Set("Cache-Control", "no-store")
+		c.Set("Pragma", "no-cache")
+		return c.Status(common.StatusOK).JSON(result)
+	})
+}
+
+// writeOAuthError ghi lỗi theo RFC 6749 mục 5.2
+func (h *OAuthHandler) writeOAuthError(c fiber.Ctx, err error) error {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &services.OAuthError{Code: "server_error", Description: "Internal server error", Status: common.StatusInternalServerError}
+	}
+	if oauthErr.Status == common.StatusUnauthorized {
+		c.Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.Set("Cache-Control", "no-store")
+	return c.Status(oauthErr.Status).JSON(dto.OAuthErrorOutput{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// parseBasicClientAuth đọc client_id/client_secret từ header Authorization: Basic (RFC 6749 mục 2.3.1)
+func parseBasicClientAuth(header string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	clientID, clientSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+	// client_id và secret được form-urlencode trước khi ghép theo RFC 6749
+	if unescaped, err := url.QueryUnescape(clientID); err == nil {
+		clientID = unescaped
+	}
+	if unescaped, err := url.QueryUnescape(clientSecret); err == nil {
+		clientSecret = unescaped
+	}
+	return clientID, clientSecret, true
+}
+
+// HandleListSigningKeys trả về các khóa ký đang được công bố (không gồm khóa riêng)
+// @Router /oauth-signing-key/keys [get]
+func (h *OAuthHandler) HandleListSigningKeys(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		keys, err := h.keyService.ListPublicKeys(c.Context())
+		h.HandleResponse(c, keys, err)
+		return nil
+	})
+}
+
+// HandleRotateSigningKey xoay vòng khóa ký ngay lập tức (vd: khi nghi ngờ lộ khóa)
+// Khóa cũ vẫn nằm trong JWKS đến khi mọi token đã ký bằng nó hết hạn
+// @Router /oauth-signing-key/rotate [post]
+func (h *OAuthHandler) HandleRotateSigningKey(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		key, err := h.keyService.Rotate(c.Context())
+		h.HandleResponse(c, key, err)
+		return nil
+	})
+}
+
+// OAuthClientHandler xử lý các route quản lý OAuth client
+// Kế thừa từ BaseHandler để có các chức năng CRUD cơ bản
+type OAuthClientHandler struct {
+	BaseHandler[models.OAuthClient, dto.OAuthClientCreateInput, dto.OAuthClientUpdateInput]
+	OAuthClientService *services.OAuthClientService
+}
+
+// NewOAuthClientHandler tạo mới OAuthClientHandler
+func NewOAuthClientHandler() (*OAuthClientHandler, error) {
+	oauthClientService, err := services.NewOAuthClientService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth client service: %v", err)
+	}
+
+	baseHandler := NewBaseHandler[models.OAuthClient, dto.OAuthClientCreateInput, dto.OAuthClientUpdateInput](oauthClientService)
+	return &OAuthClientHandler{
+		BaseHandler:        *baseHandler,
+		OAuthClientService: oauthClientService,
+	}, nil
+}
+
+// UpdateById override để chỉ cho phép cập nhật các field cấu hình (không đổi được clientId/secret/tổ chức)
+// PUT /oauth-client/update-by-id/:id
+func (h *OAuthClientHandler) UpdateById(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var input dto.OAuthClientUpdateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+
+		client, err := h.getAccessibleClient(c, "OAuthClient.Update")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		updated, err := h.OAuthClientService.UpdateClient(c.Context(), client.ID, input)
+		h.HandleResponse(c, updated, err)
+		return nil
+	})
+}
+
+// HandleRotateSecret sinh client secret mới, secret chỉ được trả về một lần trong "clientSecret"
+// POST /oauth-client/:id/rotate-secret
+func (h *OAuthClientHandler) HandleRotateSecret(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		client, err := h.getAccessibleClient(c, "OAuthClient.Update")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		rotated, err := h.OAuthClientService.RotateSecret(c.Context(), client.ID)
+		h.HandleResponse(c, rotated, err)
+		return nil
+	})
+}
+
+// getAccessibleClient lấy OAuth client theo :id và kiểm tra người gọi có quyền với tổ chức sở hữu
+func (h *OAuthClientHandler) getAccessibleClient(c fiber.Ctx, permissionName string) (*models.OAuthClient, error) {
+	clientID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("ID không hợp lệ: %s", c.Params("id")), common.StatusBadRequest, err)
+	}
+
+	client, err := h.OAuthClientService.FindOneById(c.Context(), clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, permissionName)
+	if err != nil {
+		return nil, err
+	}
+	for _, orgID := range allowedOrgIDs {
+		if orgID == client.OwnerOrganizationID {
+			return &client, nil
+		}
+	}
+	return nil, common.NewError(common.ErrCodeAuth, "Bạn không có quyền quản lý OAuth client này", common.StatusForbidden, nil)
+}
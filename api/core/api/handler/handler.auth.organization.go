@@ -6,7 +6,9 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
+	"meta_commerce/core/global"
 	"meta_commerce/core/utility"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"go.mongodb.org/mongo-driver/bson"
@@ -112,6 +114,16 @@ func (h *OrganizationHandler) InsertOne(c fiber.Ctx) error {
 				return nil
 			}
 
+			if modelParent.ArchivedAt != nil {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeBusinessOperation,
+					"Không thể tạo tổ chức con trong tổ chức đã lưu trữ",
+					common.StatusBadRequest,
+					nil,
+				))
+				return nil
+			}
+
 			// Tính Path: parent.Path + "/" + code
 			orgModel.Path = modelParent.Path + "/" + input.Code
 
@@ -145,22 +157,180 @@ func (h *OrganizationHandler) InsertOne(c fiber.Ctx) error {
 
 // calculateLevel tính toán Level dựa trên Type và Level của parent
 func (h *OrganizationHandler) calculateLevel(orgType string, parentLevel int) int {
-	switch orgType {
-	case models.OrganizationTypeSystem:
-		return -1
-	case models.OrganizationTypeGroup:
-		return 0
-	case models.OrganizationTypeCompany:
-		return 1
-	case models.OrganizationTypeDepartment:
-		return 2
-	case models.OrganizationTypeDivision:
-		return 3
-	case models.OrganizationTypeTeam:
-		// Team có thể là Level 4+ tùy thuộc vào parent
-		return parentLevel + 1
-	default:
-		// Mặc định tăng level lên 1 so với parent
-		return parentLevel + 1
+	return services.CalculateOrganizationLevel(orgType, parentLevel)
+}
+
+// HandleMove chuyển tổ chức (cùng toàn bộ cây con) sang tổ chức cha mới
+// YÊU CẦU QUYỀN Organization.Update trên cả tổ chức được chuyển và tổ chức cha mới
+// @Summary Di chuyển tổ chức
+// @Param id path string true "Organization ID"
+// @Param body body dto.OrganizationMoveInput true "Tổ chức cha mới"
+// @Success 200 {object} models.Organization
+// @Router /organization/{id}/move [post]
+func (h *OrganizationHandler) HandleMove(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID tổ chức không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		var input dto.OrganizationMoveInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+		newParentID, err := primitive.ObjectIDFromHex(input.NewParentID)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "newParentId không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		// User phải quản lý được cả tổ chức được chuyển và tổ chức cha mới
+		if err := h.requireOrganizationAccess(c, "Organization.Update", orgID, newParentID); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.OrganizationService.MoveOrganization(c.Context(), orgID, newParentID)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleGetTree trả về cây tổ chức lồng nhau kèm số role và số user của từng node
+// Chỉ gồm tổ chức trong phạm vi Organization.Read của user (tổ chức cha chung ngoài phạm vi được giữ dưới dạng node ẩn thông tin)
+// @Summary Cây tổ chức
+// @Param rootId query string false "Chỉ lấy cây con bắt đầu từ tổ chức này"
+// @Param includeInactive query bool false "Bao gồm tổ chức không hoạt động"
+// @Success 200 {array} services.OrganizationTreeNode
+// @Router /organization/tree [get]
+func (h *OrganizationHandler) HandleGetTree(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		var rootID *primitive.ObjectID
+		if rootIDStr := c.Query("rootId"); rootIDStr != "" {
+			id, err := primitive.ObjectIDFromHex(rootIDStr)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "rootId không hợp lệ", common.StatusBadRequest, err))
+				return nil
+			}
+			rootID = &id
+		}
+
+		// Chỉ trả về tổ chức trong phạm vi Organization.Read của user
+		userIDStr, _ := c.Locals("user_id").(string)
+		userID, _ := primitive.ObjectIDFromHex(userIDStr)
+		allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, "Organization.Read")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.OrganizationService.GetOrganizationTree(c.Context(), rootID, c.Query("includeInactive") == "true", allowedOrgIDs)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleArchive lưu trữ tổ chức cùng toàn bộ tổ chức con
+// YÊU CẦU QUYỀN Organization.Delete trên tổ chức
+// @Summary Lưu trữ tổ chức
+// @Param id path string true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Router /organization/{id}/archive [post]
+func (h *OrganizationHandler) HandleArchive(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID tổ chức không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		if err := h.requireOrganizationAccess(c, "Organization.Delete", orgID); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		userIDStr, _ := c.Locals("user_id").(string)
+		userID, _ := primitive.ObjectIDFromHex(userIDStr)
+		data, err := h.OrganizationService.ArchiveOrganization(c.Context(), orgID, userID)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleRestore khôi phục tổ chức đã lưu trữ
+// YÊU CẦU QUYỀN Organization.Update trên tổ chức cha (tổ chức đã lưu trữ không còn trong phạm vi dữ liệu)
+// @Summary Khôi phục tổ chức
+// @Param id path string true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Router /organization/{id}/restore [post]
+func (h *OrganizationHandler) HandleRestore(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID tổ chức không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		if err := h.requireArchivedOrganizationAccess(c, "Organization.Update", orgID); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.OrganizationService.RestoreOrganization(c.Context(), orgID)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandlePurge xóa vĩnh viễn tổ chức đã lưu trữ quá thời gian retention (ORGANIZATION_PURGE_RETENTION_DAYS)
+// YÊU CẦU QUYỀN Organization.Purge trên tổ chức cha
+// @Summary Xóa vĩnh viễn tổ chức
+// @Param id path string true "Organization ID"
+// @Success 200 {object} services.OrganizationPurgeResult
+// @Router /organization/{id}/purge [delete]
+func (h *OrganizationHandler) HandlePurge(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID tổ chức không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		if err := h.requireArchivedOrganizationAccess(c, "Organization.Purge", orgID); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		retention := time.Duration(global.MongoDB_ServerConfig.OrganizationPurgeRetentionDays) * 24 * time.Hour
+		data, err := h.OrganizationService.PurgeOrganization(c.Context(), orgID, retention)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// requireArchivedOrganizationAccess kiểm tra quyền trên tổ chức đã lưu trữ qua tổ chức cha
+// (tổ chức đã lưu trữ không còn trong phạm vi dữ liệu); tổ chức gốc chỉ Administrator được thao tác
+func (h *OrganizationHandler) requireArchivedOrganizationAccess(c fiber.Ctx, permissionName string, orgID primitive.ObjectID) error {
+	org, err := h.OrganizationService.FindOneById(c.Context(), orgID)
+	if err != nil {
+		return err
+	}
+	if org.ParentID != nil {
+		return h.requireOrganizationAccess(c, permissionName, *org.ParentID)
+	}
+
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	isAdmin, err := services.IsUserAdministrator(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return common.NewError(common.ErrCodeAuth, "Chỉ Administrator được thao tác tổ chức gốc đã lưu trữ", common.StatusForbidden, nil)
 	}
+	return nil
 }
@@ -5,6 +5,11 @@ import (
 	"meta_commerce/core/api/dto"
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/utility"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // RoleHandler xử lý các route liên quan đến vai trò cho Fiber
@@ -53,4 +58,100 @@ func NewRoleHandler() (*RoleHandler, error) {
 	return handler, nil
 }
 
-// Các hàm đặc thù của Role (nếu có) sẽ được thêm vào đây
+// HandleInstantiateTemplate khởi tạo role từ template cho các tổ chức con
+// Parameters:
+//   - c: Context của Fiber chứa thông tin request (param id: ID template)
+//
+// Request Body:
+//   - organizationIds: Danh sách tổ chức con của tổ chức sở hữu template (1-100)
+//   - name: Tên role (optional, mặc định là tên template)
+//
+// Response:
+//   - 200: Danh sách role đã tạo (mỗi role có templateId = template)
+//   - 400: Template, tổ chức không hợp lệ (details.errorCode = INVALID_ROLE_INHERITANCE)
+//   - 403: Không có quyền với tổ chức hoặc không cấp được quyền của template (details.errorCode = DELEGATION_DENIED)
+//   - 409: Tổ chức đã có role cùng tên
+func (h *RoleHandler) HandleInstantiateTemplate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		templateID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID template không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		var input dto.RoleInstantiateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+		orgIDs := make([]primitive.ObjectID, 0, len(input.OrganizationIDs))
+		seen := make(map[primitive.ObjectID]bool, len(input.OrganizationIDs))
+		for _, id := range input.OrganizationIDs {
+			orgID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("organizationId không hợp lệ: %s", id), common.StatusBadRequest, err))
+				return nil
+			}
+			if !seen[orgID] {
+				seen[orgID] = true
+				orgIDs = append(orgIDs, orgID)
+			}
+		}
+
+		// User phải được tạo role trong tất cả tổ chức đích
+		if err := h.requireOrganizationAccess(c, "Role.Insert", orgIDs...); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.RoleService.InstantiateTemplate(c.Context(), templateID, orgIDs, input.Name)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleDetachTemplate tách role khỏi template: quyền đang kế thừa từ template được sao chép thành quyền riêng của role
+// Parameters:
+//   - c: Context của Fiber chứa thông tin request (param id: ID role)
+//
+// Response:
+//   - 200: Role sau khi tách (không còn templateId)
+//   - 400: Role không được khởi tạo từ template
+func (h *RoleHandler) HandleDetachTemplate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		id := c.Params("id")
+		if err := h.validateOrganizationAccess(c, id); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.RoleService.DetachTemplate(c.Context(), utility.String2ObjectID(id))
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
+// HandleGetPermissions lấy quyền hiệu lực của role: quyền riêng và quyền kế thừa từ role cha / template
+// Parameters:
+//   - c: Context của Fiber chứa thông tin request (param id: ID role)
+//
+// Response:
+//   - 200: Danh sách role permission, mỗi item có sourceRoleId/sourceRoleName (role sở hữu quyền) và permissionName
+func (h *RoleHandler) HandleGetPermissions(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		id := c.Params("id")
+		if err := h.validateOrganizationAccess(c, id); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.RoleService.GetRolePermissions(c.Context(), utility.String2ObjectID(id))
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
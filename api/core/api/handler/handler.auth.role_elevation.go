@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventType notification của luồng nâng quyền tạm thời
+const (
+	EventTypeRoleElevationRequested = "role_elevation_requested" // User gửi yêu cầu - routing rule gửi tới người duyệt
+	EventTypeRoleElevationApproved  = "role_elevation_approved"  // Yêu cầu được duyệt - gửi thêm cho người yêu cầu
+)
+
+// RoleElevationHandler xử lý các route yêu cầu nâng quyền tạm thời (just-in-time)
+type RoleElevationHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	RoleElevationService *services.RoleElevationService
+	userService          *services.UserService
+	roleService          *services.RoleService
+}
+
+// NewRoleElevationHandler tạo một instance mới của RoleElevationHandler
+func NewRoleElevationHandler() (*RoleElevationHandler, error) {
+	roleElevationService, err := services.NewRoleElevationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role elevation service: %v", err)
+	}
+
+	userService, err := services.NewUserService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user service: %v", err)
+	}
+
+	roleService, err := services.NewRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role service: %v", err)
+	}
+
+	return &RoleElevationHandler{
+		BaseHandler:          &BaseHandler[interface{}, interface{}, interface{}]{},
+		RoleElevationService: roleElevationService,
+		userService:          userService,
+		roleService:          roleService,
+	}, nil
+}
+
+// currentUser lấy ID user đang đăng nhập; service account và token "login as" không dùng được luồng nâng quyền
+func (h *RoleElevationHandler) currentUser(c fiber.Ctx) (primitive.ObjectID, error) {
+	if c.Locals("service_account") != nil {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "Service account không thể dùng luồng nâng quyền tạm thời", common.StatusForbidden, nil)
+	}
+	if c.Locals("impersonator_id") != nil {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "Không thể xin hoặc duyệt nâng quyền khi đang đăng nhập thay người dùng", common.StatusForbidden, nil)
+	}
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, err)
+	}
+	return userID, nil
+}
+
+// HandleRequest tạo yêu cầu nâng quyền tạm thời cho user hiện tại
+// @Summary Xin nâng quyền tạm thời
+// @Param body body dto.RoleElevationRequestInput true "Role, số giờ và lý do"
+// @Success 200 {object} models.RoleElevationRequest
+// @Router /elevation-request [post]
+func (h *RoleElevationHandler) HandleRequest(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		userID, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.RoleElevationRequestInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		request, err := h.RoleElevationService.Request(c.Context(), userID, input)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("role_elevation_request", c, map[string]interface{}{
+			"request_id":     request.ID.Hex(),
+			"role_id":        request.RoleID.Hex(),
+			"duration_hours": request.DurationHours,
+		})
+		h.notify(EventTypeRoleElevationRequested, request, false)
+
+		h.HandleResponse(c, request, nil)
+		return nil
+	})
+}
+
+// HandleListMine trả về các yêu cầu nâng quyền của user hiện tại (mới nhất trước)
+// @Router /elevation-request/mine [get]
+func (h *RoleElevationHandler) HandleListMine(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		userID, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		requests, err := h.RoleElevationService.Find(c.Context(), bson.M{"userId": userID}, mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+		h.HandleResponse(c, requests, err)
+		return nil
+	})
+}
+
+// HandleCancel để người yêu cầu tự hủy yêu cầu đang chờ duyệt
+// @Router /elevation-request/{id}/cancel [post]
+func (h *RoleElevationHandler) HandleCancel(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		userID, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		request, err := h.RoleElevationService.Cancel(c.Context(), userID, id)
+		h.HandleResponse(c, request, err)
+		return nil
+	})
+}
+
+// HandleListPending trả về các yêu cầu đang chờ duyệt trong các tổ chức mà user có quyền UserRole.Update
+// YÊU CẦU QUYỀN UserRole.Update
+// @Router /elevation-approval/pending [get]
+func (h *RoleElevationHandler) HandleListPending(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		userID, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		requests, err := h.RoleElevationService.ListPendingForApprover(c.Context(), userID)
+		h.HandleResponse(c, requests, err)
+		return nil
+	})
+}
+
+// HandleApprove duyệt yêu cầu nâng quyền: tạo role assignment có thời hạn cho người yêu cầu
+// YÊU CẦU QUYỀN UserRole.Update trong tổ chức của role
+// @Param body body dto.RoleElevationDecisionInput false "Ghi chú"
+// @Router /elevation-approval/{id}/approve [post]
+func (h *RoleElevationHandler) HandleApprove(c fiber.Ctx) error {
+	return h.handleDecision(c, true)
+}
+
+// HandleReject từ chối yêu cầu nâng quyền
+// YÊU CẦU QUYỀN UserRole.Update trong tổ chức của role
+// @Param body body dto.RoleElevationDecisionInput false "Ghi chú"
+// @Router /elevation-approval/{id}/reject [post]
+func (h *RoleElevationHandler) HandleReject(c fiber.Ctx) error {
+	return h.handleDecision(c, false)
+}
+
+// handleDecision xử lý chung cho duyệt/từ chối
+func (h *RoleElevationHandler) handleDecision(c fiber.Ctx, approve bool) error {
+	return h.SafeHandler(c, func() error {
+		approverID, err := h.currentUser(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		var input dto.RoleElevationDecisionInput
+		if len(c.Body()) > 0 {
+			if err := h.ParseRequestBody(c, &input); err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+				return nil
+			}
+		}
+
+		var request *models.RoleElevationRequest
+		action := "role_elevation_reject"
+		if approve {
+			action = "role_elevation_approve"
+			request, err = h.RoleElevationService.Approve(c.Context(), approverID, id, input.Note)
+		} else {
+			request, err = h.RoleElevationService.Reject(c.Context(), approverID, id, input.Note)
+		}
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction(action, c, map[string]interface{}{
+			"request_id":        request.ID.Hex(),
+			"requester_user_id": request.UserID.Hex(),
+			"role_id":           request.RoleID.Hex(),
+			"note":              input.Note,
+		})
+		if approve {
+			h.notify(EventTypeRoleElevationApproved, request, true)
+		}
+
+		h.HandleResponse(c, request, nil)
+		return nil
+	})
+}
+
+// notify gửi notification event của yêu cầu nâng quyền ở background
+// notifyRequester = true thì gửi thêm cho email của người yêu cầu (ngoài routing rule của eventType)
+func (h *RoleElevationHandler) notify(eventType string, request *models.RoleElevationRequest, notifyRequester bool) {
+	req := *request
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		payload := map[string]interface{}{
+			"requestId":           req.ID.Hex(),
+			"userId":              req.UserID.Hex(),
+			"roleId":              req.RoleID.Hex(),
+			"ownerOrganizationId": req.OwnerOrganizationID.Hex(),
+			"durationHours":       req.DurationHours,
+			"justification":       req.Justification,
+			"status":              req.Status,
+		}
+		if req.ValidUntil != nil {
+			payload["validUntil"] = *req.ValidUntil
+		}
+		if req.DecidedBy != nil {
+			payload["decidedBy"] = req.DecidedBy.Hex()
+			payload["decisionNote"] = req.DecisionNote
+		}
+		if role, err := h.roleService.FindOneById(ctx, req.RoleID); err == nil {
+			payload["roleName"] = role.Name
+		}
+		user, userErr := h.userService.FindOneById(ctx, req.UserID)
+		if userErr == nil {
+			payload["userName"] = user.Name
+			payload["userEmail"] = user.Email
+		}
+
+		log := logrus.WithField("request_id", req.ID.Hex())
+		if _, err := notification.Trigger(ctx, eventType, payload); err != nil {
+			log.WithError(err).Warnf("Failed to trigger %s notification", eventType)
+		}
+		if notifyRequester && userErr == nil && user.Email != "" {
+			if _, err := notification.TriggerForRecipient(ctx, eventType, user.Email, payload); err != nil {
+				log.WithError(err).Warnf("Failed to send %s notification to requester", eventType)
+			}
+		}
+	}()
+}
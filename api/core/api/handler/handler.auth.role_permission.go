@@ -6,10 +6,8 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
-	"time"
 
 	"github.com/gofiber/fiber/v3"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -102,42 +100,57 @@ func (h *RolePermissionHandler) HandleUpdateRolePermissions(c fiber.Ctx) error {
 		return nil
 	}
 
-	// Xóa tất cả role permission cũ của role
-	filter := bson.M{"roleId": roleId}
-	if _, err := h.RolePermissionService.DeleteMany(c.Context(), filter); err != nil {
+	// Thay thế danh sách quyền (service tự invalidate cache quyền của role)
+	rolePermissions, err := h.RolePermissionService.UpdateRolePermissions(c.Context(), roleId, input.Permissions)
+	if err != nil {
 		h.HandleResponse(c, nil, err)
 		return nil
 	}
 
-	// Tạo danh sách role permission mới
-	var rolePermissions []models.RolePermission
-	now := time.Now().Unix()
+	h.HandleResponse(c, rolePermissions, nil)
+	return nil
+}
 
-	for _, perm := range input.Permissions {
-		permissionIdObj, err := primitive.ObjectIDFromHex(perm.PermissionID)
-		if err != nil {
-			continue // Bỏ qua các permissionId không hợp lệ
-		}
-		rolePermission := models.RolePermission{
-			ID:           primitive.NewObjectID(),
-			RoleID:       roleId,
-			PermissionID: permissionIdObj,
-			Scope:        perm.Scope, // Sử dụng scope từ request
-			CreatedAt:    now,
-			UpdatedAt:    now,
-		}
-		rolePermissions = append(rolePermissions, rolePermission)
+// HandleValidateConditions chạy thử điều kiện (ABAC) của role permission trên các document mẫu
+// Parameters:
+//   - c: Context của Fiber chứa thông tin request
+//
+// Returns:
+//   - error: Lỗi nếu có
+//
+// Request Body:
+//   - conditions: Điều kiện cần kiểm tra (cùng định dạng với field conditions của role permission)
+//   - documents: Danh sách document mẫu (1-100), tên field theo MongoDB
+//
+// Response:
+//   - 200: Điều kiện hợp lệ, kèm kết quả so khớp từng document
+//     {
+//     "message": "Thành công",
+//     "data": {
+//     "valid": true,
+//     "results": [
+//     { "index": 0, "matched": true },
+//     { "index": 1, "matched": false, "failed": ["status"] }
+//     ]
+//     }
+//     }
+//   - 400: Dữ liệu không hợp lệ hoặc điều kiện không hợp lệ (details.errorCode = INVALID_PERMISSION_CONDITIONS)
+func (h *RolePermissionHandler) HandleValidateConditions(c fiber.Ctx) error {
+	input := new(dto.RolePermissionConditionDryRunInput)
+	if err := h.ParseRequestBody(c, input); err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
 	}
 
-	// Thêm các role permission mới bằng InsertMany thay vì InsertOne
-	if len(rolePermissions) > 0 {
-		_, err = h.RolePermissionService.InsertMany(c.Context(), rolePermissions)
-		if err != nil {
-			h.HandleResponse(c, nil, err)
-			return nil
-		}
+	results, err := services.DryRunConditions(input.Conditions, input.Documents)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
 	}
 
-	h.HandleResponse(c, rolePermissions, nil)
+	h.HandleResponse(c, fiber.Map{
+		"valid":   true,
+		"results": results,
+	}, nil)
 	return nil
 }
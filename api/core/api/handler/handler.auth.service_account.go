@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceAccountHandler xử lý các route liên quan đến service account và API key
+// Kế thừa từ BaseHandler để có các chức năng CRUD cơ bản cho service account
+type ServiceAccountHandler struct {
+	BaseHandler[models.ServiceAccount, dto.ServiceAccountCreateInput, dto.ServiceAccountUpdateInput]
+	ServiceAccountService *services.ServiceAccountService
+	APIKeyService         *services.APIKeyService
+}
+
+// NewServiceAccountHandler tạo mới ServiceAccountHandler
+func NewServiceAccountHandler() (*ServiceAccountHandler, error) {
+	serviceAccountService, err := services.NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+
+	apiKeyService, err := services.NewAPIKeyService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key service: %v", err)
+	}
+
+	baseHandler := NewBaseHandler[models.ServiceAccount, dto.ServiceAccountCreateInput, dto.ServiceAccountUpdateInput](serviceAccountService)
+	return &ServiceAccountHandler{
+		BaseHandler:           *baseHandler,
+		ServiceAccountService: serviceAccountService,
+		APIKeyService:         apiKeyService,
+	}, nil
+}
+
+// HandleCreateAPIKey tạo API key mới cho service account
+// POST /service-account/:id/create-api-key
+// Key đầy đủ chỉ trả về một lần trong field "key" - client phải lưu lại ngay
+func (h *ServiceAccountHandler) HandleCreateAPIKey(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if err := h.rejectAPIKeyCaller(c); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.APIKeyCreateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+		input.ServiceAccountID = c.Params("id")
+
+		account, err := h.getAccessibleServiceAccount(c, c.Params("id"), "ApiKey.Insert")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if account.IsDisabled {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeBusinessOperation, "Service account đã bị vô hiệu hóa", common.StatusBadRequest, nil))
+			return nil
+		}
+
+		key, err := h.APIKeyService.CreateKey(c.Context(), *account, input, h.currentUserID(c))
+		h.HandleResponse(c, key, err)
+		return nil
+	})
+}
+
+// HandleListAPIKeys trả về các API key của service account (không bao gồm key đầy đủ)
+// GET /service-account/:id/api-keys
+func (h *ServiceAccountHandler) HandleListAPIKeys(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		account, err := h.getAccessibleServiceAccount(c, c.Params("id"), "ApiKey.Read")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		keys, err := h.APIKeyService.FindByServiceAccount(c.Context(), account.ID)
+		h.HandleResponse(c, keys, err)
+		return nil
+	})
+}
+
+// HandleRotateAPIKey xoay vòng API key: tạo key mới cùng cấu hình, key cũ bị thu hồi (hoặc còn hiệu lực trong gracePeriodSeconds)
+// POST /service-account/api-keys/:keyId/rotate
+func (h *ServiceAccountHandler) HandleRotateAPIKey(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if err := h.rejectAPIKeyCaller(c); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		var input dto.APIKeyRotateInput
+		if len(c.Body()) > 0 {
+			if err := h.ParseRequestBody(c, &input); err != nil {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeValidationFormat,
+					fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+					common.StatusBadRequest,
+					err,
+				))
+				return nil
+			}
+		}
+		if input.GracePeriodSeconds < 0 {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "gracePeriodSeconds không được âm", common.StatusBadRequest, nil))
+			return nil
+		}
+
+		key, err := h.getAccessibleAPIKey(c, "ApiKey.Update")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		newKey, err := h.APIKeyService.RotateKey(c.Context(), key.ID, time.Duration(input.GracePeriodSeconds)*time.Second, h.currentUserID(c))
+		h.HandleResponse(c, newKey, err)
+		return nil
+	})
+}
+
+// HandleRevokeAPIKey thu hồi API key
+// POST /service-account/api-keys/:keyId/revoke
+func (h *ServiceAccountHandler) HandleRevokeAPIKey(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if err := h.rejectAPIKeyCaller(c); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		key, err := h.getAccessibleAPIKey(c, "ApiKey.Delete")
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		revoked, err := h.APIKeyService.RevokeKey(c.Context(), key.ID, h.currentUserID(c))
+		h.HandleResponse(c, revoked, err)
+		return nil
+	})
+}
+
+// rejectAPIKeyCaller chặn quản lý API key khi người gọi xác thực bằng API key
+// Key mới (hoặc key xoay vòng) có thể rộng hơn scopes của key đang gọi nên chỉ user đăng nhập mới được tạo/xoay vòng/thu hồi key
+func (h *ServiceAccountHandler) rejectAPIKeyCaller(c fiber.Ctx) error {
+	if _, ok := c.Locals("api_key").(models.APIKey); ok {
+		return common.NewError(common.ErrCodeAuth, "Không thể quản lý API key khi xác thực bằng API key", common.StatusForbidden, nil)
+	}
+	return nil
+}
+
+// currentUserID lấy ID của principal đang gọi API (user hoặc service account)
+func (h *ServiceAccountHandler) currentUserID(c fiber.Ctx) primitive.ObjectID {
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	return userID
+}
+
+// getAccessibleServiceAccount lấy service account theo ID và kiểm tra người gọi có quyền với tổ chức sở hữu
+func (h *ServiceAccountHandler) getAccessibleServiceAccount(c fiber.Ctx, idStr string, permissionName string) (*models.ServiceAccount, error) {
+	accountID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("ID không hợp lệ: %s", idStr), common.StatusBadRequest, err)
+	}
+
+	account, err := h.ServiceAccountService.FindOneById(c.Context(), accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chỉ tổ chức có scope 0/1: scope 2 (bản ghi của mình) không cho quản lý service account của tổ chức
+	access, err := services.GetUserDataAccess(c.Context(), h.currentUserID(c), permissionName)
+	if err != nil {
+		return nil, err
+	}
+	for _, orgID := range access.OrganizationIDs {
+		if orgID == account.OwnerOrganizationID {
+			return &account, nil
+		}
+	}
+	return nil, common.NewError(common.ErrCodeAuth, "Bạn không có quyền quản lý service account này", common.StatusForbidden, nil)
+}
+
+// getAccessibleAPIKey lấy API key theo :keyId và kiểm tra quyền qua service account sở hữu
+func (h *ServiceAccountHandler) getAccessibleAPIKey(c fiber.Ctx, permissionName string) (*models.APIKey, error) {
+	keyID, err := primitive.ObjectIDFromHex(c.Params("keyId"))
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("ID không hợp lệ: %s", c.Params("keyId")), common.StatusBadRequest, err)
+	}
+
+	key, err := h.APIKeyService.FindOneById(c.Context(), keyID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.getAccessibleServiceAccount(c, key.ServiceAccountID.Hex(), permissionName); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
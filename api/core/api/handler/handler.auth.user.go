@@ -8,11 +8,11 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
+	"meta_commerce/core/identity"
 	"meta_commerce/core/logger"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -22,6 +22,7 @@ type UserHandler struct {
 	userService     *services.UserService
 	roleService     *services.RoleService
 	userRoleService *services.UserRoleService
+	loginGuard      *services.LoginGuardService
 }
 
 // NewUserHandler tạo một instance mới của UserHandler
@@ -42,12 +43,18 @@ func NewUserHandler() (*UserHandler, error) {
 		return nil, fmt.Errorf("failed to create user role service: %v", err)
 	}
 
+	loginGuard, err := services.NewLoginGuardService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login guard service: %v", err)
+	}
+
 	baseHandler := NewBaseHandler[models.User, dto.UserCreateInput, dto.UserChangeInfoInput](userService)
 	handler := &UserHandler{
 		BaseHandler:     baseHandler,
 		userService:     userService,
 		roleService:     roleService,
 		userRoleService: userRoleService,
+		loginGuard:      loginGuard,
 	}
 
 	return handler, nil
@@ -61,6 +68,16 @@ func (h *UserHandler) HandleLogout(c fiber.Ctx) error {
 		return nil
 	}
 
+	// Token "login as": đăng xuất chỉ kết thúc phiên impersonation, không đăng xuất thiết bị của user
+	if tokenClaims, ok := c.Locals("token_claims").(*models.JwtToken); ok && tokenClaims.ImpersonatorID != "" {
+		err := h.userService.EndImpersonation(context.Background(), tokenClaims)
+		if err == nil {
+			logger.LogAction("impersonation_end", c, nil)
+		}
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
 	var input dto.UserLogoutInput
 	if err := h.ParseRequestBody(c, &input); err != nil {
 		h.HandleResponse(c, nil, err)
@@ -73,7 +90,13 @@ func (h *UserHandler) HandleLogout(c fiber.Ctx) error {
 		return nil
 	}
 
-	err = h.userService.Logout(context.Background(), objID, &input)
+	// Claims của access token hiện tại (do AuthMiddleware lưu) để thu hồi ngay token đang dùng
+	var claims *models.JwtToken
+	if tokenClaims, ok := c.Locals("token_claims").(*models.JwtToken); ok {
+		claims = tokenClaims
+	}
+
+	err = h.userService.Logout(context.Background(), objID, &input, claims)
 	h.HandleResponse(c, nil, err)
 	return nil
 }
@@ -113,6 +136,9 @@ func (h *UserHandler) HandleGetProfile(c fiber.Ctx) error {
 
 // HandleUpdateProfile cập nhật thông tin profile của người dùng
 func (h *UserHandler) HandleUpdateProfile(c fiber.Ctx) error {
+	if rejectImpersonation(c, h.HandleResponse) {
+		return nil
+	}
 	userID := c.Locals("user_id")
 	if userID == nil {
 		h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, nil))
@@ -199,7 +225,9 @@ func (h *UserHandler) HandleGetUserRoles(c fiber.Ctx) error {
 
 	// Lấy danh sách user role - CHỈ lấy các role trực tiếp của user
 	// KHÔNG lấy children/parents organizations
-	filter := bson.M{"userId": objID}
+	// Chỉ lấy assignment còn hiệu lực (validFrom/validUntil)
+	filter := services.ActiveUserRoleFilter()
+	filter["userId"] = objID
 	userRoles, err := h.userRoleService.Find(context.Background(), filter, nil)
 	if err != nil {
 		logger.GetAppLogger().WithFields(logrus.Fields{
@@ -264,7 +292,7 @@ func (h *UserHandler) HandleGetUserRoles(c fiber.Ctx) error {
 		// QUAN TRỌNG: Context làm việc là ROLE, không phải organization
 		// Mỗi role = một context làm việc
 		// Organization được tự động suy ra từ role khi user chọn role
-		result = append(result, map[string]interface{}{
+		item := map[string]interface{}{
 			"roleId":             role.ID.Hex(),
 			"roleName":           role.Name,
 			"ownerOrganizationId": org.ID.Hex(), // Nhất quán với model Role (OwnerOrganizationID)
@@ -272,7 +300,12 @@ func (h *UserHandler) HandleGetUserRoles(c fiber.Ctx) error {
 			"organizationCode":   org.Code,
 			"organizationType":   org.Type,
 			"organizationLevel":  org.Level,
-		})
+		}
+		// Role tạm thời (JIT) - frontend hiển thị thời điểm hết hạn
+		if userRole.ValidUntil != nil {
+			item["validUntil"] = *userRole.ValidUntil
+		}
+		result = append(result, item)
 	}
 
 	logger.GetAppLogger().WithFields(logrus.Fields{
@@ -288,12 +321,14 @@ func (h *UserHandler) HandleGetUserRoles(c fiber.Ctx) error {
 // HandleLoginWithFirebase xử lý đăng nhập bằng Firebase ID token
 // @Summary Đăng nhập bằng Firebase
 // @Description Xác thực Firebase ID token và trả về JWT token nếu thành công
+// @Description Thất bại nhiều lần liên tiếp (theo danh tính hoặc IP) sẽ bị khóa tạm thời (429)
 // @Accept json
 // @Produce json
 // @Param input body dto.FirebaseLoginInput true "Firebase ID token và hwid"
 // @Success 200 {object} models.User
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /auth/login/firebase [post]
 func (h *UserHandler) HandleLoginWithFirebase(c fiber.Ctx) error {
 	var input dto.FirebaseLoginInput
@@ -302,7 +337,102 @@ func (h *UserHandler) HandleLoginWithFirebase(c fiber.Ctx) error {
 		return nil
 	}
 
-	user, err := h.userService.LoginWithFirebase(context.Background(), &input)
+	input.IP = c.IP()
+	input.UserAgent = c.Get("User-Agent")
+
+	info := services.LoginRequestInfo{
+		Provider:     identity.ProviderFirebase,
+		IdentityHint: services.LoginIdentityHint(identity.ProviderFirebase, input.IDToken),
+		IP:           input.IP,
+		UserAgent:    input.UserAgent,
+		Hwid:         input.Hwid,
+	}
+	user, err := h.guardedLogin(c, info, func(ctx context.Context) (*models.User, error) {
+		return h.userService.LoginWithFirebase(ctx, &input)
+	})
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	// Loại bỏ thông tin nhạy cảm trước khi trả về
+	user.Password = ""
+	user.Salt = ""
+	user.Tokens = nil
+
+	h.HandleResponse(c, user, nil)
+	return nil
+}
+
+// HandleLoginWithIdentity xử lý đăng nhập bằng token của identity provider đã đăng ký
+// @Summary Đăng nhập bằng identity provider
+// @Description Xác thực token của provider (firebase, oidc, local...) và trả về JWT token nếu thành công
+// @Description Thất bại nhiều lần liên tiếp (theo danh tính hoặc IP) sẽ bị khóa tạm thời (429)
+// @Accept json
+// @Produce json
+// @Param provider path string true "Tên identity provider"
+// @Param input body dto.IdentityLoginInput true "Token của provider và hwid"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/login/{provider} [post]
+func (h *UserHandler) HandleLoginWithIdentity(c fiber.Ctx) error {
+	var input dto.IdentityLoginInput
+	if err := h.ParseRequestBody(c, &input); err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	input.IP = c.IP()
+	input.UserAgent = c.Get("User-Agent")
+
+	provider := c.Params("provider")
+	info := services.LoginRequestInfo{
+		Provider:     provider,
+		IdentityHint: services.LoginIdentityHint(provider, input.IDToken),
+		IP:           input.IP,
+		UserAgent:    input.UserAgent,
+		Hwid:         input.Hwid,
+	}
+	user, err := h.guardedLogin(c, info, func(ctx context.Context) (*models.User, error) {
+		return h.userService.LoginWithIdentity(ctx, provider, &input)
+	})
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	// Loại bỏ thông tin nhạy cảm trước khi trả về
+	user.Password = ""
+	user.Salt = ""
+	user.Tokens = nil
+
+	h.HandleResponse(c, user, nil)
+	return nil
+}
+
+// HandleRefreshToken xử lý lấy access token mới bằng refresh token
+// @Summary Làm mới access token
+// @Description Nhận refresh token, trả về access token và refresh token mới (refresh token cũ bị thu hồi)
+// @Accept json
+// @Produce json
+// @Param input body dto.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *UserHandler) HandleRefreshToken(c fiber.Ctx) error {
+	var input dto.RefreshTokenInput
+	if err := h.ParseRequestBody(c, &input); err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	input.IP = c.IP()
+	input.UserAgent = c.Get("User-Agent")
+
+	user, err := h.userService.RefreshToken(context.Background(), &input)
 	if err != nil {
 		h.HandleResponse(c, nil, err)
 		return nil
@@ -316,3 +446,132 @@ func (h *UserHandler) HandleLoginWithFirebase(c fiber.Ctx) error {
 	h.HandleResponse(c, user, nil)
 	return nil
 }
+
+// --------------------------------
+// Session Methods (mỗi thiết bị/hwid là một phiên đăng nhập)
+// --------------------------------
+
+// HandleGetSessions lấy danh sách thiết bị đang đăng nhập của người dùng hiện tại
+// @Summary Danh sách phiên đăng nhập
+// @Description Trả về các thiết bị đang đăng nhập (hwid, thời điểm đăng nhập, hoạt động gần nhất, IP, User-Agent).
+// @Description Phiên của thiết bị đang gọi API có current = true.
+// @Produce json
+// @Success 200 {array} map[string]interface{}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *UserHandler) HandleGetSessions(c fiber.Ctx) error {
+	objID, err := h.getCurrentUserObjectID(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	sessions, err := h.userService.ListSessions(context.Background(), objID)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	h.HandleResponse(c, sessionsToResponse(sessions, getCurrentHwid(c)), nil)
+	return nil
+}
+
+// HandleDeleteSession đăng xuất một thiết bị của người dùng hiện tại
+// @Summary Đăng xuất một thiết bị
+// @Param hwid path string true "Hardware ID của thiết bị"
+// @Success 200 {object} nil
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/sessions/{hwid} [delete]
+func (h *UserHandler) HandleDeleteSession(c fiber.Ctx) error {
+	if rejectImpersonation(c, h.HandleResponse) {
+		return nil
+	}
+	objID, err := h.getCurrentUserObjectID(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	hwid := c.Params("hwid")
+	if hwid == "" {
+		h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationInput, "Thiếu hwid", common.StatusBadRequest, nil))
+		return nil
+	}
+
+	err = h.userService.RevokeSession(context.Background(), objID, hwid, "session_revoke")
+	h.HandleResponse(c, nil, err)
+	return nil
+}
+
+// HandleRevokeOtherSessions đăng xuất tất cả thiết bị khác ngoài thiết bị đang gọi API
+// @Summary Đăng xuất các thiết bị khác
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/sessions/revoke-others [post]
+func (h *UserHandler) HandleRevokeOtherSessions(c fiber.Ctx) error {
+	if rejectImpersonation(c, h.HandleResponse) {
+		return nil
+	}
+	objID, err := h.getCurrentUserObjectID(c)
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	revoked, err := h.userService.RevokeOtherSessions(context.Background(), objID, getCurrentHwid(c), "session_revoke_others")
+	if err != nil {
+		h.HandleResponse(c, nil, err)
+		return nil
+	}
+
+	h.HandleResponse(c, fiber.Map{"revokedCount": revoked}, nil)
+	return nil
+}
+
+// getCurrentUserObjectID lấy ObjectID của user đã xác thực từ context
+func (h *UserHandler) getCurrentUserObjectID(c fiber.Ctx) (primitive.ObjectID, error) {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, nil)
+	}
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, common.NewError(common.ErrCodeValidationFormat, "Invalid user ID", common.StatusBadRequest, err)
+	}
+	return objID, nil
+}
+
+// getCurrentHwid lấy hwid của thiết bị đang gọi API từ claims của access token
+func getCurrentHwid(c fiber.Ctx) string {
+	if claims, ok := c.Locals("token_claims").(*models.JwtToken); ok {
+		return claims.Hwid
+	}
+	return ""
+}
+
+// sessionsToResponse chuyển danh sách phiên thành dữ liệu trả về cho client
+func sessionsToResponse(sessions []models.Token, currentHwid string) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, map[string]interface{}{
+			"hwid":             session.Hwid,
+			"createdAt":        session.CreatedAt,
+			"lastSeenAt":       session.LastSeenAt,
+			"ip":               session.IP,
+			"userAgent":        session.UserAgent,
+			"refreshExpiresAt": session.RefreshExpiresAt,
+			"current":          currentHwid != "" && session.Hwid == currentHwid,
+		})
+	}
+	return result
+}
+
+// rejectImpersonation từ chối các thao tác thay đổi tài khoản (profile, phiên đăng nhập) khi đang dùng token "login as"
+// Quản trị viên chỉ được xem như user, không được đăng xuất thiết bị hay sửa thông tin của user
+func rejectImpersonation(c fiber.Ctx, respond func(fiber.Ctx, interface{}, error)) bool {
+	if c.Locals("impersonator_id") == nil {
+		return false
+	}
+	respond(c, nil, common.NewError(common.ErrCodeAuth, "Không thể thay đổi tài khoản của người dùng khi đang đăng nhập thay", common.StatusForbidden, nil))
+	return true
+}
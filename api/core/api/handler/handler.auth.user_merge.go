@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+
+	"meta_commerce/core/api/dto"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/logger"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserMergeHandler xử lý các route phát hiện và gộp tài khoản trùng
+type UserMergeHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	MergeService *services.UserMergeService
+}
+
+// NewUserMergeHandler tạo một instance mới của UserMergeHandler
+func NewUserMergeHandler() (*UserMergeHandler, error) {
+	mergeService, err := services.NewUserMergeService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user merge service: %v", err)
+	}
+
+	return &UserMergeHandler{
+		BaseHandler:  &BaseHandler[interface{}, interface{}, interface{}]{},
+		MergeService: mergeService,
+	}, nil
+}
+
+// HandleListCandidates trả về các nhóm tài khoản có cùng email/số điện thoại đã xác thực nhưng khác firebaseUid
+// YÊU CẦU QUYỀN User.Merge
+// @Summary Danh sách tài khoản trùng
+// @Success 200 {array} dto.DuplicateUserGroup
+// @Router /account-merge/candidates [get]
+func (h *UserMergeHandler) HandleListCandidates(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		groups, err := h.MergeService.FindDuplicateCandidates(c.Context())
+		h.HandleResponse(c, groups, err)
+		return nil
+	})
+}
+
+// HandleMerge gộp tài khoản nguồn vào tài khoản đích (tài khoản nguồn bị xóa)
+// YÊU CẦU QUYỀN User.Merge
+// @Summary Gộp tài khoản trùng
+// @Param body body dto.UserMergeInput true "Tài khoản nguồn và tài khoản giữ lại"
+// @Success 200 {object} dto.UserMergeResult
+// @Router /account-merge [post]
+func (h *UserMergeHandler) HandleMerge(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if c.Locals("impersonator_id") != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Không thể gộp tài khoản khi đang đăng nhập thay người dùng", common.StatusForbidden, nil))
+			return nil
+		}
+
+		var input dto.UserMergeInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+		sourceID, err := primitive.ObjectIDFromHex(input.SourceUserID)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "sourceUserId không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+		targetID, err := primitive.ObjectIDFromHex(input.TargetUserID)
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "targetUserId không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		result, err := h.MergeService.Merge(c.Context(), sourceID, targetID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("user_merge", c, map[string]interface{}{
+			"source_user_id": result.SourceUserID,
+			"target_user_id": result.TargetUserID,
+			"user_roles":     result.UserRoles,
+			"agents":         result.Agents,
+			"access_tokens":  result.AccessTokens,
+			"references":     result.References,
+		})
+		h.HandleResponse(c, result, nil)
+		return nil
+	})
+}
@@ -57,6 +57,15 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) InsertOne(c fiber.Ctx) error
 			}
 		}
 
+		// ✅ Ghi nhận người tạo (phân quyền scope 2 - chỉ bản ghi của mình)
+		h.setCreatedBy(c, input)
+
+		// ✅ Document thêm mới phải thỏa điều kiện của quyền Insert (nếu có)
+		if err := h.checkPermissionConditions(c, permissionActionInsert, *input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		// ✅ Lưu userID vào context để service có thể check admin
 		ctx := c.Context()
 		if userIDStr, ok := c.Locals("user_id").(string); ok && userIDStr != "" {
@@ -109,6 +118,15 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) InsertMany(c fiber.Ctx) error
 					h.setOrganizationID(&inputs[i], *activeOrgID)
 				}
 			}
+
+			// ✅ Ghi nhận người tạo (phân quyền scope 2 - chỉ bản ghi của mình)
+			h.setCreatedBy(c, &inputs[i])
+
+			// ✅ Document thêm mới phải thỏa điều kiện của quyền Insert (nếu có)
+			if err := h.checkPermissionConditions(c, permissionActionInsert, inputs[i]); err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
 		}
 
 		data, err := h.BaseService.InsertMany(c.Context(), inputs)
@@ -137,6 +155,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOne(c fiber.Ctx) error {
 		// ✅ Tự động thêm filter ownerOrganizationId nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 		filter = h.applyOrganizationFilter(c, filter)
 
+		// ✅ Chỉ lấy document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		options, err := h.processMongoOptions(c, true)
 		if err != nil {
 			h.HandleResponse(c, nil, err)
@@ -187,6 +208,10 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOneById(c fiber.Ctx) erro
 		}
 
 		data, err := h.BaseService.FindOneById(c.Context(), utility.String2ObjectID(id))
+		if err == nil {
+			// ✅ Document phải thỏa điều kiện của quyền Read (nếu có)
+			err = h.checkPermissionConditions(c, permissionActionRead, data)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
@@ -230,6 +255,10 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindManyByIds(c fiber.Ctx) er
 		}
 
 		data, err := h.BaseService.FindManyByIds(c.Context(), objectIds)
+		if err == nil {
+			// ✅ Bỏ các document không thỏa điều kiện của quyền Read (nếu có)
+			data = h.filterByPermissionConditions(c, permissionActionRead, data)
+		}
 		h.HandleResponse(c, data, err)
 		return nil
 	})
@@ -260,6 +289,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindWithPagination(c fiber.Ct
 		// ✅ Tự động thêm filter ownerOrganizationId nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 		filter = h.applyOrganizationFilter(c, filter)
 
+		// ✅ Chỉ lấy document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		options, err := h.processMongoOptions(c, false)
 		if err != nil {
 			h.HandleResponse(c, nil, err)
@@ -322,6 +354,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) Find(c fiber.Ctx) error {
 		// ✅ Tự động thêm filter ownerOrganizationId nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 		filter = h.applyOrganizationFilter(c, filter)
 
+		// ✅ Chỉ lấy document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		options, err := h.processMongoOptions(c, false)
 		if err != nil {
 			h.HandleResponse(c, nil, err)
@@ -399,6 +434,19 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateOne(c fiber.Ctx) error
 			delete(updateData, "ownerOrganizationId")
 		}
 
+		// ✅ createdBy/assignedTo không sửa qua update (đổi người phụ trách qua /assign)
+		h.stripRecordOwnershipFields(updateData)
+
+		// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+		h.stripReadOnlyFields(c, updateData)
+
+		// ✅ Chỉ cập nhật document thỏa điều kiện của quyền Update, dữ liệu mới không được vi phạm điều kiện
+		filter, err = h.applyUpdateConditions(c, filter, updateData)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		// Tạo update data với $set operator
 		update := &services.UpdateData{
 			Set: updateData,
@@ -465,6 +513,19 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateMany(c fiber.Ctx) error
 			delete(updateData, "ownerOrganizationId")
 		}
 
+		// ✅ createdBy/assignedTo không sửa qua update (đổi người phụ trách qua /assign)
+		h.stripRecordOwnershipFields(updateData)
+
+		// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+		h.stripReadOnlyFields(c, updateData)
+
+		// ✅ Chỉ cập nhật document thỏa điều kiện của quyền Update, dữ liệu mới không được vi phạm điều kiện
+		filter, err = h.applyUpdateConditions(c, filter, updateData)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		// Tạo update data với $set operator
 		update := &services.UpdateData{
 			Set: updateData,
@@ -553,6 +614,18 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpdateById(c fiber.Ctx) error
 			delete(updateData, "ownerOrganizationId")
 		}
 
+		// ✅ createdBy/assignedTo không sửa qua update (đổi người phụ trách qua /assign)
+		h.stripRecordOwnershipFields(updateData)
+
+		// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+		h.stripReadOnlyFields(c, updateData)
+
+		// ✅ Document hiện tại và dữ liệu mới phải thỏa điều kiện của quyền Update (nếu có)
+		if err := h.checkDocumentConditionsByID(c, permissionActionUpdate, utility.String2ObjectID(id), updateData); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		// Tạo update data với $set operator
 		update := &services.UpdateData{
 			Set: updateData,
@@ -588,6 +661,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) DeleteOne(c fiber.Ctx) error
 			return nil
 		}
 
+		// ✅ Chỉ xóa document thỏa điều kiện của quyền Delete (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionDelete, filter)
+
 		err = h.BaseService.DeleteOne(c.Context(), filter)
 		h.HandleResponse(c, nil, err)
 		return nil
@@ -613,6 +689,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) DeleteMany(c fiber.Ctx) error
 		// ✅ Tự động thêm filter ownerOrganizationId nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
 		filter = h.applyOrganizationFilter(c, filter)
 
+		// ✅ Chỉ xóa document thỏa điều kiện của quyền Delete (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionDelete, filter)
+
 		count, err := h.BaseService.DeleteMany(c.Context(), filter)
 		h.HandleResponse(c, count, err)
 		return nil
@@ -658,6 +737,12 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) DeleteById(c fiber.Ctx) error
 			}
 		}
 
+		// ✅ Document phải thỏa điều kiện của quyền Delete (nếu có)
+		if err := h.checkDocumentConditionsByID(c, permissionActionDelete, utility.String2ObjectID(id), nil); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		err := h.BaseService.DeleteById(ctx, utility.String2ObjectID(id))
 		h.HandleResponse(c, nil, err)
 		return nil
@@ -688,6 +773,19 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOneAndUpdate(c fiber.Ctx)
 			return nil
 		}
 
+		// ✅ createdBy/assignedTo không sửa qua update (đổi người phụ trách qua /assign)
+		h.stripRecordOwnershipFields(updateData)
+
+		// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+		h.stripReadOnlyFields(c, updateData)
+
+		// ✅ Chỉ cập nhật document thỏa điều kiện của quyền Update, dữ liệu mới không được vi phạm điều kiện
+		filter, err = h.applyUpdateConditions(c, filter, updateData)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
 		// Tạo update data với $set operator
 		update := &services.UpdateData{
 			Set: updateData,
@@ -716,6 +814,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) FindOneAndDelete(c fiber.Ctx)
 			return nil
 		}
 
+		// ✅ Chỉ xóa document thỏa điều kiện của quyền Delete (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionDelete, filter)
+
 		data, err := h.BaseService.FindOneAndDelete(c.Context(), filter, nil)
 		h.HandleResponse(c, data, err)
 		return nil
@@ -767,6 +868,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) CountDocuments(c fiber.Ctx) e
 			"endpoint": c.Path(),
 		}).Debug("Filter sau khi parse")
 
+		// ✅ Chỉ đếm document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		count, err := h.BaseService.CountDocuments(c.Context(), filter)
 		h.HandleResponse(c, count, err)
 		return nil
@@ -795,6 +899,19 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) Distinct(c fiber.Ctx) error {
 			return nil
 		}
 
+		// ✅ Không cho distinct/filter theo field bị ẩn/che với user hiện tại (field policy)
+		if err := h.validateFilterFieldPolicies(c, map[string]interface{}{field: nil}); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if err := h.validateFilterFieldPolicies(c, filter); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		// ✅ Chỉ lấy giá trị từ document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		data, err := h.BaseService.Distinct(c.Context(), field, filter)
 		h.HandleResponse(c, data, err)
 		return nil
@@ -853,6 +970,25 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) Upsert(c fiber.Ctx) error {
 			}
 		}
 
+		// ✅ createdBy/assignedTo không nhận từ upsert (đổi người phụ trách qua /assign)
+		h.clearRecordOwnership(input)
+
+		// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+		h.clearReadOnlyFields(c, input)
+
+		// ✅ Chỉ cập nhật document thỏa điều kiện của quyền Update, dữ liệu mới không được vi phạm điều kiện
+		if len(h.permissionConditions(c, permissionActionUpdate)) > 0 {
+			set, err := services.DocumentToConditionMap(*input)
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			if filter, err = h.applyUpdateConditions(c, filter, set); err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+		}
+
 		// Gọi Upsert với struct T - extract sẽ tự động chạy trong ToMap() khi ToUpdateData() được gọi
 		data, err := h.BaseService.Upsert(c.Context(), filter, *input)
 		h.HandleResponse(c, data, err)
@@ -904,6 +1040,25 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) UpsertMany(c fiber.Ctx) error
 					h.setOrganizationID(&inputs[i], *activeOrgID)
 				}
 			}
+
+			// ✅ createdBy/assignedTo không nhận từ upsert (đổi người phụ trách qua /assign)
+			h.clearRecordOwnership(&inputs[i])
+
+			// ✅ Bỏ qua các field user không có quyền sửa (field policy readonly)
+			h.clearReadOnlyFields(c, &inputs[i])
+
+			// ✅ Chỉ cập nhật document thỏa điều kiện của quyền Update, dữ liệu mới không được vi phạm điều kiện
+			if len(h.permissionConditions(c, permissionActionUpdate)) > 0 {
+				set, err := services.DocumentToConditionMap(inputs[i])
+				if err != nil {
+					h.HandleResponse(c, nil, err)
+					return nil
+				}
+				if filter, err = h.applyUpdateConditions(c, filter, set); err != nil {
+					h.HandleResponse(c, nil, err)
+					return nil
+				}
+			}
 		}
 
 		// Convert filter từ bson.M sang map[string]interface{} cho UpsertMany
@@ -936,6 +1091,9 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) DocumentExists(c fiber.Ctx) e
 			return nil
 		}
 
+		// ✅ Chỉ xét document thỏa điều kiện của quyền Read (nếu có)
+		filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 		exists, err := h.BaseService.DocumentExists(c.Context(), filter)
 		h.HandleResponse(c, exists, err)
 		return nil
@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/utility"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// ====================================
+// FIELD POLICY (PHÂN QUYỀN THEO FIELD)
+// ====================================
+//
+// Field policy khai báo bằng struct tag `fieldPolicy` trên model, gồm các quy tắc cách nhau bởi ";":
+//
+//	fieldPolicy:"mask:phone=PcPosCustomer.ReadSensitive;readonly=PcPosCustomer.UpdateSensitive"
+//
+// Mỗi quy tắc có dạng action[:masker]=permission và áp dụng khi user KHÔNG có permission
+// (bỏ "=permission" thì quy tắc luôn áp dụng):
+//   - hidden: ẩn field khỏi response, không được filter/distinct theo field
+//   - mask: che một phần giá trị (string, []string) theo masker phone | email, mặc định che giữa chuỗi;
+//     không được filter/distinct theo field
+//   - readonly: bỏ qua field trong dữ liệu update/upsert
+
+// Các action của field policy
+const (
+	fieldPolicyHidden   = "hidden"
+	fieldPolicyMask     = "mask"
+	fieldPolicyReadOnly = "readonly"
+)
+
+// fieldRule một quy tắc của field policy
+type fieldRule struct {
+	Action     string
+	Masker     string
+	Permission string // Rỗng = luôn áp dụng
+}
+
+// fieldPolicy các quy tắc của một field (theo tên json và bson)
+type fieldPolicy struct {
+	JSONName string
+	BSONName string
+	Rules    []fieldRule
+}
+
+// fieldPolicyCache cache field policy đã parse theo kiểu model (reflect.Type → []fieldPolicy)
+var fieldPolicyCache sync.Map
+
+// getFieldPolicies lấy field policy của kiểu model (parse struct tag một lần rồi cache)
+func getFieldPolicies(t reflect.Type) []fieldPolicy {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := fieldPolicyCache.Load(t); ok {
+		return cached.([]fieldPolicy)
+	}
+
+	var policies []fieldPolicy
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("fieldPolicy")
+		if tag == "" {
+			continue
+		}
+
+		policy := fieldPolicy{
+			JSONName: tagName(field.Tag.Get("json"), field.Name),
+			BSONName: tagName(field.Tag.Get("bson"), field.Name),
+		}
+		for _, part := range strings.Split(tag, ";") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			var rule fieldRule
+			action := part
+			if idx := strings.Index(part, "="); idx >= 0 {
+				action, rule.Permission = part[:idx], strings.TrimSpace(part[idx+1:])
+			}
+			if idx := strings.Index(action, ":"); idx >= 0 {
+				action, rule.Masker = action[:idx], action[idx+1:]
+			}
+			rule.Action = strings.TrimSpace(action)
+			switch rule.Action {
+			case fieldPolicyHidden, fieldPolicyMask, fieldPolicyReadOnly:
+				policy.Rules = append(policy.Rules, rule)
+			default:
+				logrus.WithFields(logrus.Fields{
+					"model":  t.Name(),
+					"field":  field.Name,
+					"action": rule.Action,
+				}).Warn("Unknown field policy action, rule ignored")
+			}
+		}
+		if len(policy.Rules) > 0 {
+			policies = append(policies, policy)
+		}
+	}
+
+	fieldPolicyCache.Store(t, policies)
+	return policies
+}
+
+// tagName lấy tên field từ json/bson tag (bỏ các option như omitempty), tag rỗng thì dùng tên field
+func tagName(tag string, fallback string) string {
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// fieldPolicies field policy của model T
+func (h *BaseHandler[T, CreateInput, UpdateInput]) fieldPolicies() []fieldPolicy {
+	return getFieldPolicies(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// hasPermission kiểm tra user có permission trong role context hiện tại không
+// Permissions do AuthMiddleware lưu vào context; không có (route không qua AuthMiddleware) = không có quyền
+func (h *BaseHandler[T, CreateInput, UpdateInput]) hasPermission(c fiber.Ctx, name string) bool {
+	permissions, _ := c.Locals("permissions").(services.EffectivePermissions)
+	return permissions.Has(name)
+}
+
+// readRestrictions các field bị hạn chế khi đọc với user hiện tại (json name → rule hidden/mask)
+// Một field vừa có rule hidden vừa có rule mask đang áp dụng thì hidden được ưu tiên
+func (h *BaseHandler[T, CreateInput, UpdateInput]) readRestrictions(c fiber.Ctx) map[string]fieldRule {
+	policies := h.fieldPolicies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	restrictions := make(map[string]fieldRule)
+	for _, policy := range policies {
+		for _, rule := range policy.Rules {
+			if rule.Action == fieldPolicyReadOnly {
+				continue
+			}
+			if rule.Permission != "" && h.hasPermission(c, rule.Permission) {
+				continue
+			}
+			if current, ok := restrictions[policy.JSONName]; ok && current.Action == fieldPolicyHidden {
+				continue
+			}
+			restrictions[policy.JSONName] = rule
+		}
+	}
+	return restrictions
+}
+
+// readOnlyFields các field user hiện tại không được ghi (gồm cả tên json và bson)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) readOnlyFields(c fiber.Ctx) map[string]fieldPolicy {
+	policies := h.fieldPolicies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]fieldPolicy)
+	for _, policy := range policies {
+		for _, rule := range policy.Rules {
+			if rule.Action != fieldPolicyReadOnly {
+				continue
+			}
+			if rule.Permission != "" && h.hasPermission(c, rule.Permission) {
+				continue
+			}
+			fields[policy.JSONName] = policy
+			fields[policy.BSONName] = policy
+		}
+	}
+	return fields
+}
+
+// applyReadFieldPolicies ẩn/che các field bị hạn chế trong dữ liệu trả về
+// Hỗ trợ T, *T, []T và *models.PaginateResult[T]; các kiểu khác (count, distinct...) giữ nguyên
+func (h *BaseHandler[T, CreateInput, UpdateInput]) applyReadFieldPolicies(c fiber.Ctx, data interface{}) interface{} {
+	restrictions := h.readRestrictions(c)
+	if len(restrictions) == 0 || data == nil {
+		return data
+	}
+
+	switch v := data.(type) {
+	case T:
+		return maskDocument(v, restrictions)
+	case *T:
+		if v == nil {
+			return data
+		}
+		return maskDocument(*v, restrictions)
+	case []T:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, maskDocument(item, restrictions))
+		}
+		return items
+	case *models.PaginateResult[T]:
+		if v == nil {
+			return data
+		}
+		items := make([]interface{}, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, maskDocument(item, restrictions))
+		}
+		return fiber.Map{
+			"page":      v.Page,
+			"limit":     v.Limit,
+			"itemCount": v.ItemCount,
+			"items":     items,
+			"total":     v.Total,
+			"totalPage": v.TotalPage,
+		}
+	}
+	return data
+}
+
+// maskDocument chuyển document thành map (theo json tag) rồi ẩn/che các field bị hạn chế
+// Giá trị không phải string/[]string của field mask sẽ bị ẩn vì không che được một phần
+func maskDocument(doc interface{}, restrictions map[string]fieldRule) interface{} {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	var result map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return doc
+	}
+
+	for name, rule := range restrictions {
+		value, ok := result[name]
+		if !ok || value == nil {
+			continue
+		}
+		if rule.Action == fieldPolicyHidden {
+			delete(result, name)
+			continue
+		}
+		if masked, ok := maskValue(value, rule.Masker); ok {
+			result[name] = masked
+		} else {
+			delete(result, name)
+		}
+	}
+	return result
+}
+
+// maskValue che giá trị string hoặc mảng string theo masker
+func maskValue(value interface{}, masker string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return maskString(v, masker), true
+	case []interface{}:
+		masked := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			masked = append(masked, maskString(s, masker))
+		}
+		return masked, true
+	}
+	return nil, false
+}
+
+// maskString che chuỗi theo masker (phone | email | mặc định)
+func maskString(value string, masker string) string {
+	switch masker {
+	case "phone":
+		return utility.MaskPhone(value)
+	case "email":
+		return utility.MaskEmail(value)
+	default:
+		return utility.MaskString(value, 2, 2)
+	}
+}
+
+// stripReadOnlyFields xóa các field user không được ghi khỏi dữ liệu update ($set)
+// Hỗ trợ cả key dạng "field.subfield"; giá trị bị che từ response gửi ngược lại cũng bị bỏ qua an toàn
+func (h *BaseHandler[T, CreateInput, UpdateInput]) stripReadOnlyFields(c fiber.Ctx, updateData map[string]interface{}) {
+	fields := h.readOnlyFields(c)
+	if len(fields) == 0 {
+		return
+	}
+	for key := range updateData {
+		if _, ok := fields[strings.SplitN(key, ".", 2)[0]]; ok {
+			delete(updateData, key)
+		}
+	}
+}
+
+// clearReadOnlyFields đặt về giá trị rỗng các field user không được ghi trong model trước khi upsert (dùng reflection)
+// Field rỗng bị bỏ qua khi upsert nên giá trị hiện có được giữ nguyên
+func (h *BaseHandler[T, CreateInput, UpdateInput]) clearReadOnlyFields(c fiber.Ctx, model interface{}) {
+	fields := h.readOnlyFields(c)
+	if len(fields) == 0 {
+		return
+	}
+
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return
+	}
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := fields[tagName(t.Field(i).Tag.Get("json"), t.Field(i).Name)]; !ok {
+			continue
+		}
+		if field := val.Field(i); field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+// validateFilterFieldPolicies không cho filter theo field bị ẩn/che với user hiện tại, kể cả qua $expr/$where
+// Tránh dò giá trị thật của field (ví dụ filter theo số điện thoại) qua kết quả trả về
+func (h *BaseHandler[T, CreateInput, UpdateInput]) validateFilterFieldPolicies(c fiber.Ctx, filter map[string]interface{}) error {
+	restrictions := h.readRestrictions(c)
+	if len(restrictions) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	operators := make(map[string]bool)
+	collectFilterFields(filter, fields, operators)
+
+	// $expr, $where, $text... tham chiếu field bên trong biểu thức, không kiểm tra được theo tên field
+	// → không cho dùng khi model có field bị ẩn/che với user (tránh dò giá trị thật qua filter)
+	if len(operators) > 0 {
+		names := make([]string, 0, len(operators))
+		for operator := range operators {
+			names = append(names, operator)
+		}
+		sort.Strings(names)
+		return common.NewError(
+			common.ErrCodeAuthRole,
+			"Không được dùng toán tử này trong filter khi có trường dữ liệu nhạy cảm, chỉ hỗ trợ $and/$or/$nor",
+			common.StatusForbidden,
+			map[string]interface{}{"operators": names},
+		)
+	}
+
+	var denied []string
+	for field := range fields {
+		if _, ok := restrictions[strings.SplitN(field, ".", 2)[0]]; ok {
+			denied = append(denied, field)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	sort.Strings(denied)
+	return common.NewError(
+		common.ErrCodeAuthRole,
+		"Không có quyền lọc theo trường dữ liệu nhạy cảm",
+		common.StatusForbidden,
+		map[string]interface{}{"fields": denied},
+	)
+}
+
+// collectFilterFields thu thập tên field trong filter MongoDB (kể cả trong $and/$or/$nor)
+// operators: toán tử cấp document khác $and/$or/$nor ($expr, $where, $text...)
+func collectFilterFields(filter map[string]interface{}, fields map[string]bool, operators map[string]bool) {
+	for key, value := range filter {
+		if !strings.HasPrefix(key, "$") {
+			fields[key] = true
+			continue
+		}
+		if key != "$and" && key != "$or" && key != "$nor" {
+			operators[key] = true
+			continue
+		}
+		if conditions, ok := value.([]interface{}); ok {
+			for _, condition := range conditions {
+				if m, ok := condition.(map[string]interface{}); ok {
+					collectFilterFields(m, fields, operators)
+				}
+			}
+		}
+	}
+}
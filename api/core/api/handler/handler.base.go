@@ -197,13 +197,18 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) validateUserHasAccessToOrg(c
 	// Lấy permission name từ route (nếu có)
 	permissionName := h.getPermissionNameFromRoute(c)
 
-	// Lấy allowed organization IDs
-	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, permissionName)
+	// Lấy phạm vi dữ liệu của user
+	access, err := services.GetUserDataAccess(c.Context(), userID, permissionName)
 	if err != nil {
 		return err
 	}
 
 	// Kiểm tra organization có trong allowed list không
+	// Tổ chức chỉ có scope 2: chỉ tạo/chuyển bản ghi của mình vào được nếu model có createdBy/assignedTo
+	allowedOrgIDs := access.OrganizationIDs
+	if h.hasRecordOwnershipFields() {
+		allowedOrgIDs = access.AllOrganizationIDs()
+	}
 	for _, allowedOrgID := range allowedOrgIDs {
 		if allowedOrgID == orgID {
 			return nil // ✅ Có quyền
@@ -219,8 +224,30 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) validateUserHasAccessToOrg(c
 	)
 }
 
+// requireOrganizationAccess kiểm tra user có tất cả tổ chức trong phạm vi dữ liệu của permission
+func (h *BaseHandler[T, CreateInput, UpdateInput]) requireOrganizationAccess(c fiber.Ctx, permissionName string, orgIDs ...primitive.ObjectID) error {
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, permissionName)
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[primitive.ObjectID]bool, len(allowedOrgIDs))
+	for _, id := range allowedOrgIDs {
+		allowed[id] = true
+	}
+	for _, orgID := range orgIDs {
+		if !allowed[orgID] {
+			return common.NewError(common.ErrCodeAuth, "Bạn không có quyền quản lý tổ chức này", common.StatusForbidden, nil)
+		}
+	}
+	return nil
+}
+
 // applyOrganizationFilter tự động thêm filter ownerOrganizationId
 // CHỈ áp dụng nếu model có field OwnerOrganizationID (phân quyền dữ liệu)
+// Tổ chức chỉ có scope 2: chỉ bản ghi createdBy/assignedTo là user (nếu model có các field này)
 func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fiber.Ctx, baseFilter bson.M) bson.M {
 	// ✅ QUAN TRỌNG: Kiểm tra model có field OwnerOrganizationID không
 	if !h.hasOrganizationIDField() {
@@ -240,34 +267,79 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) applyOrganizationFilter(c fib
 		return baseFilter
 	}
 
-	// Lấy allowed organization IDs (chỉ từ scope, KHÔNG có parents)
-	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, permissionName)
-	if err != nil || len(allowedOrgIDs) == 0 {
+	// Lấy phạm vi dữ liệu (chỉ từ scope, KHÔNG có parents)
+	access, err := services.GetUserDataAccess(c.Context(), userID, permissionName)
+	if err != nil || len(access.AllOrganizationIDs()) == 0 {
 		return baseFilter
 	}
 
-	// Lấy organizations được share với user's organizations
-	sharedOrgIDs, err := services.GetSharedOrganizationIDs(c.Context(), allowedOrgIDs, permissionName)
-	if err == nil && len(sharedOrgIDs) > 0 {
-		// Hợp nhất allowedOrgIDs và sharedOrgIDs
-		allOrgIDsMap := make(map[primitive.ObjectID]bool)
-		for _, orgID := range allowedOrgIDs {
-			allOrgIDsMap[orgID] = true
-		}
-		for _, orgID := range sharedOrgIDs {
-			allOrgIDsMap[orgID] = true
+	// Model không có createdBy/assignedTo: scope 2 được xem như scope 0
+	allowedOrgIDs := access.OrganizationIDs
+	ownOrgIDs := access.OwnOrganizationIDs
+	if !h.hasRecordOwnershipFields() {
+		allowedOrgIDs = access.AllOrganizationIDs()
+		ownOrgIDs = nil
+	}
+
+	// Lấy phạm vi được share với user's organizations (share còn hiệu lực)
+	// Tổ chức chỉ có scope 2 không nhận share
+	var sharedScopes []services.SharedOrganizationScope
+	if len(allowedOrgIDs) > 0 {
+		sharedScopes, err = services.GetSharedOrganizationScopes(c.Context(), allowedOrgIDs, permissionName)
+		if err != nil {
+			sharedScopes = nil
 		}
+	}
 
-		// Convert back to slice
-		allOrgIDs := make([]primitive.ObjectID, 0, len(allOrgIDsMap))
-		for orgID := range allOrgIDsMap {
-			allOrgIDs = append(allOrgIDs, orgID)
+	// Hợp nhất allowedOrgIDs và tổ chức share toàn bộ dữ liệu
+	allOrgIDsMap := make(map[primitive.ObjectID]bool)
+	for _, orgID := range allowedOrgIDs {
+		allOrgIDsMap[orgID] = true
+	}
+	for _, scope := range sharedScopes {
+		if len(scope.DocumentFilter) == 0 {
+			allOrgIDsMap[scope.OrganizationID] = true
 		}
-		allowedOrgIDs = allOrgIDs
+	}
+	allOrgIDs := make([]primitive.ObjectID, 0, len(allOrgIDsMap))
+	for orgID := range allOrgIDsMap {
+		allOrgIDs = append(allOrgIDs, orgID)
 	}
 
 	// Thêm filter ownerOrganizationId (phân quyền dữ liệu)
-	orgFilter := bson.M{"ownerOrganizationId": bson.M{"$in": allowedOrgIDs}}
+	conditions := make([]bson.M, 0)
+	if len(allOrgIDs) > 0 {
+		conditions = append(conditions, bson.M{"ownerOrganizationId": bson.M{"$in": allOrgIDs}})
+	}
+
+	// Scope 2: chỉ bản ghi do user tạo hoặc được giao (tổ chức đã xem toàn bộ qua share thì bỏ qua)
+	ownConditionOrgIDs := make([]primitive.ObjectID, 0, len(ownOrgIDs))
+	for _, orgID := range ownOrgIDs {
+		if !allOrgIDsMap[orgID] {
+			ownConditionOrgIDs = append(ownConditionOrgIDs, orgID)
+		}
+	}
+	if len(ownConditionOrgIDs) > 0 {
+		condition := services.OwnRecordFilter(userID)
+		condition["ownerOrganizationId"] = bson.M{"$in": ownConditionOrgIDs}
+		conditions = append(conditions, condition)
+	}
+
+	// Share giới hạn theo DocumentFilter: chỉ document của tổ chức đó khớp filter
+	for _, scope := range sharedScopes {
+		if len(scope.DocumentFilter) == 0 || allOrgIDsMap[scope.OrganizationID] {
+			continue
+		}
+		condition := bson.M{"ownerOrganizationId": scope.OrganizationID}
+		for field, value := range scope.DocumentFilter {
+			condition[field] = value
+		}
+		conditions = append(conditions, condition)
+	}
+	orgFilter := conditions[0]
+	if len(conditions) > 1 {
+		orgFilter = bson.M{"$or": conditions}
+	}
 
 	// Kết hợp với baseFilter
 	if len(baseFilter) == 0 {
@@ -301,29 +373,49 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) validateOrganizationAccess(c
 		return err
 	}
 
-	// Lấy organizationId từ document (dùng reflection)
-	docOrgID := h.getOrganizationIDFromModel(doc)
-	if docOrgID == nil {
-		return nil // Không có organizationId, không cần validate
-	}
-
-	// Lấy allowed organization IDs
+	// Lấy phạm vi dữ liệu của user
 	userIDStr, _ := c.Locals("user_id").(string)
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 	permissionName := h.getPermissionNameFromRoute(c)
 
-	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, permissionName)
+	access, err := services.GetUserDataAccess(c.Context(), userID, permissionName)
 	if err != nil {
 		return err
 	}
 
+	return h.checkDocumentAccess(doc, userID, access)
+}
+
+// checkDocumentAccess kiểm tra document có thuộc phạm vi dữ liệu của user không
+// Tổ chức chỉ có scope 2: document phải do user tạo hoặc được giao cho user (nếu model có createdBy/assignedTo)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) checkDocumentAccess(doc T, userID primitive.ObjectID, access *services.UserDataAccess) error {
+	// Lấy organizationId từ document (dùng reflection)
+	docOrgID := h.getOrganizationIDFromModel(doc)
+	if docOrgID == nil {
+		return nil // Không có organizationId, không cần validate
+	}
+
 	// Kiểm tra document có thuộc allowed organizations không
-	for _, allowedOrgID := range allowedOrgIDs {
+	for _, allowedOrgID := range access.OrganizationIDs {
 		if allowedOrgID == *docOrgID {
 			return nil // Có quyền truy cập
 		}
 	}
 
+	for _, ownOrgID := range access.OwnOrganizationIDs {
+		if ownOrgID != *docOrgID {
+			continue
+		}
+		if !h.hasRecordOwnershipFields() {
+			return nil // Model không có createdBy/assignedTo: scope 2 được xem như scope 0
+		}
+		createdBy, assignedTo := h.getRecordOwnershipFromModel(doc)
+		if !userID.IsZero() && (createdBy == userID || assignedTo == userID) {
+			return nil
+		}
+		return common.NewError(common.ErrCodeAuthRole, "Chỉ được truy cập bản ghi do bạn tạo hoặc được giao cho bạn", common.StatusForbidden, nil)
+	}
+
 	return common.NewError(common.ErrCodeAuthRole, "Không có quyền truy cập", common.StatusForbidden, nil)
 }
 
@@ -358,17 +450,8 @@ func NewBaseHandler[T any, CreateInput any, UpdateInput any](baseService service
 				"key",
 				"hash",
 			},
-			AllowedOperators: []string{
-				"$eq",
-				"$gt",
-				"$gte",
-				"$lt",
-				"$lte",
-				"$in",
-				"$nin",
-				"$exists",
-			},
-			MaxFields: 10,
+			AllowedOperators: services.DefaultFilterOperators,
+			MaxFields:        10,
 		},
 	}
 }
@@ -546,6 +629,11 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) processFilter(c fiber.Ctx) (m
 		return nil, err
 	}
 
+	// Không cho filter theo field bị ẩn/che với user hiện tại (field policy)
+	if err := h.validateFilterFieldPolicies(c, filter); err != nil {
+		return nil, err
+	}
+
 	return filter, nil
 }
 
@@ -661,16 +749,7 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) validateFilter(filter map[str
 
 	allowedOperators := h.filterOptions.AllowedOperators
 	if len(allowedOperators) == 0 {
-		allowedOperators = []string{
-			"$eq",
-			"$gt",
-			"$gte",
-			"$lt",
-			"$lte",
-			"$in",
-			"$nin",
-			"$exists",
-		}
+		allowedOperators = services.DefaultFilterOperators
 	}
 
 	maxFields := h.filterOptions.MaxFields
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"fmt"
+	"meta_commerce/core/api/dto"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"reflect"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ====================================
+// RECORD OWNERSHIP HELPER FUNCTIONS
+// ====================================
+
+// hasRecordOwnershipFields kiểm tra model có field CreatedBy và AssignedTo không (dùng reflection)
+// Hai field này dùng cho phân quyền theo bản ghi (scope 2 - chỉ bản ghi do user tạo hoặc được giao)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) hasRecordOwnershipFields() bool {
+	var zero T
+	val := reflect.ValueOf(zero)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return false
+	}
+
+	return val.FieldByName("CreatedBy").IsValid() && val.FieldByName("AssignedTo").IsValid()
+}
+
+// SupportsRecordAssignment collection có hỗ trợ giao bản ghi không (router dùng để đăng ký route /assign)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) SupportsRecordAssignment() bool {
+	return h.hasOrganizationIDField() && h.hasRecordOwnershipFields()
+}
+
+// getRecordOwnershipFromModel lấy createdBy và assignedTo từ model (dùng reflection)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) getRecordOwnershipFromModel(model interface{}) (createdBy primitive.ObjectID, assignedTo primitive.ObjectID) {
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return primitive.NilObjectID, primitive.NilObjectID
+	}
+
+	if field := val.FieldByName("CreatedBy"); field.IsValid() {
+		createdBy, _ = field.Interface().(primitive.ObjectID)
+	}
+	if field := val.FieldByName("AssignedTo"); field.IsValid() {
+		assignedTo, _ = field.Interface().(primitive.ObjectID)
+	}
+	return createdBy, assignedTo
+}
+
+// setCreatedBy gán createdBy = user hiện tại vào model khi tạo mới (dùng reflection)
+// Luôn ghi đè giá trị từ request body: người tạo không được chỉ định từ client
+func (h *BaseHandler[T, CreateInput, UpdateInput]) setCreatedBy(c fiber.Ctx, model interface{}) {
+	if !h.hasRecordOwnershipFields() {
+		return
+	}
+
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	field := val.FieldByName("CreatedBy")
+	if !field.IsValid() || !field.CanSet() {
+		return
+	}
+
+	userID := primitive.NilObjectID
+	if userIDStr, ok := c.Locals("user_id").(string); ok {
+		userID, _ = primitive.ObjectIDFromHex(userIDStr)
+	}
+	field.Set(reflect.ValueOf(userID))
+}
+
+// clearRecordOwnership xóa createdBy/assignedTo trong model trước khi upsert (dùng reflection)
+// Upsert ghi đè bằng $set nên không nhận 2 field này từ request; đổi người phụ trách qua HandleAssign
+func (h *BaseHandler[T, CreateInput, UpdateInput]) clearRecordOwnership(model interface{}) {
+	if !h.hasRecordOwnershipFields() {
+		return
+	}
+
+	val := reflect.ValueOf(model)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	for _, name := range []string{"CreatedBy", "AssignedTo"} {
+		if field := val.FieldByName(name); field.IsValid() && field.CanSet() {
+			field.Set(reflect.ValueOf(primitive.NilObjectID))
+		}
+	}
+}
+
+// stripRecordOwnershipFields xóa createdBy/assignedTo khỏi dữ liệu update
+// createdBy không được sửa; assignedTo chỉ đổi qua HandleAssign (có kiểm tra user được giao)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) stripRecordOwnershipFields(updateData map[string]interface{}) {
+	if !h.hasRecordOwnershipFields() {
+		return
+	}
+	delete(updateData, "createdBy")
+	delete(updateData, "assignedTo")
+}
+
+// HandleAssign giao bản ghi cho user (đổi assignedTo) - chuyển quyền phụ trách bản ghi
+// Body: dto.RecordAssignInput - assignedTo rỗng = bỏ giao
+//
+// Quy tắc:
+//   - Mỗi bản ghi phải nằm trong phạm vi dữ liệu của người thực hiện (giống update-by-id)
+//   - User được giao phải có quyền với tổ chức sở hữu của từng bản ghi (nếu không sẽ không thấy bản ghi)
+//
+// Response: danh sách bản ghi sau khi giao
+func (h *BaseHandler[T, CreateInput, UpdateInput]) HandleAssign(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if !h.SupportsRecordAssignment() {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeBusinessOperation, "Collection không hỗ trợ giao bản ghi", common.StatusBadRequest, nil))
+			return nil
+		}
+
+		var input dto.RecordAssignInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(input.IDs))
+		for _, idStr := range input.IDs {
+			id, err := primitive.ObjectIDFromHex(idStr)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeValidationFormat,
+					fmt.Sprintf("ID '%s' không đúng định dạng MongoDB ObjectID (phải là chuỗi hex 24 ký tự)", idStr),
+					common.StatusBadRequest,
+					nil,
+				))
+				return nil
+			}
+			ids = append(ids, id)
+		}
+
+		assignee := primitive.NilObjectID
+		if input.AssignedTo != "" {
+			var err error
+			assignee, err = primitive.ObjectIDFromHex(input.AssignedTo)
+			if err != nil {
+				h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "assignedTo không hợp lệ", common.StatusBadRequest, err))
+				return nil
+			}
+		}
+
+		docs, err := h.BaseService.FindManyByIds(c.Context(), ids)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if len(docs) != len(ids) {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeBusinessOperation, "Không tìm thấy một số bản ghi cần giao", common.StatusNotFound, nil))
+			return nil
+		}
+
+		// ✅ Validate quyền của người thực hiện với từng bản ghi
+		userIDStr, _ := c.Locals("user_id").(string)
+		userID, _ := primitive.ObjectIDFromHex(userIDStr)
+		access, err := services.GetUserDataAccess(c.Context(), userID, h.getPermissionNameFromRoute(c))
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		for _, doc := range docs {
+			if err := h.checkDocumentAccess(doc, userID, access); err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+		}
+
+		// ✅ Validate user được giao có quyền với tổ chức sở hữu của từng bản ghi
+		update := &services.UpdateData{Unset: map[string]interface{}{"assignedTo": ""}}
+		if !assignee.IsZero() {
+			// User được giao chỉ cần scope 2 tại tổ chức (xem được bản ghi được giao cho mình)
+			assigneeAccess, err := services.GetUserDataAccess(c.Context(), assignee, "")
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+			assigneeOrgIDs := assigneeAccess.AllOrganizationIDs()
+			allowed := make(map[primitive.ObjectID]bool, len(assigneeOrgIDs))
+			for _, orgID := range assigneeOrgIDs {
+				allowed[orgID] = true
+			}
+			for _, doc := range docs {
+				if orgID := h.getOrganizationIDFromModel(doc); orgID != nil && !allowed[*orgID] {
+					h.HandleResponse(c, nil, common.NewError(
+						common.ErrCodeBusinessOperation,
+						"User được giao không có quyền với tổ chức sở hữu bản ghi",
+						common.StatusBadRequest,
+						map[string]interface{}{"organizationId": orgID.Hex()},
+					))
+					return nil
+				}
+			}
+			update = &services.UpdateData{Set: map[string]interface{}{"assignedTo": assignee}}
+		}
+
+		// ✅ Lưu userID vào context để service có thể check admin
+		ctx := c.Context()
+		if !userID.IsZero() {
+			ctx = services.SetUserIDToContext(ctx, userID)
+		}
+
+		if _, err := h.BaseService.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update, nil); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		data, err := h.BaseService.FindManyByIds(c.Context(), ids)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
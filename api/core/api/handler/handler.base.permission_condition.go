@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"strings"
+
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+
+	"github.com/gofiber/fiber/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ====================================
+// PERMISSION CONDITION (ABAC)
+// ====================================
+
+// Thao tác CRUD - dùng để chọn permission của route (<Collection>.<Action>) khi áp dụng điều kiện
+const (
+	permissionActionInsert = "Insert"
+	permissionActionRead   = "Read"
+	permissionActionUpdate = "Update"
+	permissionActionDelete = "Delete"
+)
+
+// permissionConditions các ràng buộc điều kiện của thao tác với user hiện tại
+// Mỗi permission route yêu cầu ứng với action là một ràng buộc (kết hợp AND); mỗi ràng buộc là
+// các bộ điều kiện của những grant cấp permission đó (kết hợp OR). Rỗng = không giới hạn.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) permissionConditions(c fiber.Ctx, action string) [][]map[string]interface{} {
+	routePermissions, _ := c.Locals("routePermissions").([]string)
+	if len(routePermissions) == 0 {
+		return nil
+	}
+	permissions, _ := c.Locals("permissions").(services.EffectivePermissions)
+
+	var constraints [][]map[string]interface{}
+	seen := make(map[string]bool, len(routePermissions))
+	for _, name := range routePermissions {
+		if seen[name] || !strings.HasSuffix(name, "."+action) {
+			continue
+		}
+		seen[name] = true
+		if sets := permissions[name].ConditionSets(); len(sets) > 0 {
+			constraints = append(constraints, sets)
+		}
+	}
+	return constraints
+}
+
+// conditionFilter chuyển các bộ điều kiện (OR) thành filter MongoDB
+// Giá trị ObjectId dạng chuỗi được chuẩn hóa như filter từ client (normalizeFilter)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) conditionFilter(sets []map[string]interface{}) bson.M {
+	or := make([]interface{}, 0, len(sets))
+	for _, conditions := range sets {
+		or = append(or, bson.M(h.normalizeFilter(services.NormalizeConditions(conditions))))
+	}
+	if len(or) == 1 {
+		return or[0].(bson.M)
+	}
+	return bson.M{"$or": or}
+}
+
+// combineConditionFilters kết hợp filter gốc với các filter điều kiện bằng $and
+func combineConditionFilters(filter bson.M, conditionFilters []bson.M) bson.M {
+	if len(conditionFilters) == 0 {
+		return filter
+	}
+	and := make([]interface{}, 0, len(conditionFilters)+1)
+	if len(filter) > 0 {
+		and = append(and, filter)
+	}
+	for _, conditionFilter := range conditionFilters {
+		and = append(and, conditionFilter)
+	}
+	if len(and) == 1 {
+		return and[0].(bson.M)
+	}
+	return bson.M{"$and": and}
+}
+
+// applyPermissionConditions thêm điều kiện của permission (theo thao tác) vào filter
+// Dùng cho đọc, xóa theo filter: chỉ document thỏa điều kiện mới được tìm thấy / bị xóa
+func (h *BaseHandler[T, CreateInput, UpdateInput]) applyPermissionConditions(c fiber.Ctx, action string, filter bson.M) bson.M {
+	constraints := h.permissionConditions(c, action)
+	conditionFilters := make([]bson.M, 0, len(constraints))
+	for _, sets := range constraints {
+		conditionFilters = append(conditionFilters, h.conditionFilter(sets))
+	}
+	return combineConditionFilters(filter, conditionFilters)
+}
+
+// applyUpdateConditions thêm điều kiện của permission Update vào filter của thao tác cập nhật
+// Chỉ giữ các bộ điều kiện mà dữ liệu cập nhật không làm document vi phạm (xem services.CompatibleConditionSets),
+// để document sau khi cập nhật vẫn thỏa điều kiện. Không còn bộ điều kiện nào → 403.
+func (h *BaseHandler[T, CreateInput, UpdateInput]) applyUpdateConditions(c fiber.Ctx, filter bson.M, set map[string]interface{}) (bson.M, error) {
+	constraints := h.permissionConditions(c, permissionActionUpdate)
+	conditionFilters := make([]bson.M, 0, len(constraints))
+	for _, sets := range constraints {
+		compatible := services.CompatibleConditionSets(sets, set)
+		if len(compatible) == 0 {
+			return nil, permissionConditionError(permissionActionUpdate)
+		}
+		conditionFilters = append(conditionFilters, h.conditionFilter(compatible))
+	}
+	return combineConditionFilters(filter, conditionFilters), nil
+}
+
+// checkPermissionConditions kiểm tra document thỏa điều kiện của permission (theo thao tác)
+// Dùng cho thêm mới (document sắp thêm) và thao tác theo ID (document hiện tại)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) checkPermissionConditions(c fiber.Ctx, action string, doc interface{}) error {
+	return h.checkConditionConstraints(h.permissionConditions(c, action), action, doc)
+}
+
+// checkUpdateConditions kiểm tra document hiện tại và dữ liệu cập nhật ($set) thỏa điều kiện của permission Update
+func (h *BaseHandler[T, CreateInput, UpdateInput]) checkUpdateConditions(c fiber.Ctx, doc interface{}, set map[string]interface{}) error {
+	constraints := h.permissionConditions(c, permissionActionUpdate)
+	for i, sets := range constraints {
+		compatible := services.CompatibleConditionSets(sets, set)
+		if len(compatible) == 0 {
+			return permissionConditionError(permissionActionUpdate)
+		}
+		constraints[i] = compatible
+	}
+	return h.checkConditionConstraints(constraints, permissionActionUpdate, doc)
+}
+
+// checkDocumentConditionsByID kiểm tra document theo ID thỏa điều kiện của permission (chỉ đọc document khi có điều kiện)
+// Thao tác Update kiểm tra thêm dữ liệu cập nhật set (xem checkUpdateConditions)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) checkDocumentConditionsByID(c fiber.Ctx, action string, id primitive.ObjectID, set map[string]interface{}) error {
+	if len(h.permissionConditions(c, action)) == 0 {
+		return nil
+	}
+	doc, err := h.BaseService.FindOneById(c.Context(), id)
+	if err != nil {
+		return err
+	}
+	if action == permissionActionUpdate {
+		return h.checkUpdateConditions(c, doc, set)
+	}
+	return h.checkPermissionConditions(c, action, doc)
+}
+
+// checkConditionConstraints kiểm tra document thỏa mọi ràng buộc (mỗi ràng buộc thỏa khi document khớp một bộ điều kiện)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) checkConditionConstraints(constraints [][]map[string]interface{}, action string, doc interface{}) error {
+	if len(constraints) == 0 {
+		return nil
+	}
+	docMap, err := services.DocumentToConditionMap(doc)
+	if err != nil {
+		return err
+	}
+	for _, sets := range constraints {
+		matched := false
+		for _, conditions := range sets {
+			if services.MatchConditions(docMap, conditions) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return permissionConditionError(action)
+		}
+	}
+	return nil
+}
+
+// filterByPermissionConditions bỏ các document không thỏa điều kiện của permission (dùng khi đọc theo danh sách ID)
+func (h *BaseHandler[T, CreateInput, UpdateInput]) filterByPermissionConditions(c fiber.Ctx, action string, docs []T) []T {
+	constraints := h.permissionConditions(c, action)
+	if len(constraints) == 0 {
+		return docs
+	}
+	result := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		if h.checkConditionConstraints(constraints, action, doc) == nil {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// permissionConditionError lỗi document không thỏa điều kiện của permission
+func permissionConditionError(action string) error {
+	return common.NewError(
+		common.ErrCodeAuthRole,
+		"Bản ghi hoặc dữ liệu không thỏa điều kiện của quyền",
+		common.StatusForbidden,
+		map[string]interface{}{
+			"errorCode": "PERMISSION_CONDITION_DENIED",
+			"action":    action,
+		},
+	)
+}
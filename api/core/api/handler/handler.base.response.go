@@ -62,6 +62,8 @@ func (h *BaseHandler[T, CreateInput, UpdateInput]) HandleResponse(c fiber.Ctx, d
 	}
 
 	// Trường hợp thành công
+	// ✅ Ẩn/che các field nhạy cảm theo field policy của model
+	data = h.applyReadFieldPolicies(c, data)
 	c.Status(common.StatusOK).JSON(fiber.Map{
 		"code":    common.StatusOK,
 		"message": common.MsgSuccess,
@@ -98,6 +98,11 @@ func (h *FbConversationHandler) HandleFindAllSortByApiUpdate(c fiber.Ctx) error
 		filter = bson.M{"pageId": pageId}
 	}
 
+	// ✅ Tự động thêm filter ownerOrganizationId (và scope 2 - chỉ bản ghi của mình)
+	filter = h.applyOrganizationFilter(c, filter)
+	// ✅ Chỉ lấy document thỏa điều kiện của quyền Read (nếu có)
+	filter = h.applyPermissionConditions(c, permissionActionRead, filter)
+
 	// Gọi service để lấy dữ liệu
 	result, err := h.FbConversationService.FindAllSortByApiUpdate(context.Background(), page, limit, filter)
 	h.HandleResponse(c, result, err)
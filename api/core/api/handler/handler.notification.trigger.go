@@ -2,15 +2,11 @@ package handler
 
 import (
 	"fmt"
-	"time"
 
-	models "meta_commerce/core/api/models/mongodb"
-	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
 	"meta_commerce/core/notification"
 
 	"github.com/gofiber/fiber/v3"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // NotificationTriggerHandler xử lý việc trigger notification
@@ -91,8 +87,7 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 		}
 
 		// Tạo queue items cho mỗi route
-		queueItems := make([]*models.NotificationQueueItem, 0)
-		channelService, err := services.NewNotificationChannelService()
+		queueItems, err := notification.BuildQueueItems(c.Context(), routes, req.EventType, req.Payload)
 		if err != nil {
 			c.Status(common.StatusInternalServerError).JSON(fiber.Map{
 				"code":    common.ErrCodeBusinessOperation.Code,
@@ -102,47 +97,6 @@ func (h *NotificationTriggerHandler) HandleTriggerNotification(c fiber.Ctx) erro
 			return nil
 		}
 
-		for _, route := range routes {
-			// Lấy channel để biết recipients
-			channel, err := channelService.FindOneById(c.Context(), route.ChannelID)
-			if err != nil {
-				// Log error nhưng tiếp tục với route khác
-				continue
-			}
-
-			// Xác định recipients dựa trên channel type
-			var recipients []string
-			switch channel.ChannelType {
-			case "email":
-				recipients = channel.Recipients
-			case "telegram":
-				recipients = channel.ChatIDs
-			case "webhook":
-				if channel.WebhookURL != "" {
-					recipients = []string{channel.WebhookURL}
-				}
-			default:
-				continue
-			}
-
-			// Tạo queue item cho mỗi recipient
-			for _, recipient := range recipients {
-				queueItems = append(queueItems, &models.NotificationQueueItem{
-					ID:                  primitive.NewObjectID(),
-					EventType:           req.EventType,
-					OwnerOrganizationID: route.OrganizationID, // Phân quyền dữ liệu - queue item thuộc về organization này
-					ChannelID:           route.ChannelID,
-					Recipient:           recipient,
-					Payload:             req.Payload,
-					Status:              "pending",
-					RetryCount:          0,
-					MaxRetries:          3,
-					CreatedAt:           time.Now().Unix(),
-					UpdatedAt:           time.Now().Unix(),
-				})
-			}
-		}
-
 		// Enqueue items
 		if len(queueItems) > 0 {
 			err = h.queue.Enqueue(c.Context(), queueItems)
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+	"meta_commerce/core/common"
+	"meta_commerce/core/logger"
+	"meta_commerce/core/notification"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventTypeOrganizationInvitation là eventType notification gửi link mời vào tổ chức
+// Lời mời qua email: chỉ gửi cho email người được mời qua các route email của eventType
+// Lời mời qua số điện thoại: gửi theo routing rule (vd: webhook tới cổng SMS) vì hệ thống chưa có kênh SMS
+const EventTypeOrganizationInvitation = "organization_invitation"
+
+// OrganizationInvitationHandler xử lý các route lời mời người dùng vào tổ chức
+type OrganizationInvitationHandler struct {
+	*BaseHandler[interface{}, interface{}, interface{}]
+	InvitationService   *services.OrganizationInvitationService
+	organizationService *services.OrganizationService
+	roleService         *services.RoleService
+}
+
+// NewOrganizationInvitationHandler tạo một instance mới của OrganizationInvitationHandler
+func NewOrganizationInvitationHandler() (*OrganizationInvitationHandler, error) {
+	invitationService, err := services.NewOrganizationInvitationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization invitation service: %v", err)
+	}
+
+	organizationService, err := services.NewOrganizationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization service: %v", err)
+	}
+
+	roleService, err := services.NewRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role service: %v", err)
+	}
+
+	return &OrganizationInvitationHandler{
+		BaseHandler:         &BaseHandler[interface{}, interface{}, interface{}]{},
+		InvitationService:   invitationService,
+		organizationService: organizationService,
+		roleService:         roleService,
+	}, nil
+}
+
+// resolveOrganization lấy tổ chức trong URL và kiểm tra người gọi có quyền Organization.Invite với tổ chức này
+func (h *OrganizationInvitationHandler) resolveOrganization(c fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	orgID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeValidationFormat, "ID tổ chức không hợp lệ", common.StatusBadRequest, err)
+	}
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, err)
+	}
+
+	allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, "Organization.Invite")
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	for _, allowedOrgID := range allowedOrgIDs {
+		if allowedOrgID == orgID {
+			return orgID, userID, nil
+		}
+	}
+	return primitive.NilObjectID, primitive.NilObjectID, common.NewError(common.ErrCodeAuth, "Bạn không có quyền mời người dùng vào tổ chức này", common.StatusForbidden, nil)
+}
+
+// HandleCreate tạo lời mời và gửi link mời
+// YÊU CẦU QUYỀN Organization.Invite trong tổ chức
+// @Summary Mời người dùng vào tổ chức
+// @Param id path string true "Organization ID"
+// @Param body body dto.OrganizationInvitationCreateInput true "Email/số điện thoại, role và thời hạn"
+// @Success 200 {object} models.OrganizationInvitation
+// @Router /organization/{id}/invitations [post]
+func (h *OrganizationInvitationHandler) HandleCreate(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := h.resolveOrganization(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		var input dto.OrganizationInvitationCreateInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		invitation, err := h.InvitationService.Create(c.Context(), orgID, userID, input)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("organization_invitation_create", c, map[string]interface{}{
+			"invitation_id":   invitation.ID.Hex(),
+			"organization_id": orgID.Hex(),
+			"email":           invitation.Email,
+			"phone":           invitation.Phone,
+		})
+		h.send(invitation, c.Locals("user"))
+
+		h.HandleResponse(c, invitation, nil)
+		return nil
+	})
+}
+
+// HandleList trả về các lời mời của tổ chức (query status: pending, accepted, revoked)
+// YÊU CẦU QUYỀN Organization.Invite trong tổ chức
+// @Router /organization/{id}/invitations [get]
+func (h *OrganizationInvitationHandler) HandleList(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, _, err := h.resolveOrganization(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		invitations, err := h.InvitationService.ListByOrganization(c.Context(), orgID, c.Query("status"))
+		h.HandleResponse(c, invitations, err)
+		return nil
+	})
+}
+
+// HandleResend sinh link mới (link cũ hết hiệu lực) và gửi lại lời mời
+// YÊU CẦU QUYỀN Organization.Invite trong tổ chức
+// @Router /organization/{id}/invitations/{invitationId}/resend [post]
+func (h *OrganizationInvitationHandler) HandleResend(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, _, err := h.resolveOrganization(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		invitationID, err := primitive.ObjectIDFromHex(c.Params("invitationId"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID lời mời không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		invitation, err := h.InvitationService.Resend(c.Context(), orgID, invitationID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("organization_invitation_resend", c, map[string]interface{}{
+			"invitation_id":   invitation.ID.Hex(),
+			"organization_id": orgID.Hex(),
+		})
+		h.send(invitation, c.Locals("user"))
+
+		h.HandleResponse(c, invitation, nil)
+		return nil
+	})
+}
+
+// HandleRevoke thu hồi lời mời đang chờ
+// YÊU CẦU QUYỀN Organization.Invite trong tổ chức
+// @Router /organization/{id}/invitations/{invitationId}/revoke [post]
+func (h *OrganizationInvitationHandler) HandleRevoke(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		orgID, userID, err := h.resolveOrganization(c)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		invitationID, err := primitive.ObjectIDFromHex(c.Params("invitationId"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, "ID lời mời không hợp lệ", common.StatusBadRequest, err))
+			return nil
+		}
+
+		invitation, err := h.InvitationService.Revoke(c.Context(), orgID, invitationID, userID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("organization_invitation_revoke", c, map[string]interface{}{
+			"invitation_id":   invitation.ID.Hex(),
+			"organization_id": orgID.Hex(),
+		})
+		h.HandleResponse(c, invitation, nil)
+		return nil
+	})
+}
+
+// HandleAccept để user đã đăng nhập chấp nhận lời mời (email/số điện thoại đã xác thực của tài khoản phải khớp lời mời)
+// User chưa có tài khoản chấp nhận bằng cách gửi invitationToken khi đăng nhập (/auth/login/...)
+// @Param body body dto.OrganizationInvitationAcceptInput true "Token trong link mời"
+// @Router /invitation/accept [post]
+func (h *OrganizationInvitationHandler) HandleAccept(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		if c.Locals("service_account") != nil || c.Locals("impersonator_id") != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "Chỉ người được mời đăng nhập trực tiếp mới chấp nhận được lời mời", common.StatusForbidden, nil))
+			return nil
+		}
+		user, ok := c.Locals("user").(models.User)
+		if !ok {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeAuth, "User not authenticated", common.StatusUnauthorized, nil))
+			return nil
+		}
+
+		var input dto.OrganizationInvitationAcceptInput
+		if err := h.ParseRequestBody(c, &input); err != nil {
+			h.HandleResponse(c, nil, common.NewError(common.ErrCodeValidationFormat, err.Error(), common.StatusBadRequest, nil))
+			return nil
+		}
+
+		invitation, err := h.InvitationService.CheckForRecipient(c.Context(), input.Token, services.InvitationRecipient{
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			Phone:         user.Phone,
+			PhoneVerified: user.PhoneVerified,
+		})
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		accepted, err := h.InvitationService.Accept(c.Context(), invitation, user.ID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		logger.LogAction("organization_invitation_accept", c, map[string]interface{}{
+			"invitation_id":   accepted.ID.Hex(),
+			"organization_id": accepted.OwnerOrganizationID.Hex(),
+		})
+		h.HandleResponse(c, accepted, nil)
+		return nil
+	})
+}
+
+// send gửi link mời qua notification ở background
+func (h *OrganizationInvitationHandler) send(invitation *models.OrganizationInvitation, inviter interface{}) {
+	inv := *invitation
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		payload := map[string]interface{}{
+			"invitationId":        inv.ID.Hex(),
+			"ownerOrganizationId": inv.OwnerOrganizationID.Hex(),
+			"email":               inv.Email,
+			"phone":               inv.Phone,
+			"inviteLink":          inv.InviteLink,
+			"expiresAt":           inv.ExpiresAt,
+		}
+		if org, err := h.organizationService.FindOneById(ctx, inv.OwnerOrganizationID); err == nil {
+			payload["organizationName"] = org.Name
+		}
+		roleNames := make([]string, 0, len(inv.RoleIDs))
+		for _, roleID := range inv.RoleIDs {
+			if role, err := h.roleService.FindOneById(ctx, roleID); err == nil {
+				roleNames = append(roleNames, role.Name)
+			}
+		}
+		payload["roleNames"] = roleNames
+		if user, ok := inviter.(models.User); ok {
+			payload["inviterName"] = user.Name
+			payload["inviterEmail"] = user.Email
+		}
+
+		log := logrus.WithField("invitation_id", inv.ID.Hex())
+		if inv.Email != "" {
+			if _, err := notification.SendToRecipient(ctx, EventTypeOrganizationInvitation, inv.Email, payload); err != nil {
+				log.WithError(err).Warn("Failed to send organization invitation email")
+			}
+			return
+		}
+		if _, err := notification.Trigger(ctx, EventTypeOrganizationInvitation, payload); err != nil {
+			log.WithError(err).Warn("Failed to trigger organization invitation notification")
+		}
+	}()
+}
@@ -122,11 +122,32 @@ func (h *OrganizationShareHandler) CreateShare(c fiber.Ctx) error {
 			return nil
 		}
 
-		// Kiểm tra share đã tồn tại chưa
-		_, err = h.OrganizationShareService.FindOne(c.Context(), bson.M{
-			"ownerOrganizationId": ownerOrgID,
-			"toOrgId":             toOrgID,
-		}, nil)
+		// Validate: documentFilter và expiresAt
+		if err := services.ValidateDocumentFilter(input.DocumentFilter); err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+		if input.ExpiresAt != nil && *input.ExpiresAt <= utility.CurrentTimeInMilli() {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationInput,
+				"expiresAt phải lớn hơn thời điểm hiện tại (milliseconds)",
+				common.StatusBadRequest,
+				nil,
+			))
+			return nil
+		}
+
+		// Kiểm tra share toàn bộ dữ liệu còn hiệu lực đã tồn tại chưa
+		// (share giới hạn theo documentFilter có thể có nhiều share giữa 2 organizations)
+		existingFilter := services.ActiveShareFilter()
+		existingFilter["ownerOrganizationId"] = ownerOrgID
+		existingFilter["toOrgId"] = toOrgID
+		existingFilter["documentFilter"] = bson.M{"$exists": false}
+		if len(input.DocumentFilter) == 0 {
+			_, err = h.OrganizationShareService.FindOne(c.Context(), existingFilter, nil)
+		} else {
+			err = common.ErrNotFound
+		}
 
 		if err == nil {
 			// Share đã tồn tại
@@ -147,11 +168,17 @@ func (h *OrganizationShareHandler) CreateShare(c fiber.Ctx) error {
 
 		// Tạo share record
 		share := models.OrganizationShare{
-			OwnerOrganizationID: ownerOrgID,
-			ToOrgID:             toOrgID,
-			PermissionNames:     input.PermissionNames,
-			CreatedAt:           utility.CurrentTimeInMilli(),
-			CreatedBy:           userID,
+			OwnerOrganizationID:     ownerOrgID,
+			ToOrgID:                 toOrgID,
+			PermissionNames:         input.PermissionNames,
+			DocumentFilter:          input.DocumentFilter,
+			Note:                    input.Note,
+			Status:                  models.OrganizationShareStatusActive,
+			ExpiresAt:               input.ExpiresAt,
+			IncludeOwnerSubtree:     input.IncludeOwnerSubtree,
+			IncludeRecipientSubtree: input.IncludeRecipientSubtree,
+			CreatedAt:               utility.CurrentTimeInMilli(),
+			CreatedBy:               userID,
 		}
 
 		data, err := h.BaseService.InsertOne(c.Context(), share)
@@ -238,6 +265,85 @@ func (h *OrganizationShareHandler) DeleteShare(c fiber.Ctx) error {
 	})
 }
 
+// RevokeShare thu hồi sharing (giữ lại bản ghi để tra cứu, khác với DeleteShare)
+// POST /api/v1/organization-share/:id/revoke
+func (h *OrganizationShareHandler) RevokeShare(c fiber.Ctx) error {
+	return h.SafeHandler(c, func() error {
+		shareID, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeValidationFormat,
+				fmt.Sprintf("ID không hợp lệ: %s", c.Params("id")),
+				common.StatusBadRequest,
+				err,
+			))
+			return nil
+		}
+
+		var input dto.OrganizationShareRevokeInput
+		if len(c.Body()) > 0 {
+			if err := h.ParseRequestBody(c, &input); err != nil {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeValidationFormat,
+					fmt.Sprintf("Dữ liệu gửi lên không đúng định dạng: %v", err),
+					common.StatusBadRequest,
+					err,
+				))
+				return nil
+			}
+		}
+
+		share, err := h.OrganizationShareService.FindOneById(c.Context(), shareID)
+		if err != nil {
+			h.HandleResponse(c, nil, err)
+			return nil
+		}
+
+		// Validate: user là người tạo hoặc có quyền với ownerOrg (giống DeleteShare)
+		userIDStr, ok := c.Locals("user_id").(string)
+		if !ok {
+			h.HandleResponse(c, nil, common.NewError(
+				common.ErrCodeAuth,
+				"Không tìm thấy user ID",
+				common.StatusUnauthorized,
+				nil,
+			))
+			return nil
+		}
+		userID, _ := primitive.ObjectIDFromHex(userIDStr)
+
+		if share.CreatedBy != userID {
+			allowedOrgIDs, err := services.GetUserAllowedOrganizationIDs(c.Context(), userID, "")
+			if err != nil {
+				h.HandleResponse(c, nil, err)
+				return nil
+			}
+
+			hasAccess := false
+			for _, orgID := range allowedOrgIDs {
+				if orgID == share.OwnerOrganizationID {
+					hasAccess = true
+					break
+				}
+			}
+
+			if !hasAccess {
+				h.HandleResponse(c, nil, common.NewError(
+					common.ErrCodeAuth,
+					"Bạn không có quyền thu hồi share này",
+					common.StatusForbidden,
+					nil,
+				))
+				return nil
+			}
+		}
+
+		data, err := h.OrganizationShareService.RevokeShare(c.Context(), shareID, userID, input.Note)
+		h.HandleResponse(c, data, err)
+		return nil
+	})
+}
+
 // ListShares liệt kê các shares của organization
 // GET /api/v1/organization-shares?ownerOrganizationId=xxx hoặc ?toOrgId=xxx
 func (h *OrganizationShareHandler) ListShares(c fiber.Ctx) error {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"meta_commerce/core/common"
 	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -74,3 +75,14 @@ func (h *SystemHandler) HandleHealth(c fiber.Ctx) error {
 	})
 }
 
+
+// HandleCacheStats trả về thống kê các cache trong bộ nhớ của instance hiện tại
+// @Summary Thống kê cache
+// @Description Số entry, hit/miss, số entry bị loại (LRU) và hết hạn của từng cache
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Thống kê cache"
+// @Router /system/cache-stats [get]
+func (h *SystemHandler) HandleCacheStats(c fiber.Ctx) error {
+	h.HandleResponse(c, fiber.Map{"caches": utility.AllCacheStats()}, nil)
+	return nil
+}
@@ -9,12 +9,12 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
+	"meta_commerce/core/global"
 	"meta_commerce/core/logger"
 	"meta_commerce/core/utility"
 )
@@ -26,7 +26,10 @@ type AuthManager struct {
 	PermissionCRUD     *services.PermissionService
 	RolePermissionCRUD *services.RolePermissionService
 	UserRoleCRUD       *services.UserRoleService
-	Cache              *utility.Cache
+	RevokedTokenCRUD   *services.RevokedTokenService
+	APIKeyCRUD         *services.APIKeyService
+	MFACRUD            *services.MFAService
+	Cache              *utility.Cache[services.EffectivePermissions]
 }
 
 var (
@@ -81,16 +84,72 @@ func newAuthManager() (*AuthManager, error) {
 	}
 	newManager.UserRoleCRUD = userRoleService
 
-	// Khởi tạo cache với thời gian sống 5 phút và thời gian dọn dẹp 10 phút
-	newManager.Cache = utility.NewCache(5*time.Minute, 10*time.Minute)
+	revokedTokenService, err := services.NewRevokedTokenService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revoked token service: %v", err)
+	}
+	newManager.RevokedTokenCRUD = revokedTokenService
+
+	apiKeyService, err := services.NewAPIKeyService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key service: %v", err)
+	}
+	newManager.APIKeyCRUD = apiKeyService
+
+	mfaService, err := services.NewMFAService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa service: %v", err)
+	}
+	newManager.MFACRUD = mfaService
+
+	// Khởi tạo cache permissions: sống 5 phút, dọn dẹp mỗi phút, tối đa 10000 entry (LRU)
+	newManager.Cache = utility.NewCache[services.EffectivePermissions]("user_permissions", 5*time.Minute, time.Minute, 10000)
+
+	// Xóa cache quyền khi role, quyền của role hoặc role của user thay đổi
+	services.SubscribePermissionChange(newManager.invalidatePermissionCache)
 
 	return newManager, nil
 }
 
+// invalidatePermissionCache xóa các cache key permissions bị ảnh hưởng bởi event
+// - User thay đổi role: xóa mọi key của user đó
+// - Role thay đổi quyền: xóa key theo role context đó và các key tổng hợp (không có role context)
+func (am *AuthManager) invalidatePermissionCache(event services.PermissionChangeEvent) {
+	if event.All {
+		am.Cache.DeleteByPrefix("user_permissions:")
+		return
+	}
+
+	for _, userID := range event.UserIDs {
+		am.Cache.DeleteByPrefix("user_permissions:" + userID.Hex())
+	}
+
+	if len(event.RoleIDs) > 0 {
+		roleSuffixes := make([]string, len(event.RoleIDs))
+		for i, roleID := range event.RoleIDs {
+			roleSuffixes[i] = ":role:" + roleID.Hex()
+		}
+		am.Cache.DeleteFunc(func(key string) bool {
+			if !strings.HasPrefix(key, "user_permissions:") {
+				return false
+			}
+			if !strings.Contains(key, ":role:") {
+				return true
+			}
+			for _, suffix := range roleSuffixes {
+				if strings.HasSuffix(key, suffix) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
 // getUserPermissions lấy danh sách permissions của user từ cache hoặc database
 // Nếu activeRoleID được cung cấp, chỉ lấy permissions từ role đó (role context)
 // Nếu activeRoleID là nil, lấy permissions từ tất cả roles của user (backward compatibility)
-func (am *AuthManager) getUserPermissions(userID string, activeRoleID *primitive.ObjectID) (map[string]byte, error) {
+func (am *AuthManager) getUserPermissions(userID string, activeRoleID *primitive.ObjectID) (services.EffectivePermissions, error) {
 	// Tạo cache key dựa trên userID và activeRoleID (nếu có)
 	var cacheKey string
 	if activeRoleID != nil {
@@ -99,71 +158,274 @@ func (am *AuthManager) getUserPermissions(userID string, activeRoleID *primitive
 		cacheKey = "user_permissions:" + userID
 	}
 
-	// Kiểm tra cache trước để tối ưu hiệu suất
-	if cached, found := am.Cache.Get(cacheKey); found {
-		return cached.(map[string]byte), nil
-	}
+	// Lấy từ cache, nếu không có thì load từ database bằng một aggregation
+	// Nhiều request đồng thời cùng key chỉ load một lần
+	return am.Cache.GetOrLoad(cacheKey, func() (services.EffectivePermissions, error) {
+		return am.UserRoleCRUD.GetEffectivePermissions(context.TODO(), utility.String2ObjectID(userID), activeRoleID, "")
+	})
+}
 
-	// Nếu không có trong cache, lấy từ database
-	permissions := make(map[string]byte)
+// sessionTouchInterval khoảng thời gian tối thiểu giữa 2 lần cập nhật last-seen của một phiên
+// (tránh ghi database ở mọi request)
+const sessionTouchInterval = time.Minute
 
-	// Nếu có activeRoleID, chỉ lấy permissions từ role đó
-	if activeRoleID != nil {
-		// Validate user có role này không
-		_, err := am.UserRoleCRUD.FindOne(context.TODO(), bson.M{
-			"userId": utility.String2ObjectID(userID),
-			"roleId": *activeRoleID,
-		}, nil)
-		if err != nil {
-			// User không có role này, trả về map rỗng
-			am.Cache.Set(cacheKey, permissions)
-			return permissions, nil
+// touchSession cập nhật last-seen, IP, User-Agent của phiên (thiết bị) ở background
+func (am *AuthManager) touchSession(user models.User, hwid string, ip string, userAgent string) {
+	for _, t := range user.Tokens {
+		if t.Hwid != hwid {
+			continue
 		}
-
-		// Lấy danh sách permissions của role
-		findRolePermissions, err := am.RolePermissionCRUD.Find(context.TODO(), bson.M{"roleId": *activeRoleID}, nil)
-		if err != nil {
-			am.Cache.Set(cacheKey, permissions)
-			return permissions, nil
+		lastSeen := time.UnixMilli(t.LastSeenAt)
+		if time.Since(lastSeen) < sessionTouchInterval && t.IP == ip && t.UserAgent == userAgent {
+			return
 		}
 
-		// Lấy thông tin chi tiết của từng permission
-		for _, rolePermission := range findRolePermissions {
-			permission, err := am.PermissionCRUD.FindOneById(context.TODO(), rolePermission.PermissionID)
-			if err != nil {
-				continue
+		// Copy string vì Fiber tái sử dụng buffer của request sau khi handler kết thúc
+		ip = strings.Clone(ip)
+		userAgent = strings.Clone(userAgent)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := am.UserCRUD.TouchSession(ctx, user.ID, hwid, ip, userAgent); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"user_id": user.ID.Hex(),
+					"hwid":    hwid,
+					"error":   err.Error(),
+				}).Warn("⚠️ [AUTH] Failed to update session last-seen")
 			}
-			permissions[permission.Name] = rolePermission.Scope
-		}
-	} else {
-		// Lấy permissions từ tất cả roles của user (backward compatibility)
-		findRoles, err := am.UserRoleCRUD.Find(context.TODO(), bson.M{"userId": utility.String2ObjectID(userID)}, nil)
+		}()
+		return
+	}
+}
+
+// authenticateBearer xác thực access token (Authorization: Bearer <jwt>) của user
+// Lưu user, claims vào context và cập nhật last-seen của phiên
+func (am *AuthManager) authenticateBearer(c fiber.Ctx) (*models.User, error) {
+	// Lấy token từ header
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		// Ghi log vào file để debug
+		logrus.WithFields(logrus.Fields{
+			"path":   c.Path(),
+			"method": c.Method(),
+		}).Error("❌ Missing Authorization header")
+		return nil, common.ErrTokenMissing
+	}
+
+	// Log để đảm bảo middleware được gọi - dùng GetAppLogger để ghi vào file
+	logger.GetAppLogger().WithFields(logrus.Fields{
+		"path":            c.Path(),
+		"method":          c.Method(),
+		"has_auth_header": authHeader != "",
+	}).Error("🔍 [AUTH] AuthMiddleware processing request - FORCE LOG")
+
+	// Kiểm tra định dạng token
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, common.ErrTokenInvalid
+	}
+
+	token := parts[1]
+
+	// Xác thực token ngay tại server: chữ ký HS256, exp, nbf - không cần truy vấn database
+	claims, err := utility.ParseToken(global.MongoDB_ServerConfig.JwtSecret, token)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"path":  c.Path(),
+			"error": err.Error(),
+		}).Warn("❌ [AUTH] Token verification failed")
+		return nil, err
+	}
+
+	// Refresh token chỉ được dùng cho /auth/refresh, không dùng để gọi API
+	if claims.TokenType != models.JwtTokenTypeAccess {
+		return nil, common.ErrTokenInvalid
+	}
+
+	// Kiểm tra token đã bị thu hồi chưa (logout, khóa tài khoản, refresh...)
+	revoked, err := am.RevokedTokenCRUD.IsRevoked(context.Background(), claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		logrus.WithFields(logrus.Fields{
+			"path":    c.Path(),
+			"user_id": claims.UserID,
+			"jti":     claims.Id,
+		}).Warn("❌ [AUTH] Token has been revoked")
+		return nil, common.ErrTokenInvalid
+	}
+
+	// Lấy user theo ID trong token
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	user, err := am.UserCRUD.FindOneById(context.Background(), userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"path":    c.Path(),
+			"user_id": claims.UserID,
+			"error":   err.Error(),
+		}).Error("❌ [AUTH] User of token not found")
+		return nil, common.ErrTokenInvalid
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":    c.Path(),
+		"user_id": user.ID.Hex(),
+	}).Info("✅ Token verified, user authenticated")
+
+	// Kiểm tra user có bị block không
+	if user.IsBlock {
+		return nil, common.NewError(
+			common.ErrCodeAuthCredentials,
+			"Tài khoản đã bị khóa: "+user.BlockNote,
+			common.StatusForbidden,
+			nil,
+		)
+	}
+
+	// Lưu thông tin user vào context
+	c.Locals("user_id", user.ID.Hex())
+	c.Locals("user", user)
+	c.Locals("token_claims", claims)
+
+	// Token "login as": user_id là user được xem, quản trị viên thật nằm ở impersonator_id
+	if claims.ImpersonatorID != "" {
+		impersonator, err := am.authenticateImpersonator(c, claims)
 		if err != nil {
-			return nil, common.ConvertMongoError(err)
+			return nil, err
 		}
+		c.Locals("impersonator_id", impersonator.ID.Hex())
+		c.Locals("impersonator", *impersonator)
+		logger.LogAction("impersonation_request", c, map[string]interface{}{
+			"method": c.Method(),
+			"path":   c.Path(),
+			"jti":    claims.Id,
+		})
+		// Không cập nhật last-seen: token impersonation không gắn với phiên nào của user
+		return &user, nil
+	}
 
-		// Duyệt qua từng vai trò để lấy permissions
-		for _, userRole := range findRoles {
-			// Lấy danh sách permissions của vai trò
-			findRolePermissions, err := am.RolePermissionCRUD.Find(context.TODO(), bson.M{"roleId": userRole.RoleID}, nil)
-			if err != nil {
-				continue
-			}
+	// Cập nhật last-seen của phiên (thiết bị) đang dùng token
+	am.touchSession(user, claims.Hwid, c.IP(), c.Get("User-Agent"))
+	return &user, nil
+}
 
-			// Lấy thông tin chi tiết của từng permission
-			for _, rolePermission := range findRolePermissions {
-				permission, err := am.PermissionCRUD.FindOneById(context.TODO(), rolePermission.PermissionID)
-				if err != nil {
-					continue
-				}
-				permissions[permission.Name] = rolePermission.Scope
+// authenticateImpersonator kiểm tra quản trị viên trong token "login as" vẫn còn hoạt động
+// Khóa tài khoản quản trị viên sẽ chấm dứt ngay mọi phiên impersonation của họ
+func (am *AuthManager) authenticateImpersonator(c fiber.Ctx, claims *models.JwtToken) (*models.User, error) {
+	impersonatorID, err := primitive.ObjectIDFromHex(claims.ImpersonatorID)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+	impersonator, err := am.UserCRUD.FindOneById(context.Background(), impersonatorID)
+	if err != nil || impersonator.IsBlock {
+		logrus.WithFields(logrus.Fields{
+			"path":            c.Path(),
+			"user_id":         claims.UserID,
+			"impersonator_id": claims.ImpersonatorID,
+		}).Warn("❌ [AUTH] Impersonator is not available")
+		return nil, common.ErrTokenInvalid
+	}
+	return &impersonator, nil
+}
+
+// apiKeyTouchInterval khoảng thời gian tối thiểu giữa 2 lần cập nhật last-used của một API key
+const apiKeyTouchInterval = time.Minute
+
+// authenticateAPIKey xác thực API key (header X-API-Key) của service account
+// Lưu service account, API key vào context và cập nhật last-used của key ở background
+func (am *AuthManager) authenticateAPIKey(c fiber.Ctx, rawKey string) (*models.APIKey, *models.ServiceAccount, error) {
+	ip := c.IP()
+	key, account, err := am.APIKeyCRUD.Authenticate(context.Background(), rawKey, ip)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"path":  c.Path(),
+			"ip":    ip,
+			"error": err.Error(),
+		}).Warn("❌ [AUTH] API key verification failed")
+		return nil, nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":               c.Path(),
+		"service_account_id": account.ID.Hex(),
+		"api_key":            key.Prefix,
+	}).Info("✅ API key verified, service account authenticated")
+
+	// Service account dùng chung user_id với user để phân quyền qua user_roles
+	c.Locals("user_id", account.ID.Hex())
+	c.Locals("service_account", *account)
+	c.Locals("api_key", *key)
+
+	// Cập nhật last-used (bỏ qua nếu vừa cập nhật gần đây từ cùng IP)
+	if key.LastUsedAt == nil || time.Since(time.UnixMilli(*key.LastUsedAt)) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		keyID := key.ID
+		ip = strings.Clone(ip)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := am.APIKeyCRUD.TouchLastUsed(ctx, keyID, ip); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"api_key_id": keyID.Hex(),
+					"error":      err.Error(),
+				}).Warn("⚠️ [AUTH] Failed to update API key last-used")
 			}
-		}
+		}()
+	}
+	return key, account, nil
+}
+
+// checkStepUp kiểm tra access token có claim MFA còn mới cho permission nhạy cảm (MFA_STEP_UP_PERMISSIONS)
+// Trả về lỗi AUTH_004 với details.errorCode để frontend biết cần xác thực TOTP hay cần đăng ký TOTP trước
+func (am *AuthManager) checkStepUp(c fiber.Ctx, permission string) error {
+	claims, _ := c.Locals("token_claims").(*models.JwtToken)
+	if claims != nil && claims.ImpersonatorID == "" && services.IsMFAFresh(claims) {
+		return nil
+	}
+
+	details := map[string]interface{}{
+		"permission": permission,
+		"maxAge":     global.MongoDB_ServerConfig.MfaStepUpMaxAge,
 	}
+	// API key và token "login as" không có phiên để xác thực TOTP
+	if claims == nil || claims.ImpersonatorID != "" {
+		details["errorCode"] = "MFA_STEP_UP_UNAVAILABLE"
+		return common.NewError(common.ErrCodeAuthMFA, "Thao tác này yêu cầu người dùng đăng nhập trực tiếp và xác thực 2 lớp", common.StatusForbidden, details)
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return common.ErrTokenInvalid
+	}
+	enabled, err := am.MFACRUD.IsEnabled(context.Background(), userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		details["errorCode"] = "MFA_ENROLLMENT_REQUIRED"
+		return common.NewError(common.ErrCodeAuthMFA, "Thao tác này yêu cầu bật xác thực 2 lớp (TOTP) cho tài khoản", common.StatusForbidden, details)
+	}
+	details["errorCode"] = "MFA_STEP_UP_REQUIRED"
+	return common.NewError(common.ErrCodeAuthMFA, "Vui lòng nhập mã xác thực 2 lớp để tiếp tục", common.StatusForbidden, details)
+}
 
-	// Lưu vào cache để sử dụng cho các lần sau
-	am.Cache.Set(cacheKey, permissions)
-	return permissions, nil
+// setAuditActor lưu người thực hiện (và role đang làm việc) vào context của request
+// để các thao tác ghi qua service được ghi audit trail kèm người thực hiện
+func setAuditActor(c fiber.Ctx, roleID primitive.ObjectID) {
+	actor := services.AuditActor{
+		UserID:    utility.String2ObjectID(c.Locals("user_id").(string)),
+		RoleID:    roleID,
+		RequestID: c.GetRespHeader("X-Request-ID"),
+		IP:        c.IP(),
+	}
+	if impersonatorID, ok := c.Locals("impersonator_id").(string); ok {
+		actor.ImpersonatorID = utility.String2ObjectID(impersonatorID)
+	}
+	actor.ServiceAccount = c.Locals("service_account") != nil
+	c.SetContext(services.WithAuditActor(c.Context(), actor))
 }
 
 // AuthMiddleware middleware xác thực cho Fiber
@@ -182,193 +444,96 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 			"permission": requirePermission,
 		}).Error("🔒 [AUTH] AuthMiddleware EXECUTING - FORCE LOG - FIRST LINE")
 
-		// Lấy token từ header
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			// Ghi log vào file để debug
-			logrus.WithFields(logrus.Fields{
-				"path":   c.Path(),
-				"method": c.Method(),
-			}).Error("❌ Missing Authorization header")
-			HandleErrorResponse(c, common.ErrTokenMissing)
-			return nil
-		}
-
-		// Log để đảm bảo middleware được gọi - dùng GetAppLogger để ghi vào file
-		logger.GetAppLogger().WithFields(logrus.Fields{
-			"path":            c.Path(),
-			"method":          c.Method(),
-			"has_auth_header": authHeader != "",
-		}).Error("🔍 [AUTH] AuthMiddleware processing request - FORCE LOG")
-
-		// Kiểm tra định dạng token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			HandleErrorResponse(c, common.ErrTokenInvalid)
-			return nil
-		}
-
-		token := parts[1]
-
-		// Log token để debug - dùng Info level để đảm bảo hiển thị
-		tokenPreview := token
-		if len(token) > 50 {
-			tokenPreview = token[:50] + "..."
-		}
-		// Log token để debug - dùng GetAppLogger để ghi vào file
-		logger.GetAppLogger().WithFields(logrus.Fields{
-			"path":         c.Path(),
-			"token":        tokenPreview,
-			"token_length": len(token),
-		}).Error("🔍 [AUTH] Searching for user with token - FORCE LOG")
-
-		// Tìm user có token
-		// Ưu tiên query field "token" (token mới nhất) trước vì nó được cập nhật mỗi lần login
-		// Nếu không tìm thấy, query trong array "tokens" (tokens theo hwid)
-		var user models.User
-		var err error
-		var query bson.M
-
-		// Cách 1: Query field "token" (token mới nhất) - ĐÂY LÀ CÁCH CHÍNH
-		query = bson.M{"token": token}
-		logger.GetAppLogger().WithFields(logrus.Fields{
-			"path":          c.Path(),
-			"query":         query,
-			"token_length":  len(token),
-			"token_preview": tokenPreview,
-		}).Error("🔍 [AUTH] Executing Query 1: token field - FORCE LOG")
-		user, err = authManager.UserCRUD.FindOne(context.Background(), query, nil)
-		if err != nil {
-			logger.GetAppLogger().WithFields(logrus.Fields{
-				"path":     c.Path(),
-				"query":    query,
-				"error":    err.Error(),
-				"has_user": false,
-			}).Error("❌ [AUTH] Query 1 FAILED - FORCE LOG")
+		// Xác thực principal: service account (X-API-Key) hoặc user (Bearer JWT)
+		// Cả 2 loại đều set user_id (ID dùng trong user_roles) để các bước phân quyền phía sau dùng chung
+		var principalID, principalName string
+		var apiKey *models.APIKey
+		if rawKey := c.Get("X-API-Key"); rawKey != "" {
+			key, account, err := authManager.authenticateAPIKey(c, rawKey)
+			if err != nil {
+				HandleErrorResponse(c, err)
+				return nil
+			}
+			apiKey = key
+			principalID = account.ID.Hex()
+			principalName = "service-account:" + account.Name
 		} else {
-			logger.GetAppLogger().WithFields(logrus.Fields{
-				"path":     c.Path(),
-				"query":    query,
-				"user_id":  user.ID.Hex(),
-				"has_user": true,
-			}).Error("✅ [AUTH] Query 1 SUCCESS - FORCE LOG")
-		}
-
-		if err != nil {
-			logger.GetAppLogger().WithFields(logrus.Fields{
-				"path":  c.Path(),
-				"error": err.Error(),
-				"query": query,
-			}).Error("⚠️ [AUTH] Token not found in 'token' field, trying 'tokens' array - FORCE LOG")
-
-			// Cách 2: Query trong array "tokens" với dot notation
-			query = bson.M{"tokens.jwtToken": token}
-			user, err = authManager.UserCRUD.FindOne(context.Background(), query, nil)
-
+			user, err := authManager.authenticateBearer(c)
 			if err != nil {
-				logger.GetAppLogger().WithFields(logrus.Fields{
-					"path":  c.Path(),
-					"error": err.Error(),
-					"query": query,
-				}).Error("⚠️ [AUTH] Query 2 failed, trying $elemMatch - FORCE LOG")
-
-				// Cách 3: Query với $elemMatch
-				query = bson.M{
-					"tokens": bson.M{
-						"$elemMatch": bson.M{
-							"jwtToken": token,
-						},
-					},
-				}
-				user, err = authManager.UserCRUD.FindOne(context.Background(), query, nil)
-				if err != nil {
-					logger.GetAppLogger().WithFields(logrus.Fields{
-						"path":  c.Path(),
-						"error": err.Error(),
-						"query": query,
-					}).Error("⚠️ [AUTH] Query 3 ($elemMatch) also failed - FORCE LOG")
-				}
+				HandleErrorResponse(c, err)
+				return nil
 			}
+			principalID = user.ID.Hex()
+			principalName = user.Email
 		}
+		setAuditActor(c, primitive.NilObjectID)
 
-		if err != nil {
-			// Log chi tiết lỗi - dùng GetAppLogger để ghi vào file
-			logger.GetAppLogger().WithFields(logrus.Fields{
-				"path":  c.Path(),
-				"error": err.Error(),
-				"token": token[:20] + "...",
-				"query": query,
-			}).Error("❌ [AUTH] Token not found in database - FORCE LOG")
-			// Log thêm thông tin query để debug
-			logger.GetAppLogger().WithFields(logrus.Fields{
-				"path":          c.Path(),
-				"query":         query,
-				"token_preview": token[:20] + "...",
-			}).Error("❌ [AUTH] Token query details - FORCE LOG")
-			HandleErrorResponse(c, common.ErrTokenInvalid)
-			return nil
+		// Nếu không yêu cầu permission cụ thể, cho phép truy cập NGAY
+		// Đây là endpoint đặc biệt như /auth/roles - chỉ cần xác thực, không cần permission
+		if requirePermission == "" {
+			fmt.Printf("[AUTH] ✅ No permission required - Path: %s, UserID: %s - ALLOWING ACCESS\n",
+				c.Path(), principalID)
+			logrus.WithFields(logrus.Fields{
+				"path":    c.Path(),
+				"user_id": principalID,
+			}).Info("✅ No permission required - allowing access")
+			return c.Next()
 		}
 
-		// Log khi tìm thấy token - dùng Info level để đảm bảo hiển thị
-		fmt.Printf("[AUTH] ✅ Token found, user authenticated - Path: %s, UserID: %s\n",
-			c.Path(), user.ID.Hex())
-		logrus.WithFields(logrus.Fields{
-			"path":    c.Path(),
-			"user_id": user.ID.Hex(),
-		}).Info("✅ Token found, user authenticated")
-
-		// Kiểm tra user có bị block không
-		if user.IsBlock {
+		// API key có scopes: chỉ được dùng các permission trong scopes (giao với quyền RBAC của service account)
+		if !services.APIKeyAllowsPermission(apiKey, requirePermission) {
+			logrus.WithFields(logrus.Fields{
+				"user_id":    principalID,
+				"api_key":    apiKey.Prefix,
+				"permission": requirePermission,
+				"path":       c.Path(),
+			}).Warn("❌ API key scope does not include required permission")
 			HandleErrorResponse(c, common.NewError(
-				common.ErrCodeAuthCredentials,
-				"Tài khoản đã bị khóa: "+user.BlockNote,
+				common.ErrCodeAuthRole,
+				"API key không được cấp scope cho quyền này",
 				common.StatusForbidden,
 				nil,
 			))
 			return nil
 		}
 
-		// Lưu thông tin user vào context
-		c.Locals("user_id", user.ID.Hex())
-		c.Locals("user", user)
-
-		// Nếu không yêu cầu permission cụ thể, cho phép truy cập NGAY
-		// Đây là endpoint đặc biệt như /auth/roles - chỉ cần xác thực, không cần permission
-		if requirePermission == "" {
-			fmt.Printf("[AUTH] ✅ No permission required - Path: %s, UserID: %s - ALLOWING ACCESS\n",
-				c.Path(), user.ID.Hex())
+		// Token "login as" chỉ dùng để xem như user: từ chối mọi permission ghi/quản trị (kể cả permission cần step-up)
+		if c.Locals("impersonator_id") != nil && !strings.HasSuffix(requirePermission, ".Read") {
 			logrus.WithFields(logrus.Fields{
-				"path":    c.Path(),
-				"user_id": user.ID.Hex(),
-			}).Info("✅ No permission required - allowing access")
-			return c.Next()
+				"user_id":         principalID,
+				"impersonator_id": c.Locals("impersonator_id"),
+				"permission":      requirePermission,
+				"path":            c.Path(),
+			}).Warn("❌ Impersonation token used for non-read permission")
+			HandleErrorResponse(c, common.NewError(
+				common.ErrCodeAuth,
+				"Token đăng nhập thay chỉ được dùng để xem dữ liệu",
+				common.StatusForbidden,
+				nil,
+			))
+			return nil
 		}
 
 		// Lấy active role ID từ header (role context)
 		// Logic: Nếu route có require permission, PHẢI có header X-Active-Role-ID để chỉ định role context
 		activeRoleIDStr := c.Get("X-Active-Role-ID")
 
-		// Log tất cả headers để debug
-		allHeaders := make(map[string]string)
-		c.Request().Header.VisitAll(func(key, value []byte) {
-			allHeaders[string(key)] = string(value)
-		})
+		// Không log toàn bộ headers: Authorization / X-API-Key là credential
 		fmt.Printf("[AUTH] 🔍 Checking headers - Path: %s, X-Active-Role-ID: %s, Permission: %s\n",
 			c.Path(), activeRoleIDStr, requirePermission)
 		logrus.WithFields(logrus.Fields{
 			"path":               c.Path(),
 			"x_active_role_id":   activeRoleIDStr,
 			"require_permission": requirePermission,
-			"all_headers":        allHeaders,
 		}).Info("🔍 Checking headers and permission")
 
 		// Header X-Active-Role-ID là BẮT BUỘC khi route yêu cầu permission
 		if activeRoleIDStr == "" {
 			fmt.Printf("[AUTH] ❌ BLOCKING: Missing X-Active-Role-ID header - User: %s, Path: %s\n",
-				user.Email, c.Path())
+				principalName, c.Path())
 			logrus.WithFields(logrus.Fields{
-				"user_id":    user.ID.Hex(),
-				"user_email": user.Email,
+				"user_id":    principalID,
+				"user_email": principalName,
 				"path":       c.Path(),
 				"permission": requirePermission,
 			}).Error("❌ Missing X-Active-Role-ID header - BLOCKING REQUEST")
@@ -385,7 +550,7 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 		roleID, err := primitive.ObjectIDFromHex(activeRoleIDStr)
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
-				"user_id":        user.ID.Hex(),
+				"user_id":        principalID,
 				"active_role_id": activeRoleIDStr,
 				"path":           c.Path(),
 				"error":          err.Error(),
@@ -399,11 +564,13 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 			return nil
 		}
 
-		// Lấy danh sách roles của user để kiểm tra
-		userRoles, err := authManager.UserRoleCRUD.Find(context.Background(), bson.M{"userId": utility.String2ObjectID(user.ID.Hex())}, nil)
+		// Lấy danh sách roles còn hiệu lực của user để kiểm tra
+		userRoleFilter := services.ActiveUserRoleFilter()
+		userRoleFilter["userId"] = utility.String2ObjectID(principalID)
+		userRoles, err := authManager.UserRoleCRUD.Find(context.Background(), userRoleFilter, nil)
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
-				"user_id": user.ID.Hex(),
+				"user_id": principalID,
 				"error":   err.Error(),
 				"path":    c.Path(),
 			}).Error("Failed to get user roles")
@@ -418,7 +585,7 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 
 		// Log để debug - dùng Info level để đảm bảo hiển thị
 		fmt.Printf("[AUTH] 🔐 Checking permissions - User: %s, Roles: %d, Path: %s, Permission: %s, ActiveRole: %s\n",
-			user.Email, len(userRoles), c.Path(), requirePermission, roleID.Hex())
+			principalName, len(userRoles), c.Path(), requirePermission, roleID.Hex())
 
 		// Log chi tiết các role IDs của user để debug
 		userRoleIDs := make([]string, 0, len(userRoles))
@@ -428,8 +595,8 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 		fmt.Printf("[AUTH] 🔍 User role IDs: %v, Active role ID: %s\n", userRoleIDs, roleID.Hex())
 
 		logrus.WithFields(logrus.Fields{
-			"user_id":        user.ID.Hex(),
-			"user_email":     user.Email,
+			"user_id":        principalID,
+			"user_email":     principalName,
 			"roles_count":    len(userRoles),
 			"user_role_ids":  userRoleIDs,
 			"path":           c.Path(),
@@ -440,10 +607,10 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 		// Nếu user không có role nào, từ chối truy cập ngay
 		if len(userRoles) == 0 {
 			fmt.Printf("[AUTH] ❌ BLOCKING: User has no roles - User: %s, Path: %s\n",
-				user.Email, c.Path())
+				principalName, c.Path())
 			logrus.WithFields(logrus.Fields{
-				"user_id":    user.ID.Hex(),
-				"user_email": user.Email,
+				"user_id":    principalID,
+				"user_email": principalName,
 				"path":       c.Path(),
 				"permission": requirePermission,
 			}).Error("❌ User has no roles, denying access")
@@ -477,7 +644,7 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 			
 			fmt.Printf("[AUTH] ⚠️ User does not have role %s, rejecting request. Valid roles: %v\n", roleID.Hex(), validRoleIDs)
 			logrus.WithFields(logrus.Fields{
-				"user_id":        user.ID.Hex(),
+				"user_id":        principalID,
 				"active_role_id": roleID.Hex(),
 				"valid_role_ids": validRoleIDs,
 				"path":           c.Path(),
@@ -499,9 +666,10 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 		}
 
 		activeRoleID := &roleID
+		setAuditActor(c, roleID)
 
 		// Kiểm tra permission của user trong role context (active role)
-		permissions, err := authManager.getUserPermissions(user.ID.Hex(), activeRoleID)
+		permissions, err := authManager.getUserPermissions(principalID, activeRoleID)
 		if err != nil {
 			HandleErrorResponse(c, common.NewError(
 				common.ErrCodeAuthRole,
@@ -513,15 +681,15 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 		}
 
 		// Kiểm tra user có permission cần thiết trong role context không
-		scope, hasPermission := permissions[requirePermission]
+		permission, hasPermission := permissions[requirePermission]
 		if !hasPermission {
 			logrus.WithFields(logrus.Fields{
-				"user_id":             user.ID.Hex(),
-				"user_email":          user.Email,
+				"user_id":             principalID,
+				"user_email":          principalName,
 				"active_role_id":      activeRoleID.Hex(),
 				"required_permission": requirePermission,
 				"path":                c.Path(),
-				"permissions":         permissions,
+				"permissions":         permissions.Names(),
 			}).Error("❌ User does not have required permission")
 			HandleErrorResponse(c, common.NewError(
 				common.ErrCodeAuthRole,
@@ -532,8 +700,23 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 			return nil
 		}
 
+		// Step-up: permission nhạy cảm cần xác thực TOTP gần đây
+		// Chỉ áp dụng cho request thay đổi dữ liệu vì .Use() theo prefix khiến route đọc cũng đi qua middleware của Update/Insert
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && services.RequiresStepUp(requirePermission) {
+			if err := authManager.checkStepUp(c, requirePermission); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"user_id":    principalID,
+					"permission": requirePermission,
+					"path":       c.Path(),
+				}).Warn("❌ Step-up MFA required")
+				HandleErrorResponse(c, err)
+				return nil
+			}
+		}
+
+		scope := permission.Scope
 		logrus.WithFields(logrus.Fields{
-			"user_id":        user.ID.Hex(),
+			"user_id":        principalID,
 			"active_role_id": activeRoleID.Hex(),
 			"permission":     requirePermission,
 			"scope":          scope,
@@ -542,6 +725,12 @@ func AuthMiddleware(requirePermission string) fiber.Handler {
 
 		// Lưu scope tối thiểu vào context để sử dụng trong handler
 		c.Locals("minScope", scope)
+		// Lưu permissions của role context (giới hạn theo scopes của API key) để handler áp dụng field policy
+		c.Locals("permissions", services.APIKeyPermissions(apiKey, permissions))
+		// Lưu các permission route yêu cầu (.Use() theo prefix nên một request có thể đi qua nhiều AuthMiddleware)
+		// để handler áp dụng điều kiện của permission đúng thao tác (Read/Update/Delete/Insert)
+		routePermissions, _ := c.Locals("routePermissions").([]string)
+		c.Locals("routePermissions", append(routePermissions, requirePermission))
 		return c.Next()
 	}
 }
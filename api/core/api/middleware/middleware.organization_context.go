@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/gofiber/fiber/v3"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"meta_commerce/core/api/services"
@@ -89,33 +88,26 @@ func OrganizationContextMiddleware() fiber.Handler {
 	}
 }
 
-// validateUserHasRole kiểm tra user có role này không
+// validateUserHasRole kiểm tra user có role này không (assignment còn hiệu lực)
 func validateUserHasRole(ctx context.Context, userID, roleID primitive.ObjectID) (bool, error) {
 	userRoleService, err := services.NewUserRoleService()
 	if err != nil {
 		return false, err
 	}
 
-	userRoles, err := userRoleService.Find(ctx, bson.M{
-		"userId": userID,
-		"roleId": roleID,
-	}, nil)
-
-	if err != nil {
-		return false, err
-	}
-
-	return len(userRoles) > 0, nil
+	return userRoleService.IsExist(ctx, userID, roleID)
 }
 
-// getFirstUserRoleID lấy role ID đầu tiên của user
+// getFirstUserRoleID lấy role ID đầu tiên (còn hiệu lực) của user
 func getFirstUserRoleID(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
 	userRoleService, err := services.NewUserRoleService()
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
 
-	userRoles, err := userRoleService.Find(ctx, bson.M{"userId": userID}, nil)
+	filter := services.ActiveUserRoleFilter()
+	filter["userId"] = userID
+	userRoles, err := userRoleService.Find(ctx, filter, nil)
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
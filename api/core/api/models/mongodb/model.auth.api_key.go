@@ -0,0 +1,31 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyPrefix là tiền tố cố định của mọi API key (giúp nhận diện và quét lộ key)
+// Định dạng key đầy đủ: mck_<prefix>_<secret>
+const APIKeyPrefix = "mck_"
+
+// APIKey là khóa truy cập của service account
+// Chỉ lưu SHA-256 của key, key gốc chỉ trả về một lần khi tạo/xoay vòng
+type APIKey struct {
+	ID               primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	ServiceAccountID primitive.ObjectID  `json:"serviceAccountId" bson:"serviceAccountId" index:"single:1"` // Service account sở hữu key
+	Name             string              `json:"name" bson:"name"`                                          // Tên gợi nhớ của key
+	Prefix           string              `json:"prefix" bson:"prefix" index:"unique"`                       // Phần định danh công khai của key (dùng để tra cứu)
+	KeyHash          string              `json:"-" bson:"keyHash"`                                          // SHA-256 (hex) của key đầy đủ
+	Scopes           []string            `json:"scopes,omitempty" bson:"scopes,omitempty"`                  // [] hoặc nil = mọi permission của service account, ["Order.Read"] = chỉ các permission này
+	AllowedIPs       []string            `json:"allowedIps,omitempty" bson:"allowedIps,omitempty"`          // [] hoặc nil = mọi IP, hỗ trợ IP đơn và CIDR
+	ExpiresAt        *int64              `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`            // Thời điểm hết hạn (milliseconds), nil = không hết hạn
+	LastUsedAt       *int64              `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`          // Lần sử dụng gần nhất (milliseconds)
+	LastUsedIP       string              `json:"lastUsedIp,omitempty" bson:"lastUsedIp,omitempty"`          // IP của lần sử dụng gần nhất
+	RotatedFromID    *primitive.ObjectID `json:"rotatedFromId,omitempty" bson:"rotatedFromId,omitempty"`    // Key cũ mà key này thay thế (khi xoay vòng)
+	RevokedAt        *int64              `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`            // Thời điểm thu hồi (milliseconds)
+	RevokedBy        *primitive.ObjectID `json:"revokedBy,omitempty" bson:"revokedBy,omitempty"`            // User thu hồi key
+	CreatedBy        primitive.ObjectID  `json:"createdBy,omitempty" bson:"createdBy,omitempty"`            // User tạo key
+	CreatedAt        int64               `json:"createdAt" bson:"createdAt"`                                // Thời gian tạo (milliseconds)
+
+	RawKey string `json:"key,omitempty" bson:"-"` // Key đầy đủ (chỉ trả về khi tạo/xoay vòng, không lưu DB)
+}
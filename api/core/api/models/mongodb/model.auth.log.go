@@ -1,20 +1,30 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Lưu lại log các hành động trong nhóm chức năng AUTH
+// Mỗi thao tác ghi qua BaseServiceMongoImpl (insert/update/delete) và mỗi thay đổi phân quyền tạo một bản ghi (audit trail)
 type AuthLog struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`              // ID của vai trò
-	UserID    primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty"`       // ID của người dùng
-	RoleID   primitive.ObjectID `json:"roleId,omitempty" bson:"roleId,omitempty"`       // ID của vai trò
-	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId,omitempty" bson:"ownerOrganizationId,omitempty"` // Tổ chức sở hữu dữ liệu (phân quyền)
-	Collection string             `json:"collection,omitempty" bson:"collection,omitempty"` // Tên bảng	
-	Action    string             `json:"action,omitempty" bson:"action,omitempty"`       // Hành động
-	Describe  string             `json:"describe,omitempty" bson:"describe,omitempty"`   // Mô tả hành động
-	OldData   string             `json:"oldData,omitempty" bson:"oldData,omitempty"`     // Dữ liệu cũ
-	NewData   string             `json:"newData,omitempty" bson:"newData,omitempty"`     // Dữ liệu mới
-	CreatedAt int64              `json:"createdAt,omitempty" bson:"createdAt,omitempty"` // Thời gian tạo
-	UpdatedAt int64              `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"` // Thời gian cập nhật
-}
\ No newline at end of file
+	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                                                   // ID của bản ghi log
+	UserID              primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty" index:"single:1"`                           // ID của người thực hiện (rỗng = hệ thống/worker)
+	ImpersonatorID      primitive.ObjectID `json:"impersonatorId,omitempty" bson:"impersonatorId,omitempty"`                            // ID quản trị viên đang đăng nhập thay UserID (nếu có)
+	ServiceAccount      bool               `json:"serviceAccount,omitempty" bson:"serviceAccount,omitempty"`                            // true nếu người thực hiện là service account (API key)
+	RoleID              primitive.ObjectID `json:"roleId,omitempty" bson:"roleId,omitempty"`                                            // Role đang làm việc (X-Active-Role-ID)
+	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId,omitempty" bson:"ownerOrganizationId,omitempty" index:"single:1"` // Tổ chức sở hữu dữ liệu (phân quyền)
+	Collection          string             `json:"collection,omitempty" bson:"collection,omitempty" index:"single:1"`                   // Tên bảng
+	DocumentID          primitive.ObjectID `json:"documentId,omitempty" bson:"documentId,omitempty" index:"single:1"`                   // ID của document bị thay đổi
+	Action              string             `json:"action,omitempty" bson:"action,omitempty"`                                            // Hành động (insert, update, delete)
+	Describe            string             `json:"describe,omitempty" bson:"describe,omitempty"`                                        // Mô tả hành động
+	OldData             string             `json:"oldData,omitempty" bson:"oldData,omitempty"`                                          // Dữ liệu cũ (JSON, khi xóa)
+	NewData             string             `json:"newData,omitempty" bson:"newData,omitempty"`                                          // Dữ liệu mới (JSON, khi tạo)
+	Changes             []interface{}      `json:"changes,omitempty" bson:"changes,omitempty"`                                          // Thay đổi theo field khi cập nhật: [{field: [cũ, mới]}]
+	RequestID           string             `json:"requestId,omitempty" bson:"requestId,omitempty"`                                      // X-Request-ID của request
+	IP                  string             `json:"ip,omitempty" bson:"ip,omitempty"`                                                    // IP của người thực hiện
+	ExpireAt            time.Time          `json:"-" bson:"expireAt,omitempty" index:"ttl:0"`                                           // Thời điểm xóa bản ghi (AUDIT_LOG_RETENTION_DAYS)
+	CreatedAt           int64              `json:"createdAt,omitempty" bson:"createdAt,omitempty" index:"single:1"`                     // Thời gian tạo
+	UpdatedAt           int64              `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`                                      // Thời gian cập nhật
+}
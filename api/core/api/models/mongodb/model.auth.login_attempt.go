@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loại bộ đếm đăng nhập thất bại
+const (
+	LoginAttemptTypeIdentity = "identity" // Theo danh tính (provider:subject) - chống dò một tài khoản từ nhiều IP
+	LoginAttemptTypeIP       = "ip"       // Theo IP - chống một IP dò nhiều tài khoản
+	LoginAttemptTypeMFA      = "mfa"      // Theo user - chống dò mã xác thực 2 lớp (TOTP/recovery code)
+)
+
+// LoginAttempt là bộ đếm đăng nhập thất bại của một danh tính, một IP hoặc bộ đếm nhập sai mã xác thực 2 lớp của một user
+// Đủ LOGIN_MAX_FAILED_ATTEMPTS lần thất bại thì bị khóa, mỗi lần khóa sau dài gấp đôi lần trước (progressive lockout)
+// Document tự động bị xóa bởi TTL index khi không có lần thất bại nào trong 24 giờ (đặt lại mức khóa)
+type LoginAttempt struct {
+	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Key           string             `json:"key" bson:"key" index:"unique"`                          // "identity:{provider}:{subject}", "ip:{ip}" hoặc "mfa:{userId}"
+	Type          string             `json:"type" bson:"type"`                                       // LoginAttemptTypeIdentity, LoginAttemptTypeIP hoặc LoginAttemptTypeMFA
+	Failures      int                `json:"failures" bson:"failures"`                               // Số lần thất bại liên tiếp trong LOGIN_FAILURE_WINDOW
+	LockCount     int                `json:"lockCount" bson:"lockCount"`                             // Số lần đã bị khóa (quyết định thời gian khóa tiếp theo)
+	LockedUntil   int64              `json:"lockedUntil,omitempty" bson:"lockedUntil,omitempty"`     // Bị khóa đến thời điểm này (Unix milli)
+	LastFailureAt int64              `json:"lastFailureAt,omitempty" bson:"lastFailureAt,omitempty"` // Thời điểm thất bại gần nhất (Unix milli)
+	ExpireAt      time.Time          `json:"-" bson:"expireAt" index:"ttl:0"`                        // Thời điểm xóa bộ đếm
+	CreatedAt     int64              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     int64              `json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kết quả của một lần đăng nhập
+const (
+	LoginOutcomeSuccess = "success" // Đăng nhập thành công
+	LoginOutcomeFailure = "failure" // Token không hợp lệ, user bị khóa, lời mời không hợp lệ...
+	LoginOutcomeLocked  = "locked"  // Bị từ chối vì danh tính hoặc IP đang bị khóa do thất bại nhiều lần
+)
+
+// LoginEvent là một lần đăng nhập (lịch sử đăng nhập)
+// Document tự động bị xóa bởi TTL index sau LOGIN_EVENT_RETENTION_DAYS ngày
+type LoginEvent struct {
+	ID              primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty" index:"single:1"`  // User đăng nhập (rỗng nếu token không hợp lệ)
+	Provider        string             `json:"provider" bson:"provider"`                                   // Identity provider (firebase, oidc, local...), "mfa" cho lần xác thực 2 lớp
+	IdentityKey     string             `json:"identityKey,omitempty" bson:"identityKey,omitempty"`         // "provider:subject" đã xác thực (rỗng nếu đăng nhập thất bại với provider dạng token)
+	IP              string             `json:"ip,omitempty" bson:"ip,omitempty" index:"single:1"`          // IP của request
+	UserAgent       string             `json:"userAgent,omitempty" bson:"userAgent,omitempty"`             // User-Agent của request
+	Hwid            string             `json:"hwid,omitempty" bson:"hwid,omitempty"`                       // Hardware ID của thiết bị
+	Outcome         string             `json:"outcome" bson:"outcome"`                                     // success, failure, locked
+	Reason          string             `json:"reason,omitempty" bson:"reason,omitempty"`                   // Lý do thất bại
+	NewDevice       bool               `json:"newDevice,omitempty" bson:"newDevice,omitempty"`             // Thiết bị (hwid) chưa từng đăng nhập thành công
+	UnusualLocation bool               `json:"unusualLocation,omitempty" bson:"unusualLocation,omitempty"` // Dải mạng của IP khác các lần đăng nhập thành công gần đây
+	ExpireAt        time.Time          `json:"-" bson:"expireAt,omitempty" index:"ttl:0"`                  // Thời điểm xóa bản ghi (LOGIN_EVENT_RETENTION_DAYS)
+	CreatedAt       int64              `json:"createdAt" bson:"createdAt" index:"single:1"`                // Thời điểm đăng nhập (Unix milli)
+}
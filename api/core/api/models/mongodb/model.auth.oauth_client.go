@@ -0,0 +1,37 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Các grant type OAuth2 được hỗ trợ
+const (
+	OAuthGrantAuthorizationCode = "authorization_code" // Đăng nhập user qua ứng dụng khác (bắt buộc PKCE với client public)
+	OAuthGrantClientCredentials = "client_credentials" // Dịch vụ gọi dịch vụ, principal là service account của client
+)
+
+// OAuthClientIDPrefix là tiền tố của client_id do hệ thống sinh
+const OAuthClientIDPrefix = "mcc_"
+
+// OAuthClient là ứng dụng/dịch vụ được đăng ký để nhận token do hệ thống này phát hành
+// Chỉ lưu SHA-256 của client secret, secret gốc chỉ trả về một lần khi tạo/xoay vòng
+type OAuthClient struct {
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	ClientID            string              `json:"clientId" bson:"clientId" index:"unique"`                         // client_id công khai (hệ thống sinh)
+	ClientSecretHash    string              `json:"-" bson:"clientSecretHash,omitempty"`                             // SHA-256 (hex) của client secret, rỗng với client public
+	Name                string              `json:"name" bson:"name" validate:"required"`                            // Tên ứng dụng
+	Describe            string              `json:"describe" bson:"describe"`                                        // Mô tả
+	OwnerOrganizationID primitive.ObjectID  `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức sở hữu client (phân quyền dữ liệu)
+	IsPublic            bool                `json:"isPublic" bson:"isPublic"`                                        // true = client public (SPA, mobile) không có secret, bắt buộc PKCE
+	RedirectURIs        []string            `json:"redirectUris,omitempty" bson:"redirectUris,omitempty"`            // Redirect URI hợp lệ cho authorization_code (khớp chính xác)
+	GrantTypes          []string            `json:"grantTypes" bson:"grantTypes"`                                    // Grant type được phép (authorization_code, client_credentials)
+	Scopes              []string            `json:"scopes,omitempty" bson:"scopes,omitempty"`                        // Scope client được yêu cầu; [] = chỉ các scope OIDC chuẩn
+	ServiceAccountID    *primitive.ObjectID `json:"serviceAccountId,omitempty" bson:"serviceAccountId,omitempty"`    // Principal của token client_credentials (bắt buộc khi bật grant này)
+	RoleID              *primitive.ObjectID `json:"roleId,omitempty" bson:"roleId,omitempty"`                        // Role của service account đưa vào claims của token client_credentials
+	IsDisabled          bool                `json:"isDisabled" bson:"isDisabled"`                                    // true = từ chối mọi yêu cầu cấp token
+	CreatedBy           primitive.ObjectID  `json:"createdBy,omitempty" bson:"createdBy,omitempty"`                  // User tạo client
+	CreatedAt           int64               `json:"createdAt" bson:"createdAt"`                                      // Thời gian tạo
+	UpdatedAt           int64               `json:"updatedAt" bson:"updatedAt"`                                      // Thời gian cập nhật
+
+	ClientSecret string `json:"clientSecret,omitempty" bson:"-"` // Client secret đầy đủ (chỉ trả về khi tạo/xoay vòng, không lưu DB)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthAuthorizationCode là authorization code cấp cho client sau khi user đồng ý
+// Code chỉ dùng được một lần, document tự động bị xóa bởi TTL index khi hết hạn
+type OAuthAuthorizationCode struct {
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	CodeHash            string              `json:"-" bson:"codeHash" index:"unique"`                         // SHA-256 (hex) của code
+	ClientID            string              `json:"clientId" bson:"clientId"`                                 // client_id nhận code
+	UserID              primitive.ObjectID  `json:"userId" bson:"userId"`                                     // User đã đồng ý cấp quyền
+	RedirectURI         string              `json:"redirectUri" bson:"redirectUri"`                           // redirect_uri của yêu cầu authorize (phải khớp khi đổi token)
+	Scope               string              `json:"scope" bson:"scope"`                                       // Scope đã cấp (cách nhau bởi dấu cách)
+	Nonce               string              `json:"nonce,omitempty" bson:"nonce,omitempty"`                   // nonce của OIDC, đưa vào ID token
+	CodeChallenge       string              `json:"-" bson:"codeChallenge,omitempty"`                         // PKCE code_challenge
+	CodeChallengeMethod string              `json:"-" bson:"codeChallengeMethod,omitempty"`                   // PKCE method (S256)
+	RoleID              *primitive.ObjectID `json:"roleId,omitempty" bson:"roleId,omitempty"`                 // Role đang làm việc của user khi authorize
+	OrganizationID      *primitive.ObjectID `json:"organizationId,omitempty" bson:"organizationId,omitempty"` // Tổ chức của role đang làm việc
+	AuthTime            int64               `json:"authTime" bson:"authTime"`                                 // Thời điểm user xác thực (Unix giây)
+	ExpireAt            time.Time           `json:"expireAt" bson:"expireAt" index:"ttl:0"`                   // Thời điểm code hết hạn
+	CreatedAt           int64               `json:"createdAt" bson:"createdAt"`                               // Thời gian tạo
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trạng thái của khóa ký token OAuth
+const (
+	OAuthSigningKeyActive  = "active"  // Khóa đang dùng để ký token mới
+	OAuthSigningKeyRetired = "retired" // Khóa đã xoay vòng, chỉ còn công bố trong JWKS để verify token cũ
+)
+
+// OAuthSigningKey là cặp khóa RSA dùng để ký access token / ID token (RS256)
+// Khóa công khai được công bố qua JWKS, khóa riêng không bao giờ trả ra API
+type OAuthSigningKey struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Kid        string             `json:"kid" bson:"kid" index:"unique"`                  // Key ID (header "kid" của token)
+	Algorithm  string             `json:"alg" bson:"alg"`                                 // Thuật toán ký (RS256)
+	PrivateKey string             `json:"-" bson:"privateKey"`                            // Khóa riêng dạng PEM
+	PublicKey  string             `json:"publicKey" bson:"publicKey"`                     // Khóa công khai dạng PEM
+	Status     string             `json:"status" bson:"status" index:"single:1"`          // active | retired
+	CreatedAt  int64              `json:"createdAt" bson:"createdAt"`                     // Thời gian tạo (milliseconds)
+	RetiredAt  int64              `json:"retiredAt,omitempty" bson:"retiredAt,omitempty"` // Thời điểm ngừng ký token mới (milliseconds)
+}
@@ -28,6 +28,8 @@ type Organization struct {
 	Level          int                 `json:"level" bson:"level" index:"single:1"`                                                                                                                  // Cấp độ (-1 = system root, 0 = group, 1 = company, 2 = department, ...)
 	IsActive       bool                `json:"isActive" bson:"isActive" index:"single:1"`                                                                                                           // Trạng thái hoạt động
 	IsSystem       bool                `json:"-" bson:"isSystem" index:"single:1"`                                                                                                                  // true = dữ liệu hệ thống, không thể xóa (chỉ dùng nội bộ, không expose ra API)
+	ArchivedAt     *int64              `json:"archivedAt,omitempty" bson:"archivedAt,omitempty" index:"single:1"`                                                                                 // Thời gian lưu trữ (nil = chưa lưu trữ). Tổ chức lưu trữ không còn trong phạm vi dữ liệu và role của nó không cấp quyền
+	ArchivedBy     *primitive.ObjectID `json:"archivedBy,omitempty" bson:"archivedBy,omitempty"`                                                                                                   // User thực hiện lưu trữ
 	CreatedAt      int64               `json:"createdAt" bson:"createdAt"`                                                                                                                          // Thời gian tạo
 	UpdatedAt      int64               `json:"updatedAt" bson:"updatedAt"`                                                                                                                          // Thời gian cập nhật
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevokedToken lưu jti của các JWT đã bị thu hồi (denylist)
+// Document tự động bị xóa bởi TTL index khi token gốc đã hết hạn
+type RevokedToken struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Jti       string             `json:"jti" bson:"jti" index:"unique"`          // JWT ID đã bị thu hồi
+	UserID    primitive.ObjectID `json:"userId" bson:"userId" index:"single:1"`  // User sở hữu token
+	Reason    string             `json:"reason" bson:"reason"`                   // Lý do thu hồi (logout, block, refresh, reuse...)
+	ExpireAt  time.Time          `json:"expireAt" bson:"expireAt" index:"ttl:0"` // Thời điểm token gốc hết hạn - sau thời điểm này không cần giữ trong denylist
+	CreatedAt int64              `json:"createdAt" bson:"createdAt"`             // Thời gian thu hồi
+}
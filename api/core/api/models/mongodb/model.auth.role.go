@@ -6,12 +6,15 @@ import (
 
 // Vai trò
 type Role struct {
-	_Relationships struct{}          `relationship:"collection:user_roles,field:roleId,message:Không thể xóa role vì có %d user đang sử dụng role này. Vui lòng gỡ role khỏi các user trước.|collection:role_permissions,field:roleId,message:Không thể xóa role vì có %d permission đang được gán cho role này. Vui lòng gỡ các permission trước."` // Relationship definitions - không export, chỉ dùng cho tag parsing
+	_Relationships struct{}          `relationship:"collection:user_roles,field:roleId,message:Không thể xóa role vì có %d user đang sử dụng role này. Vui lòng gỡ role khỏi các user trước.|collection:role_permissions,field:roleId,message:Không thể xóa role vì có %d permission đang được gán cho role này. Vui lòng gỡ các permission trước.|collection:roles,field:parentRoleId,message:Không thể xóa role vì có %d role đang kế thừa quyền từ role này. Vui lòng gỡ liên kết role cha trước.|collection:roles,field:templateId,message:Không thể xóa template vì có %d role đang được khởi tạo từ template này. Vui lòng tách các role khỏi template trước."` // Relationship definitions - không export, chỉ dùng cho tag parsing
 	ID                 primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                                                                                                                      // ID của vai trò
 	Name               string             `json:"name" bson:"name" index:"compound:role_org_name_unique"`                                                                                                 // Tên vai trò (unique trong mỗi Organization)
 	Describe           string             `json:"describe" bson:"describe"`                                                                                                                                 // Mô tả vai trò
 	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1,compound:role_org_name_unique"`                                                        // Tổ chức sở hữu dữ liệu (phân quyền) + Logic business - Có thể chỉ định khi create, có thể update với validation quyền
 	IsSystem       bool               `json:"-" bson:"isSystem" index:"single:1"`                                                                                                                   // true = dữ liệu hệ thống, không thể xóa (chỉ dùng nội bộ, không expose ra API)
+	IsTemplate          bool                `json:"isTemplate" bson:"isTemplate" index:"single:1"`                     // true = role mẫu (chỉ thuộc tổ chức hệ thống/tập đoàn), dùng để khởi tạo role cho các tổ chức con
+	TemplateID          *primitive.ObjectID `json:"templateId,omitempty" bson:"templateId,omitempty" index:"single:1"`     // Template mà role được khởi tạo từ đó - role kế thừa quyền của template (thay đổi của template áp dụng ngay), tách khỏi template để quản lý quyền riêng
+	ParentRoleID        *primitive.ObjectID `json:"parentRoleId,omitempty" bson:"parentRoleId,omitempty" index:"single:1"` // Role cha (cùng tổ chức hoặc tổ chức cấp trên) - role kế thừa toàn bộ quyền của role cha, không cần sao chép role_permissions
 	CreatedAt      int64              `json:"createdAt" bson:"createdAt"`                                                                                                                              // Thời gian tạo
 	UpdatedAt      int64              `json:"updatedAt" bson:"updatedAt"`                                                                                                                              // Thời gian cập nhật
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleElevationStatus định nghĩa trạng thái của yêu cầu nâng quyền tạm thời
+const (
+	RoleElevationStatusPending   = "pending"   // Chờ duyệt
+	RoleElevationStatusApproved  = "approved"  // Đã duyệt, role assignment có thời hạn đang hiệu lực
+	RoleElevationStatusRejected  = "rejected"  // Bị từ chối
+	RoleElevationStatusCancelled = "cancelled" // Người yêu cầu tự hủy trước khi được duyệt
+	RoleElevationStatusExpired   = "expired"   // Role assignment đã hết hạn và bị gỡ (job đánh dấu)
+)
+
+// RoleElevationRequest là yêu cầu nâng quyền tạm thời (just-in-time): user xin một role trong N giờ kèm lý do
+// Người duyệt có quyền UserRole.Update trong tổ chức của role duyệt → tạo UserRole có validUntil
+type RoleElevationRequest struct {
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID  `json:"userId" bson:"userId" index:"single:1"`                           // User xin nâng quyền
+	RoleID              primitive.ObjectID  `json:"roleId" bson:"roleId" index:"single:1"`                           // Role được xin
+	OwnerOrganizationID primitive.ObjectID  `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức của role (phạm vi của người duyệt)
+	DurationHours       int                 `json:"durationHours" bson:"durationHours"`                              // Số giờ được giữ role kể từ lúc duyệt
+	Justification       string              `json:"justification" bson:"justification"`                              // Lý do xin quyền
+	Status              string              `json:"status" bson:"status" index:"single:1"`                           // pending, approved, rejected, cancelled, expired
+	DecidedBy           *primitive.ObjectID `json:"decidedBy,omitempty" bson:"decidedBy,omitempty"`                  // Người duyệt/từ chối
+	DecidedAt           *int64              `json:"decidedAt,omitempty" bson:"decidedAt,omitempty"`                  // Thời điểm duyệt/từ chối (milliseconds)
+	DecisionNote        string              `json:"decisionNote,omitempty" bson:"decisionNote,omitempty"`            // Ghi chú của người duyệt
+	UserRoleID          *primitive.ObjectID `json:"userRoleId,omitempty" bson:"userRoleId,omitempty"`                // Role assignment được tạo khi duyệt
+	ValidUntil          *int64              `json:"validUntil,omitempty" bson:"validUntil,omitempty"`                // Thời điểm role assignment hết hạn (milliseconds)
+	CreatedAt           int64               `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64               `json:"updatedAt" bson:"updatedAt"`
+}
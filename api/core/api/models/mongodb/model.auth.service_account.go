@@ -0,0 +1,21 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceAccount là tài khoản dịch vụ (bot đồng bộ, dịch vụ nội bộ) thuộc một tổ chức
+// Service account là một principal như user: được gán role qua user_roles (userId = ID của service account)
+// và xác thực bằng API key thay vì Firebase/JWT
+type ServiceAccount struct {
+	_Relationships      struct{}            `relationship:"collection:auth_user_roles,field:userId,message:Không thể xóa service account vì có %d role đang được gán. Vui lòng gỡ các role trước."` // Relationship definitions - không export, chỉ dùng cho tag parsing
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`                                                                                                              // ID của service account (dùng làm userId trong user_roles)
+	OwnerOrganizationID primitive.ObjectID  `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`                                                                                // Tổ chức sở hữu service account (phân quyền dữ liệu)
+	Name                string              `json:"name" bson:"name" validate:"required"`                                                                                                           // Tên service account
+	Describe            string              `json:"describe" bson:"describe"`                                                                                                                       // Mô tả mục đích sử dụng
+	AgentID             *primitive.ObjectID `json:"agentId,omitempty" bson:"agentId,omitempty"`                                                                                                     // Trợ lý (bot) sử dụng service account này (nếu có)
+	IsDisabled          bool                `json:"isDisabled" bson:"isDisabled"`                                                                                                                   // true = vô hiệu hóa, mọi API key của service account bị từ chối
+	CreatedBy           primitive.ObjectID  `json:"createdBy,omitempty" bson:"createdBy,omitempty"`                                                                                                 // User tạo service account
+	CreatedAt           int64               `json:"createdAt" bson:"createdAt"`                                                                                                                     // Thời gian tạo
+	UpdatedAt           int64               `json:"updatedAt" bson:"updatedAt"`                                                                                                                     // Thời gian cập nhật
+}
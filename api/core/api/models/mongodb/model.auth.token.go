@@ -2,6 +2,12 @@ package models
 
 import "github.com/dgrijalva/jwt-go"
 
+// Loại JWT token
+const (
+	JwtTokenTypeAccess  = "access"  // Access token - thời gian sống ngắn, dùng để gọi API
+	JwtTokenTypeRefresh = "refresh" // Refresh token - thời gian sống dài, chỉ dùng để lấy access token mới
+)
+
 // JwtToken ,contains data that will enrypted in JWT token
 // When jwt token will decrypt, token model will returns
 // Need this model to authenticate and validate resources access by loggedIn user
@@ -11,16 +17,33 @@ import "github.com/dgrijalva/jwt-go"
 // - ID: ID của người dùng.
 // - Time: Thời gian liên quan đến token.
 // - RandomNum: Số ngẫu nhiên để tăng tính bảo mật.
-// - StandardClaims: Các yêu cầu tiêu chuẩn của JWT.
+// - TokenType: Loại token (access/refresh).
+// - Hwid: ID phần cứng của thiết bị sở hữu token.
+// - ImpersonatorID: ID của quản trị viên đang đăng nhập thay (chỉ có trong token impersonation).
+// - MfaAt: Thời điểm xác thực TOTP gần nhất của phiên (dùng cho step-up).
+// - StandardClaims: Các yêu cầu tiêu chuẩn của JWT (jti, exp, nbf, iat).
 type JwtToken struct {
-	UserID       string `json:"userId"`    // User ID
-	Time         string `json:"time"`      // Time
-	RandomNumber string `json:"randomNumber"` // Random number
+	UserID         string `json:"userId"`         // User ID
+	Time           string `json:"time"`           // Time
+	RandomNumber   string `json:"randomNumber"`   // Random number
+	TokenType      string `json:"typ,omitempty"`  // Loại token: access hoặc refresh
+	Hwid           string `json:"hwid,omitempty"` // Hardware ID của thiết bị
+	ImpersonatorID string `json:"imp,omitempty"`  // Quản trị viên đang đăng nhập thay user (rỗng = token thường)
+	MfaAt          int64  `json:"mfa,omitempty"`  // Thời điểm xác thực TOTP gần nhất (Unix giây), 0 = chưa xác thực
 	jwt.StandardClaims
 }
 
 type Token struct {
-	Hwid     string `json:"hwid" bson:"hwid,omitempty"`                   // Hardware ID
-	RoleID   string `json:"roleId" bson:"roleId,omitempty"`               // Role ID
-	JwtToken string `json:"jwtToken,omitempty" bson:"jwtToken,omitempty"` // Token
+	Hwid             string `json:"hwid" bson:"hwid,omitempty"`                                   // Hardware ID
+	RoleID           string `json:"roleId" bson:"roleId,omitempty"`                               // Role ID
+	JwtToken         string `json:"jwtToken,omitempty" bson:"jwtToken,omitempty"`                 // Token
+	AccessJti        string `json:"-" bson:"accessJti,omitempty"`                                 // jti của access token hiện tại
+	RefreshJti       string `json:"-" bson:"refreshJti,omitempty"`                                // jti của refresh token hiện tại (dùng để phát hiện refresh token bị dùng lại)
+	AccessExpiresAt  int64  `json:"accessExpiresAt,omitempty" bson:"accessExpiresAt,omitempty"`   // Thời điểm hết hạn access token (Unix giây)
+	RefreshExpiresAt int64  `json:"refreshExpiresAt,omitempty" bson:"refreshExpiresAt,omitempty"` // Thời điểm hết hạn refresh token (Unix giây)
+	CreatedAt        int64  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`               // Thời điểm đăng nhập trên thiết bị (Unix milli)
+	LastSeenAt       int64  `json:"lastSeenAt,omitempty" bson:"lastSeenAt,omitempty"`             // Thời điểm hoạt động gần nhất (Unix milli)
+	IP               string `json:"ip,omitempty" bson:"ip,omitempty"`                             // IP của request gần nhất
+	UserAgent        string `json:"userAgent,omitempty" bson:"userAgent,omitempty"`               // User-Agent của request gần nhất
+	MfaAt            int64  `json:"mfaAt,omitempty" bson:"mfaAt,omitempty"`                       // Thời điểm xác thực TOTP gần nhất của phiên (Unix giây), giữ lại khi refresh
 }
@@ -15,18 +15,30 @@ type User struct {
 	Password      string             `json:"-" bson:"password,omitempty"`                                  // DEPRECATED: Không còn sử dụng - Firebase quản lý authentication
 	Salt          string             `json:"-" bson:"salt,omitempty"`                                      // DEPRECATED: Không còn sử dụng - Firebase quản lý authentication
 	Phone         string             `json:"phone,omitempty" bson:"phone,omitempty" index:"unique,sparse"` // Số điện thoại (sparse để cho phép null) - Optional vì dùng Firebase
-	FirebaseUID   string             `json:"firebaseUid" bson:"firebaseUid" index:"unique"`                // Firebase User ID (primary key để link với Firebase)
+	FirebaseUID   string             `json:"firebaseUid" bson:"firebaseUid,omitempty" index:"unique,sparse"` // Firebase User ID (giữ để tương thích, liên kết chính nằm trong Identities)
+	Identities    []UserIdentity     `json:"identities,omitempty" bson:"identities,omitempty"`             // Các danh tính từ identity provider đã liên kết với user (firebase, oidc, local...)
+	IdentityKeys  []string           `json:"-" bson:"identityKeys,omitempty" index:"unique,sparse"`        // Khóa "provider:subject" của Identities - dùng để tìm user và đảm bảo một danh tính chỉ thuộc một user
 	EmailVerified bool               `json:"emailVerified" bson:"emailVerified"`                           // Email đã được xác thực
 	PhoneVerified bool               `json:"phoneVerified" bson:"phoneVerified"`                           // Số điện thoại đã được xác thực
 	AvatarURL     string             `json:"avatarUrl" bson:"avatarUrl"`                                   // URL avatar
 	Token         string             `json:"token" bson:"token"`                                           // Token xác thực mới nhất của người dùng
 	Tokens        []Token            `json:"-" bson:"tokens"`                                              // Danh sách các token đang hiệụ lực (mỗi hwid sẽ có một token)
+	RefreshToken  string             `json:"refreshToken,omitempty" bson:"-"`                            // Refresh token vừa cấp (chỉ trả về khi login/refresh, không lưu DB)
+	TokenExpiresAt int64             `json:"tokenExpiresAt,omitempty" bson:"-"`                          // Thời điểm hết hạn access token (Unix giây, chỉ trả về khi login/refresh)
 	IsBlock       bool               `json:"-" bson:"isBlock"`                                             // Trạng thái bị khóa
 	BlockNote     string             `json:"-" bson:"blockNote"`                                           // Ghi chú về việc bị khóa
 	CreatedAt     int64              `json:"createdAt" bson:"createdAt"`                                   // Thời gian tạo
 	UpdatedAt     int64              `json:"updatedAt" bson:"updatedAt"`                                   // Thời gian cập nhật
 }
 
+// UserIdentity là một danh tính của user tại một identity provider
+type UserIdentity struct {
+	Provider string `json:"provider" bson:"provider"`               // Tên provider (firebase, oidc, local...)
+	Subject  string `json:"subject" bson:"subject"`                 // ID của user trong provider
+	Email    string `json:"email,omitempty" bson:"email,omitempty"` // Email provider trả về tại thời điểm liên kết
+	LinkedAt int64  `json:"linkedAt" bson:"linkedAt"`               // Thời điểm liên kết
+}
+
 // PaginateResult đại diện cho kết quả phân trang
 type UserPaginateResult struct {
 	// Trang hiện tại
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserMFA là cấu hình xác thực 2 lớp (TOTP) của một user
+// Secret được mã hóa AES-GCM, recovery code chỉ lưu SHA-256; enabled = false là đang đăng ký (chưa xác nhận mã đầu tiên)
+type UserMFA struct {
+	ID                 primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID             primitive.ObjectID `json:"userId" bson:"userId" index:"unique"`                      // User sở hữu
+	SecretEncrypted    string             `json:"-" bson:"secretEncrypted"`                                 // Secret TOTP đã mã hóa (base64 nonce + ciphertext)
+	Enabled            bool               `json:"enabled" bson:"enabled"`                                   // true = đã xác nhận, dùng được cho step-up
+	EnabledAt          *int64             `json:"enabledAt,omitempty" bson:"enabledAt,omitempty"`           // Thời điểm bật (milliseconds)
+	RecoveryCodeHashes []string           `json:"-" bson:"recoveryCodeHashes,omitempty"`                    // SHA-256 (hex) các recovery code chưa dùng
+	LastUsedStep       int64              `json:"-" bson:"lastUsedStep"`                                    // Bước thời gian của mã TOTP dùng gần nhất (chặn dùng lại mã)
+	LastVerifiedAt     *int64             `json:"lastVerifiedAt,omitempty" bson:"lastVerifiedAt,omitempty"` // Lần xác thực thành công gần nhất (milliseconds)
+	CreatedAt          int64              `json:"createdAt" bson:"createdAt"`                               // Thời gian tạo (milliseconds)
+	UpdatedAt          int64              `json:"updatedAt" bson:"updatedAt"`                               // Thời gian cập nhật (milliseconds)
+
+	RecoveryCodesRemaining int `json:"recoveryCodesRemaining" bson:"-"` // Số recovery code còn lại (chỉ dùng trong response)
+}
@@ -8,13 +8,17 @@ import (
 // ID: ID của vai trò người dùng, được lưu trữ dưới dạng ObjectID của MongoDB.
 // UserID: ID của người dùng, được lưu trữ dưới dạng ObjectID của MongoDB.
 // RoleID: ID của vai trò, được lưu trữ dưới dạng ObjectID của MongoDB.
+// ValidFrom/ValidUntil: Khoảng thời gian gán role có hiệu lực (milliseconds), nil = không giới hạn.
+// ElevationRequestID: Yêu cầu nâng quyền tạm thời (JIT) đã tạo ra assignment này.
 // CreatedAt: Thời gian tạo vai trò người dùng, được lưu trữ dưới dạng timestamp.
 // UpdatedAt: Thời gian cập nhật vai trò người dùng, được lưu trữ dưới dạng timestamp.
 type UserRole struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`     // ID của vai trò người dùng
-	UserID    primitive.ObjectID `json:"userId" bson:"userId" index:"single:1"` // ID của người dùng
-	RoleID    primitive.ObjectID `json:"roleId" bson:"roleId" index:"single:1"` // ID của vai trò
-	CreatedAt int64              `json:"createdAt" bson:"createdAt"`            // Thời gian tạo
-	UpdatedAt int64              `json:"updatedAt" bson:"updatedAt"`            // Thời gian cập nhật
+	ID                 primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`                                 // ID của vai trò người dùng
+	UserID             primitive.ObjectID  `json:"userId" bson:"userId" index:"single:1"`                             // ID của người dùng
+	RoleID             primitive.ObjectID  `json:"roleId" bson:"roleId" index:"single:1"`                             // ID của vai trò
+	ValidFrom          *int64              `json:"validFrom,omitempty" bson:"validFrom,omitempty"`                    // Bắt đầu có hiệu lực (milliseconds), nil = ngay lập tức
+	ValidUntil         *int64              `json:"validUntil,omitempty" bson:"validUntil,omitempty" index:"single:1"` // Hết hiệu lực (milliseconds), nil = vĩnh viễn
+	ElevationRequestID *primitive.ObjectID `json:"elevationRequestId,omitempty" bson:"elevationRequestId,omitempty"`  // Yêu cầu nâng quyền tạm thời tạo ra assignment (nếu có)
+	CreatedAt          int64               `json:"createdAt" bson:"createdAt"`                                        // Thời gian tạo
+	UpdatedAt          int64               `json:"updatedAt" bson:"updatedAt"`                                        // Thời gian cập nhật
 }
-
@@ -16,12 +16,12 @@ type Customer struct {
 	// PhoneNumbers: Merge từ tất cả nguồn vào array
 	// - POS: phone_numbers (array) - ưu tiên
 	// - Pancake: phone_numbers (array)
-	PhoneNumbers []string `json:"phoneNumbers" bson:"phoneNumbers" index:"text" extract:"PosData\\.phone_numbers,optional,priority=1,merge=merge_array|PanCakeData\\.phone_numbers,optional,priority=2,merge=merge_array"`
+	PhoneNumbers []string `json:"phoneNumbers" bson:"phoneNumbers" index:"text" extract:"PosData\\.phone_numbers,optional,priority=1,merge=merge_array|PanCakeData\\.phone_numbers,optional,priority=2,merge=merge_array" fieldPolicy:"mask:phone=Customer.ReadSensitive;readonly=Customer.UpdateSensitive"`
 
 	// Email: Ưu tiên POS (priority=1) hơn Pancake (priority=2)
 	// - POS: emails (array) → lấy email đầu tiên
 	// - Pancake: email (string)
-	Email string `json:"email" bson:"email" index:"text" extract:"PosData\\.emails,converter=array_first,optional,priority=1,merge=priority|PanCakeData\\.email,converter=string,optional,priority=2,merge=priority"`
+	Email string `json:"email" bson:"email" index:"text" extract:"PosData\\.emails,converter=array_first,optional,priority=1,merge=priority|PanCakeData\\.email,converter=string,optional,priority=2,merge=priority" fieldPolicy:"mask:email=Customer.ReadSensitive;readonly=Customer.UpdateSensitive"`
 
 	// ===== COMMON IDENTIFIER =====
 	// CustomerId: ID chung để identify customer từ cả 2 nguồn (dùng cho filter khi upsert)
@@ -37,8 +37,8 @@ type Customer struct {
 	PosCustomerId string `json:"posCustomerId" bson:"posCustomerId" index:"text,unique,sparse" extract:"PosData\\.id,converter=string,optional"` // UUID string - ID của hệ thống POS (unique, sparse)
 
 	// ===== SOURCE-SPECIFIC DATA =====
-	PanCakeData map[string]interface{} `json:"panCakeData,omitempty" bson:"panCakeData,omitempty"` // Dữ liệu gốc từ Pancake API
-	PosData     map[string]interface{} `json:"posData,omitempty" bson:"posData,omitempty"`         // Dữ liệu gốc từ POS API
+	PanCakeData map[string]interface{} `json:"panCakeData,omitempty" bson:"panCakeData,omitempty" fieldPolicy:"hidden=Customer.ReadSensitive"` // Dữ liệu gốc từ Pancake API
+	PosData     map[string]interface{} `json:"posData,omitempty" bson:"posData,omitempty" fieldPolicy:"hidden=Customer.ReadSensitive"`         // Dữ liệu gốc từ POS API
 
 	// ===== EXTRACTED FIELDS (Từ các nguồn) =====
 	// Common fields có thể có từ cả 2 nguồn - ưu tiên POS (priority=1)
@@ -50,20 +50,24 @@ type Customer struct {
 	PanCakeUpdatedAt int64  `json:"panCakeUpdatedAt" bson:"panCakeUpdatedAt" extract:"PanCakeData\\.updated_at,converter=time,format=2006-01-02T15:04:05.000000,optional"` // Thời gian cập nhật từ Pancake
 
 	// POS-specific
-	CustomerLevelId   string        `json:"customerLevelId,omitempty" bson:"customerLevelId,omitempty" extract:"PosData\\.level_id,converter=string,optional,merge=overwrite"`               // UUID string
-	Point             int64         `json:"point,omitempty" bson:"point,omitempty" extract:"PosData\\.reward_point,converter=int64,optional,merge=overwrite"`                                // Điểm tích lũy
-	TotalOrder        int64         `json:"totalOrder,omitempty" bson:"totalOrder,omitempty" extract:"PosData\\.order_count,converter=int64,optional,merge=overwrite"`                       // Tổng đơn hàng
-	TotalSpent        float64       `json:"totalSpent,omitempty" bson:"totalSpent,omitempty" extract:"PosData\\.purchased_amount,converter=number,optional,merge=overwrite"`                 // Tổng tiền đã mua
-	SucceedOrderCount int64         `json:"succeedOrderCount,omitempty" bson:"succeedOrderCount,omitempty" extract:"PosData\\.succeed_order_count,converter=int64,optional,merge=overwrite"` // Số đơn hàng thành công
-	TagIds            []interface{} `json:"tagIds,omitempty" bson:"tagIds,omitempty" extract:"PosData\\.tags,optional,merge=overwrite"`                                                      // Tags (array)
-	PosLastOrderAt    int64         `json:"posLastOrderAt,omitempty" bson:"posLastOrderAt,omitempty" extract:"PosData\\.last_order_at,converter=time,format=2006-01-02T15:04:05Z,optional"`  // Thời gian đơn hàng cuối
-	PosAddresses      []interface{} `json:"posAddresses,omitempty" bson:"posAddresses,omitempty" extract:"PosData\\.shop_customer_address,optional,merge=overwrite"`                         // Địa chỉ (array)
-	PosReferralCode   string        `json:"posReferralCode,omitempty" bson:"posReferralCode,omitempty" extract:"PosData\\.referral_code,converter=string,optional,merge=overwrite"`          // Mã giới thiệu
-	PosIsBlock        bool          `json:"posIsBlock,omitempty" bson:"posIsBlock,omitempty" extract:"PosData\\.is_block,converter=bool,optional,merge=overwrite"`                           // Trạng thái block
+	CustomerLevelId   string        `json:"customerLevelId,omitempty" bson:"customerLevelId,omitempty" extract:"PosData\\.level_id,converter=string,optional,merge=overwrite"`                                                                     // UUID string
+	Point             int64         `json:"point,omitempty" bson:"point,omitempty" extract:"PosData\\.reward_point,converter=int64,optional,merge=overwrite"`                                                                                      // Điểm tích lũy
+	TotalOrder        int64         `json:"totalOrder,omitempty" bson:"totalOrder,omitempty" extract:"PosData\\.order_count,converter=int64,optional,merge=overwrite"`                                                                             // Tổng đơn hàng
+	TotalSpent        float64       `json:"totalSpent,omitempty" bson:"totalSpent,omitempty" extract:"PosData\\.purchased_amount,converter=number,optional,merge=overwrite"`                                                                       // Tổng tiền đã mua
+	SucceedOrderCount int64         `json:"succeedOrderCount,omitempty" bson:"succeedOrderCount,omitempty" extract:"PosData\\.succeed_order_count,converter=int64,optional,merge=overwrite"`                                                       // Số đơn hàng thành công
+	TagIds            []interface{} `json:"tagIds,omitempty" bson:"tagIds,omitempty" extract:"PosData\\.tags,optional,merge=overwrite"`                                                                                                            // Tags (array)
+	PosLastOrderAt    int64         `json:"posLastOrderAt,omitempty" bson:"posLastOrderAt,omitempty" extract:"PosData\\.last_order_at,converter=time,format=2006-01-02T15:04:05Z,optional"`                                                        // Thời gian đơn hàng cuối
+	PosAddresses      []interface{} `json:"posAddresses,omitempty" bson:"posAddresses,omitempty" extract:"PosData\\.shop_customer_address,optional,merge=overwrite" fieldPolicy:"hidden=Customer.ReadSensitive;readonly=Customer.UpdateSensitive"` // Địa chỉ (array)
+	PosReferralCode   string        `json:"posReferralCode,omitempty" bson:"posReferralCode,omitempty" extract:"PosData\\.referral_code,converter=string,optional,merge=overwrite"`                                                                // Mã giới thiệu
+	PosIsBlock        bool          `json:"posIsBlock,omitempty" bson:"posIsBlock,omitempty" extract:"PosData\\.is_block,converter=bool,optional,merge=overwrite"`                                                                                 // Trạng thái block
 
 	// ===== ORGANIZATION =====
 	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức sở hữu dữ liệu (phân quyền)
 
+	// ===== RECORD OWNERSHIP =====
+	CreatedBy  primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty" index:"single:1"`   // User tạo bản ghi (phân quyền scope 2 - chỉ bản ghi của mình)
+	AssignedTo primitive.ObjectID `json:"assignedTo,omitempty" bson:"assignedTo,omitempty" index:"single:1"` // User được giao phụ trách bản ghi (phân quyền scope 2, đổi qua API assign)
+
 	// ===== METADATA =====
 	Sources   []string `json:"sources" bson:"sources"`     // ["pancake", "pos"] - Track nguồn dữ liệu
 	CreatedAt int64    `json:"createdAt" bson:"createdAt"` // Thời gian tạo
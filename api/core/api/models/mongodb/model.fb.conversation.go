@@ -19,6 +19,10 @@ type FbConversation struct {
 	// ===== ORGANIZATION =====
 	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức sở hữu dữ liệu (phân quyền)
 
+	// ===== RECORD OWNERSHIP =====
+	CreatedBy  primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty" index:"single:1"`   // User tạo bản ghi (phân quyền scope 2 - chỉ bản ghi của mình)
+	AssignedTo primitive.ObjectID `json:"assignedTo,omitempty" bson:"assignedTo,omitempty" index:"single:1"` // User được giao phụ trách bản ghi (phân quyền scope 2, đổi qua API assign)
+
 	CreatedAt int64 `json:"createdAt" bson:"createdAt"` // Thời gian tạo quyền
 	UpdatedAt int64 `json:"updatedAt" bson:"updatedAt"` // Thời gian cập nhật quyền
 }
@@ -14,9 +14,9 @@ type FbCustomer struct {
 	PageId     string `json:"pageId" bson:"pageId" index:"text" extract:"PanCakeData\\.page_id,converter=string,optional"`        // Facebook Page ID (extract từ PanCakeData["page_id"])
 
 	// ===== BASIC INFO =====
-	Name         string   `json:"name" bson:"name" index:"text" extract:"PanCakeData\\.name,converter=string,optional"`         // Tên khách hàng (extract từ PanCakeData["name"])
-	PhoneNumbers []string `json:"phoneNumbers" bson:"phoneNumbers" index:"text" extract:"PanCakeData\\.phone_numbers,optional"` // Số điện thoại (extract từ PanCakeData["phone_numbers"], array)
-	Email        string   `json:"email" bson:"email" index:"text" extract:"PanCakeData\\.email,converter=string,optional"`      // Email (extract từ PanCakeData["email"])
+	Name         string   `json:"name" bson:"name" index:"text" extract:"PanCakeData\\.name,converter=string,optional"`                                                                                               // Tên khách hàng (extract từ PanCakeData["name"])
+	PhoneNumbers []string `json:"phoneNumbers" bson:"phoneNumbers" index:"text" extract:"PanCakeData\\.phone_numbers,optional" fieldPolicy:"mask:phone=FbCustomer.ReadSensitive;readonly=FbCustomer.UpdateSensitive"` // Số điện thoại (extract từ PanCakeData["phone_numbers"], array)
+	Email        string   `json:"email" bson:"email" index:"text" extract:"PanCakeData\\.email,converter=string,optional" fieldPolicy:"mask:email=FbCustomer.ReadSensitive;readonly=FbCustomer.UpdateSensitive"`      // Email (extract từ PanCakeData["email"])
 
 	// ===== ADDITIONAL INFO =====
 	Birthday string `json:"birthday,omitempty" bson:"birthday,omitempty" extract:"PanCakeData\\.birthday,converter=string,optional"` // Ngày sinh (extract từ PanCakeData["birthday"])
@@ -24,11 +24,15 @@ type FbCustomer struct {
 	LivesIn  string `json:"livesIn,omitempty" bson:"livesIn,omitempty" extract:"PanCakeData\\.lives_in,converter=string,optional"`   // Nơi ở (extract từ PanCakeData["lives_in"])
 
 	// ===== SOURCE DATA =====
-	PanCakeData map[string]interface{} `json:"panCakeData,omitempty" bson:"panCakeData,omitempty"` // Dữ liệu gốc từ Pancake API
+	PanCakeData map[string]interface{} `json:"panCakeData,omitempty" bson:"panCakeData,omitempty" fieldPolicy:"hidden=FbCustomer.ReadSensitive"` // Dữ liệu gốc từ Pancake API
 
 	// ===== ORGANIZATION =====
 	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức sở hữu dữ liệu (phân quyền)
 
+	// ===== RECORD OWNERSHIP =====
+	CreatedBy  primitive.ObjectID `json:"createdBy,omitempty" bson:"createdBy,omitempty" index:"single:1"`   // User tạo bản ghi (phân quyền scope 2 - chỉ bản ghi của mình)
+	AssignedTo primitive.ObjectID `json:"assignedTo,omitempty" bson:"assignedTo,omitempty" index:"single:1"` // User được giao phụ trách bản ghi (phân quyền scope 2, đổi qua API assign)
+
 	// ===== METADATA =====
 	PanCakeUpdatedAt int64 `json:"panCakeUpdatedAt" bson:"panCakeUpdatedAt" extract:"PanCakeData\\.updated_at,converter=time,format=2006-01-02T15:04:05.000000,optional"` // Thời gian cập nhật từ Pancake (extract từ PanCakeData["updated_at"])
 	CreatedAt        int64 `json:"createdAt" bson:"createdAt"`                                                                                                            // Thời gian tạo
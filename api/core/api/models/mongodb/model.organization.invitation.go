@@ -0,0 +1,36 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationInvitationStatus định nghĩa trạng thái của lời mời
+const (
+	OrganizationInvitationStatusPending  = "pending"  // Chờ chấp nhận (quá expiresAt thì không dùng được nữa, có thể gửi lại)
+	OrganizationInvitationStatusAccepted = "accepted" // Đã chấp nhận, user_roles đã được tạo
+	OrganizationInvitationStatusRevoked  = "revoked"  // Đã bị thu hồi
+)
+
+// OrganizationInvitation là lời mời một người (theo email hoặc số điện thoại) tham gia tổ chức với các role cho trước
+// Người được mời nhận link có chữ ký; khi đăng nhập kèm token của link, danh tính được gắn vào lời mời và user_roles được tạo tự động
+type OrganizationInvitation struct {
+	ID                  primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerOrganizationID primitive.ObjectID   `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức mời
+	Email               string               `json:"email,omitempty" bson:"email,omitempty" index:"single:1"`         // Email người được mời (chữ thường)
+	Phone               string               `json:"phone,omitempty" bson:"phone,omitempty" index:"single:1"`         // Số điện thoại người được mời
+	RoleIDs             []primitive.ObjectID `json:"roleIds" bson:"roleIds"`                                          // Các role (thuộc tổ chức) được gán khi chấp nhận
+	Status              string               `json:"status" bson:"status" index:"single:1"`                           // pending, accepted, revoked
+	TokenHash           string               `json:"-" bson:"tokenHash"`                                              // SHA-256 (hex) của nonce trong link hiện tại (gửi lại = nonce mới, link cũ hết hiệu lực)
+	ExpiresAt           int64                `json:"expiresAt" bson:"expiresAt"`                                      // Thời điểm link hết hạn (milliseconds)
+	InvitedBy           primitive.ObjectID   `json:"invitedBy" bson:"invitedBy"`                                      // User tạo lời mời
+	SendCount           int                  `json:"sendCount" bson:"sendCount"`                                      // Số lần đã gửi link
+	LastSentAt          int64                `json:"lastSentAt" bson:"lastSentAt"`                                    // Lần gửi gần nhất (milliseconds)
+	AcceptedBy          *primitive.ObjectID  `json:"acceptedBy,omitempty" bson:"acceptedBy,omitempty"`                // User đã chấp nhận
+	AcceptedAt          *int64               `json:"acceptedAt,omitempty" bson:"acceptedAt,omitempty"`                // Thời điểm chấp nhận (milliseconds)
+	RevokedBy           *primitive.ObjectID  `json:"revokedBy,omitempty" bson:"revokedBy,omitempty"`                  // User thu hồi
+	RevokedAt           *int64               `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`                  // Thời điểm thu hồi (milliseconds)
+	CreatedAt           int64                `json:"createdAt" bson:"createdAt"`
+	UpdatedAt           int64                `json:"updatedAt" bson:"updatedAt"`
+
+	InviteLink string `json:"inviteLink,omitempty" bson:"-"` // Link mời đầy đủ (chỉ trả về khi tạo/gửi lại, không lưu DB)
+}
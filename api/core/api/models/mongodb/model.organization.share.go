@@ -4,13 +4,29 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// OrganizationShareStatus định nghĩa trạng thái của share
+const (
+	OrganizationShareStatusActive  = "active"  // Đang hiệu lực
+	OrganizationShareStatusRevoked = "revoked" // Đã bị thu hồi
+	OrganizationShareStatusExpired = "expired" // Đã hết hạn (job đánh dấu khi quá expiresAt)
+)
+
 // OrganizationShare đại diện cho việc share dữ liệu giữa các organizations
-// Organization A có thể share tất cả data của mình với Organization B
+// Organization A có thể share tất cả data của mình (hoặc một phần, qua DocumentFilter) với Organization B
+// Với IncludeOwnerSubtree/IncludeRecipientSubtree, share áp dụng theo cây tổ chức hiện tại (tổ chức con mới tạo/di chuyển tự động được tính)
 type OrganizationShare struct {
-	ID                  primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	OwnerOrganizationID primitive.ObjectID `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"` // Tổ chức sở hữu dữ liệu (phân quyền) - Organization share data với ToOrgID
-	ToOrgID             primitive.ObjectID `json:"toOrgId" bson:"toOrgId" index:"single:1"`                         // Organization nhận data
-	PermissionNames     []string           `json:"permissionNames,omitempty" bson:"permissionNames,omitempty"`      // [] hoặc nil = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể
-	CreatedAt           int64              `json:"createdAt" bson:"createdAt"`
-	CreatedBy           primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	ID                      primitive.ObjectID     `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerOrganizationID     primitive.ObjectID     `json:"ownerOrganizationId" bson:"ownerOrganizationId" index:"single:1"`         // Tổ chức sở hữu dữ liệu (phân quyền) - Organization share data với ToOrgID
+	ToOrgID                 primitive.ObjectID     `json:"toOrgId" bson:"toOrgId" index:"single:1"`                                 // Organization nhận data
+	PermissionNames         []string               `json:"permissionNames,omitempty" bson:"permissionNames,omitempty"`              // [] hoặc nil = tất cả permissions, ["Order.Read", "Order.Create"] = chỉ share với permissions cụ thể
+	DocumentFilter          map[string]interface{} `json:"documentFilter,omitempty" bson:"documentFilter,omitempty"`                // nil = tất cả document của OwnerOrganizationID, {"pageId": "123"} = chỉ document khớp filter (so sánh bằng)
+	IncludeOwnerSubtree     bool                   `json:"includeOwnerSubtree" bson:"includeOwnerSubtree"`                          // true = share cả dữ liệu của các tổ chức con của OwnerOrganizationID (tính động theo Path)
+	IncludeRecipientSubtree bool                   `json:"includeRecipientSubtree" bson:"includeRecipientSubtree" index:"single:1"` // true = các tổ chức con của ToOrgID cũng nhận share (tính động theo Path)
+	Note                    string                 `json:"note,omitempty" bson:"note,omitempty"`                                    // Lý do / ghi chú khi share
+	Status                  string                 `json:"status" bson:"status" index:"single:1"`                                   // Trạng thái: active, revoked, expired (rỗng = active, dữ liệu cũ)
+	ExpiresAt               *int64                 `json:"expiresAt,omitempty" bson:"expiresAt,omitempty" index:"single:1"`         // Thời điểm hết hạn (milliseconds), nil = không hết hạn
+	RevokedAt               *int64                 `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`                          // Thời điểm thu hồi (milliseconds)
+	RevokedBy               *primitive.ObjectID    `json:"revokedBy,omitempty" bson:"revokedBy,omitempty"`                          // User thu hồi share
+	CreatedAt               int64                  `json:"createdAt" bson:"createdAt"`
+	CreatedBy               primitive.ObjectID     `json:"createdBy" bson:"createdBy"`
 }
@@ -78,6 +78,12 @@ type CRUDHandler interface {
 	DocumentExists(c fiber.Ctx) error
 }
 
+// RecordAssigner là handler CRUD hỗ trợ giao bản ghi (collection có createdBy/assignedTo - phân quyền scope 2)
+type RecordAssigner interface {
+	SupportsRecordAssignment() bool
+	HandleAssign(c fiber.Ctx) error
+}
+
 // Router quản lý việc định tuyến cho API
 type Router struct {
 	app *fiber.App
@@ -150,6 +156,19 @@ var (
 	userRoleConfig          = readWriteConfig
 	agentConfig             = readWriteConfig
 	organizationShareConfig = readWriteConfig
+	serviceAccountConfig    = readWriteConfig
+	// OAuth client: không có update/upsert hàng loạt vì secret và clientId do server sinh
+	oauthClientConfig = CRUDConfig{
+		InsOne: true, InsMany: false,
+		Find: true, FindOne: true, FindById: true,
+		FindIds: true, Paginate: true,
+		UpdOne: false, UpdMany: false, UpdById: true,
+		FindUpd: false,
+		DelOne:  false, DelMany: false, DelById: true,
+		FindDel: false,
+		Count:   true, Distinct: false,
+		Upsert: false, UpsMany: false, Exists: true,
+	}
 
 	// Pancake Module Collections
 	accessTokenConfig   = readWriteConfig
@@ -239,6 +258,9 @@ func (r *Router) registerCRUDRoutes(router fiber.Router, prefix string, h CRUDHa
 	authDeleteMiddleware := middleware.AuthMiddleware(permissionPrefix + ".Delete")
 	fmt.Printf("[ROUTER] Middleware created for prefix: %s\n", prefix)
 
+	// Đăng ký resource cho chế độ explain của /auth/effective-permissions
+	handler.RegisterPermissionResource(permissionPrefix, h)
+
 	// Create operations
 	if config.InsOne {
 		registerRouteWithMiddleware(router, prefix, "POST", "/insert-one", []fiber.Handler{authMiddleware, orgContextMiddleware}, h.InsertOne)
@@ -277,6 +299,10 @@ func (r *Router) registerCRUDRoutes(router fiber.Router, prefix string, h CRUDHa
 	if config.FindUpd {
 		registerRouteWithMiddleware(router, prefix, "PUT", "/find-one-and-update", []fiber.Handler{authUpdateMiddleware, orgContextMiddleware}, h.FindOneAndUpdate)
 	}
+	// Giao bản ghi (đổi assignedTo) - chỉ collection có createdBy/assignedTo
+	if assigner, ok := h.(RecordAssigner); ok && config.UpdById && assigner.SupportsRecordAssignment() {
+		registerRouteWithMiddleware(router, prefix, "PUT", "/assign", []fiber.Handler{authUpdateMiddleware, orgContextMiddleware}, assigner.HandleAssign)
+	}
 
 	// Delete operations
 	if config.DelOne {
@@ -324,6 +350,27 @@ func registerAdminRoutes(router fiber.Router) error {
 		return fmt.Errorf("failed to create admin handler: %v", err)
 	}
 
+	// Quản lý phiên đăng nhập (thiết bị) và lịch sử đăng nhập của người dùng - dành cho bộ phận hỗ trợ
+	// Prefix là đường dẫn đầy đủ "/admin/user/:id/..." và đăng ký TRƯỚC các route prefix "/admin/user" bên dưới,
+	// vì .Use() khớp theo tiền tố chuỗi: middleware của "/admin/user" sẽ áp dụng chồng lên mọi route "/admin/user..." đăng ký sau nó
+	sessionMiddleware := middleware.AuthMiddleware("User.Session")
+	registerRouteWithMiddleware(router, "/admin/user/:id/sessions", "GET", "", []fiber.Handler{sessionMiddleware}, adminHandler.HandleGetUserSessions)
+	registerRouteWithMiddleware(router, "/admin/user/:id/sessions", "POST", "/revoke-all", []fiber.Handler{sessionMiddleware}, adminHandler.HandleRevokeAllUserSessions)
+	registerRouteWithMiddleware(router, "/admin/user/:id/sessions", "DELETE", "/:hwid", []fiber.Handler{sessionMiddleware}, adminHandler.HandleRevokeUserSession)
+	registerRouteWithMiddleware(router, "/admin/user/:id/login-history", "GET", "", []fiber.Handler{sessionMiddleware}, adminHandler.HandleGetUserLoginHistory)
+
+	// Quyền hiệu lực và phạm vi dữ liệu của user (giải thích 403 / danh sách rỗng)
+	accessHandler, err := handler.NewAccessHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create access handler: %v", err)
+	}
+	effectivePermissionMiddleware := middleware.AuthMiddleware("User.EffectivePermission")
+	registerRouteWithMiddleware(router, "/admin/user/:id/effective-permissions", "GET", "", []fiber.Handler{effectivePermissionMiddleware}, accessHandler.HandleGetUserEffectivePermissions)
+
+	// Đăng nhập thay người dùng ("login as") - token ngắn hạn mang cả danh tính quản trị viên và user
+	impersonateMiddleware := middleware.AuthMiddleware("User.Impersonate")
+	registerRouteWithMiddleware(router, "/admin/user/:id/impersonate", "POST", "", []fiber.Handler{impersonateMiddleware}, adminHandler.HandleImpersonateUser)
+
 	// Các route đặc biệt cho quản trị viên
 	// FIX: Dùng registerRouteWithMiddleware với .Use() method (cách đúng) thay vì cách trực tiếp có bug trong Fiber v3
 	blockMiddleware := middleware.AuthMiddleware("User.Block")
@@ -333,6 +380,16 @@ func registerAdminRoutes(router fiber.Router) error {
 	setRoleMiddleware := middleware.AuthMiddleware("User.SetRole")
 	registerRouteWithMiddleware(router, "/admin/user", "POST", "/role", []fiber.Handler{setRoleMiddleware}, adminHandler.HandleSetRole)
 
+	// Phát hiện và gộp tài khoản trùng (cùng email/số điện thoại đã xác thực, khác firebaseUid)
+	// Prefix riêng "/account-merge" để không bị middleware của "/admin" và "/user" áp dụng chồng
+	userMergeHandler, err := handler.NewUserMergeHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create user merge handler: %v", err)
+	}
+	mergeMiddleware := middleware.AuthMiddleware("User.Merge")
+	registerRouteWithMiddleware(router, "/account-merge", "GET", "/candidates", []fiber.Handler{mergeMiddleware}, userMergeHandler.HandleListCandidates)
+	registerRouteWithMiddleware(router, "/account-merge", "POST", "", []fiber.Handler{mergeMiddleware}, userMergeHandler.HandleMerge)
+
 	// Thiết lập administrator (yêu cầu quyền Init.SetAdmin)
 	setAdminMiddleware := middleware.AuthMiddleware("Init.SetAdmin")
 	registerRouteWithMiddleware(router, "/admin/user", "POST", "/set-administrator/:id", []fiber.Handler{setAdminMiddleware}, adminHandler.HandleAddAdministrator)
@@ -352,6 +409,7 @@ func registerSystemRoutes(router fiber.Router) error {
 
 	// System routes
 	router.Get("/system/health", systemHandler.HandleHealth)
+	registerRouteWithMiddleware(router, "/system", "GET", "/cache-stats", []fiber.Handler{middleware.AuthMiddleware("System.Read")}, systemHandler.HandleCacheStats)
 
 	return nil
 }
@@ -369,6 +427,22 @@ func (r *Router) registerAuthRoutes(router fiber.Router) error {
 	// Các route xác thực cá nhân
 	// Firebase Authentication - Nhận Firebase ID token và tạo JWT
 	router.Post("/auth/login/firebase", userHandler.HandleLoginWithFirebase)
+	// Identity provider khác (oidc, local...) - route /auth/login/firebase ở trên được ưu tiên khớp trước
+	router.Post("/auth/login/:provider", userHandler.HandleLoginWithIdentity)
+	// Refresh token - Đổi refresh token lấy access token mới (public, không qua AuthMiddleware)
+	// ⚠️ Phải đăng ký TRƯỚC các route dùng registerRouteWithMiddleware với prefix /auth,
+	// vì .Use() trên group /auth sẽ áp dụng cho mọi route /auth đăng ký sau đó
+	router.Post("/auth/refresh", userHandler.HandleRefreshToken)
+
+	// OAuth2/OIDC issuer cho các service khác - discovery, JWKS và token endpoint là public
+	// ⚠️ Phải đăng ký TRƯỚC các route dùng registerRouteWithMiddleware với prefix bắt đầu bằng /oauth
+	oauthHandler, err := handler.NewOAuthHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create oauth handler: %v", err)
+	}
+	router.Get("/.well-known/openid-configuration", oauthHandler.HandleDiscovery)
+	router.Get("/oauth/jwks", oauthHandler.HandleJWKS)
+	router.Post("/oauth/token", oauthHandler.HandleToken)
 
 	// Logout - Xóa JWT token
 	// FIX: Dùng registerRouteWithMiddleware với .Use() method (cách đúng) thay vì cách trực tiếp có bug trong Fiber v3
@@ -387,6 +461,35 @@ func (r *Router) registerAuthRoutes(router fiber.Router) error {
 	authRolesMiddleware := middleware.AuthMiddleware("")
 	registerRouteWithMiddleware(router, "/auth", "GET", "/roles", []fiber.Handler{authRolesMiddleware}, userHandler.HandleGetUserRoles)
 
+	// Sessions - Quản lý thiết bị đang đăng nhập (mỗi hwid là một phiên)
+	registerRouteWithMiddleware(router, "/auth", "GET", "/sessions", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleGetSessions)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/sessions/revoke-others", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleRevokeOtherSessions)
+	registerRouteWithMiddleware(router, "/auth", "DELETE", "/sessions/:hwid", []fiber.Handler{authOnlyMiddleware}, userHandler.HandleDeleteSession)
+
+	// Xác thực 2 lớp (TOTP) - đăng ký, step-up cho thao tác nhạy cảm, recovery code
+	mfaHandler, err := handler.NewMFAHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create mfa handler: %v", err)
+	}
+	registerRouteWithMiddleware(router, "/auth", "GET", "/mfa", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleGetStatus)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/mfa/totp/enroll", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleEnroll)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/mfa/totp/confirm", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleConfirm)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/mfa/verify", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleVerify)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/mfa/recovery-codes", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleRegenerateRecoveryCodes)
+	registerRouteWithMiddleware(router, "/auth", "POST", "/mfa/disable", []fiber.Handler{authOnlyMiddleware}, mfaHandler.HandleDisable)
+
+	// Effective permissions - Giải thích quyền hiệu lực và phạm vi dữ liệu của user hiện tại
+	accessHandler, err := handler.NewAccessHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create access handler: %v", err)
+	}
+	registerRouteWithMiddleware(router, "/auth", "GET", "/effective-permissions", []fiber.Handler{authOnlyMiddleware}, accessHandler.HandleGetMyEffectivePermissions)
+
+	// OAuth authorize - user đã đăng nhập đồng ý cấp authorization code cho client
+	// Prefix là "/oauth/authorize" (không phải "/oauth") vì .Use() khớp theo tiền tố chuỗi,
+	// prefix "/oauth" sẽ kéo theo cả /oauth-client và /oauth-signing-key
+	registerRouteWithMiddleware(router, "/oauth/authorize", "POST", "", []fiber.Handler{authOnlyMiddleware}, oauthHandler.HandleAuthorize)
+
 	return nil
 }
 
@@ -416,25 +519,36 @@ func (r *Router) registerRBACRoutes(router fiber.Router) error {
 	// CRUD routes
 	r.registerCRUDRoutes(router, "/permission", permHandler, permConfig, "Permission")
 
-	// Role routes
-	roleHandler, err := handler.NewRoleHandler()
-	if err != nil {
-		return fmt.Errorf("failed to create role handler: %v", err)
-	}
-	r.registerCRUDRoutes(router, "/role", roleHandler, roleConfig, "Role")
-
 	// RolePermission routes
+	// ⚠️ Phải đăng ký TRƯỚC các route prefix "/role": .Use() khớp theo tiền tố chuỗi nên middleware của "/role"
+	// (Role.*) sẽ áp dụng chồng lên mọi route "/role-permission" đăng ký sau nó
 	rolePermHandler, err := handler.NewRolePermissionHandler()
 	if err != nil {
 		return fmt.Errorf("failed to create role permission handler: %v", err)
 	}
 	// Route đặc biệt cho cập nhật quyền của vai trò
 	// FIX: Dùng registerRouteWithMiddleware với .Use() method (cách đúng) thay vì cách trực tiếp có bug trong Fiber v3
+	// Route chạy thử điều kiện (ABAC) của role permission trên document mẫu
+	// Prefix đầy đủ, đăng ký TRƯỚC update-role: middleware RolePermission.Update của group "/role-permission" khớp mọi path có tiền tố này
+	rolePermReadMiddleware := middleware.AuthMiddleware("RolePermission.Read")
+	registerRouteWithMiddleware(router, "/role-permission/validate-conditions", "POST", "", []fiber.Handler{rolePermReadMiddleware}, rolePermHandler.HandleValidateConditions)
 	rolePermUpdateMiddleware := middleware.AuthMiddleware("RolePermission.Update")
 	registerRouteWithMiddleware(router, "/role-permission", "PUT", "/update-role", []fiber.Handler{rolePermUpdateMiddleware}, rolePermHandler.HandleUpdateRolePermissions)
 	// CRUD routes
 	r.registerCRUDRoutes(router, "/role-permission", rolePermHandler, rolePermConfig, "RolePermission")
 
+	// Role routes
+	roleHandler, err := handler.NewRoleHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create role handler: %v", err)
+	}
+	// Template và kế thừa quyền: khởi tạo role từ template, tách role khỏi template, xem quyền gồm cả quyền kế thừa
+	// Prefix đầy đủ, đăng ký TRƯỚC CRUD "/role" để middleware của mỗi route không áp dụng chồng lên route khác
+	registerRouteWithMiddleware(router, "/role/:id/instantiate", "POST", "", []fiber.Handler{middleware.AuthMiddleware("Role.Insert")}, roleHandler.HandleInstantiateTemplate)
+	registerRouteWithMiddleware(router, "/role/:id/detach-template", "POST", "", []fiber.Handler{middleware.AuthMiddleware("Role.Update")}, roleHandler.HandleDetachTemplate)
+	registerRouteWithMiddleware(router, "/role/:id/permissions", "GET", "", []fiber.Handler{middleware.AuthMiddleware("RolePermission.Read")}, roleHandler.HandleGetPermissions)
+	r.registerCRUDRoutes(router, "/role", roleHandler, roleConfig, "Role")
+
 	// UserRole routes
 	userRoleHandler, err := handler.NewUserRoleHandler()
 	if err != nil {
@@ -447,16 +561,32 @@ func (r *Router) registerRBACRoutes(router fiber.Router) error {
 	// CRUD routes
 	r.registerCRUDRoutes(router, "/user-role", userRoleHandler, userRoleConfig, "UserRole")
 
-	// Organization routes
-	organizationHandler, err := handler.NewOrganizationHandler()
+	// Nâng quyền tạm thời (JIT): user xin role trong N giờ, người có UserRole.Update trong tổ chức của role duyệt
+	// Dùng 2 prefix riêng (không dùng /role-... hay /user-role/...) vì .Use() của Fiber khớp theo tiền tố chuỗi
+	roleElevationHandler, err := handler.NewRoleElevationHandler()
 	if err != nil {
-		return fmt.Errorf("failed to create organization handler: %v", err)
+		return fmt.Errorf("failed to create role elevation handler: %v", err)
 	}
-	fmt.Printf("Registering organization routes with prefix: /organization\n")
-	r.registerCRUDRoutes(router, "/organization", organizationHandler, readWriteConfig, "Organization")
-	fmt.Printf("Organization routes registered successfully\n")
+	elevationAuthMiddleware := middleware.AuthMiddleware("")
+	registerRouteWithMiddleware(router, "/elevation-request", "POST", "", []fiber.Handler{elevationAuthMiddleware}, roleElevationHandler.HandleRequest)
+	registerRouteWithMiddleware(router, "/elevation-request", "GET", "/mine", []fiber.Handler{elevationAuthMiddleware}, roleElevationHandler.HandleListMine)
+	registerRouteWithMiddleware(router, "/elevation-request", "POST", "/:id/cancel", []fiber.Handler{elevationAuthMiddleware}, roleElevationHandler.HandleCancel)
+	registerRouteWithMiddleware(router, "/elevation-approval", "GET", "/pending", []fiber.Handler{userRoleUpdateMiddleware}, roleElevationHandler.HandleListPending)
+	registerRouteWithMiddleware(router, "/elevation-approval", "POST", "/:id/approve", []fiber.Handler{userRoleUpdateMiddleware}, roleElevationHandler.HandleApprove)
+	registerRouteWithMiddleware(router, "/elevation-approval", "POST", "/:id/reject", []fiber.Handler{userRoleUpdateMiddleware}, roleElevationHandler.HandleReject)
+
+	// Audit trail (chỉ đọc): lịch sử thay đổi dữ liệu và phân quyền của các tổ chức trong phạm vi quyền AuditLog.Read
+	auditLogHandler, err := handler.NewAuditLogHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create audit log handler: %v", err)
+	}
+	auditLogReadMiddleware := middleware.AuthMiddleware("AuditLog.Read")
+	registerRouteWithMiddleware(router, "/audit-log", "GET", "", []fiber.Handler{auditLogReadMiddleware}, auditLogHandler.HandleList)
+	registerRouteWithMiddleware(router, "/audit-log", "GET", "/:id", []fiber.Handler{auditLogReadMiddleware}, auditLogHandler.HandleGetByID)
 
 	// Organization Share routes
+	// ⚠️ Phải đăng ký TRƯỚC các route prefix "/organization": .Use() khớp theo tiền tố chuỗi nên middleware của "/organization"
+	// (Organization.*) sẽ áp dụng chồng lên mọi route "/organization-share" đăng ký sau nó
 	organizationShareHandler, err := handler.NewOrganizationShareHandler()
 	if err != nil {
 		return fmt.Errorf("failed to create organization share handler: %v", err)
@@ -468,9 +598,42 @@ func (r *Router) registerRBACRoutes(router fiber.Router) error {
 	orgContextMiddleware := middleware.OrganizationContextMiddleware()
 	registerRouteWithMiddleware(router, "/organization-share", "POST", "", []fiber.Handler{orgShareCreateMiddleware, orgContextMiddleware}, organizationShareHandler.CreateShare)
 	registerRouteWithMiddleware(router, "/organization-share", "DELETE", "/:id", []fiber.Handler{orgShareDeleteMiddleware, orgContextMiddleware}, organizationShareHandler.DeleteShare)
+	registerRouteWithMiddleware(router, "/organization-share", "POST", "/:id/revoke", []fiber.Handler{orgShareDeleteMiddleware, orgContextMiddleware}, organizationShareHandler.RevokeShare)
 	// CRUD routes - đăng ký đầy đủ các operation CRUD (Find, FindById, Update, v.v.)
 	r.registerCRUDRoutes(router, "/organization-share", organizationShareHandler, organizationShareConfig, "OrganizationShare")
 
+	// Organization routes
+	organizationHandler, err := handler.NewOrganizationHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create organization handler: %v", err)
+	}
+	fmt.Printf("Registering organization routes with prefix: /organization\n")
+	// Di chuyển, cây tổ chức, lưu trữ/khôi phục/xóa vĩnh viễn
+	// Prefix là đường dẫn đầy đủ của từng route và đăng ký TRƯỚC CRUD "/organization" để middleware không áp dụng chồng lên nhau
+	orgUpdateMiddleware := middleware.AuthMiddleware("Organization.Update")
+	orgReadMiddleware := middleware.AuthMiddleware("Organization.Read")
+	registerRouteWithMiddleware(router, "/organization/:id/move", "POST", "", []fiber.Handler{orgUpdateMiddleware}, organizationHandler.HandleMove)
+	registerRouteWithMiddleware(router, "/organization/tree", "GET", "", []fiber.Handler{orgReadMiddleware}, organizationHandler.HandleGetTree)
+	registerRouteWithMiddleware(router, "/organization/:id/archive", "POST", "", []fiber.Handler{middleware.AuthMiddleware("Organization.Delete")}, organizationHandler.HandleArchive)
+	registerRouteWithMiddleware(router, "/organization/:id/restore", "POST", "", []fiber.Handler{orgUpdateMiddleware}, organizationHandler.HandleRestore)
+	registerRouteWithMiddleware(router, "/organization/:id/purge", "DELETE", "", []fiber.Handler{middleware.AuthMiddleware("Organization.Purge")}, organizationHandler.HandlePurge)
+	// Lời mời vào tổ chức: quyền Organization.Invite được kiểm tra theo tổ chức trong URL ở handler
+	invitationHandler, err := handler.NewOrganizationInvitationHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create organization invitation handler: %v", err)
+	}
+	orgInviteMiddleware := middleware.AuthMiddleware("Organization.Invite")
+	// Prefix đầy đủ, đăng ký TRƯỚC CRUD "/organization"; resend/revoke đăng ký trước "/organization/:id/invitations"
+	// (tạo và liệt kê dùng chung path nên middleware Organization.Invite chạy 2 lần với 2 route này - cùng permission nên không đổi kết quả)
+	registerRouteWithMiddleware(router, "/organization/:id/invitations/:invitationId/resend", "POST", "", []fiber.Handler{orgInviteMiddleware}, invitationHandler.HandleResend)
+	registerRouteWithMiddleware(router, "/organization/:id/invitations/:invitationId/revoke", "POST", "", []fiber.Handler{orgInviteMiddleware}, invitationHandler.HandleRevoke)
+	registerRouteWithMiddleware(router, "/organization/:id/invitations", "POST", "", []fiber.Handler{orgInviteMiddleware}, invitationHandler.HandleCreate)
+	registerRouteWithMiddleware(router, "/organization/:id/invitations", "GET", "", []fiber.Handler{orgInviteMiddleware}, invitationHandler.HandleList)
+	r.registerCRUDRoutes(router, "/organization", organizationHandler, readWriteConfig, "Organization")
+	// Chấp nhận lời mời khi đã đăng nhập - chỉ cần xác thực (người được mời chưa có quyền gì trong tổ chức)
+	registerRouteWithMiddleware(router, "/invitation", "POST", "/accept", []fiber.Handler{middleware.AuthMiddleware("")}, invitationHandler.HandleAccept)
+	fmt.Printf("Organization routes registered successfully\n")
+
 	// Agent routes
 	agentHandler, err := handler.NewAgentHandler()
 	if err != nil {
@@ -484,6 +647,35 @@ func (r *Router) registerRBACRoutes(router fiber.Router) error {
 	registerRouteWithMiddleware(router, "/agent", "POST", "/check-out/:id", []fiber.Handler{agentCheckOutMiddleware}, agentHandler.HandleCheckOut) // Route check-out cho agent
 	r.registerCRUDRoutes(router, "/agent", agentHandler, agentConfig, "Agent")
 
+	// Service account routes (principal của máy, xác thực bằng API key qua header X-API-Key)
+	serviceAccountHandler, err := handler.NewServiceAccountHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create service account handler: %v", err)
+	}
+	// API key: prefix là đường dẫn đầy đủ của từng route và đăng ký TRƯỚC CRUD "/service-account"
+	// (.Use() khớp theo tiền tố chuỗi nên prefix chung sẽ làm middleware ApiKey.* / ServiceAccount.* áp dụng chồng lên nhau)
+	registerRouteWithMiddleware(router, "/service-account/:id/create-api-key", "POST", "", []fiber.Handler{middleware.AuthMiddleware("ApiKey.Insert")}, serviceAccountHandler.HandleCreateAPIKey)
+	registerRouteWithMiddleware(router, "/service-account/:id/api-keys", "GET", "", []fiber.Handler{middleware.AuthMiddleware("ApiKey.Read")}, serviceAccountHandler.HandleListAPIKeys)
+	registerRouteWithMiddleware(router, "/service-account/api-keys/:keyId/rotate", "POST", "", []fiber.Handler{middleware.AuthMiddleware("ApiKey.Update")}, serviceAccountHandler.HandleRotateAPIKey)
+	registerRouteWithMiddleware(router, "/service-account/api-keys/:keyId/revoke", "POST", "", []fiber.Handler{middleware.AuthMiddleware("ApiKey.Delete")}, serviceAccountHandler.HandleRevokeAPIKey)
+	r.registerCRUDRoutes(router, "/service-account", serviceAccountHandler, serviceAccountConfig, "ServiceAccount")
+
+	// OAuth client routes (ứng dụng nhận token do hệ thống phát hành)
+	oauthClientHandler, err := handler.NewOAuthClientHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client handler: %v", err)
+	}
+	registerRouteWithMiddleware(router, "/oauth-client", "POST", "/:id/rotate-secret", []fiber.Handler{middleware.AuthMiddleware("OAuthClient.Update")}, oauthClientHandler.HandleRotateSecret)
+	r.registerCRUDRoutes(router, "/oauth-client", oauthClientHandler, oauthClientConfig, "OAuthClient")
+
+	// Khóa ký token OAuth (dùng chung toàn hệ thống)
+	oauthKeyHandler, err := handler.NewOAuthHandler()
+	if err != nil {
+		return fmt.Errorf("failed to create oauth handler: %v", err)
+	}
+	registerRouteWithMiddleware(router, "/oauth-signing-key", "GET", "/keys", []fiber.Handler{middleware.AuthMiddleware("OAuthSigningKey.Read")}, oauthKeyHandler.HandleListSigningKeys)
+	registerRouteWithMiddleware(router, "/oauth-signing-key", "POST", "/rotate", []fiber.Handler{middleware.AuthMiddleware("OAuthSigningKey.Update")}, oauthKeyHandler.HandleRotateSigningKey)
+
 	return nil
 }
 
@@ -207,6 +207,7 @@ func (s *BaseServiceMongoImpl[T]) InsertOne(ctx context.Context, data T) (T, err
 		return zero, common.ConvertMongoError(err)
 	}
 
+	recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, created)
 	return created, nil
 }
 
@@ -250,6 +251,9 @@ func (s *BaseServiceMongoImpl[T]) InsertMany(ctx context.Context, data []T) ([]T
 		return nil, common.ConvertMongoError(err)
 	}
 
+	for _, item := range created {
+		recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, item)
+	}
 	return created, nil
 }
 
@@ -392,6 +396,11 @@ func (s *BaseServiceMongoImpl[T]) UpdateOne(ctx context.Context, filter interfac
 		return zero, common.ConvertMongoError(err)
 	}
 
+	if result.UpsertedID != nil {
+		recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, updated)
+	} else {
+		s.auditUpdated(ctx, existing)
+	}
 	return updated, nil
 }
 
@@ -442,6 +451,7 @@ func (s *BaseServiceMongoImpl[T]) UpdateMany(ctx context.Context, filter interfa
 		return 0, common.ConvertMongoError(err)
 	}
 
+	s.auditUpdated(ctx, existingDocs...)
 	return result.ModifiedCount, nil
 }
 
@@ -483,6 +493,7 @@ func (s *BaseServiceMongoImpl[T]) DeleteOne(ctx context.Context, filter interfac
 		return common.ErrNotFound
 	}
 
+	recordAudit(ctx, s.collection.Name(), AuditActionDelete, existing, nil)
 	return nil
 }
 
@@ -519,6 +530,9 @@ func (s *BaseServiceMongoImpl[T]) DeleteMany(ctx context.Context, filter interfa
 		return 0, common.ConvertMongoError(err)
 	}
 
+	for _, existing := range existingDocs {
+		recordAudit(ctx, s.collection.Name(), AuditActionDelete, existing, nil)
+	}
 	return result.DeletedCount, nil
 }
 
@@ -599,6 +613,11 @@ func (s *BaseServiceMongoImpl[T]) FindOneAndUpdate(ctx context.Context, filter i
 		}
 	}
 
+	if isExisting {
+		s.auditUpdated(ctx, existing)
+	} else if created := auditDataMap(result); created != nil && !auditObjectID(created["id"]).IsZero() {
+		recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, result)
+	}
 	return result, nil
 }
 
@@ -665,6 +684,7 @@ func (s *BaseServiceMongoImpl[T]) FindOneAndDelete(ctx context.Context, filter i
 		return zero, common.ConvertMongoError(err)
 	}
 
+	recordAudit(ctx, s.collection.Name(), AuditActionDelete, result, nil)
 	return result, nil
 }
 
@@ -862,6 +882,7 @@ func (s *BaseServiceMongoImpl[T]) UpdateById(ctx context.Context, id primitive.O
 		return zero, common.ConvertMongoError(err)
 	}
 
+	recordAudit(ctx, s.collection.Name(), AuditActionUpdate, existing, updated)
 	return updated, nil
 }
 
@@ -900,6 +921,7 @@ func (s *BaseServiceMongoImpl[T]) DeleteById(ctx context.Context, id primitive.O
 		return common.ErrNotFound
 	}
 
+	recordAudit(ctx, s.collection.Name(), AuditActionDelete, existing, nil)
 	return nil
 }
 
@@ -1072,6 +1094,7 @@ func (s *BaseServiceMongoImpl[T]) Upsert(ctx context.Context, filter interface{}
 								err = s.collection.FindOneAndUpdate(ctx, filter, updateData, opts).Decode(&upserted)
 								if err == nil {
 									logrus.Debug("Upsert: Upsert thành công sau khi xóa field từ document cũ")
+									recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, upserted)
 									return upserted, nil
 								}
 							}
@@ -1094,6 +1117,11 @@ func (s *BaseServiceMongoImpl[T]) Upsert(ctx context.Context, filter interface{}
 		"collection": s.collection.Name(),
 	}).Debug("Upsert: Upsert thành công")
 
+	if isExisting {
+		recordAudit(ctx, s.collection.Name(), AuditActionUpdate, existing, upserted)
+	} else {
+		recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, upserted)
+	}
 	return upserted, nil
 }
 
@@ -1175,6 +1203,9 @@ func (s *BaseServiceMongoImpl[T]) UpsertMany(ctx context.Context, filter interfa
 			if err = cursor.All(ctx, &upserted); err != nil {
 				return nil, common.ConvertMongoError(err)
 			}
+			for _, item := range upserted {
+				recordAudit(ctx, s.collection.Name(), AuditActionInsert, nil, item)
+			}
 		}
 	}
 
@@ -1190,6 +1221,10 @@ func (s *BaseServiceMongoImpl[T]) UpsertMany(ctx context.Context, filter interfa
 		if err = cursor.All(ctx, &updated); err != nil {
 			return nil, common.ConvertMongoError(err)
 		}
+		// Không có phiên bản cũ để so sánh: ghi dữ liệu mới
+		for _, item := range updated {
+			recordAudit(ctx, s.collection.Name(), AuditActionUpdate, nil, item)
+		}
 
 		// Kết hợp cả documents mới và documents đã update
 		upserted = append(upserted, updated...)
@@ -8,8 +8,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"meta_commerce/core/api/dto"
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -78,17 +81,26 @@ func (s *AdminService) SetRole(ctx context.Context, email string, roleID primiti
 		return nil, common.ConvertMongoError(err)
 	}
 
-	// Cập nhật Role cho User
-	user.Token = roleID.Hex() // Sử dụng Token để lưu RoleID
-	user.UpdatedAt = time.Now().Unix()
-
-	// Cập nhật User
-	updatedUser, err := s.userService.UpdateById(ctx, user.ID, user)
+	// Gán Role cho User qua user_roles (bỏ qua nếu đã được gán)
+	exists, err := s.userRoleService.IsExist(ctx, user.ID, roleID)
 	if err != nil {
 		return nil, err
 	}
+	if !exists {
+		now := time.Now().Unix()
+		_, err = s.userRoleService.InsertOne(ctx, models.UserRole{
+			ID:        primitive.NewObjectID(),
+			UserID:    user.ID,
+			RoleID:    roleID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return &updatedUser, nil
+	return &user, nil
 }
 
 // BlockUser chặn hoặc bỏ chặn User dựa trên Email và trạng thái Block
@@ -115,6 +127,15 @@ func (s *AdminService) BlockUser(ctx context.Context, email string, block bool,
 		return nil, err
 	}
 
+	// Khóa tài khoản: thu hồi ngay toàn bộ token đang hiệu lực của user
+	if block {
+		if err := s.userService.RevokeAllSessions(ctx, user.ID, "block"); err != nil {
+			return nil, err
+		}
+		updatedUser.Tokens = []models.Token{}
+		updatedUser.Token = ""
+	}
+
 	return &updatedUser, nil
 }
 
@@ -144,3 +165,113 @@ func (s *AdminService) UnBlockUser(ctx context.Context, email string) (*models.U
 
 	return &updatedUser, nil
 }
+
+// Impersonate phát hành token "login as" để quản trị viên xem hệ thống đúng như user đang thấy
+// Token mang cả 2 danh tính: userId = user được xem, imp = quản trị viên; chỉ là access token ngắn hạn,
+// không có refresh token và không thêm phiên (thiết bị) vào user
+// allowedOrgIDs: các tổ chức trong phạm vi quyền User.Impersonate của quản trị viên - user phải có role thuộc một trong các tổ chức này
+func (s *AdminService) Impersonate(ctx context.Context, impersonatorID primitive.ObjectID, targetID primitive.ObjectID, input dto.ImpersonateInput, allowedOrgIDs []primitive.ObjectID) (*dto.ImpersonateOutput, *models.User, error) {
+	if impersonatorID == targetID {
+		return nil, nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể đăng nhập thay chính mình", common.StatusBadRequest, nil)
+	}
+
+	target, err := s.userService.FindOneById(ctx, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target.IsBlock {
+		return nil, nil, common.NewError(common.ErrCodeBusinessOperation, "Tài khoản đã bị khóa, không thể đăng nhập thay", common.StatusBadRequest, nil)
+	}
+
+	// Chỉ Administrator mới được đăng nhập thay Administrator khác (tránh leo thang quyền qua impersonation)
+	targetIsAdmin, err := IsUserAdministrator(ctx, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if targetIsAdmin {
+		impersonatorIsAdmin, err := IsUserAdministrator(ctx, impersonatorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !impersonatorIsAdmin {
+			return nil, nil, common.NewError(common.ErrCodeAuth, "Không thể đăng nhập thay Administrator", common.StatusForbidden, nil)
+		}
+	}
+
+	var roleID *primitive.ObjectID
+	if input.RoleID != "" {
+		id, err := primitive.ObjectIDFromHex(input.RoleID)
+		if err != nil {
+			return nil, nil, common.NewError(common.ErrCodeValidationFormat, "roleId không hợp lệ", common.StatusBadRequest, err)
+		}
+		assigned, err := s.userRoleService.IsExist(ctx, targetID, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !assigned {
+			return nil, nil, common.NewError(common.ErrCodeValidationInput, "User không có role này", common.StatusBadRequest, nil)
+		}
+		roleID = &id
+	}
+
+	// User phải thuộc phạm vi quản lý của quản trị viên: có role (hoặc role được chọn) thuộc tổ chức được phép
+	inScope, err := s.userHasRoleInOrganizations(ctx, targetID, roleID, allowedOrgIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !inScope {
+		return nil, nil, common.NewError(common.ErrCodeAuth, "User không thuộc tổ chức bạn được phép đăng nhập thay", common.StatusForbidden, nil)
+	}
+
+	cfg := global.MongoDB_ServerConfig
+	claims, err := utility.NewJwtClaims(targetID.Hex(), "impersonation", models.JwtTokenTypeAccess, time.Duration(cfg.ImpersonationTokenTTL)*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims.ImpersonatorID = impersonatorID.Hex()
+	token, err := utility.SignToken(cfg.JwtSecret, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dto.ImpersonateOutput{
+		AccessToken:        token,
+		ExpiresAt:          claims.ExpiresAt,
+		ImpersonatedUserID: targetID.Hex(),
+		ImpersonatorID:     impersonatorID.Hex(),
+		RoleID:             input.RoleID,
+	}, &target, nil
+}
+
+// userHasRoleInOrganizations kiểm tra user có role thuộc một trong các tổ chức orgIDs
+// roleID khác nil: chỉ xét role này (role đã được kiểm tra là gán cho user)
+func (s *AdminService) userHasRoleInOrganizations(ctx context.Context, userID primitive.ObjectID, roleID *primitive.ObjectID, orgIDs []primitive.ObjectID) (bool, error) {
+	if len(orgIDs) == 0 {
+		return false, nil
+	}
+
+	roleIDs := []primitive.ObjectID{}
+	if roleID != nil {
+		roleIDs = append(roleIDs, *roleID)
+	} else {
+		userRoles, err := s.userRoleService.Find(ctx, bson.M{"userId": userID}, nil)
+		if err != nil && err != common.ErrNotFound {
+			return false, err
+		}
+		for _, userRole := range userRoles {
+			roleIDs = append(roleIDs, userRole.RoleID)
+		}
+	}
+	if len(roleIDs) == 0 {
+		return false, nil
+	}
+
+	_, err := s.roleService.FindOne(ctx, bson.M{
+		"_id":                 bson.M{"$in": roleIDs},
+		"ownerOrganizationId": bson.M{"$in": orgIDs},
+	}, nil)
+	if err == common.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -9,6 +9,7 @@ import (
 
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
+	"meta_commerce/core/identity"
 	"meta_commerce/core/utility"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -186,12 +187,18 @@ var InitialPermissions = []models.Permission{
 	{Name: "User.Delete", Describe: "Quyền xóa người dùng", Group: "Auth", Category: "User"},
 	{Name: "User.Block", Describe: "Quyền khóa/mở khóa người dùng", Group: "Auth", Category: "User"},
 	{Name: "User.SetRole", Describe: "Quyền phân quyền cho người dùng", Group: "Auth", Category: "User"},
+	{Name: "User.Session", Describe: "Quyền xem và thu hồi phiên đăng nhập (thiết bị), xem lịch sử đăng nhập của người dùng", Group: "Auth", Category: "User"},
+	{Name: "User.EffectivePermission", Describe: "Quyền xem quyền hiệu lực và phạm vi dữ liệu của người dùng", Group: "Auth", Category: "User"},
+	{Name: "User.Impersonate", Describe: "Quyền đăng nhập thay người dùng (login as) để hỗ trợ", Group: "Auth", Category: "User"},
+	{Name: "User.Merge", Describe: "Quyền phát hiện và gộp tài khoản trùng (cùng email/số điện thoại đã xác thực)", Group: "Auth", Category: "User"},
 
 	// Quản lý tổ chức: Thêm, xem, sửa, xóa
 	{Name: "Organization.Insert", Describe: "Quyền tạo tổ chức", Group: "Auth", Category: "Organization"},
 	{Name: "Organization.Read", Describe: "Quyền xem danh sách tổ chức", Group: "Auth", Category: "Organization"},
 	{Name: "Organization.Update", Describe: "Quyền cập nhật tổ chức", Group: "Auth", Category: "Organization"},
 	{Name: "Organization.Delete", Describe: "Quyền xóa tổ chức", Group: "Auth", Category: "Organization"},
+	{Name: "Organization.Purge", Describe: "Quyền xóa vĩnh viễn tổ chức đã lưu trữ", Group: "Auth", Category: "Organization"},
+	{Name: "Organization.Invite", Describe: "Quyền mời người dùng vào tổ chức (tạo, gửi lại, thu hồi lời mời)", Group: "Auth", Category: "Organization"},
 
 	// Quản lý chia sẻ dữ liệu giữa các tổ chức: Thêm, xem, sửa, xóa
 	{Name: "OrganizationShare.Insert", Describe: "Quyền tạo chia sẻ dữ liệu giữa các tổ chức (CRUD)", Group: "Auth", Category: "OrganizationShare"},
@@ -201,6 +208,27 @@ var InitialPermissions = []models.Permission{
 	// Quyền đặc biệt cho route CreateShare (có validation riêng về quyền với fromOrg)
 	{Name: "OrganizationShare.Create", Describe: "Quyền tạo chia sẻ dữ liệu giữa các tổ chức (route đặc biệt)", Group: "Auth", Category: "OrganizationShare"},
 
+	// Audit trail: lịch sử thay đổi dữ liệu và phân quyền (chỉ đọc)
+	{Name: "AuditLog.Read", Describe: "Quyền xem lịch sử thay đổi dữ liệu (audit log) của tổ chức", Group: "Auth", Category: "AuditLog"},
+
+	// Quản lý service account (principal của máy) và API key
+	{Name: "ServiceAccount.Insert", Describe: "Quyền tạo service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ServiceAccount.Read", Describe: "Quyền xem danh sách service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ServiceAccount.Update", Describe: "Quyền cập nhật, vô hiệu hóa service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ServiceAccount.Delete", Describe: "Quyền xóa service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ApiKey.Insert", Describe: "Quyền tạo API key cho service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ApiKey.Read", Describe: "Quyền xem danh sách API key của service account", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ApiKey.Update", Describe: "Quyền xoay vòng API key", Group: "Auth", Category: "ServiceAccount"},
+	{Name: "ApiKey.Delete", Describe: "Quyền thu hồi API key", Group: "Auth", Category: "ServiceAccount"},
+
+	// Quản lý OAuth client: ứng dụng nhận access/ID token do hệ thống phát hành
+	{Name: "OAuthClient.Insert", Describe: "Quyền đăng ký OAuth client", Group: "Auth", Category: "OAuth"},
+	{Name: "OAuthClient.Read", Describe: "Quyền xem danh sách OAuth client", Group: "Auth", Category: "OAuth"},
+	{Name: "OAuthClient.Update", Describe: "Quyền cập nhật OAuth client, xoay vòng client secret", Group: "Auth", Category: "OAuth"},
+	{Name: "OAuthClient.Delete", Describe: "Quyền xóa OAuth client", Group: "Auth", Category: "OAuth"},
+	{Name: "OAuthSigningKey.Read", Describe: "Quyền xem các khóa ký token OAuth", Group: "Auth", Category: "OAuth"},
+	{Name: "OAuthSigningKey.Update", Describe: "Quyền xoay vòng khóa ký token OAuth", Group: "Auth", Category: "OAuth"},
+
 	// Quản lý vai trò: Thêm, xem, sửa, xóa vai trò
 	{Name: "Role.Insert", Describe: "Quyền tạo vai trò", Group: "Auth", Category: "Role"},
 	{Name: "Role.Read", Describe: "Quyền xem danh sách vai trò", Group: "Auth", Category: "Role"},
@@ -233,6 +261,10 @@ var InitialPermissions = []models.Permission{
 	{Name: "Agent.CheckIn", Describe: "Quyền kiểm tra trạng thái đại lý", Group: "Auth", Category: "Agent"},
 	{Name: "Agent.CheckOut", Describe: "Quyền kiểm tra trạng thái đại lý", Group: "Auth", Category: "Agent"},
 
+	// ==================================== SYSTEM MODULE ===========================================
+	// Giám sát hệ thống: Xem thống kê cache
+	{Name: "System.Read", Describe: "Quyền xem thông tin giám sát hệ thống (thống kê cache)", Group: "System", Category: "System"},
+
 	// ==================================== PANCAKE MODULE ===========================================
 	// Quản lý token truy cập: Thêm, xem, sửa, xóa token
 	{Name: "AccessToken.Insert", Describe: "Quyền tạo token", Group: "Pancake", Category: "AccessToken"},
@@ -277,23 +309,29 @@ var InitialPermissions = []models.Permission{
 	{Name: "FbMessageItem.Update", Describe: "Quyền cập nhật tin nhắn item", Group: "Pancake", Category: "FbMessageItem"},
 	{Name: "FbMessageItem.Delete", Describe: "Quyền xóa tin nhắn item", Group: "Pancake", Category: "FbMessageItem"},
 
-	// Quản lý khách hàng: Thêm, xem, sửa, xóa
+	// Quản lý khách hàng: Thêm, xem, sửa, xóa, xem/sửa thông tin nhạy cảm
 	{Name: "Customer.Insert", Describe: "Quyền tạo khách hàng", Group: "Pancake", Category: "Customer"},
 	{Name: "Customer.Read", Describe: "Quyền xem danh sách khách hàng", Group: "Pancake", Category: "Customer"},
 	{Name: "Customer.Update", Describe: "Quyền cập nhật thông tin khách hàng", Group: "Pancake", Category: "Customer"},
 	{Name: "Customer.Delete", Describe: "Quyền xóa khách hàng", Group: "Pancake", Category: "Customer"},
+	{Name: "Customer.ReadSensitive", Describe: "Quyền xem đầy đủ thông tin nhạy cảm của khách hàng (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "Customer"},
+	{Name: "Customer.UpdateSensitive", Describe: "Quyền sửa thông tin nhạy cảm của khách hàng (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "Customer"},
 
-	// Quản lý khách hàng Facebook: Thêm, xem, sửa, xóa
+	// Quản lý khách hàng Facebook: Thêm, xem, sửa, xóa, xem/sửa thông tin nhạy cảm
 	{Name: "FbCustomer.Insert", Describe: "Quyền tạo khách hàng Facebook", Group: "Pancake", Category: "FbCustomer"},
 	{Name: "FbCustomer.Read", Describe: "Quyền xem danh sách khách hàng Facebook", Group: "Pancake", Category: "FbCustomer"},
 	{Name: "FbCustomer.Update", Describe: "Quyền cập nhật thông tin khách hàng Facebook", Group: "Pancake", Category: "FbCustomer"},
 	{Name: "FbCustomer.Delete", Describe: "Quyền xóa khách hàng Facebook", Group: "Pancake", Category: "FbCustomer"},
+	{Name: "FbCustomer.ReadSensitive", Describe: "Quyền xem đầy đủ thông tin nhạy cảm của khách hàng Facebook (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "FbCustomer"},
+	{Name: "FbCustomer.UpdateSensitive", Describe: "Quyền sửa thông tin nhạy cảm của khách hàng Facebook (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "FbCustomer"},
 
-	// Quản lý khách hàng POS: Thêm, xem, sửa, xóa
+	// Quản lý khách hàng POS: Thêm, xem, sửa, xóa, xem/sửa thông tin nhạy cảm
 	{Name: "PcPosCustomer.Insert", Describe: "Quyền tạo khách hàng POS", Group: "Pancake", Category: "PcPosCustomer"},
 	{Name: "PcPosCustomer.Read", Describe: "Quyền xem danh sách khách hàng POS", Group: "Pancake", Category: "PcPosCustomer"},
 	{Name: "PcPosCustomer.Update", Describe: "Quyền cập nhật thông tin khách hàng POS", Group: "Pancake", Category: "PcPosCustomer"},
 	{Name: "PcPosCustomer.Delete", Describe: "Quyền xóa khách hàng POS", Group: "Pancake", Category: "PcPosCustomer"},
+	{Name: "PcPosCustomer.ReadSensitive", Describe: "Quyền xem đầy đủ thông tin nhạy cảm của khách hàng POS (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "PcPosCustomer"},
+	{Name: "PcPosCustomer.UpdateSensitive", Describe: "Quyền sửa thông tin nhạy cảm của khách hàng POS (SĐT, email, dữ liệu gốc)", Group: "Pancake", Category: "PcPosCustomer"},
 
 	// Quản lý cửa hàng Pancake POS: Thêm, xem, sửa, xóa
 	{Name: "PcPosShop.Insert", Describe: "Quyền tạo cửa hàng từ Pancake POS", Group: "Pancake", Category: "PcPosShop"},
@@ -570,12 +608,12 @@ func (h *InitService) InitRole() error {
 				continue // Bỏ qua nếu insert thất bại
 			}
 		} else {
-			// Nếu đã có, kiểm tra scope - nếu là 0 thì cập nhật thành 1 (để admin có quyền xem tất cả)
+			// Nếu đã có, kiểm tra scope - nếu khác 1 (0 hoặc 2) thì cập nhật thành 1 (để admin có quyền xem tất cả)
 			var existingModelRP models.RolePermission
 			bsonBytes, _ := bson.Marshal(existingRP)
 			err = bson.Unmarshal(bsonBytes, &existingModelRP)
-			if err == nil && existingModelRP.Scope == 0 {
-				// Cập nhật scope từ 0 → 1 (chỉ tổ chức → tổ chức + các tổ chức con)
+			if err == nil && existingModelRP.Scope != models.PermissionScopeSubtree {
+				// Cập nhật scope thành 1 (tổ chức + các tổ chức con)
 				updateData := bson.M{
 					"$set": bson.M{
 						"scope": 1,
@@ -656,12 +694,12 @@ func (h *InitService) CheckPermissionForAdministrator() (err error) {
 				continue
 			}
 		} else {
-			// Nếu đã có, kiểm tra scope - nếu là 0 thì cập nhật thành 1 (để admin có quyền xem tất cả)
+			// Nếu đã có, kiểm tra scope - nếu khác 1 (0 hoặc 2) thì cập nhật thành 1 (để admin có quyền xem tất cả)
 			var existingModelRP models.RolePermission
 			bsonBytes, _ := bson.Marshal(existingRP)
 			err = bson.Unmarshal(bsonBytes, &existingModelRP)
-			if err == nil && existingModelRP.Scope == 0 {
-				// Cập nhật scope từ 0 → 1 (chỉ tổ chức → tổ chức + các tổ chức con)
+			if err == nil && existingModelRP.Scope != models.PermissionScopeSubtree {
+				// Cập nhật scope thành 1 (tổ chức + các tổ chức con)
 				updateData := bson.M{
 					"$set": bson.M{
 						"scope": 1,
@@ -751,7 +789,8 @@ func (h *InitService) InitAdminUser(firebaseUID string) error {
 	}
 
 	// Kiểm tra user đã tồn tại chưa
-	filter := bson.M{"firebaseUid": firebaseUID}
+	identityKey := identity.IdentityKey(identity.ProviderFirebase, firebaseUID)
+	filter := bson.M{"$or": []bson.M{{"identityKeys": identityKey}, {"firebaseUid": firebaseUID}}}
 	existingUser, err := h.userService.FindOne(context.TODO(), filter, nil)
 	if err != nil && err != common.ErrNotFound {
 		return fmt.Errorf("failed to check existing admin user: %v", err)
@@ -770,7 +809,14 @@ func (h *InitService) InitAdminUser(firebaseUID string) error {
 		// Tạo user mới
 		currentTime := time.Now().Unix()
 		newUser := &models.User{
-			FirebaseUID:   firebaseUID,
+			FirebaseUID: firebaseUID,
+			Identities: []models.UserIdentity{{
+				Provider: identity.ProviderFirebase,
+				Subject:  firebaseUID,
+				Email:    firebaseUser.Email,
+				LinkedAt: time.Now().UnixMilli(),
+			}},
+			IdentityKeys:  []string{identityKey},
 			Email:         firebaseUser.Email,
 			EmailVerified: firebaseUser.EmailVerified,
 			Phone:         firebaseUser.PhoneNumber,
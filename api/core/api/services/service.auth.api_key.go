@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAPIKeyInvalid lỗi chung khi API key không hợp lệ (không tiết lộ lý do cụ thể cho client)
+var ErrAPIKeyInvalid = common.NewError(common.ErrCodeAuthCredentials, "API key không hợp lệ hoặc đã hết hiệu lực", common.StatusUnauthorized, nil)
+
+// APIKeyService là service quản lý API key của service account
+type APIKeyService struct {
+	*BaseServiceMongoImpl[models.APIKey]
+	serviceAccountService *ServiceAccountService
+	permissionService     *PermissionService
+}
+
+// NewAPIKeyService tạo mới APIKeyService
+func NewAPIKeyService() (*APIKeyService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.ApiKeys)
+	if !exist {
+		return nil, fmt.Errorf("failed to get api_keys collection: %v", common.ErrNotFound)
+	}
+
+	serviceAccountService, err := NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+
+	permissionService, err := NewPermissionService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create permission service: %v", err)
+	}
+
+	return &APIKeyService{
+		BaseServiceMongoImpl:  NewBaseServiceMongo[models.APIKey](collection),
+		serviceAccountService: serviceAccountService,
+		permissionService:     permissionService,
+	}, nil
+}
+
+// generateAPIKey sinh key mới, trả về (key đầy đủ, prefix, hash)
+// Định dạng: mck_<12 ký tự hex>_<43 ký tự base64url>
+func generateAPIKey() (string, string, string, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	rawKey := models.APIKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return rawKey, prefix, hashAPIKey(rawKey), nil
+}
+
+// hashAPIKey trả về SHA-256 (hex) của key
+// Key có entropy cao (256 bit) nên không cần hàm băm chậm như mật khẩu
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAPIKeyPrefix tách prefix từ key đầy đủ
+func parseAPIKeyPrefix(rawKey string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawKey, models.APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// validateAPIKeyOptions kiểm tra scopes (phải là permission tồn tại), danh sách IP và thời điểm hết hạn
+func (s *APIKeyService) validateAPIKeyOptions(ctx context.Context, scopes []string, allowedIPs []string, expiresAt *int64) error {
+	if len(scopes) > 0 {
+		names, err := s.permissionService.Distinct(ctx, "name", bson.M{"name": bson.M{"$in": scopes}})
+		if err != nil && err != common.ErrNotFound {
+			return err
+		}
+		existing := make(map[string]bool, len(names))
+		for _, name := range names {
+			if str, ok := name.(string); ok {
+				existing[str] = true
+			}
+		}
+		for _, scope := range scopes {
+			if !existing[scope] {
+				return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Scope '%s' không phải permission hợp lệ", scope), common.StatusBadRequest, nil)
+			}
+		}
+	}
+
+	for _, ip := range allowedIPs {
+		if net.ParseIP(ip) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("allowedIps: '%s' không phải IP hoặc CIDR hợp lệ", ip), common.StatusBadRequest, nil)
+		}
+	}
+
+	if expiresAt != nil && *expiresAt <= utility.CurrentTimeInMilli() {
+		return common.NewError(common.ErrCodeValidationInput, "expiresAt phải là thời điểm trong tương lai", common.StatusBadRequest, nil)
+	}
+	return nil
+}
+
+// CreateKey tạo API key mới cho service account
+// Key đầy đủ chỉ được trả về một lần trong RawKey
+func (s *APIKeyService) CreateKey(ctx context.Context, account models.ServiceAccount, input dto.APIKeyCreateInput, createdBy primitive.ObjectID) (*models.APIKey, error) {
+	if err := s.validateAPIKeyOptions(ctx, input.Scopes, input.AllowedIPs, input.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return s.insertKey(ctx, models.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             input.Name,
+		Scopes:           input.Scopes,
+		AllowedIPs:       input.AllowedIPs,
+		ExpiresAt:        input.ExpiresAt,
+		CreatedBy:        createdBy,
+	})
+}
+
+// insertKey sinh key và lưu vào database
+func (s *APIKeyService) insertKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	rawKey, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể sinh API key", common.StatusInternalServerError, err)
+	}
+	key.Prefix = prefix
+	key.KeyHash = hash
+
+	created, err := s.BaseServiceMongoImpl.InsertOne(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	created.RawKey = rawKey
+	return &created, nil
+}
+
+// isAPIKeyActive kiểm tra key chưa bị thu hồi và chưa hết hạn
+func isAPIKeyActive(key models.APIKey, now int64) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || *key.ExpiresAt > now)
+}
+
+// RotateKey xoay vòng API key: tạo key mới cùng cấu hình (tên, scopes, IP, hạn) và vô hiệu hóa key cũ
+// gracePeriod > 0: key cũ còn hiệu lực thêm gracePeriod (để client chuyển sang key mới), = 0: thu hồi ngay
+func (s *APIKeyService) RotateKey(ctx context.Context, keyID primitive.ObjectID, gracePeriod time.Duration, rotatedBy primitive.ObjectID) (*models.APIKey, error) {
+	oldKey, err := s.FindOneById(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	now := utility.CurrentTimeInMilli()
+	if !isAPIKeyActive(oldKey, now) {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "API key đã bị thu hồi hoặc hết hạn, không thể xoay vòng", common.StatusConflict, nil)
+	}
+
+	newKey, err := s.insertKey(ctx, models.APIKey{
+		ServiceAccountID: oldKey.ServiceAccountID,
+		Name:             oldKey.Name,
+		Scopes:           oldKey.Scopes,
+		AllowedIPs:       oldKey.AllowedIPs,
+		ExpiresAt:        oldKey.ExpiresAt,
+		RotatedFromID:    &oldKey.ID,
+		CreatedBy:        rotatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var update bson.M
+	if gracePeriod > 0 {
+		graceUntil := now + gracePeriod.Milliseconds()
+		if oldKey.ExpiresAt != nil && *oldKey.ExpiresAt < graceUntil {
+			graceUntil = *oldKey.ExpiresAt
+		}
+		update = bson.M{"$set": bson.M{"expiresAt": graceUntil}}
+	} else {
+		update = bson.M{"$set": bson.M{"revokedAt": now, "revokedBy": rotatedBy}}
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": oldKey.ID}, update); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	return newKey, nil
+}
+
+// RevokeKey thu hồi API key (có hiệu lực ngay ở request tiếp theo)
+func (s *APIKeyService) RevokeKey(ctx context.Context, keyID, revokedBy primitive.ObjectID) (*models.APIKey, error) {
+	key, err := s.FindOneById(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "API key đã bị thu hồi", common.StatusConflict, nil)
+	}
+
+	now := utility.CurrentTimeInMilli()
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": key.ID}, bson.M{"$set": bson.M{"revokedAt": now, "revokedBy": revokedBy}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	key.RevokedAt = &now
+	key.RevokedBy = &revokedBy
+	return &key, nil
+}
+
+// FindByServiceAccount trả về các API key của service account (mới nhất trước)
+func (s *APIKeyService) FindByServiceAccount(ctx context.Context, accountID primitive.ObjectID) ([]models.APIKey, error) {
+	keys, err := s.Find(ctx, bson.M{"serviceAccountId": accountID}, mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	return keys, nil
+}
+
+// Authenticate xác thực API key gửi từ client tại địa chỉ ip
+// Trả về key và service account sở hữu nếu key hợp lệ, còn hiệu lực, IP được phép và service account đang hoạt động
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string, ip string) (*models.APIKey, *models.ServiceAccount, error) {
+	prefix, ok := parseAPIKeyPrefix(rawKey)
+	if !ok {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.FindOne(ctx, bson.M{"prefix": prefix}, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if !isAPIKeyActive(key, utility.CurrentTimeInMilli()) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if !IsIPAllowed(key.AllowedIPs, ip) {
+		return nil, nil, common.NewError(common.ErrCodeAuthCredentials, "API key không được phép sử dụng từ địa chỉ IP này", common.StatusForbidden, nil)
+	}
+
+	account, err := s.serviceAccountService.FindOneById(ctx, key.ServiceAccountID)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	if account.IsDisabled {
+		return nil, nil, common.NewError(common.ErrCodeAuthCredentials, "Service account đã bị vô hiệu hóa", common.StatusForbidden, nil)
+	}
+
+	return &key, &account, nil
+}
+
+// TouchLastUsed cập nhật thời điểm và IP sử dụng gần nhất của key
+func (s *APIKeyService) TouchLastUsed(ctx context.Context, keyID primitive.ObjectID, ip string) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": keyID}, bson.M{"$set": bson.M{
+		"lastUsedAt": utility.CurrentTimeInMilli(),
+		"lastUsedIp": ip,
+	}})
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// IsIPAllowed kiểm tra ip có nằm trong danh sách cho phép (IP đơn hoặc CIDR)
+// Danh sách rỗng = cho phép mọi IP
+func IsIPAllowed(allowedIPs []string, ip string) bool {
+	if len(allowedIPs) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, allowed := range allowedIPs {
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil {
+			if allowedIP.Equal(parsed) {
+				return true
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyAllowsPermission kiểm tra permission có nằm trong scopes của key
+// Scopes rỗng = key được dùng mọi permission của service account
+func APIKeyAllowsPermission(key *models.APIKey, permissionName string) bool {
+	if key == nil || len(key.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range key.Scopes {
+		if scope == permissionName {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyPermissions giới hạn permissions theo scopes của key (key nil hoặc scopes rỗng = giữ nguyên)
+func APIKeyPermissions(key *models.APIKey, permissions EffectivePermissions) EffectivePermissions {
+	if key == nil || len(key.Scopes) == 0 {
+		return permissions
+	}
+	result := make(EffectivePermissions, len(key.Scopes))
+	for name, permission := range permissions {
+		if APIKeyAllowsPermission(key, name) {
+			result[name] = permission
+		}
+	}
+	return result
+}
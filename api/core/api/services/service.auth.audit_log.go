@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Các hành động ghi vào audit trail
+const (
+	AuditActionInsert = "insert"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+const (
+	auditActorContextKey  contextKey = "audit_actor"
+	auditBufferContextKey contextKey = "audit_buffer"
+)
+
+// AuditActor là người thực hiện request, được AuthMiddleware lưu vào context của request
+// để mọi thao tác ghi trong request được ghi audit kèm người thực hiện
+type AuditActor struct {
+	UserID         primitive.ObjectID // User (hoặc service account) thực hiện
+	ImpersonatorID primitive.ObjectID // Quản trị viên đang đăng nhập thay UserID (nếu có)
+	ServiceAccount bool               // true nếu xác thực bằng API key
+	RoleID         primitive.ObjectID // Role đang làm việc (X-Active-Role-ID)
+	RequestID      string             // X-Request-ID
+	IP             string             // IP của request
+}
+
+// WithAuditActor lưu người thực hiện vào context
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorContextKey, actor)
+}
+
+// GetAuditActorFromContext lấy người thực hiện từ context
+func GetAuditActorFromContext(ctx context.Context) (AuditActor, bool) {
+	actor, ok := ctx.Value(auditActorContextKey).(AuditActor)
+	return actor, ok
+}
+
+// auditBuffer giữ các bản ghi audit phát sinh trong transaction để ghi sau khi transaction commit
+// Ghi audit ngay trong transaction thì lỗi ghi audit làm abort cả thao tác chính, và bản ghi của transaction bị hủy vẫn có thể còn lại
+type auditBuffer struct {
+	entries []models.AuthLog
+}
+
+// withAuditBuffer trả về context giữ bản ghi audit thay vì ghi ngay, dùng làm context của session.WithTransaction
+func withAuditBuffer(ctx context.Context) (context.Context, *auditBuffer) {
+	buffer := &auditBuffer{}
+	return context.WithValue(ctx, auditBufferContextKey, buffer), buffer
+}
+
+// reset bỏ các bản ghi của lần chạy trước (WithTransaction chạy lại callback khi gặp lỗi tạm thời)
+func (b *auditBuffer) reset() {
+	b.entries = nil
+}
+
+// flush ghi các bản ghi đã giữ, gọi sau khi transaction commit với context không thuộc transaction
+func (b *auditBuffer) flush(ctx context.Context) {
+	for _, entry := range b.entries {
+		writeAuditEntry(ctx, entry)
+	}
+	b.entries = nil
+}
+
+// AuditLogService truy vấn audit trail (auth_logs) - chỉ đọc, bản ghi được tạo tự động khi ghi dữ liệu
+type AuditLogService struct {
+	*BaseServiceMongoImpl[models.AuthLog]
+}
+
+// NewAuditLogService tạo mới AuditLogService
+func NewAuditLogService() (*AuditLogService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.AuthLogs)
+	if !exist {
+		return nil, fmt.Errorf("failed to get auth_logs collection: %v", common.ErrNotFound)
+	}
+
+	return &AuditLogService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.AuthLog](collection),
+	}, nil
+}
+
+// Query tìm bản ghi audit của các tổ chức được phép, mới nhất trước
+func (s *AuditLogService) Query(ctx context.Context, orgIDs []primitive.ObjectID, input dto.AuditLogFilter, page, limit int64) (*models.PaginateResult[models.AuthLog], error) {
+	filter := bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}}
+	if input.Collection != "" {
+		filter["collection"] = input.Collection
+	}
+	if !input.DocumentID.IsZero() {
+		filter["documentId"] = input.DocumentID
+	}
+	if !input.ActorID.IsZero() {
+		filter["userId"] = input.ActorID
+	}
+	if input.Action != "" {
+		filter["action"] = input.Action
+	}
+	if input.From > 0 || input.To > 0 {
+		createdAt := bson.M{}
+		if input.From > 0 {
+			createdAt["$gte"] = input.From
+		}
+		if input.To > 0 {
+			createdAt["$lte"] = input.To
+		}
+		filter["createdAt"] = createdAt
+	}
+
+	opts := mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	return s.FindWithPagination(ctx, filter, page, limit, opts)
+}
+
+// auditEnabled kiểm tra collection có cần ghi audit không (AUDIT_LOG_ENABLED, AUDIT_LOG_EXCLUDE_COLLECTIONS)
+func auditEnabled(collectionName string) bool {
+	config := global.MongoDB_ServerConfig
+	if config == nil || !config.AuditLogEnabled || collectionName == global.MongoDB_ColNames.AuthLogs {
+		return false
+	}
+	for _, name := range strings.Split(config.AuditLogExcludeCollections, ",") {
+		if strings.TrimSpace(name) == collectionName {
+			return false
+		}
+	}
+	return true
+}
+
+// recordAudit ghi audit cho một document: insert (oldDoc = nil), update, delete (newDoc = nil)
+// Cập nhật không thay đổi field nào thì không ghi
+func recordAudit(ctx context.Context, collectionName string, action string, oldDoc interface{}, newDoc interface{}) {
+	if !auditEnabled(collectionName) {
+		return
+	}
+
+	oldMap := auditDataMap(oldDoc)
+	newMap := auditDataMap(newDoc)
+	entry := models.AuthLog{
+		Collection: collectionName,
+		Action:     action,
+	}
+	switch {
+	case oldMap != nil && newMap != nil:
+		entry.Changes = auditChanges(oldMap, newMap)
+		if len(entry.Changes) == 0 {
+			return
+		}
+	case newMap != nil:
+		entry.NewData = auditJSON(newMap)
+	case oldMap != nil:
+		entry.OldData = auditJSON(oldMap)
+	default:
+		return
+	}
+
+	doc := newMap
+	if doc == nil {
+		doc = oldMap
+	}
+	entry.DocumentID = auditObjectID(doc["id"])
+	entry.OwnerOrganizationID = auditObjectID(doc["ownerOrganizationId"])
+	if entry.OwnerOrganizationID.IsZero() && collectionName == global.MongoDB_ColNames.Organizations {
+		entry.OwnerOrganizationID = entry.DocumentID
+	}
+	recordAuditEntry(ctx, entry)
+}
+
+// recordAuditEntry bổ sung người thực hiện, thời gian và ghi bản ghi audit
+// Trong transaction (context của withAuditBuffer) bản ghi được giữ lại đến khi commit, không ghi bằng session của transaction
+func recordAuditEntry(ctx context.Context, entry models.AuthLog) {
+	if !auditEnabled(entry.Collection) {
+		return
+	}
+	if buffer, ok := ctx.Value(auditBufferContextKey).(*auditBuffer); ok {
+		buffer.entries = append(buffer.entries, entry)
+		return
+	}
+	writeAuditEntry(ctx, entry)
+}
+
+// writeAuditEntry ghi bản ghi audit, lỗi ghi audit chỉ được log, không làm hỏng thao tác chính
+func writeAuditEntry(ctx context.Context, entry models.AuthLog) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.AuthLogs)
+	if !exist {
+		return
+	}
+
+	if actor, ok := GetAuditActorFromContext(ctx); ok {
+		entry.UserID = actor.UserID
+		entry.ImpersonatorID = actor.ImpersonatorID
+		entry.ServiceAccount = actor.ServiceAccount
+		entry.RoleID = actor.RoleID
+		entry.RequestID = actor.RequestID
+		entry.IP = actor.IP
+	}
+	// Document không thuộc tổ chức nào (user, permission...): ghi theo tổ chức của role đang làm việc
+	if entry.OwnerOrganizationID.IsZero() && !entry.RoleID.IsZero() {
+		if roles, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Roles); exist {
+			var role models.Role
+			if err := roles.FindOne(ctx, bson.M{"_id": entry.RoleID}, mongoopts.FindOne().SetProjection(bson.M{"ownerOrganizationId": 1})).Decode(&role); err == nil {
+				entry.OwnerOrganizationID = role.OwnerOrganizationID
+			}
+		}
+	}
+
+	now := time.Now()
+	entry.CreatedAt = now.UnixMilli()
+	entry.UpdatedAt = entry.CreatedAt
+	if days := global.MongoDB_ServerConfig.AuditLogRetentionDays; days > 0 {
+		entry.ExpireAt = now.AddDate(0, 0, days)
+	}
+
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"collection":  entry.Collection,
+			"action":      entry.Action,
+			"document_id": entry.DocumentID.Hex(),
+		}).WithError(err).Warn("Failed to write audit log")
+	}
+}
+
+// auditMaskedFields các field chứa giá trị bí mật vẫn được trả qua API (vd: value của access token)
+// Audit chỉ ghi nhận có thay đổi, không lưu giá trị
+// Các field có field policy hidden/mask (struct tag `fieldPolicy`) cũng được che như vậy (auditProtectedFields)
+var auditMaskedFields = map[string]bool{
+	"value":        true,
+	"token":        true,
+	"accessToken":  true,
+	"refreshToken": true,
+	"password":     true,
+	"secret":       true,
+	"clientSecret": true,
+}
+
+// auditProtectedFieldCache cache các field được bảo vệ theo kiểu model (reflect.Type → map[string]bool)
+var auditProtectedFieldCache sync.Map
+
+// auditProtectedFields các field (tên json) có field policy hidden hoặc mask
+// /audit-log chỉ yêu cầu AuditLog.Read, không theo permission của từng field, nên không được lưu giá trị thật của các field này
+func auditProtectedFields(doc interface{}) map[string]bool {
+	t := reflect.TypeOf(doc)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := auditProtectedFieldCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		for _, rule := range strings.Split(field.Tag.Get("fieldPolicy"), ";") {
+			// Quy tắc dạng action[:masker]=permission
+			action := strings.TrimSpace(strings.SplitN(strings.SplitN(rule, "=", 2)[0], ":", 2)[0])
+			if action != "hidden" && action != "mask" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = true
+		}
+	}
+	auditProtectedFieldCache.Store(t, fields)
+	return fields
+}
+
+// auditMaskValue thay giá trị bằng dấu vết HMAC (khóa JWT_SECRET) để vẫn nhận biết được thay đổi
+// Dùng HMAC thay vì hash thường: giá trị ít biến thể như số điện thoại không dò ngược được từ dấu vết
+func auditMaskValue(value interface{}) string {
+	mac := hmac.New(sha256.New, []byte(global.MongoDB_ServerConfig.JwtSecret))
+	mac.Write([]byte(fmt.Sprint(value)))
+	return fmt.Sprintf("***%x", mac.Sum(nil))[:11]
+}
+
+// auditDataMap chuyển document thành map theo JSON (bỏ các field json:"-" như token, hash, secret;
+// che auditMaskedFields và các field có field policy hidden/mask)
+func auditDataMap(doc interface{}) map[string]interface{} {
+	if doc == nil {
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	protected := auditProtectedFields(doc)
+	for key, value := range result {
+		if (auditMaskedFields[key] || protected[key]) && value != nil && value != "" {
+			result[key] = auditMaskValue(value)
+		}
+	}
+	return result
+}
+
+// auditChanges so sánh 2 phiên bản document theo field (utility.MyMapDiff)
+// MyMapDiff chỉ so sánh field có ở cả 2 phiên bản, field được thêm/bỏ (omitempty) được bổ sung riêng
+func auditChanges(oldMap, newMap map[string]interface{}) []interface{} {
+	delete(oldMap, "updatedAt")
+	delete(newMap, "updatedAt")
+
+	changes := utility.MyMapDiff(newMap, oldMap)
+	for key, oldVal := range oldMap {
+		if _, ok := newMap[key]; !ok {
+			changes = append(changes, map[string]interface{}{key: []interface{}{oldVal, nil}})
+		}
+	}
+	for key, newVal := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			changes = append(changes, map[string]interface{}{key: []interface{}{nil, newVal}})
+		}
+	}
+	return changes
+}
+
+// auditJSON chuyển map thành chuỗi JSON để lưu OldData/NewData
+func auditJSON(data map[string]interface{}) string {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	return string(bytes)
+}
+
+// auditObjectID đọc ObjectID dạng hex từ giá trị JSON
+func auditObjectID(value interface{}) primitive.ObjectID {
+	hex, ok := value.(string)
+	if !ok {
+		return primitive.NilObjectID
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return id
+}
+
+// auditUpdated ghi audit cập nhật cho các document: đọc lại phiên bản mới theo ID và so sánh với phiên bản cũ
+func (s *BaseServiceMongoImpl[T]) auditUpdated(ctx context.Context, existingDocs ...T) {
+	name := s.collection.Name()
+	if !auditEnabled(name) || len(existingDocs) == 0 {
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(existingDocs))
+	before := make(map[primitive.ObjectID]T, len(existingDocs))
+	for _, doc := range existingDocs {
+		if id := auditObjectID(auditDataMap(doc)["id"]); !id.IsZero() {
+			ids = append(ids, id)
+			before[id] = doc
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return
+	}
+	var updatedDocs []T
+	if err := cursor.All(ctx, &updatedDocs); err != nil {
+		return
+	}
+	for _, updated := range updatedDocs {
+		id := auditObjectID(auditDataMap(updated)["id"])
+		if existing, ok := before[id]; ok {
+			recordAudit(ctx, name, AuditActionUpdate, existing, updated)
+		}
+	}
+}
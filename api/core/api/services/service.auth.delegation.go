@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Lý do một quyền bị từ chối khi phân quyền (chống leo thang đặc quyền)
+const (
+	DelegationReasonPermissionNotHeld      = "permission_not_held"       // Người thực hiện không có permission này
+	DelegationReasonOrganizationOutOfScope = "organization_out_of_scope" // Tổ chức của role nằm ngoài phạm vi permission của người thực hiện
+	DelegationReasonScopeExceeded          = "scope_exceeded"            // Cấp scope rộng hơn scope người thực hiện có tại tổ chức này (vd: scope 1 khi chỉ có scope 0, scope 0 khi chỉ có scope 2)
+	DelegationReasonConditionsExceeded     = "conditions_exceeded"       // Người thực hiện chỉ có permission kèm điều kiện, cấp không điều kiện hoặc điều kiện khác
+)
+
+// DelegationViolation là một quyền mà người thực hiện không được phép cấp
+type DelegationViolation struct {
+	RoleID         primitive.ObjectID     `json:"roleId"`                   // Role được cấp quyền
+	RoleName       string                 `json:"roleName,omitempty"`       // Tên role
+	OrganizationID primitive.ObjectID     `json:"organizationId"`           // Tổ chức sở hữu role
+	PermissionID   primitive.ObjectID     `json:"permissionId"`             // Permission bị từ chối
+	PermissionName string                 `json:"permissionName,omitempty"` // Tên permission
+	Scope          byte                   `json:"scope"`                    // Scope yêu cầu cấp
+	Conditions     map[string]interface{} `json:"conditions,omitempty"`     // Điều kiện yêu cầu cấp
+	Reason         string                 `json:"reason"`                   // permission_not_held, organization_out_of_scope, scope_exceeded, conditions_exceeded
+}
+
+const delegationBypassContextKey contextKey = "delegation_bypass"
+
+// withoutDelegationCaller trả về context không có người thực hiện đối với quy tắc phân quyền (audit vẫn ghi người thực hiện)
+// Chỉ dùng khi quyền đã được kiểm tra với người cấp ở bước trước, vd: gán role của lời mời khi người nhận chấp nhận
+// (người nhận là người thực hiện request nhưng role do người mời cấp, đã kiểm tra lúc tạo lời mời)
+func withoutDelegationCaller(ctx context.Context) context.Context {
+	return context.WithValue(ctx, delegationBypassContextKey, true)
+}
+
+// delegationCaller trả về người thực hiện request và role đang làm việc (nếu có)
+// ok = false khi không có người thực hiện (init, worker...) hoặc context đã bỏ người thực hiện (withoutDelegationCaller)
+// → không áp dụng quy tắc phân quyền
+func delegationCaller(ctx context.Context) (userID primitive.ObjectID, activeRoleID *primitive.ObjectID, ok bool) {
+	if bypass, _ := ctx.Value(delegationBypassContextKey).(bool); bypass {
+		return primitive.NilObjectID, nil, false
+	}
+	if actor, exist := GetAuditActorFromContext(ctx); exist && !actor.UserID.IsZero() {
+		if !actor.RoleID.IsZero() {
+			roleID := actor.RoleID
+			activeRoleID = &roleID
+		}
+		return actor.UserID, activeRoleID, true
+	}
+	if userID, exist := GetUserIDFromContext(ctx); exist && !userID.IsZero() {
+		return userID, nil, true
+	}
+	return primitive.NilObjectID, nil, false
+}
+
+// validateRolePermissionDelegation kiểm tra người thực hiện được phép cấp các role permission:
+// chỉ cấp permission mình có, scope không rộng hơn và điều kiện không lỏng hơn của mình, cho role thuộc tổ chức trong phạm vi của mình.
+// Quyền role đã có với scope bằng hoặc rộng hơn và điều kiện bao trùm được bỏ qua (gửi lại danh sách quyền cũ không bị chặn).
+// Quyền cấp cho role cũng được cấp cho các role kế thừa từ role đó (role con, role khởi tạo từ template) nên được kiểm tra cả tại tổ chức của các role này.
+// pendingRoles: role chưa lưu hoặc đang thay đổi (dùng thay cho dữ liệu trong database khi xác định tổ chức của role)
+func validateRolePermissionDelegation(ctx context.Context, grants []models.RolePermission, pendingRoles ...models.Role) error {
+	if _, _, ok := delegationCaller(ctx); !ok || len(grants) == 0 {
+		return nil
+	}
+
+	rolePermissions, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RolePermissions)
+	if !exist {
+		return common.ErrNotFound
+	}
+	conditions := make([]bson.M, 0, len(grants))
+	for _, grant := range grants {
+		conditions = append(conditions, bson.M{"roleId": grant.RoleID, "permissionId": grant.PermissionID})
+	}
+	var existing []models.RolePermission
+	if err := findDelegationDocs(ctx, rolePermissions, bson.M{"$or": conditions}, &existing); err != nil {
+		return err
+	}
+	held := make(map[[2]primitive.ObjectID][]models.RolePermission, len(existing))
+	for _, rolePermission := range existing {
+		key := [2]primitive.ObjectID{rolePermission.RoleID, rolePermission.PermissionID}
+		held[key] = append(held[key], rolePermission)
+	}
+
+	newGrants := make([]models.RolePermission, 0, len(grants))
+	for _, grant := range grants {
+		alreadyHeld := false
+		for _, rolePermission := range held[[2]primitive.ObjectID{grant.RoleID, grant.PermissionID}] {
+			if scopeRank(rolePermission.Scope) >= scopeRank(grant.Scope) && ConditionsWithin(rolePermission.Conditions, grant.Conditions) {
+				alreadyHeld = true
+				break
+			}
+		}
+		if !alreadyHeld {
+			newGrants = append(newGrants, grant)
+		}
+	}
+
+	var inheritedGrants []models.RolePermission
+	dependents := make(map[primitive.ObjectID][]models.Role)
+	for _, grant := range newGrants {
+		if grant.RoleID.IsZero() {
+			continue
+		}
+		roles, ok := dependents[grant.RoleID]
+		if !ok {
+			var err error
+			if roles, _, err = inheritingRoles(ctx, []primitive.ObjectID{grant.RoleID}); err != nil {
+				return err
+			}
+			dependents[grant.RoleID] = roles
+		}
+		for _, role := range roles {
+			inheritedGrant := grant
+			inheritedGrant.RoleID = role.ID
+			inheritedGrants = append(inheritedGrants, inheritedGrant)
+		}
+	}
+	return checkDelegation(ctx, append(newGrants, inheritedGrants...), pendingRoles...)
+}
+
+// validateUserRoleDelegation kiểm tra người thực hiện được phép gán các role cho user:
+// người thực hiện phải cấp được mọi permission của role (theo quy tắc của validateRolePermissionDelegation).
+// Role user đã có vĩnh viễn được bỏ qua.
+func validateUserRoleDelegation(ctx context.Context, assignments []models.UserRole) error {
+	if _, _, ok := delegationCaller(ctx); !ok || len(assignments) == 0 {
+		return nil
+	}
+
+	userRoles, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.UserRoles)
+	if !exist {
+		return common.ErrNotFound
+	}
+	conditions := make([]bson.M, 0, len(assignments))
+	for _, assignment := range assignments {
+		conditions = append(conditions, bson.M{"userId": assignment.UserID, "roleId": assignment.RoleID})
+	}
+	var existing []models.UserRole
+	filter := bson.M{"$or": conditions, "validUntil": permanentUserRoleFilter["validUntil"]}
+	if err := findDelegationDocs(ctx, userRoles, filter, &existing); err != nil {
+		return err
+	}
+	assigned := make(map[[2]primitive.ObjectID]bool, len(existing))
+	for _, userRole := range existing {
+		assigned[[2]primitive.ObjectID{userRole.UserID, userRole.RoleID}] = true
+	}
+
+	roleIDs := make([]primitive.ObjectID, 0, len(assignments))
+	for _, assignment := range assignments {
+		if !assigned[[2]primitive.ObjectID{assignment.UserID, assignment.RoleID}] {
+			roleIDs = append(roleIDs, assignment.RoleID)
+		}
+	}
+	return validateRoleDelegation(ctx, roleIDs)
+}
+
+// validateRoleDelegation kiểm tra người thực hiện cấp được toàn bộ permission của các role, kể cả quyền kế thừa từ role cha / template
+// (gán role = cấp mọi permission của role)
+func validateRoleDelegation(ctx context.Context, roleIDs []primitive.ObjectID) error {
+	if _, _, ok := delegationCaller(ctx); !ok || len(roleIDs) == 0 {
+		return nil
+	}
+
+	roles, err := loadRoles(ctx, roleIDs)
+	if err != nil {
+		return err
+	}
+	roleList := make([]models.Role, 0, len(roles))
+	for _, role := range roles {
+		roleList = append(roleList, role)
+	}
+	rolePermissions, err := roleInheritedPermissions(ctx, roleList, true)
+	if err != nil {
+		return err
+	}
+	grants := make([]models.RolePermission, 0, len(rolePermissions))
+	for _, rolePermission := range rolePermissions {
+		grants = append(grants, rolePermission.RolePermission)
+	}
+	return checkDelegation(ctx, grants)
+}
+
+// checkDelegation so sánh từng quyền cần cấp với permission hiệu lực của người thực hiện
+// Một grant của người thực hiện (tổ chức G, scope sg) cho phép cấp scope s cho role thuộc tổ chức O khi:
+//   - O = G và sg >= s, hoặc
+//   - sg = 1 và O là tổ chức con của G (cây con của O nằm trong cây con của G)
+//
+// và grant của người thực hiện không có điều kiện, hoặc có điều kiện giống hệt điều kiện cần cấp
+// pendingRoles: role chưa lưu hoặc đang thay đổi, dùng thay cho dữ liệu trong database
+func checkDelegation(ctx context.Context, grants []models.RolePermission, pendingRoles ...models.Role) error {
+	callerID, activeRoleID, ok := delegationCaller(ctx)
+	if !ok || len(grants) == 0 {
+		return nil
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return err
+	}
+	callerPermissions, err := userRoleService.GetEffectivePermissions(ctx, callerID, activeRoleID, "")
+	if err != nil {
+		return err
+	}
+
+	roles, organizations, permissionNames, err := loadDelegationTargets(ctx, grants, pendingRoles)
+	if err != nil {
+		return err
+	}
+
+	var violations []DelegationViolation
+	for _, grant := range grants {
+		role := roles[grant.RoleID]
+		violation := DelegationViolation{
+			RoleID:         grant.RoleID,
+			RoleName:       role.Name,
+			OrganizationID: role.OwnerOrganizationID,
+			PermissionID:   grant.PermissionID,
+			PermissionName: permissionNames[grant.PermissionID],
+			Scope:          grant.Scope,
+			Conditions:     grant.Conditions,
+		}
+
+		callerPermission := callerPermissions[violation.PermissionName]
+		if violation.PermissionName == "" || callerPermission == nil {
+			violation.Reason = DelegationReasonPermissionNotHeld
+			violations = append(violations, violation)
+			continue
+		}
+
+		inScope, wideEnough, allowed := false, false, false
+		path := organizations[role.OwnerOrganizationID]
+		for _, callerGrant := range callerPermission.Grants {
+			covers := false
+			if callerGrant.OrganizationID == role.OwnerOrganizationID {
+				inScope = true
+				covers = scopeRank(callerGrant.Scope) >= scopeRank(grant.Scope)
+			} else if callerGrant.Scope == models.PermissionScopeSubtree && callerGrant.OrganizationPath != "" && strings.HasPrefix(path, callerGrant.OrganizationPath+"/") {
+				inScope, covers = true, true
+			}
+			if covers {
+				wideEnough = true
+				if ConditionsWithin(callerGrant.Conditions, grant.Conditions) {
+					allowed = true
+				}
+			}
+		}
+		switch {
+		case !inScope:
+			violation.Reason = DelegationReasonOrganizationOutOfScope
+		case !wideEnough:
+			violation.Reason = DelegationReasonScopeExceeded
+		case !allowed:
+			violation.Reason = DelegationReasonConditionsExceeded
+		default:
+			continue
+		}
+		violations = append(violations, violation)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return common.NewError(
+		common.ErrCodeAuthRole,
+		"Không thể cấp quyền hoặc phạm vi vượt quá quyền của bạn",
+		common.StatusForbidden,
+		map[string]interface{}{
+			"errorCode":  "DELEGATION_DENIED",
+			"violations": violations,
+		},
+	)
+}
+
+// loadDelegationTargets lấy role (tên, tổ chức), path tổ chức và tên permission của các quyền cần cấp
+// Role trong pendingRoles được dùng thay cho dữ liệu trong database
+func loadDelegationTargets(ctx context.Context, grants []models.RolePermission, pendingRoles []models.Role) (map[primitive.ObjectID]models.Role, map[primitive.ObjectID]string, map[primitive.ObjectID]string, error) {
+	roleIDs := make([]primitive.ObjectID, 0, len(grants))
+	permissionIDs := make([]primitive.ObjectID, 0, len(grants))
+	for _, grant := range grants {
+		roleIDs = append(roleIDs, grant.RoleID)
+		permissionIDs = append(permissionIDs, grant.PermissionID)
+	}
+
+	roleCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Roles)
+	if !exist {
+		return nil, nil, nil, common.ErrNotFound
+	}
+	var roleList []models.Role
+	if err := findDelegationDocs(ctx, roleCollection, bson.M{"_id": bson.M{"$in": roleIDs}}, &roleList); err != nil {
+		return nil, nil, nil, err
+	}
+	roles := make(map[primitive.ObjectID]models.Role, len(roleList)+len(pendingRoles))
+	orgIDs := make([]primitive.ObjectID, 0, len(roleList)+len(pendingRoles))
+	for _, role := range append(roleList, pendingRoles...) {
+		roles[role.ID] = role
+		orgIDs = append(orgIDs, role.OwnerOrganizationID)
+	}
+
+	organizationCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Organizations)
+	if !exist {
+		return nil, nil, nil, common.ErrNotFound
+	}
+	var organizationList []models.Organization
+	if err := findDelegationDocs(ctx, organizationCollection, bson.M{"_id": bson.M{"$in": orgIDs}}, &organizationList); err != nil {
+		return nil, nil, nil, err
+	}
+	organizations := make(map[primitive.ObjectID]string, len(organizationList))
+	for _, organization := range organizationList {
+		organizations[organization.ID] = organization.Path
+	}
+
+	permissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Permissions)
+	if !exist {
+		return nil, nil, nil, common.ErrNotFound
+	}
+	var permissionList []models.Permission
+	if err := findDelegationDocs(ctx, permissionCollection, bson.M{"_id": bson.M{"$in": permissionIDs}}, &permissionList); err != nil {
+		return nil, nil, nil, err
+	}
+	permissionNames := make(map[primitive.ObjectID]string, len(permissionList))
+	for _, permission := range permissionList {
+		permissionNames[permission.ID] = permission.Name
+	}
+
+	return roles, organizations, permissionNames, nil
+}
+
+// findDelegationDocs tìm document trực tiếp trên collection (không qua BaseServiceMongoImpl để không trả ErrNotFound khi rỗng)
+func findDelegationDocs(ctx context.Context, collection *mongo.Collection, filter interface{}, results interface{}) error {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if err := cursor.All(ctx, results); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// delegationUpdatedDocs trả về các document khớp filter sau khi áp dụng $set (và $setOnInsert nếu upsert tạo mới)
+// của các field liên quan đến phân quyền, để kiểm tra trước khi cập nhật. Trả về nil nếu update không đổi các field này.
+func delegationUpdatedDocs[T any](ctx context.Context, collection *mongo.Collection, filter interface{}, update interface{}, upsert bool, fields ...string) ([]T, error) {
+	if _, _, ok := delegationCaller(ctx); !ok {
+		return nil, nil
+	}
+	updateData, err := ToUpdateData(update)
+	if err != nil {
+		return nil, common.ErrInvalidFormat
+	}
+	changed := false
+	for _, field := range fields {
+		_, inSet := updateData.Set[field]
+		_, inSetOnInsert := updateData.SetOnInsert[field]
+		changed = changed || inSet || (upsert && inSetOnInsert)
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	if filter == nil {
+		filter = bson.D{}
+	}
+	var docs []bson.M
+	if err := findDelegationDocs(ctx, collection, filter, &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 && upsert {
+		doc := bson.M{}
+		for _, field := range fields {
+			if value, ok := updateData.SetOnInsert[field]; ok {
+				doc[field] = value
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	results := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		for _, field := range fields {
+			if value, ok := updateData.Set[field]; ok {
+				doc[field] = value
+			}
+			// Giá trị từ JSON: ObjectID dạng hex, số dạng float64
+			switch value := doc[field].(type) {
+			case string:
+				if id, err := primitive.ObjectIDFromHex(value); err == nil {
+					doc[field] = id
+				}
+			case float64:
+				doc[field] = int32(value)
+			}
+		}
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, common.ErrInvalidFormat
+		}
+		var result T
+		if err := bson.Unmarshal(data, &result); err != nil {
+			return nil, common.ErrInvalidFormat
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Nguồn của một tổ chức trong phạm vi dữ liệu
+const (
+	OrganizationAccessViaRole    = "role"    // Tổ chức sở hữu role (scope 0 và 1)
+	OrganizationAccessViaSubtree = "subtree" // Tổ chức con của tổ chức sở hữu role (scope 1)
+	OrganizationAccessViaOwn     = "own"     // Tổ chức sở hữu role, chỉ bản ghi do user tạo hoặc được giao (scope 2)
+	OrganizationAccessViaShare   = "share"   // Tổ chức share dữ liệu với một tổ chức trong phạm vi (OrganizationShare)
+)
+
+// OrganizationAccess là một tổ chức user truy cập được và lý do
+type OrganizationAccess struct {
+	OrganizationID primitive.ObjectID     `json:"organizationId"`
+	Via            string                 `json:"via"`                      // role | subtree | own | share
+	RoleID         *primitive.ObjectID    `json:"roleId,omitempty"`         // Role cấp quyền (via = role | subtree | own)
+	ShareID        *primitive.ObjectID    `json:"shareId,omitempty"`        // Share cấp quyền (via = share)
+	DocumentFilter map[string]interface{} `json:"documentFilter,omitempty"` // Share chỉ áp dụng cho document khớp filter (via = share)
+}
+
+// ResolvedPermission là permission hiệu lực kèm danh sách tổ chức cụ thể mà nó cho phép
+type ResolvedPermission struct {
+	Name          string               `json:"name"`
+	Scope         byte                 `json:"scope"` // 0 = chỉ tổ chức của role, 1 = tổ chức và các tổ chức con, 2 = chỉ bản ghi của mình
+	Grants        []PermissionGrant    `json:"grants"`
+	Organizations []OrganizationAccess `json:"organizations"`
+}
+
+// DataScope là phạm vi dữ liệu mà BaseHandler.applyOrganizationFilter áp dụng cho user
+// (tính trên tất cả role của user, không phụ thuộc role context)
+type DataScope struct {
+	OrganizationIDs       []primitive.ObjectID `json:"organizationIds"`       // Từ role/scope của user
+	OwnOrganizationIDs    []primitive.ObjectID `json:"ownOrganizationIds"`    // Từ role scope 2: chỉ bản ghi createdBy/assignedTo là user (collection có các field này)
+	SharedOrganizationIDs []primitive.ObjectID `json:"sharedOrganizationIds"` // Từ OrganizationShare
+}
+
+// EffectiveAccess là toàn bộ quyền hiệu lực và phạm vi dữ liệu của user
+type EffectiveAccess struct {
+	UserID       primitive.ObjectID   `json:"userId"`
+	ActiveRoleID *primitive.ObjectID  `json:"activeRoleId,omitempty"` // Role context dùng để tính permissions (nil = tất cả role)
+	Permissions  []ResolvedPermission `json:"permissions"`
+	DataScope    DataScope            `json:"dataScope"`
+}
+
+// AccessCheck là một bước kiểm tra trong quá trình giải thích quyết định
+type AccessCheck struct {
+	Check  string `json:"check"` // user | role | permission | organization | condition | document
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason"`
+}
+
+// AccessDecision là kết quả giải thích quyền của user với một permission (và một document nếu có)
+type AccessDecision struct {
+	Permission string        `json:"permission"`
+	Allowed    bool          `json:"allowed"`
+	Checks     []AccessCheck `json:"checks"`
+}
+
+// DocumentOwnership là thông tin sở hữu của document cần giải thích quyền
+type DocumentOwnership struct {
+	DocumentID          primitive.ObjectID
+	HasOrganizationID   bool                   // false = collection không phân quyền theo tổ chức
+	OwnerOrganizationID *primitive.ObjectID    // nil = document không có tổ chức sở hữu
+	HasRecordOwnership  bool                   // true = collection có createdBy/assignedTo (áp dụng scope 2)
+	CreatedBy           primitive.ObjectID     // User tạo document
+	AssignedTo          primitive.ObjectID     // User được giao document
+	Fields              map[string]interface{} // Document theo tên field MongoDB (DocumentToConditionMap) để so khớp điều kiện của permission
+}
+
+// EffectiveAccessService tính quyền hiệu lực, phạm vi dữ liệu và giải thích quyết định phân quyền
+type EffectiveAccessService struct {
+	userService         *UserService
+	userRoleService     *UserRoleService
+	organizationService *OrganizationService
+}
+
+// NewEffectiveAccessService tạo mới EffectiveAccessService
+func NewEffectiveAccessService() (*EffectiveAccessService, error) {
+	userService, err := NewUserService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user service: %v", err)
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
+	}
+
+	organizationService, err := NewOrganizationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization service: %v", err)
+	}
+
+	return &EffectiveAccessService{
+		userService:         userService,
+		userRoleService:     userRoleService,
+		organizationService: organizationService,
+	}, nil
+}
+
+// GetEffectiveAccess lấy permissions (kèm role nguồn và tổ chức cụ thể) và phạm vi dữ liệu của user
+// activeRoleID: Nếu khác nil, permissions chỉ tính từ role này (giống AuthMiddleware với header X-Active-Role-ID)
+func (s *EffectiveAccessService) GetEffectiveAccess(ctx context.Context, userID primitive.ObjectID, activeRoleID *primitive.ObjectID) (*EffectiveAccess, error) {
+	permissions, err := s.userRoleService.GetEffectivePermissions(ctx, userID, activeRoleID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.resolvePermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Phạm vi dữ liệu luôn tính trên tất cả role (giống applyOrganizationFilter)
+	allPermissions := permissions
+	if activeRoleID != nil {
+		allPermissions, err = s.userRoleService.GetEffectivePermissions(ctx, userID, nil, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	dataScope, err := s.resolveDataScope(ctx, allPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EffectiveAccess{
+		UserID:       userID,
+		ActiveRoleID: activeRoleID,
+		Permissions:  resolved,
+		DataScope:    *dataScope,
+	}, nil
+}
+
+// Explain giải thích vì sao user được/không được dùng permission (và truy cập document nếu có)
+// Các bước kiểm tra giống AuthMiddleware → BaseHandler.validateOrganizationAccess/applyOrganizationFilter → điều kiện (ABAC) của permission
+func (s *EffectiveAccessService) Explain(ctx context.Context, userID primitive.ObjectID, activeRoleID *primitive.ObjectID, permissionName string, document *DocumentOwnership) (*AccessDecision, error) {
+	decision := &AccessDecision{Permission: permissionName}
+	deny := func(check, reason string) (*AccessDecision, error) {
+		decision.Checks = append(decision.Checks, AccessCheck{Check: check, Passed: false, Reason: reason})
+		return decision, nil
+	}
+	pass := func(check, reason string) {
+		decision.Checks = append(decision.Checks, AccessCheck{Check: check, Passed: true, Reason: reason})
+	}
+
+	// 1. User (hoặc service account)
+	user, err := s.userService.FindOneById(ctx, userID)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	if err == common.ErrNotFound {
+		account, err := s.userRoleService.serviceAccountService.FindOneById(ctx, userID)
+		if err != nil {
+			if err == common.ErrNotFound {
+				return deny("user", "Không tìm thấy người dùng")
+			}
+			return nil, err
+		}
+		if account.IsDisabled {
+			return deny("user", "Service account đang bị vô hiệu hóa")
+		}
+		pass("user", fmt.Sprintf("Service account \"%s\" đang hoạt động (quyền thực tế còn bị giới hạn bởi scopes của API key)", account.Name))
+	} else {
+		if user.IsBlock {
+			return deny("user", "Tài khoản đang bị khóa: "+user.BlockNote)
+		}
+		pass("user", "Tài khoản đang hoạt động")
+	}
+
+	// 2. Role context
+	if activeRoleID != nil {
+		exists, err := s.userRoleService.IsExist(ctx, userID, *activeRoleID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return deny("role", fmt.Sprintf("Người dùng không có role %s (X-Active-Role-ID)", activeRoleID.Hex()))
+		}
+		pass("role", fmt.Sprintf("Người dùng có role %s", activeRoleID.Hex()))
+	} else {
+		pass("role", "Không chỉ định role context: xét trên tất cả role. Lưu ý: request thật phải gửi header X-Active-Role-ID và chỉ được tính quyền của role đó")
+	}
+
+	// 3. Permission
+	permissions, err := s.userRoleService.GetEffectivePermissions(ctx, userID, activeRoleID, permissionName)
+	if err != nil {
+		return nil, err
+	}
+	permission, ok := permissions[permissionName]
+	if !ok {
+		return deny("permission", fmt.Sprintf("Không role nào (trong phạm vi xét) được gán permission %s", permissionName))
+	}
+	for _, grant := range permission.Grants {
+		reason := fmt.Sprintf("Role %q (%s) cấp %s với scope %d tại tổ chức %s", grant.RoleName, grant.RoleID.Hex(), permissionName, grant.Scope, grant.OrganizationID.Hex())
+		if len(grant.Conditions) > 0 {
+			reason += fmt.Sprintf(", chỉ với document khớp điều kiện %v", grant.Conditions)
+		}
+		pass("permission", reason)
+	}
+
+	// 4. Tổ chức sở hữu document
+	if document != nil {
+		if !document.HasOrganizationID {
+			pass("organization", "Collection không phân quyền theo tổ chức")
+		} else if document.OwnerOrganizationID == nil {
+			pass("organization", "Document không có tổ chức sở hữu")
+		} else {
+			// Phạm vi dữ liệu tính trên tất cả role của user (giống BaseHandler)
+			allPermissions, err := s.userRoleService.GetEffectivePermissions(ctx, userID, nil, "")
+			if err != nil {
+				return nil, err
+			}
+			accesses, err := s.resolveOrganizations(ctx, allPermissions.Names(), allPermissions)
+			if err != nil {
+				return nil, err
+			}
+
+			orgID := *document.OwnerOrganizationID
+			var matched []OrganizationAccess
+			for _, access := range accesses {
+				if access.OrganizationID == orgID {
+					matched = append(matched, access)
+				}
+			}
+			if len(matched) == 0 {
+				return deny("organization", fmt.Sprintf("Document %s thuộc tổ chức %s, không nằm trong phạm vi dữ liệu của người dùng (không thuộc role/scope và không được share)", document.DocumentID.Hex(), orgID.Hex()))
+			}
+			// Scope 2: chỉ bản ghi do user tạo hoặc được giao
+			if matched[0].Via == OrganizationAccessViaOwn && document.HasRecordOwnership && document.CreatedBy != userID && document.AssignedTo != userID {
+				return deny("organization", fmt.Sprintf("Người dùng chỉ có quyền với bản ghi của mình (scope 2) tại tổ chức %s, document %s không do người dùng tạo và không được giao cho người dùng", orgID.Hex(), document.DocumentID.Hex()))
+			}
+			for _, access := range matched {
+				pass("organization", describeOrganizationAccess(orgID, access))
+			}
+		}
+	}
+
+	// 5. Điều kiện (ABAC) của permission: document phải khớp điều kiện của ít nhất một grant
+	if sets := permission.ConditionSets(); len(sets) > 0 {
+		if document == nil || document.Fields == nil {
+			pass("condition", fmt.Sprintf("Mọi grant của %s đều có điều kiện: chỉ document khớp một trong các điều kiện mới được truy cập (truyền documentId để kiểm tra)", permissionName))
+		} else {
+			matched := false
+			for _, grant := range permission.Grants {
+				if MatchConditions(document.Fields, grant.Conditions) {
+					matched = true
+					pass("condition", fmt.Sprintf("Document %s khớp điều kiện %v của role %q (%s)", document.DocumentID.Hex(), grant.Conditions, grant.RoleName, grant.RoleID.Hex()))
+				}
+			}
+			if !matched {
+				return deny("condition", fmt.Sprintf("Document %s không khớp điều kiện của grant nào cấp %s: %v", document.DocumentID.Hex(), permissionName, sets))
+			}
+		}
+	} else if document != nil {
+		pass("condition", fmt.Sprintf("Có grant cấp %s không kèm điều kiện, không giới hạn theo nội dung document", permissionName))
+	}
+
+	decision.Allowed = true
+	return decision, nil
+}
+
+// describeOrganizationAccess mô tả lý do user truy cập được tổ chức
+func describeOrganizationAccess(orgID primitive.ObjectID, access OrganizationAccess) string {
+	switch access.Via {
+	case OrganizationAccessViaSubtree:
+		return fmt.Sprintf("Tổ chức %s là tổ chức con của tổ chức sở hữu role %s (scope 1)", orgID.Hex(), access.RoleID.Hex())
+	case OrganizationAccessViaOwn:
+		return fmt.Sprintf("Tổ chức %s sở hữu role %s, chỉ bản ghi do người dùng tạo hoặc được giao (scope 2)", orgID.Hex(), access.RoleID.Hex())
+	case OrganizationAccessViaShare:
+		if len(access.DocumentFilter) > 0 {
+			return fmt.Sprintf("Tổ chức %s share dữ liệu qua share %s, chỉ document khớp %v (chỉ áp dụng cho danh sách/lọc, không áp dụng cho thao tác theo ID)", orgID.Hex(), access.ShareID.Hex(), access.DocumentFilter)
+		}
+		return fmt.Sprintf("Tổ chức %s share dữ liệu qua share %s (chỉ áp dụng cho danh sách/lọc, không áp dụng cho thao tác theo ID)", orgID.Hex(), access.ShareID.Hex())
+	default:
+		return fmt.Sprintf("Tổ chức %s sở hữu role %s", orgID.Hex(), access.RoleID.Hex())
+	}
+}
+
+// resolvePermissions tính danh sách tổ chức cụ thể cho từng permission
+func (s *EffectiveAccessService) resolvePermissions(ctx context.Context, permissions EffectivePermissions) ([]ResolvedPermission, error) {
+	subtrees, err := s.getSubtrees(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.findSharesTo(ctx, permissions, subtrees)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResolvedPermission, 0, len(permissions))
+	for _, name := range permissions.Names() {
+		permission := permissions[name]
+		result = append(result, ResolvedPermission{
+			Name:          name,
+			Scope:         permission.Scope,
+			Grants:        permission.Grants,
+			Organizations: organizationAccesses(permission, subtrees, shares),
+		})
+	}
+	return result, nil
+}
+
+// resolveDataScope tính phạm vi dữ liệu (giống GetUserAllowedOrganizationIDs + GetSharedOrganizationIDs với permissionName rỗng)
+func (s *EffectiveAccessService) resolveDataScope(ctx context.Context, permissions EffectivePermissions) (*DataScope, error) {
+	accesses, err := s.resolveOrganizations(ctx, permissions.Names(), permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	direct := make(map[primitive.ObjectID]bool)
+	own := make(map[primitive.ObjectID]bool)
+	shared := make(map[primitive.ObjectID]bool)
+	for _, access := range accesses {
+		switch access.Via {
+		case OrganizationAccessViaShare:
+			shared[access.OrganizationID] = true
+		case OrganizationAccessViaOwn:
+			own[access.OrganizationID] = true
+		default:
+			direct[access.OrganizationID] = true
+		}
+	}
+
+	return &DataScope{
+		OrganizationIDs:       sortedObjectIDs(direct),
+		OwnOrganizationIDs:    sortedObjectIDs(own),
+		SharedOrganizationIDs: sortedObjectIDs(shared),
+	}, nil
+}
+
+// resolveOrganizations gộp tổ chức của nhiều permission; share không giới hạn theo permission name
+// (giống applyOrganizationFilter, vốn gọi GetSharedOrganizationIDs với permissionName rỗng)
+func (s *EffectiveAccessService) resolveOrganizations(ctx context.Context, names []string, permissions EffectivePermissions) ([]OrganizationAccess, error) {
+	merged := &EffectivePermission{}
+	for _, name := range names {
+		merged.Grants = append(merged.Grants, permissions[name].Grants...)
+	}
+	single := EffectivePermissions{"": merged}
+
+	subtrees, err := s.getSubtrees(ctx, single)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.findSharesTo(ctx, single, subtrees)
+	if err != nil {
+		return nil, err
+	}
+	return organizationAccesses(merged, subtrees, shares), nil
+}
+
+// getSubtrees lấy tổ chức con của các tổ chức có grant scope 1, nhóm theo path
+func (s *EffectiveAccessService) getSubtrees(ctx context.Context, permissions EffectivePermissions) (map[string][]primitive.ObjectID, error) {
+	pathSet := make(map[string]bool)
+	for _, permission := range permissions {
+		for _, grant := range permission.Grants {
+			if grant.Scope == 1 {
+				pathSet[grant.OrganizationPath] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(pathSet))
+	for path := range pathSet {
+		paths = append(paths, path)
+	}
+	return s.organizationService.GetChildrenIDsGroupedByPath(ctx, paths)
+}
+
+// findSharesTo lấy tất cả share còn hiệu lực tới các tổ chức trong phạm vi của permissions (giống GetSharedOrganizationScopes)
+func (s *EffectiveAccessService) findSharesTo(ctx context.Context, permissions EffectivePermissions, subtrees map[string][]primitive.ObjectID) ([]ResolvedShare, error) {
+	orgSet := make(map[primitive.ObjectID]bool)
+	for _, permission := range permissions {
+		for _, access := range organizationAccesses(permission, subtrees, nil) {
+			orgSet[access.OrganizationID] = true
+		}
+	}
+	if len(orgSet) == 0 {
+		return nil, nil
+	}
+	return ResolveActiveShares(ctx, sortedObjectIDs(orgSet), "")
+}
+
+// organizationAccesses tính danh sách tổ chức (không trùng) mà một permission cho phép, kèm lý do
+// shares = nil: bỏ qua tổ chức đến từ share
+func organizationAccesses(permission *EffectivePermission, subtrees map[string][]primitive.ObjectID, shares []ResolvedShare) []OrganizationAccess {
+	seen := make(map[primitive.ObjectID]bool)
+	result := make([]OrganizationAccess, 0)
+	add := func(access OrganizationAccess) {
+		if !seen[access.OrganizationID] {
+			seen[access.OrganizationID] = true
+			result = append(result, access)
+		}
+	}
+
+	for _, grant := range permission.Grants {
+		if grant.Scope == models.PermissionScopeOwn {
+			continue
+		}
+		roleID := grant.RoleID
+		add(OrganizationAccess{OrganizationID: grant.OrganizationID, Via: OrganizationAccessViaRole, RoleID: &roleID})
+	}
+	for _, grant := range permission.Grants {
+		if grant.Scope != 1 {
+			continue
+		}
+		roleID := grant.RoleID
+		for _, childID := range subtrees[grant.OrganizationPath] {
+			add(OrganizationAccess{OrganizationID: childID, Via: OrganizationAccessViaSubtree, RoleID: &roleID})
+		}
+	}
+
+	// Chỉ share tới tổ chức có được trực tiếp từ role/scope 0, 1 mới có hiệu lực (không lan truyền qua share khác,
+	// tổ chức chỉ có scope 2 không nhận share)
+	direct := make(map[primitive.ObjectID]bool, len(seen))
+	for orgID := range seen {
+		direct[orgID] = true
+	}
+
+	// Scope 2 chỉ thêm tổ chức chưa có từ scope 0, 1
+	for _, grant := range permission.Grants {
+		if grant.Scope != models.PermissionScopeOwn {
+			continue
+		}
+		roleID := grant.RoleID
+		add(OrganizationAccess{OrganizationID: grant.OrganizationID, Via: OrganizationAccessViaOwn, RoleID: &roleID})
+	}
+	for _, resolved := range shares {
+		share := resolved.Share
+		if !shareAppliesToPermission(share, permission.Name) {
+			continue
+		}
+		received := false
+		for _, recipientID := range resolved.RecipientOrganizationIDs {
+			if direct[recipientID] {
+				received = true
+				break
+			}
+		}
+		if !received {
+			continue
+		}
+		shareID := share.ID
+		for _, ownerID := range resolved.OwnerOrganizationIDs {
+			add(OrganizationAccess{OrganizationID: ownerID, Via: OrganizationAccessViaShare, ShareID: &shareID, DocumentFilter: share.DocumentFilter})
+		}
+	}
+	return result
+}
+
+// shareAppliesToPermission kiểm tra share có áp dụng cho permission không
+// PermissionNames rỗng = share tất cả permissions; permissionName rỗng = không lọc theo permission
+func shareAppliesToPermission(share models.OrganizationShare, permissionName string) bool {
+	if permissionName == "" || len(share.PermissionNames) == 0 {
+		return true
+	}
+	for _, name := range share.PermissionNames {
+		if name == permissionName {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedObjectIDs chuyển set ObjectID thành slice đã sắp xếp (kết quả ổn định cho API)
+func sortedObjectIDs(set map[primitive.ObjectID]bool) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+	return ids
+}
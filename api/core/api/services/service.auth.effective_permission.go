@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PermissionGrant là một nguồn cấp permission cho user: permission đến từ role nào, thuộc tổ chức nào, scope bao nhiêu, điều kiện gì
+type PermissionGrant struct {
+	RoleID                primitive.ObjectID     `json:"roleId" bson:"roleId"`                                                   // Role cấp permission
+	RoleName              string                 `json:"roleName" bson:"roleName"`                                               // Tên role
+	OrganizationID        primitive.ObjectID     `json:"organizationId" bson:"organizationId"`                                   // Tổ chức sở hữu role
+	OrganizationPath      string                 `json:"organizationPath" bson:"organizationPath"`                               // Path của tổ chức (dùng để tính tổ chức con khi scope = 1)
+	Scope                 byte                   `json:"scope" bson:"scope"`                                                     // Scope của permission trong role này
+	Conditions            map[string]interface{} `json:"conditions,omitempty" bson:"conditions,omitempty"`                       // Điều kiện trên document (rỗng = không giới hạn)
+	InheritedFromRoleID   *primitive.ObjectID    `json:"inheritedFromRoleId,omitempty" bson:"inheritedFromRoleId,omitempty"`     // Role cha / template sở hữu permission (nil = permission của chính role)
+	InheritedFromRoleName string                 `json:"inheritedFromRoleName,omitempty" bson:"inheritedFromRoleName,omitempty"` // Tên role cha / template
+}
+
+// EffectivePermission là permission hiệu lực của user, tổng hợp từ tất cả role
+type EffectivePermission struct {
+	Name   string            `json:"name"`   // Tên permission
+	Scope  byte              `json:"scope"`  // Scope rộng nhất trong các grant (xem scopeRank)
+	Grants []PermissionGrant `json:"grants"` // Các role cấp permission này
+}
+
+// EffectivePermissions là tập permission hiệu lực của user, key = tên permission
+type EffectivePermissions map[string]*EffectivePermission
+
+// Has kiểm tra user có permission không
+func (p EffectivePermissions) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+// Names trả về danh sách tên permission (đã sắp xếp)
+func (p EffectivePermissions) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scopeRank trả về độ rộng của scope để so sánh: chỉ bản ghi của mình < tổ chức < tổ chức và tổ chức con
+// Giá trị scope không theo thứ tự độ rộng (scope 2 - bản ghi của mình - được thêm sau) nên không so sánh trực tiếp
+func scopeRank(scope byte) int {
+	switch scope {
+	case models.PermissionScopeOwn:
+		return 0
+	case models.PermissionScopeOrganization:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// add thêm một grant vào permission, cập nhật scope rộng nhất
+func (p EffectivePermissions) add(name string, grant PermissionGrant) {
+	permission, ok := p[name]
+	if !ok {
+		permission = &EffectivePermission{Name: name, Scope: grant.Scope}
+		p[name] = permission
+	}
+	if scopeRank(grant.Scope) > scopeRank(permission.Scope) {
+		permission.Scope = grant.Scope
+	}
+	grant.Conditions = NormalizeConditions(grant.Conditions)
+	permission.Grants = append(permission.Grants, grant)
+}
+
+// effectiveRoleRow là một role user đang có (kết quả aggregation user_roles → roles → organizations)
+type effectiveRoleRow struct {
+	Role             models.Role `bson:"role"`
+	OrganizationPath string      `bson:"organizationPath"`
+}
+
+// effectivePermissionRow là một role permission kèm tên permission (kết quả aggregation role_permissions → permissions)
+type effectivePermissionRow struct {
+	RoleID         primitive.ObjectID     `bson:"roleId"`
+	Scope          byte                   `bson:"scope"`
+	Conditions     map[string]interface{} `bson:"conditions"`
+	PermissionName string                 `bson:"permissionName"`
+}
+
+// GetEffectivePermissions lấy permission hiệu lực của user: aggregation lấy các role của user, rồi aggregation lấy
+// permission của các role đó và các role cha / template mà chúng kế thừa (quyền kế thừa áp dụng tại tổ chức của role được gán)
+// Parameters:
+//   - userID: ID của user
+//   - activeRoleID: Nếu khác nil, chỉ lấy permission từ role này (role context); user không có role này → rỗng
+//   - permissionName: Nếu khác rỗng, chỉ lấy permission có tên này
+// Role thuộc tổ chức đã lưu trữ và role assignment ngoài khoảng validFrom/validUntil bị bỏ qua
+func (s *UserRoleService) GetEffectivePermissions(ctx context.Context, userID primitive.ObjectID, activeRoleID *primitive.ObjectID, permissionName string) (EffectivePermissions, error) {
+	match := ActiveUserRoleFilter()
+	match["userId"] = userID
+	if activeRoleID != nil {
+		match["roleId"] = *activeRoleID
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         global.MongoDB_ColNames.Roles,
+			"localField":   "roleId",
+			"foreignField": "_id",
+			"as":           "role",
+		}}},
+		{{Key: "$unwind", Value: "$role"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         global.MongoDB_ColNames.Organizations,
+			"localField":   "role.ownerOrganizationId",
+			"foreignField": "_id",
+			"as":           "organization",
+		}}},
+		// Role thuộc tổ chức đã lưu trữ không còn cấp quyền
+		{{Key: "$match", Value: bson.M{"organization.archivedAt": bson.M{"$exists": false}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"role":             1,
+			"organizationPath": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$organization.path", 0}}, ""}},
+		}}},
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	var roleRows []effectiveRoleRow
+	if err := cursor.All(ctx, &roleRows); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	permissions := make(EffectivePermissions)
+	if len(roleRows) == 0 {
+		return permissions, nil
+	}
+
+	// Role cha / template mà các role kế thừa (chỉ đọc thêm khi có liên kết)
+	roles := make([]models.Role, 0, len(roleRows))
+	for _, row := range roleRows {
+		roles = append(roles, row.Role)
+	}
+	ancestors, err := resolveRoleAncestors(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]primitive.ObjectID, 0, len(roles))
+	added := make(map[primitive.ObjectID]bool)
+	for _, role := range roles {
+		for _, source := range append([]models.Role{role}, ancestors[role.ID]...) {
+			if !added[source.ID] {
+				added[source.ID] = true
+				roleIDs = append(roleIDs, source.ID)
+			}
+		}
+	}
+
+	rolePermissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RolePermissions)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"roleId": bson.M{"$in": roleIDs}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         global.MongoDB_ColNames.Permissions,
+			"localField":   "permissionId",
+			"foreignField": "_id",
+			"as":           "permission",
+		}}},
+		{{Key: "$unwind", Value: "$permission"}},
+	}
+	if permissionName != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"permission.name": permissionName}}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{
+		"_id":            0,
+		"roleId":         1,
+		"scope":          1,
+		"conditions":     1,
+		"permissionName": "$permission.name",
+	}}})
+
+	cursor, err = rolePermissionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	var rows []effectivePermissionRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	byRole := make(map[primitive.ObjectID][]effectivePermissionRow)
+	for _, row := range rows {
+		byRole[row.RoleID] = append(byRole[row.RoleID], row)
+	}
+
+	for _, roleRow := range roleRows {
+		role := roleRow.Role
+		for _, source := range append([]models.Role{role}, ancestors[role.ID]...) {
+			for _, row := range byRole[source.ID] {
+				grant := PermissionGrant{
+					RoleID:           role.ID,
+					RoleName:         role.Name,
+					OrganizationID:   role.OwnerOrganizationID,
+					OrganizationPath: roleRow.OrganizationPath,
+					Scope:            row.Scope,
+					Conditions:       row.Conditions,
+				}
+				if source.ID != role.ID {
+					sourceID := source.ID
+					grant.InheritedFromRoleID = &sourceID
+					grant.InheritedFromRoleName = source.Name
+				}
+				permissions.add(row.PermissionName, grant)
+			}
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/identity"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// loginAttemptResetPeriod bộ đếm (và mức khóa) bị xóa sau khoảng thời gian này không có lần thất bại nào
+const loginAttemptResetPeriod = 24 * time.Hour
+
+// loginAnomalyHistorySize số lần đăng nhập thành công gần nhất dùng để so sánh thiết bị/dải mạng
+const loginAnomalyHistorySize = 20
+
+// LoginRequestInfo thông tin của một lần đăng nhập dùng cho bộ đếm thất bại và lịch sử đăng nhập
+// Provider dạng token (firebase, oidc, local) chỉ biết danh tính sau khi verify chữ ký nên chỉ đếm theo IP:
+// bộ đếm theo danh tính chỉ dùng khi danh tính đã được xác thực trước khi đăng nhập (IdentityKey)
+type LoginRequestInfo struct {
+	Provider     string // Identity provider (firebase, oidc, local...)
+	IdentityKey  string // "provider:subject" đã được xác thực - dùng cho bộ đếm theo danh tính, rỗng với provider dạng token
+	IdentityHint string // "provider:subject" đọc từ token chưa verify - chỉ ghi vào lịch sử khi đăng nhập thành công
+	IP           string
+	UserAgent    string
+	Hwid         string
+}
+
+// LoginGuardService chống dò đăng nhập (khóa theo danh tính và IP) và lưu lịch sử đăng nhập
+type LoginGuardService struct {
+	*BaseServiceMongoImpl[models.LoginEvent]
+	attempts *mongo.Collection
+}
+
+// NewLoginGuardService tạo mới LoginGuardService
+func NewLoginGuardService() (*LoginGuardService, error) {
+	eventCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.LoginEvents)
+	if !exist {
+		return nil, fmt.Errorf("failed to get auth_login_events collection: %v", common.ErrNotFound)
+	}
+	attemptCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.LoginAttempts)
+	if !exist {
+		return nil, fmt.Errorf("failed to get auth_login_attempts collection: %v", common.ErrNotFound)
+	}
+
+	return &LoginGuardService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.LoginEvent](eventCollection),
+		attempts:             attemptCollection,
+	}, nil
+}
+
+// LoginIdentityHint đọc danh tính "provider:subject" từ claim "sub" của token mà KHÔNG xác thực chữ ký
+// Ai cũng tạo được token giả với sub bất kỳ: không dùng làm khóa bộ đếm thất bại hay gắn lần thất bại vào user,
+// chỉ ghi vào lịch sử khi đăng nhập thành công (lúc đó token đã được verify)
+func LoginIdentityHint(provider string, token string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return ""
+	}
+	return identity.IdentityKey(provider, subject)
+}
+
+// loginCounter một bộ đếm thất bại áp dụng cho lần đăng nhập
+type loginCounter struct {
+	key         string
+	counterType string
+	maxFailures int
+}
+
+// counters trả về các bộ đếm đang bật cho lần đăng nhập (theo danh tính, theo IP)
+func (info LoginRequestInfo) counters() []loginCounter {
+	config := global.MongoDB_ServerConfig
+	var counters []loginCounter
+	if info.IdentityKey != "" && config.LoginMaxFailedAttempts > 0 {
+		counters = append(counters, loginCounter{models.LoginAttemptTypeIdentity + ":" + info.IdentityKey, models.LoginAttemptTypeIdentity, config.LoginMaxFailedAttempts})
+	}
+	if info.IP != "" && config.LoginMaxFailedAttemptsPerIP > 0 {
+		counters = append(counters, loginCounter{models.LoginAttemptTypeIP + ":" + info.IP, models.LoginAttemptTypeIP, config.LoginMaxFailedAttemptsPerIP})
+	}
+	return counters
+}
+
+// LockedUntil trả về thời điểm (Unix milli) danh tính hoặc IP của lần đăng nhập hết bị khóa, 0 nếu không bị khóa
+func (s *LoginGuardService) LockedUntil(ctx context.Context, info LoginRequestInfo) (int64, error) {
+	keys := make([]string, 0, 2)
+	for _, counter := range info.counters() {
+		keys = append(keys, counter.key)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().UnixMilli()
+	cursor, err := s.attempts.Find(ctx, bson.M{"key": bson.M{"$in": keys}, "lockedUntil": bson.M{"$gt": now}})
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	var locked []models.LoginAttempt
+	if err := cursor.All(ctx, &locked); err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+
+	var until int64
+	for _, attempt := range locked {
+		if attempt.LockedUntil > until {
+			until = attempt.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// LoginRetryAfter số giây (làm tròn lên) đến khi hết bị khóa
+func LoginRetryAfter(lockedUntil int64) int64 {
+	return (lockedUntil - time.Now().UnixMilli() + 999) / 1000
+}
+
+// LoginLockedError lỗi trả về khi danh tính hoặc IP đang bị khóa
+func LoginLockedError(lockedUntil int64) error {
+	retryAfter := LoginRetryAfter(lockedUntil)
+	return common.NewError(
+		common.ErrCodeAuthCredentials,
+		fmt.Sprintf("Đăng nhập thất bại quá nhiều lần, vui lòng thử lại sau %d giây", retryAfter),
+		common.StatusTooManyRequests,
+		map[string]interface{}{
+			"lockedUntil": lockedUntil,
+			"retryAfter":  retryAfter,
+		},
+	)
+}
+
+// RecordFailure tăng bộ đếm thất bại của danh tính và IP (khóa khi đủ số lần) và lưu lịch sử đăng nhập
+// Lần thất bại chỉ được gắn vào user khi danh tính đã được xác thực (IdentityKey), không theo IdentityHint
+func (s *LoginGuardService) RecordFailure(ctx context.Context, info LoginRequestInfo, reason string) {
+	for _, counter := range info.counters() {
+		if err := s.incrementFailure(ctx, counter); err != nil {
+			logrus.WithError(err).WithField("key", counter.key).Warn("Failed to record login failure")
+		}
+	}
+	s.insertEvent(ctx, models.LoginEvent{
+		UserID:      s.userIDForIdentity(ctx, info.IdentityKey),
+		Provider:    info.Provider,
+		IdentityKey: info.IdentityKey,
+		IP:          info.IP,
+		UserAgent:   info.UserAgent,
+		Hwid:        info.Hwid,
+		Outcome:     models.LoginOutcomeFailure,
+		Reason:      reason,
+	})
+}
+
+// RecordLocked lưu lịch sử lần đăng nhập bị từ chối do đang bị khóa (không tăng bộ đếm)
+func (s *LoginGuardService) RecordLocked(ctx context.Context, info LoginRequestInfo) {
+	s.insertEvent(ctx, models.LoginEvent{
+		UserID:      s.userIDForIdentity(ctx, info.IdentityKey),
+		Provider:    info.Provider,
+		IdentityKey: info.IdentityKey,
+		IP:          info.IP,
+		UserAgent:   info.UserAgent,
+		Hwid:        info.Hwid,
+		Outcome:     models.LoginOutcomeLocked,
+	})
+}
+
+// userIDForIdentity tìm user đã liên kết danh tính (đã xác thực) để lần đăng nhập thất bại xuất hiện trong lịch sử của user đó
+func (s *LoginGuardService) userIDForIdentity(ctx context.Context, identityKey string) primitive.ObjectID {
+	users, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Users)
+	if identityKey == "" || !exist {
+		return primitive.NilObjectID
+	}
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	if err := users.FindOne(ctx, bson.M{"identityKeys": identityKey}, opts).Decode(&user); err != nil {
+		return primitive.NilObjectID
+	}
+	return user.ID
+}
+
+// RecordSuccess xóa bộ đếm thất bại của danh tính, phát hiện thiết bị mới / dải mạng lạ và lưu lịch sử đăng nhập
+// Bộ đếm theo IP không bị xóa để một tài khoản hợp lệ không mở khóa được IP đang dò tài khoản khác
+// user là user sau khi đăng nhập (phiên của thiết bị hiện tại đã được cập nhật)
+func (s *LoginGuardService) RecordSuccess(ctx context.Context, info LoginRequestInfo, user *models.User) *models.LoginEvent {
+	identityKey := info.IdentityKey
+	if identityKey != "" {
+		if _, err := s.attempts.DeleteOne(ctx, bson.M{"key": models.LoginAttemptTypeIdentity + ":" + identityKey}); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID.Hex()).Warn("Failed to reset login failures")
+		}
+	} else {
+		// Đăng nhập thành công nghĩa là token đã được verify, sub đọc trước đó là đúng
+		identityKey = info.IdentityHint
+	}
+
+	event := models.LoginEvent{
+		UserID:      user.ID,
+		Provider:    info.Provider,
+		IdentityKey: identityKey,
+		IP:          info.IP,
+		UserAgent:   info.UserAgent,
+		Hwid:        info.Hwid,
+		Outcome:     models.LoginOutcomeSuccess,
+	}
+	event.NewDevice, event.UnusualLocation = s.detectAnomalies(ctx, info, user)
+	s.insertEvent(ctx, event)
+	return &event
+}
+
+// detectAnomalies so sánh thiết bị và dải mạng với các lần đăng nhập thành công gần đây và các phiên đang có
+// Lần đăng nhập đầu tiên (chưa có gì để so sánh) không bị coi là bất thường
+func (s *LoginGuardService) detectAnomalies(ctx context.Context, info LoginRequestInfo, user *models.User) (newDevice bool, unusualLocation bool) {
+	knownDevices := map[string]bool{}
+	knownNetworks := map[string]bool{}
+	// Phiên của thiết bị hiện tại vừa được ghi đè bởi lần đăng nhập này nên không dùng để so sánh
+	for _, session := range user.Tokens {
+		if session.Hwid == info.Hwid {
+			continue
+		}
+		knownDevices[session.Hwid] = true
+		if network := loginNetwork(session.IP); network != "" {
+			knownNetworks[network] = true
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(loginAnomalyHistorySize).
+		SetProjection(bson.M{"hwid": 1, "ip": 1})
+	cursor, err := s.collection.Find(ctx, bson.M{"userId": user.ID, "outcome": models.LoginOutcomeSuccess}, opts)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID.Hex()).Warn("Failed to load login history")
+		return false, false
+	}
+	var history []models.LoginEvent
+	if err := cursor.All(ctx, &history); err != nil {
+		return false, false
+	}
+	for _, event := range history {
+		knownDevices[event.Hwid] = true
+		if network := loginNetwork(event.IP); network != "" {
+			knownNetworks[network] = true
+		}
+	}
+
+	if len(knownDevices) == 0 {
+		return false, false
+	}
+	newDevice = !knownDevices[info.Hwid]
+	if network := loginNetwork(info.IP); network != "" && len(knownNetworks) > 0 {
+		unusualLocation = !knownNetworks[network]
+	}
+	return newDevice, unusualLocation
+}
+
+// LoginProviderMFA giá trị provider của lịch sử xác thực 2 lớp (nhập sai / bị khóa) trong lịch sử đăng nhập
+const LoginProviderMFA = "mfa"
+
+// mfaCounter bộ đếm nhập sai mã xác thực 2 lớp của user, nil nếu MFA_MAX_FAILED_ATTEMPTS = 0
+func mfaCounter(userID primitive.ObjectID) *loginCounter {
+	maxFailures := global.MongoDB_ServerConfig.MfaMaxFailedAttempts
+	if maxFailures <= 0 {
+		return nil
+	}
+	return &loginCounter{models.LoginAttemptTypeMFA + ":" + userID.Hex(), models.LoginAttemptTypeMFA, maxFailures}
+}
+
+// MFALockedUntil trả về thời điểm (Unix milli) user hết bị khóa xác thực 2 lớp, 0 nếu không bị khóa
+func (s *LoginGuardService) MFALockedUntil(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	counter := mfaCounter(userID)
+	if counter == nil {
+		return 0, nil
+	}
+	var attempt models.LoginAttempt
+	err := s.attempts.FindOne(ctx, bson.M{"key": counter.key, "lockedUntil": bson.M{"$gt": time.Now().UnixMilli()}}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, common.ConvertMongoError(err)
+	}
+	return attempt.LockedUntil, nil
+}
+
+// MFALockedError lỗi trả về khi user đang bị khóa xác thực 2 lớp do nhập sai nhiều lần
+func MFALockedError(lockedUntil int64) error {
+	retryAfter := LoginRetryAfter(lockedUntil)
+	return common.NewError(
+		common.ErrCodeAuthMFA,
+		fmt.Sprintf("Nhập sai mã xác thực quá nhiều lần, vui lòng thử lại sau %d giây", retryAfter),
+		common.StatusTooManyRequests,
+		map[string]interface{}{
+			"lockedUntil": lockedUntil,
+			"retryAfter":  retryAfter,
+		},
+	)
+}
+
+// RecordMFAFailure tăng bộ đếm nhập sai mã xác thực 2 lớp của user (khóa khi đủ số lần) và lưu vào lịch sử đăng nhập của user
+// info.Provider bị bỏ qua, lịch sử luôn có provider LoginProviderMFA
+func (s *LoginGuardService) RecordMFAFailure(ctx context.Context, userID primitive.ObjectID, info LoginRequestInfo, reason string) {
+	if counter := mfaCounter(userID); counter != nil {
+		if err := s.incrementFailure(ctx, *counter); err != nil {
+			logrus.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to record mfa failure")
+		}
+	}
+	s.insertEvent(ctx, models.LoginEvent{
+		UserID:    userID,
+		Provider:  LoginProviderMFA,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Hwid:      info.Hwid,
+		Outcome:   models.LoginOutcomeFailure,
+		Reason:    reason,
+	})
+}
+
+// RecordMFALocked lưu lịch sử lần xác thực 2 lớp bị từ chối do đang bị khóa (không tăng bộ đếm)
+func (s *LoginGuardService) RecordMFALocked(ctx context.Context, userID primitive.ObjectID, info LoginRequestInfo) {
+	s.insertEvent(ctx, models.LoginEvent{
+		UserID:    userID,
+		Provider:  LoginProviderMFA,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Hwid:      info.Hwid,
+		Outcome:   models.LoginOutcomeLocked,
+	})
+}
+
+// RecordMFASuccess xóa bộ đếm nhập sai mã xác thực 2 lớp của user
+// Không lưu lịch sử: lần xác thực thành công đã có trong log hành động (mfa_verify...)
+func (s *LoginGuardService) RecordMFASuccess(ctx context.Context, userID primitive.ObjectID) {
+	if _, err := s.attempts.DeleteOne(ctx, bson.M{"key": models.LoginAttemptTypeMFA + ":" + userID.Hex()}); err != nil {
+		logrus.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to reset mfa failures")
+	}
+}
+
+// ListByUser lấy lịch sử đăng nhập của user, mới nhất trước
+func (s *LoginGuardService) ListByUser(ctx context.Context, userID primitive.ObjectID, page, limit int64) (*models.PaginateResult[models.LoginEvent], error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	return s.FindWithPagination(ctx, bson.M{"userId": userID}, page, limit, opts)
+}
+
+// incrementFailure tăng bộ đếm thất bại, khóa khi đạt maxFailures
+// Thời gian khóa: LOGIN_LOCKOUT_BASE_SECONDS * 2^(số lần đã khóa), tối đa LOGIN_LOCKOUT_MAX_SECONDS
+func (s *LoginGuardService) incrementFailure(ctx context.Context, counter loginCounter) error {
+	config := global.MongoDB_ServerConfig
+	now := time.Now()
+	nowMilli := now.UnixMilli()
+
+	// Lần thất bại trước đã quá LOGIN_FAILURE_WINDOW: đếm lại từ đầu
+	windowStart := nowMilli - int64(config.LoginFailureWindow)*1000
+	if _, err := s.attempts.UpdateOne(ctx,
+		bson.M{"key": counter.key, "lastFailureAt": bson.M{"$lt": windowStart}},
+		bson.M{"$set": bson.M{"failures": 0}},
+	); err != nil {
+		return common.ConvertMongoError(err)
+	}
+
+	var attempt models.LoginAttempt
+	err := s.attempts.FindOneAndUpdate(ctx,
+		bson.M{"key": counter.key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{
+				"lastFailureAt": nowMilli,
+				"expireAt":      now.Add(loginAttemptResetPeriod),
+				"updatedAt":     nowMilli,
+			},
+			"$setOnInsert": bson.M{
+				"type":      counter.counterType,
+				"lockCount": 0,
+				"createdAt": nowMilli,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if attempt.Failures < counter.maxFailures {
+		return nil
+	}
+
+	lockout := time.Duration(config.LoginLockoutBaseSeconds) * time.Second
+	maxLockout := time.Duration(config.LoginLockoutMaxSeconds) * time.Second
+	for i := 0; i < attempt.LockCount && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if maxLockout > 0 && lockout > maxLockout {
+		lockout = maxLockout
+	}
+	lockedUntil := now.Add(lockout)
+
+	// Điều kiện failures khớp để các request đồng thời chỉ khóa một lần
+	_, err = s.attempts.UpdateOne(ctx,
+		bson.M{"_id": attempt.ID, "failures": attempt.Failures},
+		bson.M{
+			"$set": bson.M{
+				"failures":    0,
+				"lockedUntil": lockedUntil.UnixMilli(),
+				"expireAt":    lockedUntil.Add(loginAttemptResetPeriod),
+			},
+			"$inc": bson.M{"lockCount": 1},
+		},
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"key":          counter.key,
+		"lock_count":   attempt.LockCount + 1,
+		"locked_until": lockedUntil.UnixMilli(),
+	}).Warn("Login locked after too many failed attempts")
+	return nil
+}
+
+// insertEvent ghi lịch sử đăng nhập trực tiếp (không qua audit trail), lỗi chỉ được log
+func (s *LoginGuardService) insertEvent(ctx context.Context, event models.LoginEvent) {
+	now := time.Now()
+	event.CreatedAt = now.UnixMilli()
+	if days := global.MongoDB_ServerConfig.LoginEventRetentionDays; days > 0 {
+		event.ExpireAt = now.AddDate(0, 0, days)
+	}
+	if _, err := s.collection.InsertOne(ctx, event); err != nil {
+		logrus.WithError(err).WithField("outcome", event.Outcome).Warn("Failed to write login event")
+	}
+}
+
+// loginNetwork trả về dải mạng của IP (IPv4 /16, IPv6 /48) để so sánh vị trí đăng nhập
+// Không dùng cơ sở dữ liệu GeoIP: đổi sang dải mạng khác được coi là vị trí lạ
+func loginNetwork(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mfaRecoveryCodeCount số recovery code sinh ra mỗi lần
+const mfaRecoveryCodeCount = 10
+
+// mfaTOTPSkew số bước thời gian (30 giây) được lệch so với đồng hồ server
+const mfaTOTPSkew = 1
+
+// ErrMFACodeInvalid mã TOTP/recovery code sai hoặc đã được dùng
+var ErrMFACodeInvalid = common.NewError(common.ErrCodeAuthMFA, "Mã xác thực không đúng hoặc đã được sử dụng", common.StatusBadRequest, nil)
+
+// ErrMFANotEnabled user chưa bật xác thực 2 lớp
+var ErrMFANotEnabled = common.NewError(common.ErrCodeAuthMFA, "Tài khoản chưa bật xác thực 2 lớp", common.StatusBadRequest, nil)
+
+// MFAService quản lý xác thực 2 lớp (TOTP) của user
+type MFAService struct {
+	*BaseServiceMongoImpl[models.UserMFA]
+	collection *mongo.Collection
+}
+
+// NewMFAService tạo mới MFAService
+func NewMFAService() (*MFAService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.UserMFA)
+	if !exist {
+		return nil, fmt.Errorf("failed to get user_mfa collection: %v", common.ErrNotFound)
+	}
+
+	return &MFAService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.UserMFA](collection),
+		collection:           collection,
+	}, nil
+}
+
+// mfaEncryptionKey trả về key AES-256 mã hóa secret TOTP
+// MFA_ENCRYPTION_KEY (base64 32 byte) nếu có, ngược lại dẫn xuất từ JWT_SECRET
+func mfaEncryptionKey() ([]byte, error) {
+	cfg := global.MongoDB_ServerConfig
+	if cfg.MfaEncryptionKey == "" {
+		sum := sha256.Sum256([]byte("mfa:" + cfg.JwtSecret))
+		return sum[:], nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.MfaEncryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, common.NewError(common.ErrCodeInternalServer, "MFA_ENCRYPTION_KEY phải là base64 của 32 byte", common.StatusInternalServerError, err)
+	}
+	return key, nil
+}
+
+// RequiresStepUp kiểm tra permission có nằm trong MFA_STEP_UP_PERMISSIONS không
+func RequiresStepUp(permissionName string) bool {
+	for _, name := range strings.Split(global.MongoDB_ServerConfig.MfaStepUpPermissions, ",") {
+		if strings.TrimSpace(name) == permissionName {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMFAFresh kiểm tra claims có claim MFA chưa quá MFA_STEP_UP_MAX_AGE giây
+func IsMFAFresh(claims *models.JwtToken) bool {
+	if claims == nil || claims.MfaAt == 0 {
+		return false
+	}
+	return time.Now().Unix()-claims.MfaAt <= int64(global.MongoDB_ServerConfig.MfaStepUpMaxAge)
+}
+
+// findByUser lấy cấu hình MFA của user, trả về nil nếu user chưa đăng ký
+func (s *MFAService) findByUser(ctx context.Context, userID primitive.ObjectID) (*models.UserMFA, error) {
+	var record models.UserMFA
+	if err := s.collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&record); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	return &record, nil
+}
+
+// GetStatus trả về trạng thái MFA của user (enabled = false nếu chưa đăng ký)
+func (s *MFAService) GetStatus(ctx context.Context, userID primitive.ObjectID) (*models.UserMFA, error) {
+	record, err := s.findByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return &models.UserMFA{UserID: userID}, nil
+	}
+	record.RecoveryCodesRemaining = len(record.RecoveryCodeHashes)
+	return record, nil
+}
+
+// IsEnabled kiểm tra user đã bật MFA chưa
+func (s *MFAService) IsEnabled(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"userId": userID, "enabled": true})
+	if err != nil {
+		return false, common.ConvertMongoError(err)
+	}
+	return count > 0, nil
+}
+
+// Enroll bắt đầu đăng ký TOTP: sinh secret mới (ghi đè lần đăng ký chưa xác nhận trước đó)
+// MFA chỉ được bật sau khi user xác nhận bằng mã đầu tiên (Confirm)
+func (s *MFAService) Enroll(ctx context.Context, user models.User) (*dto.MFAEnrollOutput, error) {
+	secret, err := utility.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	key, err := mfaEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utility.EncryptAESGCM(key, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	// Chỉ ghi đè khi chưa bật; đã bật thì upsert trùng unique userId → báo lỗi
+	_, err = s.collection.UpdateOne(ctx,
+		bson.M{"userId": user.ID, "enabled": false},
+		bson.M{
+			"$set": bson.M{
+				"secretEncrypted": encrypted,
+				"lastUsedStep":    0,
+				"updatedAt":       now,
+			},
+			"$setOnInsert": bson.M{
+				"userId":    user.ID,
+				"enabled":   false,
+				"createdAt": now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, common.NewError(common.ErrCodeBusinessState, "Xác thực 2 lớp đã được bật, hãy tắt trước khi đăng ký lại", common.StatusConflict, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	if account == "" {
+		account = user.ID.Hex()
+	}
+	return &dto.MFAEnrollOutput{
+		Secret:     secret,
+		OtpauthURL: utility.TOTPURI(global.MongoDB_ServerConfig.MfaIssuer, account, secret),
+	}, nil
+}
+
+// Confirm xác nhận đăng ký TOTP bằng mã đầu tiên, bật MFA và trả về recovery code (chỉ một lần)
+func (s *MFAService) Confirm(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	record, err := s.findByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, common.NewError(common.ErrCodeBusinessState, "Chưa bắt đầu đăng ký xác thực 2 lớp", common.StatusBadRequest, nil)
+	}
+	if record.Enabled {
+		return nil, common.NewError(common.ErrCodeBusinessState, "Xác thực 2 lớp đã được bật", common.StatusConflict, nil)
+	}
+
+	step, err := s.matchTOTP(record, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	// Điều kiện theo secret: tránh bật nhầm secret cũ nếu user vừa đăng ký lại ở nơi khác
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": record.ID, "enabled": false, "secretEncrypted": record.SecretEncrypted},
+		bson.M{"$set": bson.M{
+			"enabled":            true,
+			"enabledAt":          now,
+			"recoveryCodeHashes": hashes,
+			"lastUsedStep":       step,
+			"lastVerifiedAt":     now,
+			"updatedAt":          now,
+		}},
+	)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrMFACodeInvalid
+	}
+	return codes, nil
+}
+
+// Verify xác thực mã TOTP hoặc recovery code của user đã bật MFA
+// Mỗi mã TOTP (theo bước thời gian) và mỗi recovery code chỉ dùng được một lần
+func (s *MFAService) Verify(ctx context.Context, userID primitive.ObjectID, input dto.MFAVerifyInput) error {
+	record, err := s.findByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if record == nil || !record.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	now := time.Now().UnixMilli()
+	if input.Code != "" {
+		step, err := s.matchTOTP(record, input.Code)
+		if err != nil {
+			return err
+		}
+		result, err := s.collection.UpdateOne(ctx,
+			bson.M{"_id": record.ID, "enabled": true, "lastUsedStep": bson.M{"$lt": step}},
+			bson.M{"$set": bson.M{"lastUsedStep": step, "lastVerifiedAt": now, "updatedAt": now}},
+		)
+		if err != nil {
+			return common.ConvertMongoError(err)
+		}
+		if result.MatchedCount == 0 {
+			return ErrMFACodeInvalid
+		}
+		return nil
+	}
+
+	recoveryCode := normalizeRecoveryCode(input.RecoveryCode)
+	if recoveryCode == "" {
+		return common.NewError(common.ErrCodeValidationInput, "Cần nhập code hoặc recoveryCode", common.StatusBadRequest, nil)
+	}
+	hash := hashAPIKey(recoveryCode)
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": record.ID, "enabled": true, "recoveryCodeHashes": hash},
+		bson.M{
+			"$pull": bson.M{"recoveryCodeHashes": hash},
+			"$set":  bson.M{"lastVerifiedAt": now, "updatedAt": now},
+		},
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes tạo bộ recovery code mới (bộ cũ hết hiệu lực), yêu cầu mã TOTP
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, dto.MFAVerifyInput{Code: code}); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.collection.UpdateOne(ctx,
+		bson.M{"userId": userID, "enabled": true},
+		bson.M{"$set": bson.M{"recoveryCodeHashes": hashes, "updatedAt": time.Now().UnixMilli()}},
+	); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	return codes, nil
+}
+
+// Disable tắt MFA của user sau khi xác thực mã TOTP hoặc recovery code
+func (s *MFAService) Disable(ctx context.Context, userID primitive.ObjectID, input dto.MFAVerifyInput) error {
+	if err := s.Verify(ctx, userID, input); err != nil {
+		return err
+	}
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"userId": userID}); err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// matchTOTP giải mã secret và kiểm tra mã TOTP, trả về bước thời gian khớp
+func (s *MFAService) matchTOTP(record *models.UserMFA, code string) (int64, error) {
+	key, err := mfaEncryptionKey()
+	if err != nil {
+		return 0, err
+	}
+	secret, err := utility.DecryptAESGCM(key, record.SecretEncrypted)
+	if err != nil {
+		return 0, common.NewError(common.ErrCodeInternalServer, "Không giải mã được secret TOTP", common.StatusInternalServerError, err)
+	}
+	step, ok := utility.ValidateTOTP(string(secret), code, time.Now(), mfaTOTPSkew)
+	if !ok {
+		return 0, ErrMFACodeInvalid
+	}
+	return step, nil
+}
+
+// generateRecoveryCodes sinh recovery code dạng xxxx-xxxx-xxxx, trả về (code, SHA-256 của code)
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12])
+		hashes = append(hashes, hashAPIKey(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode bỏ dấu gạch, khoảng trắng và chuyển về chữ thường trước khi băm
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Các scope OIDC chuẩn, luôn được phép với authorization_code
+const (
+	OAuthScopeOpenID  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopeEmail   = "email"
+	OAuthScopePhone   = "phone"
+)
+
+var oauthStandardScopes = map[string]bool{
+	OAuthScopeOpenID:  true,
+	OAuthScopeProfile: true,
+	OAuthScopeEmail:   true,
+	OAuthScopePhone:   true,
+}
+
+// OAuthError là lỗi của token endpoint theo RFC 6749 mục 5.2
+// Dùng kiểu lỗi riêng thay cho common.Error vì thư viện OAuth phía client đọc đúng định dạng {error, error_description}
+type OAuthError struct {
+	Code        string // invalid_request, invalid_client, invalid_grant, unauthorized_client, unsupported_grant_type, invalid_scope
+	Description string
+	Status      int
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// newOAuthError tạo OAuthError với HTTP status theo mã lỗi
+func newOAuthError(code string, description string) *OAuthError {
+	status := common.StatusBadRequest
+	if code == "invalid_client" {
+		status = common.StatusUnauthorized
+	}
+	return &OAuthError{Code: code, Description: description, Status: status}
+}
+
+// ErrOAuthInvalidClient lỗi chung khi xác thực client thất bại (không tiết lộ lý do cụ thể)
+var ErrOAuthInvalidClient = newOAuthError("invalid_client", "Client authentication failed")
+
+// OAuthService phát hành authorization code, access token và ID token (RS256) cho các OAuth client
+type OAuthService struct {
+	codes           *BaseServiceMongoImpl[models.OAuthAuthorizationCode]
+	clientService   *OAuthClientService
+	keyService      *OAuthKeyService
+	userService     *UserService
+	roleService     *RoleService
+	userRoleService *UserRoleService
+}
+
+// NewOAuthService tạo mới OAuthService
+func NewOAuthService() (*OAuthService, error) {
+	codeCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.OAuthCodes)
+	if !exist {
+		return nil, fmt.Errorf("failed to get oauth_codes collection: %v", common.ErrNotFound)
+	}
+
+	clientService, err := NewOAuthClientService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth client service: %v", err)
+	}
+	keyService, err := NewOAuthKeyService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth key service: %v", err)
+	}
+	userService, err := NewUserService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user service: %v", err)
+	}
+	roleService, err := NewRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role service: %v", err)
+	}
+
+	return &OAuthService{
+		codes:           NewBaseServiceMongo[models.OAuthAuthorizationCode](codeCollection),
+		clientService:   clientService,
+		keyService:      keyService,
+		userService:     userService,
+		roleService:     roleService,
+		userRoleService: clientService.userRoleService,
+	}, nil
+}
+
+// Discovery trả về OpenID Provider Metadata (/.well-known/openid-configuration)
+func (s *OAuthService) Discovery() map[string]interface{} {
+	issuer := strings.TrimSuffix(global.MongoDB_ServerConfig.OAuthIssuerURL, "/")
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"jwks_uri":                              issuer + "/oauth/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{models.OAuthGrantAuthorizationCode, models.OAuthGrantClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{OAuthScopeOpenID, OAuthScopeProfile, OAuthScopeEmail, OAuthScopePhone},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce",
+			"name", "picture", "email", "email_verified", "phone_number", "phone_number_verified",
+			"role_id", "org_id",
+		},
+	}
+}
+
+// JWKS trả về bộ khóa công khai để verify token
+func (s *OAuthService) JWKS(ctx context.Context) (dto.OAuthJWKS, error) {
+	return s.keyService.JWKS(ctx)
+}
+
+// parseScopes tách chuỗi scope thành danh sách không trùng lặp
+func parseScopes(scope string) []string {
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, item := range strings.Fields(scope) {
+		if !seen[item] {
+			seen[item] = true
+			scopes = append(scopes, item)
+		}
+	}
+	return scopes
+}
+
+// hasScope kiểm tra danh sách scope có chứa scope cần tìm
+func hasScope(scopes []string, scope string) bool {
+	for _, item := range scopes {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRoleOrganization trả về tổ chức sở hữu của role (đưa vào claim org_id)
+func (s *OAuthService) resolveRoleOrganization(ctx context.Context, roleID *primitive.ObjectID) (*primitive.ObjectID, error) {
+	if roleID == nil || roleID.IsZero() {
+		return nil, nil
+	}
+	role, err := s.roleService.FindOneById(ctx, *roleID)
+	if err != nil {
+		return nil, err
+	}
+	return &role.OwnerOrganizationID, nil
+}
+
+// Authorize cấp authorization code cho user đang đăng nhập
+// activeRoleID (header X-Active-Role-ID) được ghi vào code và trở thành claim role_id/org_id của token
+func (s *OAuthService) Authorize(ctx context.Context, userID primitive.ObjectID, activeRoleID *primitive.ObjectID, input dto.OAuthAuthorizeInput) (*dto.OAuthAuthorizeOutput, error) {
+	if input.ResponseType != "code" {
+		return nil, common.NewError(common.ErrCodeValidationInput, "responseType chỉ hỗ trợ 'code'", common.StatusBadRequest, nil)
+	}
+
+	client, err := s.clientService.FindOne(ctx, bson.M{"clientId": input.ClientID}, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeValidationInput, "clientId không tồn tại", common.StatusBadRequest, nil)
+		}
+		return nil, err
+	}
+	if client.IsDisabled || !oauthClientAllowsGrant(&client, models.OAuthGrantAuthorizationCode) {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Client không được phép dùng authorization_code", common.StatusBadRequest, nil)
+	}
+
+	// Redirect URI phải khớp chính xác (không so khớp tiền tố) để tránh lộ code qua open redirect
+	redirectAllowed := false
+	for _, uri := range client.RedirectURIs {
+		if uri == input.RedirectURI {
+			redirectAllowed = true
+			break
+		}
+	}
+	if !redirectAllowed {
+		return nil, common.NewError(common.ErrCodeValidationInput, "redirectUri chưa được đăng ký cho client", common.StatusBadRequest, nil)
+	}
+
+	scopes := parseScopes(input.Scope)
+	if len(scopes) == 0 {
+		scopes = []string{OAuthScopeOpenID}
+	}
+	for _, scope := range scopes {
+		if !oauthStandardScopes[scope] && !hasScope(client.Scopes, scope) {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Scope '%s' không được phép cho client", scope), common.StatusBadRequest, nil)
+		}
+	}
+
+	if input.CodeChallenge != "" && input.CodeChallengeMethod != "S256" {
+		return nil, common.NewError(common.ErrCodeValidationInput, "codeChallengeMethod chỉ hỗ trợ S256", common.StatusBadRequest, nil)
+	}
+	if client.IsPublic && input.CodeChallenge == "" {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Client public bắt buộc dùng PKCE (codeChallenge)", common.StatusBadRequest, nil)
+	}
+
+	var organizationID *primitive.ObjectID
+	if activeRoleID != nil {
+		assigned, err := s.userRoleService.IsExist(ctx, userID, *activeRoleID)
+		if err != nil {
+			return nil, err
+		}
+		if !assigned {
+			return nil, common.NewError(common.ErrCodeAuthRole, "User không có role được chọn trong X-Active-Role-ID", common.StatusForbidden, nil)
+		}
+		if organizationID, err = s.resolveRoleOrganization(ctx, activeRoleID); err != nil {
+			return nil, err
+		}
+	}
+
+	codeBytes := make([]byte, 32)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể sinh authorization code", common.StatusInternalServerError, err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	now := time.Now()
+	if _, err := s.codes.InsertOne(ctx, models.OAuthAuthorizationCode{
+		CodeHash:            hashAPIKey(code),
+		ClientID:            client.ClientID,
+		UserID:              userID,
+		RedirectURI:         input.RedirectURI,
+		Scope:               strings.Join(scopes, " "),
+		Nonce:               input.Nonce,
+		CodeChallenge:       input.CodeChallenge,
+		CodeChallengeMethod: input.CodeChallengeMethod,
+		RoleID:              activeRoleID,
+		OrganizationID:      organizationID,
+		AuthTime:            now.Unix(),
+		ExpireAt:            now.Add(time.Duration(global.MongoDB_ServerConfig.OAuthCodeTTL) * time.Second),
+	}); err != nil {
+		return nil, err
+	}
+
+	redirectURL, err := url.Parse(input.RedirectURI)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationInput, "redirectUri không hợp lệ", common.StatusBadRequest, err)
+	}
+	query := redirectURL.Query()
+	query.Set("code", code)
+	if input.State != "" {
+		query.Set("state", input.State)
+	}
+	redirectURL.RawQuery = query.Encode()
+
+	return &dto.OAuthAuthorizeOutput{
+		Code:        code,
+		State:       input.State,
+		RedirectURL: redirectURL.String(),
+	}, nil
+}
+
+// Token xử lý token endpoint: xác thực client rồi phát hành token theo grant_type
+func (s *OAuthService) Token(ctx context.Context, input dto.OAuthTokenInput) (*dto.OAuthTokenOutput, error) {
+	if input.GrantType != models.OAuthGrantAuthorizationCode && input.GrantType != models.OAuthGrantClientCredentials {
+		return nil, newOAuthError("unsupported_grant_type", "grant_type must be authorization_code or client_credentials")
+	}
+
+	client, err := s.clientService.AuthenticateClient(ctx, input.ClientID, input.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !oauthClientAllowsGrant(client, input.GrantType) {
+		return nil, newOAuthError("unauthorized_client", "Client is not allowed to use this grant type")
+	}
+
+	if input.GrantType == models.OAuthGrantAuthorizationCode {
+		return s.exchangeCode(ctx, client, input)
+	}
+	return s.clientCredentials(ctx, client, input)
+}
+
+// exchangeCode đổi authorization code lấy access token (+ ID token nếu có scope openid)
+func (s *OAuthService) exchangeCode(ctx context.Context, client *models.OAuthClient, input dto.OAuthTokenInput) (*dto.OAuthTokenOutput, error) {
+	if input.Code == "" {
+		return nil, newOAuthError("invalid_request", "code is required")
+	}
+
+	// Xóa code ngay khi đọc để đảm bảo mỗi code chỉ dùng được một lần
+	var code models.OAuthAuthorizationCode
+	if err := s.codes.collection.FindOneAndDelete(ctx, bson.M{"codeHash": hashAPIKey(input.Code)}).Decode(&code); err != nil {
+		return nil, newOAuthError("invalid_grant", "Authorization code is invalid or has been used")
+	}
+	if time.Now().After(code.ExpireAt) {
+		return nil, newOAuthError("invalid_grant", "Authorization code has expired")
+	}
+	if code.ClientID != client.ClientID {
+		return nil, newOAuthError("invalid_grant", "Authorization code was issued to another client")
+	}
+	if code.RedirectURI != input.RedirectURI {
+		return nil, newOAuthError("invalid_grant", "redirect_uri does not match")
+	}
+	if code.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(input.CodeVerifier))
+		expected := base64.RawURLEncoding.EncodeToString(sum[:])
+		if input.CodeVerifier == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(code.CodeChallenge)) != 1 {
+			return nil, newOAuthError("invalid_grant", "code_verifier is invalid")
+		}
+	}
+
+	user, err := s.userService.FindOneById(ctx, code.UserID)
+	if err != nil || user.IsBlock {
+		return nil, newOAuthError("invalid_grant", "User is not available")
+	}
+
+	scopes := parseScopes(code.Scope)
+	accessToken, expiresIn, err := s.signAccessToken(ctx, client, user.ID.Hex(), code.Scope, code.RoleID, code.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	output := &dto.OAuthTokenOutput{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn,
+		Scope:       code.Scope,
+	}
+
+	if hasScope(scopes, OAuthScopeOpenID) {
+		idToken, err := s.signIDToken(ctx, client, &user, scopes, &code)
+		if err != nil {
+			return nil, err
+		}
+		output.IDToken = idToken
+	}
+	return output, nil
+}
+
+// clientCredentials phát hành access token cho service account của client
+func (s *OAuthService) clientCredentials(ctx context.Context, client *models.OAuthClient, input dto.OAuthTokenInput) (*dto.OAuthTokenOutput, error) {
+	if client.ServiceAccountID == nil {
+		return nil, newOAuthError("unauthorized_client", "Client has no service account")
+	}
+	account, err := s.clientService.serviceAccountService.FindOneById(ctx, *client.ServiceAccountID)
+	if err != nil || account.IsDisabled {
+		return nil, newOAuthError("unauthorized_client", "Service account is not available")
+	}
+
+	scopes := parseScopes(input.Scope)
+	for _, scope := range scopes {
+		if !hasScope(client.Scopes, scope) {
+			return nil, newOAuthError("invalid_scope", fmt.Sprintf("Scope '%s' is not allowed for this client", scope))
+		}
+	}
+	scope := strings.Join(scopes, " ")
+
+	organizationID, err := s.resolveRoleOrganization(ctx, client.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresIn, err := s.signAccessToken(ctx, client, account.ID.Hex(), scope, client.RoleID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OAuthTokenOutput{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn,
+		Scope:       scope,
+	}, nil
+}
+
+// signJWT ký claims bằng khóa RS256 đang active, header có kid để downstream chọn khóa trong JWKS
+func (s *OAuthService) signJWT(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	kid, privateKey, err := s.keyService.SigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(privateKey)
+}
+
+// addRoleClaims thêm role đang làm việc và tổ chức của role vào claims
+func addRoleClaims(claims jwt.MapClaims, roleID, organizationID *primitive.ObjectID) {
+	if roleID != nil && !roleID.IsZero() {
+		claims["role_id"] = roleID.Hex()
+	}
+	if organizationID != nil && !organizationID.IsZero() {
+		claims["org_id"] = organizationID.Hex()
+	}
+}
+
+// signAccessToken tạo access token RS256 (aud = client_id)
+func (s *OAuthService) signAccessToken(ctx context.Context, client *models.OAuthClient, subject string, scope string, roleID, organizationID *primitive.ObjectID) (string, int64, error) {
+	jti, err := utility.NewTokenID()
+	if err != nil {
+		return "", 0, err
+	}
+	ttl := int64(global.MongoDB_ServerConfig.OAuthAccessTokenTTL)
+	now := time.Now().Unix()
+	claims := jwt.MapClaims{
+		"iss":       strings.TrimSuffix(global.MongoDB_ServerConfig.OAuthIssuerURL, "/"),
+		"sub":       subject,
+		"aud":       client.ClientID,
+		"client_id": client.ClientID,
+		"iat":       now,
+		"nbf":       now,
+		"exp":       now + ttl,
+		"jti":       jti,
+		"token_use": "access",
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	addRoleClaims(claims, roleID, organizationID)
+
+	token, err := s.signJWT(ctx, claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, ttl, nil
+}
+
+// signIDToken tạo ID token OIDC với các claim theo scope đã cấp
+func (s *OAuthService) signIDToken(ctx context.Context, client *models.OAuthClient, user *models.User, scopes []string, code *models.OAuthAuthorizationCode) (string, error) {
+	now := time.Now().Unix()
+	claims := jwt.MapClaims{
+		"iss":       strings.TrimSuffix(global.MongoDB_ServerConfig.OAuthIssuerURL, "/"),
+		"sub":       user.ID.Hex(),
+		"aud":       client.ClientID,
+		"iat":       now,
+		"exp":       now + int64(global.MongoDB_ServerConfig.OAuthIDTokenTTL),
+		"auth_time": code.AuthTime,
+		"token_use": "id",
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	if hasScope(scopes, OAuthScopeProfile) {
+		claims["name"] = user.Name
+		if user.AvatarURL != "" {
+			claims["picture"] = user.AvatarURL
+		}
+	}
+	if hasScope(scopes, OAuthScopeEmail) && user.Email != "" {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerified
+	}
+	if hasScope(scopes, OAuthScopePhone) && user.Phone != "" {
+		claims["phone_number"] = user.Phone
+		claims["phone_number_verified"] = user.PhoneVerified
+	}
+	addRoleClaims(claims, code.RoleID, code.OrganizationID)
+
+	return s.signJWT(ctx, claims)
+}
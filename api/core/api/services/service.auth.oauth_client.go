@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthClientService là service quản lý OAuth client đăng ký nhận token
+type OAuthClientService struct {
+	*BaseServiceMongoImpl[models.OAuthClient]
+	serviceAccountService *ServiceAccountService
+	userRoleService       *UserRoleService
+}
+
+// NewOAuthClientService tạo mới OAuthClientService
+func NewOAuthClientService() (*OAuthClientService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.OAuthClients)
+	if !exist {
+		return nil, fmt.Errorf("failed to get oauth_clients collection: %v", common.ErrNotFound)
+	}
+
+	serviceAccountService, err := NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
+	}
+
+	return &OAuthClientService{
+		BaseServiceMongoImpl:  NewBaseServiceMongo[models.OAuthClient](collection),
+		serviceAccountService: serviceAccountService,
+		userRoleService:       userRoleService,
+	}, nil
+}
+
+// generateOAuthClientCredentials sinh client_id và client secret (secret rỗng nếu isPublic)
+func generateOAuthClientCredentials(isPublic bool) (string, string, error) {
+	idBytes := make([]byte, 12)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	clientID := models.OAuthClientIDPrefix + hex.EncodeToString(idBytes)
+	if isPublic {
+		return clientID, "", nil
+	}
+
+	secret, err := generateOAuthClientSecret()
+	if err != nil {
+		return "", "", err
+	}
+	return clientID, secret, nil
+}
+
+// generateOAuthClientSecret sinh client secret ngẫu nhiên 256 bit (base64url)
+func generateOAuthClientSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// validateClientConfig kiểm tra grant type, redirect URI, scope và service account của client
+func (s *OAuthClientService) validateClientConfig(ctx context.Context, client *models.OAuthClient) error {
+	if len(client.GrantTypes) == 0 {
+		return common.NewError(common.ErrCodeValidationInput, "grantTypes không được để trống", common.StatusBadRequest, nil)
+	}
+
+	hasAuthCode, hasClientCredentials := false, false
+	for _, grant := range client.GrantTypes {
+		switch grant {
+		case models.OAuthGrantAuthorizationCode:
+			hasAuthCode = true
+		case models.OAuthGrantClientCredentials:
+			hasClientCredentials = true
+		default:
+			return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("grantType '%s' không được hỗ trợ", grant), common.StatusBadRequest, nil)
+		}
+	}
+
+	if hasAuthCode {
+		if len(client.RedirectURIs) == 0 {
+			return common.NewError(common.ErrCodeValidationInput, "redirectUris là bắt buộc khi dùng authorization_code", common.StatusBadRequest, nil)
+		}
+		for _, redirectURI := range client.RedirectURIs {
+			parsed, err := url.Parse(redirectURI)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" {
+				return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("redirectUri '%s' phải là URL tuyệt đối và không có fragment", redirectURI), common.StatusBadRequest, nil)
+			}
+		}
+	}
+
+	for _, scope := range client.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\"\\") {
+			return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Scope '%s' không hợp lệ", scope), common.StatusBadRequest, nil)
+		}
+	}
+
+	if !hasClientCredentials {
+		client.ServiceAccountID = nil
+		client.RoleID = nil
+		return nil
+	}
+
+	// client_credentials: token đại diện cho service account nên client phải giữ được secret
+	if client.IsPublic {
+		return common.NewError(common.ErrCodeValidationInput, "Client public không được dùng client_credentials", common.StatusBadRequest, nil)
+	}
+	if client.ServiceAccountID == nil || client.ServiceAccountID.IsZero() {
+		return common.NewError(common.ErrCodeValidationInput, "serviceAccountId là bắt buộc khi dùng client_credentials", common.StatusBadRequest, nil)
+	}
+	account, err := s.serviceAccountService.FindOneById(ctx, *client.ServiceAccountID)
+	if err != nil {
+		return common.NewError(common.ErrCodeValidationInput, "Service account không tồn tại", common.StatusBadRequest, err)
+	}
+	if account.OwnerOrganizationID != client.OwnerOrganizationID {
+		return common.NewError(common.ErrCodeValidationInput, "Service account phải thuộc cùng tổ chức với OAuth client", common.StatusBadRequest, nil)
+	}
+	if client.RoleID != nil && !client.RoleID.IsZero() {
+		assigned, err := s.userRoleService.IsExist(ctx, account.ID, *client.RoleID)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return common.NewError(common.ErrCodeValidationInput, "roleId phải là role đã gán cho service account", common.StatusBadRequest, nil)
+		}
+	}
+	return nil
+}
+
+// InsertOne override method InsertOne để sinh client_id/secret và kiểm tra cấu hình
+// Client secret chỉ được trả về một lần trong ClientSecret
+func (s *OAuthClientService) InsertOne(ctx context.Context, data models.OAuthClient) (models.OAuthClient, error) {
+	if data.OwnerOrganizationID.IsZero() {
+		return data, common.NewError(common.ErrCodeValidationInput, "OAuth client phải thuộc một tổ chức (ownerOrganizationId)", common.StatusBadRequest, nil)
+	}
+	if err := s.validateClientConfig(ctx, &data); err != nil {
+		return data, err
+	}
+
+	clientID, secret, err := generateOAuthClientCredentials(data.IsPublic)
+	if err != nil {
+		return data, common.NewError(common.ErrCodeInternalServer, "Không thể sinh client credentials", common.StatusInternalServerError, err)
+	}
+	data.ClientID = clientID
+	data.ClientSecretHash = ""
+	if secret != "" {
+		data.ClientSecretHash = hashAPIKey(secret)
+	}
+	data.IsDisabled = false
+	if userID, ok := GetUserIDFromContext(ctx); ok {
+		data.CreatedBy = userID
+	}
+
+	created, err := s.BaseServiceMongoImpl.InsertOne(ctx, data)
+	if err != nil {
+		return created, err
+	}
+	created.ClientSecret = secret
+	return created, nil
+}
+
+// UpdateClient cập nhật cấu hình client (không đổi được clientId, secret và tổ chức sở hữu)
+func (s *OAuthClientService) UpdateClient(ctx context.Context, id primitive.ObjectID, input dto.OAuthClientUpdateInput) (*models.OAuthClient, error) {
+	client, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		if *input.Name == "" {
+			return nil, common.NewError(common.ErrCodeValidationInput, "name không được để trống", common.StatusBadRequest, nil)
+		}
+		client.Name = *input.Name
+	}
+	if input.Describe != nil {
+		client.Describe = *input.Describe
+	}
+	if input.RedirectURIs != nil {
+		client.RedirectURIs = input.RedirectURIs
+	}
+	if input.GrantTypes != nil {
+		client.GrantTypes = input.GrantTypes
+	}
+	if input.Scopes != nil {
+		client.Scopes = input.Scopes
+	}
+	if input.ServiceAccountID != nil {
+		client.ServiceAccountID = nil
+		if *input.ServiceAccountID != "" {
+			accountID, err := primitive.ObjectIDFromHex(*input.ServiceAccountID)
+			if err != nil {
+				return nil, common.NewError(common.ErrCodeValidationFormat, "serviceAccountId không hợp lệ", common.StatusBadRequest, err)
+			}
+			client.ServiceAccountID = &accountID
+		}
+	}
+	if input.RoleID != nil {
+		client.RoleID = nil
+		if *input.RoleID != "" {
+			roleID, err := primitive.ObjectIDFromHex(*input.RoleID)
+			if err != nil {
+				return nil, common.NewError(common.ErrCodeValidationFormat, "roleId không hợp lệ", common.StatusBadRequest, err)
+			}
+			client.RoleID = &roleID
+		}
+	}
+	if input.IsDisabled != nil {
+		client.IsDisabled = *input.IsDisabled
+	}
+
+	if err := s.validateClientConfig(ctx, &client); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, client.ID, &UpdateData{
+		Set: map[string]interface{}{
+			"name":             client.Name,
+			"describe":         client.Describe,
+			"redirectUris":     client.RedirectURIs,
+			"grantTypes":       client.GrantTypes,
+			"scopes":           client.Scopes,
+			"serviceAccountId": client.ServiceAccountID,
+			"roleId":           client.RoleID,
+			"isDisabled":       client.IsDisabled,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RotateSecret sinh client secret mới, secret cũ hết hiệu lực ngay
+func (s *OAuthClientService) RotateSecret(ctx context.Context, id primitive.ObjectID) (*models.OAuthClient, error) {
+	client, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Client public không có client secret", common.StatusBadRequest, nil)
+	}
+
+	secret, err := generateOAuthClientSecret()
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể sinh client secret", common.StatusInternalServerError, err)
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": client.ID}, bson.M{"$set": bson.M{"clientSecretHash": hashAPIKey(secret)}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	client.ClientSecret = secret
+	return &client, nil
+}
+
+// AuthenticateClient xác thực client tại token endpoint
+// Client confidential phải gửi đúng secret; client public không được gửi secret
+func (s *OAuthClientService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrOAuthInvalidClient
+	}
+	client, err := s.FindOne(ctx, bson.M{"clientId": clientID}, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+	if client.IsDisabled {
+		return nil, ErrOAuthInvalidClient
+	}
+
+	if client.IsPublic {
+		if clientSecret != "" {
+			return nil, ErrOAuthInvalidClient
+		}
+		return &client, nil
+	}
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hashAPIKey(clientSecret)), []byte(client.ClientSecretHash)) != 1 {
+		return nil, ErrOAuthInvalidClient
+	}
+	return &client, nil
+}
+
+// oauthClientAllowsGrant kiểm tra client được phép dùng grant type
+func oauthClientAllowsGrant(client *models.OAuthClient, grantType string) bool {
+	for _, grant := range client.GrantTypes {
+		if grant == grantType {
+			return true
+		}
+	}
+	return false
+}
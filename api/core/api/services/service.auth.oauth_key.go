@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// oauthSigningKeyBits là độ dài khóa RSA dùng để ký token
+	oauthSigningKeyBits = 2048
+	// oauthKeyCacheTTL là thời gian cache bộ khóa trong bộ nhớ (các instance khác thấy khóa mới sau tối đa khoảng này)
+	oauthKeyCacheTTL = time.Minute
+)
+
+// oauthKeyCache cache bộ khóa ký dùng chung cho mọi OAuthKeyService trong process
+var oauthKeyCache struct {
+	mu         sync.RWMutex
+	keys       []models.OAuthSigningKey // Khóa active + khóa retired còn trong thời gian công bố (mới nhất trước)
+	activeKey  *rsa.PrivateKey
+	activeKid  string
+	activeAt   int64
+	loadedAt   time.Time
+	rotateLock sync.Mutex
+}
+
+// OAuthKeyService quản lý khóa RSA ký token OAuth/OIDC và bộ khóa công khai JWKS
+type OAuthKeyService struct {
+	*BaseServiceMongoImpl[models.OAuthSigningKey]
+}
+
+// NewOAuthKeyService tạo mới OAuthKeyService
+func NewOAuthKeyService() (*OAuthKeyService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.OAuthKeys)
+	if !exist {
+		return nil, fmt.Errorf("failed to get oauth_signing_keys collection: %v", common.ErrNotFound)
+	}
+
+	return &OAuthKeyService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.OAuthSigningKey](collection),
+	}, nil
+}
+
+// oauthKeyRetention là thời gian khóa retired còn được công bố trong JWKS
+// Bằng thời hạn dài nhất của token đã ký để downstream vẫn verify được token cũ sau khi xoay vòng
+func oauthKeyRetention() time.Duration {
+	cfg := global.MongoDB_ServerConfig
+	ttl := cfg.OAuthAccessTokenTTL
+	if cfg.OAuthIDTokenTTL > ttl {
+		ttl = cfg.OAuthIDTokenTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// loadKeys đọc bộ khóa từ DB vào cache nếu cache đã cũ
+func (s *OAuthKeyService) loadKeys(ctx context.Context, force bool) error {
+	oauthKeyCache.mu.RLock()
+	fresh := !force && time.Since(oauthKeyCache.loadedAt) < oauthKeyCacheTTL
+	oauthKeyCache.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	retiredAfter := time.Now().Add(-oauthKeyRetention()).UnixMilli()
+	keys, err := s.Find(ctx, bson.M{"$or": []bson.M{
+		{"status": models.OAuthSigningKeyActive},
+		{"status": models.OAuthSigningKeyRetired, "retiredAt": bson.M{"$gt": retiredAfter}},
+	}}, mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil && err != common.ErrNotFound {
+		return err
+	}
+
+	var activeKey *rsa.PrivateKey
+	var activeKid string
+	var activeAt int64
+	for _, key := range keys {
+		if key.Status != models.OAuthSigningKeyActive {
+			continue
+		}
+		privateKey, err := utility.ParseRsaPrivateKeyFromPemStr(key.PrivateKey)
+		if err != nil {
+			logrus.WithError(err).WithField("kid", key.Kid).Error("OAuth: Không parse được khóa ký, bỏ qua")
+			continue
+		}
+		activeKey, activeKid, activeAt = privateKey, key.Kid, key.CreatedAt
+		break
+	}
+
+	oauthKeyCache.mu.Lock()
+	oauthKeyCache.keys = keys
+	oauthKeyCache.activeKey = activeKey
+	oauthKeyCache.activeKid = activeKid
+	oauthKeyCache.activeAt = activeAt
+	oauthKeyCache.loadedAt = time.Now()
+	oauthKeyCache.mu.Unlock()
+	return nil
+}
+
+// SigningKey trả về khóa đang dùng để ký token (kid, private key)
+// Tự tạo khóa đầu tiên nếu chưa có và tự xoay vòng khi khóa quá OAUTH_KEY_ROTATION_DAYS
+func (s *OAuthKeyService) SigningKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	if err := s.loadKeys(ctx, false); err != nil {
+		return "", nil, err
+	}
+
+	oauthKeyCache.mu.RLock()
+	kid, key, createdAt := oauthKeyCache.activeKid, oauthKeyCache.activeKey, oauthKeyCache.activeAt
+	oauthKeyCache.mu.RUnlock()
+
+	rotationDays := global.MongoDB_ServerConfig.OAuthKeyRotationDays
+	expired := rotationDays > 0 && time.Since(time.UnixMilli(createdAt)) > time.Duration(rotationDays)*24*time.Hour
+	if key != nil && !expired {
+		return kid, key, nil
+	}
+
+	// Chỉ một goroutine xoay vòng; các goroutine khác đọc lại cache sau khi xong
+	oauthKeyCache.rotateLock.Lock()
+	defer oauthKeyCache.rotateLock.Unlock()
+	if err := s.loadKeys(ctx, true); err != nil {
+		return "", nil, err
+	}
+	oauthKeyCache.mu.RLock()
+	kid, key, createdAt = oauthKeyCache.activeKid, oauthKeyCache.activeKey, oauthKeyCache.activeAt
+	oauthKeyCache.mu.RUnlock()
+	expired = rotationDays > 0 && time.Since(time.UnixMilli(createdAt)) > time.Duration(rotationDays)*24*time.Hour
+	if key != nil && !expired {
+		return kid, key, nil
+	}
+
+	if _, err := s.rotate(ctx); err != nil {
+		return "", nil, err
+	}
+	oauthKeyCache.mu.RLock()
+	defer oauthKeyCache.mu.RUnlock()
+	if oauthKeyCache.activeKey == nil {
+		return "", nil, common.NewError(common.ErrCodeInternalServer, "Không có khóa ký token khả dụng", common.StatusInternalServerError, nil)
+	}
+	return oauthKeyCache.activeKid, oauthKeyCache.activeKey, nil
+}
+
+// Rotate tạo khóa ký mới; khóa cũ chuyển sang retired và vẫn được công bố trong JWKS đến hết thời hạn token đã ký
+func (s *OAuthKeyService) Rotate(ctx context.Context) (*models.OAuthSigningKey, error) {
+	oauthKeyCache.rotateLock.Lock()
+	defer oauthKeyCache.rotateLock.Unlock()
+	return s.rotate(ctx)
+}
+
+// rotate thực hiện xoay vòng khóa (caller phải giữ rotateLock)
+func (s *OAuthKeyService) rotate(ctx context.Context) (*models.OAuthSigningKey, error) {
+	privateKey, publicKey, err := utility.GenerateKeyPair(oauthSigningKeyBits)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể tạo khóa ký", common.StatusInternalServerError, err)
+	}
+	publicPem, err := utility.ExportRsaPublicKeyAsPemStr(publicKey)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể xuất khóa công khai", common.StatusInternalServerError, err)
+	}
+	kid, err := utility.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.InsertOne(ctx, models.OAuthSigningKey{
+		Kid:        kid,
+		Algorithm:  "RS256",
+		PrivateKey: utility.ExportRsaPrivateKeyAsPemStr(privateKey),
+		PublicKey:  publicPem,
+		Status:     models.OAuthSigningKeyActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if _, err := s.collection.UpdateMany(ctx,
+		bson.M{"status": models.OAuthSigningKeyActive, "_id": bson.M{"$ne": created.ID}},
+		bson.M{"$set": bson.M{"status": models.OAuthSigningKeyRetired, "retiredAt": now.UnixMilli()}},
+	); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	// Xóa hẳn các khóa retired đã quá thời gian công bố (không còn token nào hợp lệ được ký bằng chúng)
+	if _, err := s.collection.DeleteMany(ctx, bson.M{
+		"status":    models.OAuthSigningKeyRetired,
+		"retiredAt": bson.M{"$lte": now.Add(-oauthKeyRetention()).UnixMilli()},
+	}); err != nil {
+		logrus.WithError(err).Warn("OAuth: Lỗi khi dọn khóa ký cũ")
+	}
+
+	logrus.WithField("kid", kid).Info("OAuth: Đã xoay vòng khóa ký token")
+	if err := s.loadKeys(ctx, true); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListPublicKeys trả về các khóa đang được công bố (active + retired còn hiệu lực), không gồm khóa riêng
+func (s *OAuthKeyService) ListPublicKeys(ctx context.Context) ([]models.OAuthSigningKey, error) {
+	if err := s.loadKeys(ctx, false); err != nil {
+		return nil, err
+	}
+	oauthKeyCache.mu.RLock()
+	defer oauthKeyCache.mu.RUnlock()
+	return append([]models.OAuthSigningKey{}, oauthKeyCache.keys...), nil
+}
+
+// JWKS trả về bộ khóa công khai theo định dạng RFC 7517 để downstream verify token offline
+func (s *OAuthKeyService) JWKS(ctx context.Context) (dto.OAuthJWKS, error) {
+	keys, err := s.ListPublicKeys(ctx)
+	if err != nil {
+		return dto.OAuthJWKS{}, err
+	}
+
+	jwks := dto.OAuthJWKS{Keys: make([]dto.OAuthJSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		publicKey, err := utility.ParseRsaPublicKeyFromPemStr(key.PublicKey)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, dto.OAuthJSONWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: key.Algorithm,
+			Kid: key.Kid,
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		})
+	}
+	return jwks, nil
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
 	"meta_commerce/core/global"
@@ -63,6 +65,77 @@ func (s *OrganizationService) GetChildrenIDs(ctx context.Context, parentID primi
 	return ids, nil
 }
 
+// GetChildrenIDsGroupedByPath lấy ID organization con của nhiều organization trong một truy vấn, nhóm theo path cha
+// Kết quả của mỗi path gồm cả chính organization có path đó
+// Dùng khi cần biết tổ chức con nào đến từ tổ chức cha nào (vd: giải thích quyền)
+func (s *OrganizationService) GetChildrenIDsGroupedByPath(ctx context.Context, paths []string) (map[string][]primitive.ObjectID, error) {
+	result := make(map[string][]primitive.ObjectID, len(paths))
+	conditions := make([]bson.M, 0, len(paths)*2)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		conditions = append(conditions, subtreePathConditions(path)...)
+	}
+	if len(conditions) == 0 {
+		return result, nil
+	}
+
+	orgs, err := s.Find(ctx, bson.M{"$or": conditions, "isActive": true}, mongoopts.Find().SetProjection(bson.M{"_id": 1, "path": 1}))
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		for _, org := range orgs {
+			if org.Path == path || strings.HasPrefix(org.Path, path+"/") {
+				result[path] = append(result[path], org.ID)
+			}
+		}
+	}
+	return result, nil
+}
+
+// subtreePathConditions trả về điều kiện khớp organization có path đúng bằng path và mọi organization con của nó
+// Tiền tố con phải kết thúc bằng "/" để "/ACME/SALES" không khớp tổ chức ngang cấp "/ACME/SALES2"
+func subtreePathConditions(path string) []bson.M {
+	return []bson.M{
+		{"path": path},
+		{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(path+"/")}},
+	}
+}
+
+// GetChildrenIDsByPaths lấy ID của tất cả organization con của nhiều organization trong một truy vấn
+// paths: Path của các organization cha (dùng cho Scope = 1 khi tính quyền của nhiều role cùng lúc)
+func (s *OrganizationService) GetChildrenIDsByPaths(ctx context.Context, paths []string) ([]primitive.ObjectID, error) {
+	conditions := make([]bson.M, 0, len(paths)*2)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		conditions = append(conditions, subtreePathConditions(path)...)
+	}
+	if len(conditions) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+
+	values, err := s.Distinct(ctx, "_id", bson.M{"$or": conditions, "isActive": true})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // GetParentIDs lấy tất cả ID của organization cha (dùng cho inverse lookup - xem dữ liệu cấp trên)
 // Đi ngược lên cây organization để lấy tất cả parent IDs
 func (s *OrganizationService) GetParentIDs(ctx context.Context, childID primitive.ObjectID) ([]primitive.ObjectID, error) {
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OrganizationPurgeResult là số bản ghi đã xóa khi purge tổ chức
+type OrganizationPurgeResult struct {
+	Organizations   int64 `json:"organizations"`
+	Roles           int64 `json:"roles"`
+	RolePermissions int64 `json:"rolePermissions"`
+	UserRoles       int64 `json:"userRoles"`
+	Shares          int64 `json:"shares"`
+}
+
+// subtreeFilter trả về filter cho tổ chức và toàn bộ tổ chức con
+func subtreeFilter(org models.Organization) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"_id": org.ID},
+		bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(org.Path+"/")}},
+	}}
+}
+
+// ArchiveOrganization lưu trữ tổ chức cùng toàn bộ tổ chức con (isActive = false, archivedAt, archivedBy)
+// Sau khi lưu trữ: tổ chức không còn trong phạm vi dữ liệu và role thuộc tổ chức không còn cấp quyền
+func (s *OrganizationService) ArchiveOrganization(ctx context.Context, orgID, archivedBy primitive.ObjectID) (*models.Organization, error) {
+	org, err := s.FindOneById(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.Type == models.OrganizationTypeSystem || org.IsSystem {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể lưu trữ tổ chức hệ thống", common.StatusForbidden, nil)
+	}
+	if org.ArchivedAt != nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Tổ chức đã được lưu trữ", common.StatusConflict, nil)
+	}
+
+	now := time.Now().Unix()
+	filter := subtreeFilter(org)
+	filter["archivedAt"] = bson.M{"$exists": false}
+	_, err = s.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"isActive":   false,
+		"archivedAt": now,
+		"archivedBy": archivedBy,
+		"updatedAt":  now,
+	}})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	publishAllPermissionChange()
+	recordAuditEntry(ctx, models.AuthLog{
+		Collection:          s.collection.Name(),
+		DocumentID:          org.ID,
+		OwnerOrganizationID: org.ID,
+		Action:              "archive",
+		Describe:            "Lưu trữ tổ chức cùng các tổ chức con",
+	})
+
+	org.IsActive = false
+	org.ArchivedAt = &now
+	org.ArchivedBy = &archivedBy
+	org.UpdatedAt = now
+	return &org, nil
+}
+
+// RestoreOrganization khôi phục tổ chức đã lưu trữ cùng các tổ chức con được lưu trữ cùng lúc
+// Tổ chức con được lưu trữ riêng trước đó vẫn giữ trạng thái lưu trữ
+func (s *OrganizationService) RestoreOrganization(ctx context.Context, orgID primitive.ObjectID) (*models.Organization, error) {
+	org, err := s.FindOneById(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ArchivedAt == nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Tổ chức chưa được lưu trữ", common.StatusBadRequest, nil)
+	}
+	if org.ParentID != nil {
+		parent, err := s.FindOneById(ctx, *org.ParentID)
+		if err != nil && err != common.ErrNotFound {
+			return nil, err
+		}
+		if err == nil && parent.ArchivedAt != nil {
+			return nil, common.NewError(common.ErrCodeBusinessOperation, "Tổ chức cha đang được lưu trữ, cần khôi phục tổ chức cha trước", common.StatusBadRequest, nil)
+		}
+	}
+
+	now := time.Now().Unix()
+	filter := subtreeFilter(org)
+	filter["archivedAt"] = *org.ArchivedAt
+	_, err = s.collection.UpdateMany(ctx, filter, bson.M{
+		"$set":   bson.M{"isActive": true, "updatedAt": now},
+		"$unset": bson.M{"archivedAt": "", "archivedBy": ""},
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	publishAllPermissionChange()
+	recordAuditEntry(ctx, models.AuthLog{
+		Collection:          s.collection.Name(),
+		DocumentID:          org.ID,
+		OwnerOrganizationID: org.ID,
+		Action:              "restore",
+		Describe:            "Khôi phục tổ chức cùng các tổ chức con được lưu trữ cùng lúc",
+	})
+
+	org.IsActive = true
+	org.ArchivedAt = nil
+	org.ArchivedBy = nil
+	org.UpdatedAt = now
+	return &org, nil
+}
+
+// PurgeOrganization xóa vĩnh viễn tổ chức đã lưu trữ cùng toàn bộ tổ chức con, role, role permission,
+// user role và share liên quan (trong một transaction)
+// Chỉ được purge khi đã lưu trữ đủ retention; dữ liệu nghiệp vụ thuộc tổ chức không bị xóa
+func (s *OrganizationService) PurgeOrganization(ctx context.Context, orgID primitive.ObjectID, retention time.Duration) (*OrganizationPurgeResult, error) {
+	org, err := s.FindOneById(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ArchivedAt == nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Chỉ được xóa vĩnh viễn tổ chức đã lưu trữ", common.StatusBadRequest, nil)
+	}
+	purgeableAt := time.Unix(*org.ArchivedAt, 0).Add(retention)
+	if time.Now().Before(purgeableAt) {
+		return nil, common.NewError(common.ErrCodeBusinessOperation,
+			fmt.Sprintf("Tổ chức chỉ được xóa vĩnh viễn sau %s", purgeableAt.Format(time.RFC3339)),
+			common.StatusBadRequest, nil)
+	}
+
+	// Toàn bộ cây con phải đang lưu trữ (không xóa tổ chức con còn hoạt động)
+	activeFilter := subtreeFilter(org)
+	activeFilter["archivedAt"] = bson.M{"$exists": false}
+	activeCount, err := s.CountDocuments(ctx, activeFilter)
+	if err != nil {
+		return nil, err
+	}
+	if activeCount > 0 {
+		return nil, common.NewError(common.ErrCodeBusinessOperation,
+			fmt.Sprintf("Có %d tổ chức con chưa được lưu trữ", activeCount),
+			common.StatusBadRequest, nil)
+	}
+
+	orgIDs, err := s.Distinct(ctx, "_id", subtreeFilter(org))
+	if err != nil {
+		return nil, err
+	}
+	roleIDs, err := s.roleService.Distinct(ctx, "_id", bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}})
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+
+	rolePermissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RolePermissions)
+	if !exist {
+		return nil, fmt.Errorf("failed to get role_permissions collection: %v", common.ErrNotFound)
+	}
+	userRoleCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.UserRoles)
+	if !exist {
+		return nil, fmt.Errorf("failed to get user_roles collection: %v", common.ErrNotFound)
+	}
+	shareService, err := NewOrganizationShareService()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer session.EndSession(ctx)
+
+	result := &OrganizationPurgeResult{}
+	// Audit của transaction chỉ được ghi sau khi commit
+	txCtx, audits := withAuditBuffer(ctx)
+	_, err = session.WithTransaction(txCtx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		*result = OrganizationPurgeResult{}
+		audits.reset()
+		if len(roleIDs) > 0 {
+			res, err := userRoleCollection.DeleteMany(sessCtx, bson.M{"roleId": bson.M{"$in": roleIDs}})
+			if err != nil {
+				return nil, err
+			}
+			result.UserRoles = res.DeletedCount
+
+			res, err = rolePermissionCollection.DeleteMany(sessCtx, bson.M{"roleId": bson.M{"$in": roleIDs}})
+			if err != nil {
+				return nil, err
+			}
+			result.RolePermissions = res.DeletedCount
+
+			res, err = s.roleService.collection.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": roleIDs}})
+			if err != nil {
+				return nil, err
+			}
+			result.Roles = res.DeletedCount
+		}
+
+		res, err := shareService.collection.DeleteMany(sessCtx, bson.M{"$or": bson.A{
+			bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}},
+			bson.M{"toOrgId": bson.M{"$in": orgIDs}},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		result.Shares = res.DeletedCount
+
+		res, err = s.collection.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": orgIDs}})
+		if err != nil {
+			return nil, err
+		}
+		result.Organizations = res.DeletedCount
+
+		// Xóa trực tiếp (không qua BaseServiceMongoImpl): ghi một bản ghi audit tổng hợp, thuộc tổ chức cha
+		entry := models.AuthLog{
+			Collection: global.MongoDB_ColNames.Organizations,
+			DocumentID: org.ID,
+			Action:     "purge",
+			Describe: fmt.Sprintf("Xóa vĩnh viễn %d tổ chức, %d role, %d role permission, %d user role, %d share",
+				result.Organizations, result.Roles, result.RolePermissions, result.UserRoles, result.Shares),
+			OldData: auditJSON(auditDataMap(org)),
+		}
+		if org.ParentID != nil {
+			entry.OwnerOrganizationID = *org.ParentID
+		}
+		recordAuditEntry(sessCtx, entry)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	audits.flush(ctx)
+
+	publishAllPermissionChange()
+	return result, nil
+}
+
+// GetArchivedIDs trả về các ID (trong danh sách) thuộc tổ chức đã lưu trữ
+func (s *OrganizationService) GetArchivedIDs(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	archived := make(map[primitive.ObjectID]bool)
+	if len(ids) == 0 {
+		return archived, nil
+	}
+
+	values, err := s.Distinct(ctx, "_id", bson.M{"_id": bson.M{"$in": ids}, "archivedAt": bson.M{"$exists": true}})
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			archived[id] = true
+		}
+	}
+	return archived, nil
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OrganizationTreeNode là một node trong cây tổ chức
+type OrganizationTreeNode struct {
+	ID         primitive.ObjectID      `json:"id"`
+	Name       string                  `json:"name"`
+	Code       string                  `json:"code"`
+	Type       string                  `json:"type"`
+	ParentID   *primitive.ObjectID     `json:"parentId,omitempty"`
+	Path       string                  `json:"path"`
+	Level      int                     `json:"level"`
+	IsActive   bool                    `json:"isActive"`
+	ArchivedAt *int64                  `json:"archivedAt,omitempty"`
+	RoleCount  int                     `json:"roleCount"`          // Số role thuộc tổ chức (không tính tổ chức con)
+	UserCount  int                     `json:"userCount"`          // Số user có role thuộc tổ chức (không tính tổ chức con)
+	Redacted   bool                    `json:"redacted,omitempty"` // true = tổ chức ngoài phạm vi của user, chỉ giữ id/parentId để nối các nhánh trong phạm vi
+	Children   []*OrganizationTreeNode `json:"children"`
+}
+
+// CalculateOrganizationLevel tính Level dựa trên Type và Level của parent
+func CalculateOrganizationLevel(orgType string, parentLevel int) int {
+	switch orgType {
+	case models.OrganizationTypeSystem:
+		return -1
+	case models.OrganizationTypeGroup:
+		return 0
+	case models.OrganizationTypeCompany:
+		return 1
+	case models.OrganizationTypeDepartment:
+		return 2
+	case models.OrganizationTypeDivision:
+		return 3
+	default:
+		// Team (Level 4+) và loại khác: tăng level lên 1 so với parent
+		return parentLevel + 1
+	}
+}
+
+// MoveOrganization chuyển tổ chức (cùng toàn bộ cây con) sang tổ chức cha mới
+// - Không cho phép di chuyển System organization
+// - Không cho phép chuyển vào chính nó hoặc tổ chức con của nó (tạo vòng)
+// - Level mới phải lớn hơn Level của tổ chức cha (đúng thứ bậc loại tổ chức)
+// - Path/Level của tổ chức và toàn bộ tổ chức con được ghi lại trong một transaction
+func (s *OrganizationService) MoveOrganization(ctx context.Context, orgID, newParentID primitive.ObjectID) (*models.Organization, error) {
+	org, err := s.FindOneById(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.Type == models.OrganizationTypeSystem || org.IsSystem {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể di chuyển tổ chức hệ thống", common.StatusForbidden, nil)
+	}
+	if org.ArchivedAt != nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể di chuyển tổ chức đã lưu trữ", common.StatusBadRequest, nil)
+	}
+
+	newParent, err := s.FindOneById(ctx, newParentID)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeBusinessOperation, fmt.Sprintf("Không tìm thấy tổ chức cha với ID: %s", newParentID.Hex()), common.StatusBadRequest, err)
+		}
+		return nil, err
+	}
+	if newParent.ArchivedAt != nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Tổ chức cha mới đã được lưu trữ", common.StatusBadRequest, nil)
+	}
+
+	// Kiểm tra vòng: tổ chức cha mới không được là chính nó hoặc nằm trong cây con của nó
+	if newParent.ID == org.ID || strings.HasPrefix(newParent.Path, org.Path+"/") {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể chuyển tổ chức vào chính nó hoặc tổ chức con của nó", common.StatusBadRequest, nil)
+	}
+	if org.ParentID != nil && *org.ParentID == newParent.ID {
+		return &org, nil
+	}
+
+	newLevel := CalculateOrganizationLevel(org.Type, newParent.Level)
+	if newLevel <= newParent.Level {
+		return nil, common.NewError(common.ErrCodeBusinessOperation,
+			fmt.Sprintf("Tổ chức loại '%s' không thể nằm dưới tổ chức loại '%s'", org.Type, newParent.Type),
+			common.StatusBadRequest, nil)
+	}
+
+	// Lấy toàn bộ tổ chức con (kể cả không hoạt động), sắp xếp theo path để cha luôn được xử lý trước con
+	descendants, err := s.Find(ctx, bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(org.Path+"/")}}, nil)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	sort.Slice(descendants, func(i, j int) bool {
+		return strings.Count(descendants[i].Path, "/") < strings.Count(descendants[j].Path, "/")
+	})
+
+	oldPath := org.Path
+	newPath := newParent.Path + "/" + org.Code
+	now := time.Now().Unix()
+
+	levels := map[primitive.ObjectID]int{org.ID: newLevel}
+	writes := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": org.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"parentId": newParent.ID, "path": newPath, "level": newLevel, "updatedAt": now}}),
+	}
+	for _, child := range descendants {
+		var parentLevel int
+		ok := false
+		if child.ParentID != nil {
+			parentLevel, ok = levels[*child.ParentID]
+		}
+		if !ok {
+			// Dữ liệu path/parentId không nhất quán: giữ nguyên level, chỉ sửa path
+			parentLevel = child.Level - 1
+		}
+		childLevel := CalculateOrganizationLevel(child.Type, parentLevel)
+		if childLevel <= parentLevel {
+			return nil, common.NewError(common.ErrCodeBusinessOperation,
+				fmt.Sprintf("Sau khi di chuyển, tổ chức con '%s' (loại '%s') sẽ sai thứ bậc", child.Code, child.Type),
+				common.StatusBadRequest, nil)
+		}
+		levels[child.ID] = childLevel
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": child.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"path": newPath + strings.TrimPrefix(child.Path, oldPath), "level": childLevel, "updatedAt": now}}))
+	}
+
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return s.collection.BulkWrite(sessCtx, writes, mongoopts.BulkWrite().SetOrdered(true))
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	// Path của tổ chức thay đổi → phạm vi dữ liệu (scope = 1) đã cache không còn đúng
+	publishAllPermissionChange()
+
+	moved := org
+	moved.ParentID = &newParent.ID
+	moved.Path = newPath
+	moved.Level = newLevel
+	moved.UpdatedAt = now
+	recordAudit(ctx, s.collection.Name(), AuditActionUpdate, org, moved)
+	return &moved, nil
+}
+
+// GetOrganizationTree trả về cây tổ chức lồng nhau kèm số role và số user của từng node
+// Parameters:
+//   - rootID: Nếu khác nil, chỉ trả về cây con bắt đầu từ tổ chức này
+//   - includeInactive: true = bao gồm cả tổ chức không hoạt động
+//   - allowedOrgIDs: Tổ chức user được xem; tổ chức ngoài danh sách chỉ được giữ dưới dạng node ẩn thông tin (Redacted)
+//     khi nằm trên đường nối giữa các tổ chức được xem, còn lại bị loại khỏi cây
+func (s *OrganizationService) GetOrganizationTree(ctx context.Context, rootID *primitive.ObjectID, includeInactive bool, allowedOrgIDs []primitive.ObjectID) ([]*OrganizationTreeNode, error) {
+	roots := make([]*OrganizationTreeNode, 0)
+	if len(allowedOrgIDs) == 0 {
+		return roots, nil
+	}
+
+	filter := bson.M{}
+	if rootID != nil {
+		root, err := s.FindOneById(ctx, *rootID)
+		if err != nil {
+			return nil, err
+		}
+		filter["$or"] = bson.A{
+			bson.M{"_id": root.ID},
+			bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(root.Path+"/")}},
+		}
+	}
+	if !includeInactive {
+		filter["isActive"] = true
+	}
+
+	orgs, err := s.Find(ctx, filter, mongoopts.Find().SetSort(bson.D{{Key: "level", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+
+	allowed := make(map[primitive.ObjectID]bool, len(allowedOrgIDs))
+	for _, id := range allowedOrgIDs {
+		allowed[id] = true
+	}
+	visible := visibleTreeOrganizations(orgs, allowed)
+
+	orgIDs := make([]primitive.ObjectID, 0, len(orgs))
+	for _, org := range orgs {
+		if allowed[org.ID] {
+			orgIDs = append(orgIDs, org.ID)
+		}
+	}
+	roleCounts, userCounts, err := s.countRolesAndUsers(ctx, orgIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[primitive.ObjectID]*OrganizationTreeNode, len(visible))
+	for _, org := range orgs {
+		if !visible[org.ID] {
+			continue
+		}
+		if !allowed[org.ID] {
+			nodes[org.ID] = &OrganizationTreeNode{ID: org.ID, ParentID: org.ParentID, Redacted: true, Children: []*OrganizationTreeNode{}}
+			continue
+		}
+		nodes[org.ID] = &OrganizationTreeNode{
+			ID:         org.ID,
+			Name:       org.Name,
+			Code:       org.Code,
+			Type:       org.Type,
+			ParentID:   org.ParentID,
+			Path:       org.Path,
+			Level:      org.Level,
+			IsActive:   org.IsActive,
+			ArchivedAt: org.ArchivedAt,
+			RoleCount:  roleCounts[org.ID],
+			UserCount:  userCounts[org.ID],
+			Children:   []*OrganizationTreeNode{},
+		}
+	}
+
+	// Node không tìm thấy cha trong kết quả (root, gốc cây con, hoặc cha bị lọc) là node gốc
+	for _, org := range orgs {
+		node, ok := nodes[org.ID]
+		if !ok {
+			continue
+		}
+		if org.ParentID != nil {
+			if parent, ok := nodes[*org.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// visibleTreeOrganizations trả về các tổ chức được đưa vào cây: tổ chức được xem và tổ chức không được xem
+// nhưng nằm trên đường nối giữa 2 tổ chức được xem (cha chung, hoặc nằm giữa một tổ chức được xem và tổ tiên của nó)
+func visibleTreeOrganizations(orgs []models.Organization, allowed map[primitive.ObjectID]bool) map[primitive.ObjectID]bool {
+	byID := make(map[primitive.ObjectID]models.Organization, len(orgs))
+	for _, org := range orgs {
+		byID[org.ID] = org
+	}
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	var roots []primitive.ObjectID
+	for _, org := range orgs {
+		if org.ParentID != nil {
+			if _, ok := byID[*org.ParentID]; ok {
+				children[*org.ParentID] = append(children[*org.ParentID], org.ID)
+				continue
+			}
+		}
+		roots = append(roots, org.ID)
+	}
+
+	// hasAllowed: cây con (kể cả chính node) có tổ chức được xem
+	hasAllowed := make(map[primitive.ObjectID]bool, len(orgs))
+	var mark func(id primitive.ObjectID) bool
+	mark = func(id primitive.ObjectID) bool {
+		found := allowed[id]
+		for _, childID := range children[id] {
+			if mark(childID) {
+				found = true
+			}
+		}
+		hasAllowed[id] = found
+		return found
+	}
+	for _, rootID := range roots {
+		mark(rootID)
+	}
+
+	// connected: bên ngoài cây con của node có tổ chức được xem nối được qua tổ tiên của node
+	visible := make(map[primitive.ObjectID]bool)
+	var walk func(id primitive.ObjectID, connected bool)
+	walk = func(id primitive.ObjectID, connected bool) {
+		if !hasAllowed[id] {
+			return
+		}
+		branches := 0
+		for _, childID := range children[id] {
+			if hasAllowed[childID] {
+				branches++
+			}
+		}
+		if allowed[id] || branches >= 2 || (branches == 1 && connected) {
+			visible[id] = true
+		}
+		for _, childID := range children[id] {
+			if !hasAllowed[childID] {
+				continue
+			}
+			walk(childID, connected || allowed[id] || branches >= 2)
+		}
+	}
+	for _, rootID := range roots {
+		walk(rootID, false)
+	}
+	return visible
+}
+
+// countRolesAndUsers đếm số role và số user (distinct) có role thuộc từng tổ chức
+func (s *OrganizationService) countRolesAndUsers(ctx context.Context, orgIDs []primitive.ObjectID) (map[primitive.ObjectID]int, map[primitive.ObjectID]int, error) {
+	roleCounts := make(map[primitive.ObjectID]int)
+	userCounts := make(map[primitive.ObjectID]int)
+	if len(orgIDs) == 0 {
+		return roleCounts, userCounts, nil
+	}
+
+	type countRow struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+
+	roleCursor, err := s.roleService.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$ownerOrganizationId", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, nil, common.ConvertMongoError(err)
+	}
+	var roleRows []countRow
+	if err := roleCursor.All(ctx, &roleRows); err != nil {
+		return nil, nil, common.ConvertMongoError(err)
+	}
+	for _, row := range roleRows {
+		roleCounts[row.ID] = row.Count
+	}
+
+	userCursor, err := s.roleService.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         global.MongoDB_ColNames.UserRoles,
+			"localField":   "_id",
+			"foreignField": "roleId",
+			"as":           "userRole",
+		}}},
+		{{Key: "$unwind", Value: "$userRole"}},
+		{{Key: "$group", Value: bson.M{"_id": "$ownerOrganizationId", "users": bson.M{"$addToSet": "$userRole.userId"}}}},
+		{{Key: "$project", Value: bson.M{"count": bson.M{"$size": "$users"}}}},
+	})
+	if err != nil {
+		return nil, nil, common.ConvertMongoError(err)
+	}
+	var userRows []countRow
+	if err := userCursor.All(ctx, &userRows); err != nil {
+		return nil, nil, common.ConvertMongoError(err)
+	}
+	for _, row := range userRows {
+		userCounts[row.ID] = row.Count
+	}
+
+	return roleCounts, userCounts, nil
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"meta_commerce/core/common"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultFilterOperators các toán tử MongoDB an toàn được phép trong filter từ client (mặc định của BaseHandler)
+// và trong điều kiện của role permission
+var DefaultFilterOperators = []string{
+	"$eq",
+	"$gt",
+	"$gte",
+	"$lt",
+	"$lte",
+	"$in",
+	"$nin",
+	"$exists",
+}
+
+// MaxPermissionConditionFields số field tối đa trong điều kiện của một role permission (giống giới hạn filter)
+const MaxPermissionConditionFields = 10
+
+// ValidatePermissionConditions kiểm tra điều kiện của role permission
+// Điều kiện là filter MongoDB dạng {field: giá trị} hoặc {field: {toán tử: giá trị}}:
+//   - Không có toán tử ở cấp ngoài cùng ($and, $or...): các field được kết hợp bằng AND
+//   - Toán tử thuộc DefaultFilterOperators; $in/$nin nhận mảng giá trị, $exists nhận bool
+//   - Giá trị so sánh là string, số, bool hoặc null
+func ValidatePermissionConditions(conditions map[string]interface{}) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	if len(conditions) > MaxPermissionConditionFields {
+		return conditionError(fmt.Sprintf("Điều kiện vượt quá số lượng trường cho phép. Tối đa %d trường", MaxPermissionConditionFields), "")
+	}
+
+	for field, clause := range NormalizeConditions(conditions) {
+		if field == "" || strings.HasPrefix(field, "$") {
+			return conditionError(fmt.Sprintf("Trường '%s' không hợp lệ: điều kiện chỉ gồm các trường, không dùng toán tử ở cấp ngoài cùng", field), field)
+		}
+
+		operators, ok := clause.(map[string]interface{})
+		if !ok {
+			if !isConditionScalar(clause) {
+				return conditionError(fmt.Sprintf("Giá trị của trường '%s' phải là string, số, bool hoặc null", field), field)
+			}
+			continue
+		}
+		if len(operators) == 0 {
+			return conditionError(fmt.Sprintf("Trường '%s' không có toán tử", field), field)
+		}
+		for op, operand := range operators {
+			if !utility.Contains(DefaultFilterOperators, op) {
+				return conditionError(fmt.Sprintf("Toán tử '%s' không được phép. Các toán tử được phép: %v", op, DefaultFilterOperators), field)
+			}
+			switch op {
+			case "$in", "$nin":
+				values, ok := operand.([]interface{})
+				if !ok {
+					return conditionError(fmt.Sprintf("Toán tử '%s' của trường '%s' phải nhận một mảng", op, field), field)
+				}
+				for _, value := range values {
+					if !isConditionScalar(value) {
+						return conditionError(fmt.Sprintf("Phần tử của '%s' (trường '%s') phải là string, số, bool hoặc null", op, field), field)
+					}
+				}
+			case "$exists":
+				if _, ok := operand.(bool); !ok {
+					return conditionError(fmt.Sprintf("Toán tử '$exists' của trường '%s' phải nhận true/false", field), field)
+				}
+			default:
+				if !isConditionScalar(operand) {
+					return conditionError(fmt.Sprintf("Toán tử '%s' của trường '%s' phải nhận string, số, bool hoặc null", op, field), field)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// conditionError lỗi điều kiện của role permission không hợp lệ
+func conditionError(message string, field string) error {
+	details := map[string]interface{}{"errorCode": "INVALID_PERMISSION_CONDITIONS"}
+	if field != "" {
+		details["field"] = field
+	}
+	return common.NewError(common.ErrCodeValidationFormat, message, common.StatusBadRequest, details)
+}
+
+// isConditionScalar giá trị có phải giá trị so sánh hợp lệ của điều kiện không
+func isConditionScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, bool, float64, float32, int, int32, int64, json.Number, primitive.ObjectID:
+		return true
+	}
+	return false
+}
+
+// NormalizeConditions chuyển điều kiện (hoặc document) đọc từ MongoDB/JSON về map[string]interface{} và []interface{} thuần
+// Dữ liệu đọc từ MongoDB có thể chứa primitive.D / primitive.M / primitive.A ở các cấp lồng nhau
+func NormalizeConditions(conditions map[string]interface{}) map[string]interface{} {
+	if conditions == nil {
+		return nil
+	}
+	normalized, _ := normalizeConditionValue(conditions).(map[string]interface{})
+	return normalized
+}
+
+// normalizeConditionValue chuẩn hóa đệ quy một giá trị của điều kiện
+func normalizeConditionValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalizeConditionValue(item)
+		}
+		return result
+	case primitive.M:
+		return normalizeConditionValue(map[string]interface{}(v))
+	case primitive.D:
+		return normalizeConditionValue(v.Map())
+	case primitive.A:
+		return normalizeConditionValue([]interface{}(v))
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeConditionValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// SameConditions hai điều kiện có giống nhau không (không phân biệt kiểu số int/float và thứ tự key)
+func SameConditions(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	rawA, errA := json.Marshal(NormalizeConditions(a))
+	rawB, errB := json.Marshal(NormalizeConditions(b))
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+// ConditionsWithin điều kiện granted có hẹp bằng hoặc hơn điều kiện holder không
+// holder rỗng (không giới hạn) chứa mọi điều kiện; ngoài ra chỉ công nhận khi hai điều kiện giống nhau
+func ConditionsWithin(holder, granted map[string]interface{}) bool {
+	return len(holder) == 0 || SameConditions(holder, granted)
+}
+
+// ConditionSets các bộ điều kiện của permission hiệu lực (kết hợp bằng OR)
+// Trả về nil nếu có ít nhất một grant không có điều kiện (permission không bị giới hạn)
+func (p *EffectivePermission) ConditionSets() []map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+	sets := make([]map[string]interface{}, 0, len(p.Grants))
+	for _, grant := range p.Grants {
+		if len(grant.Conditions) == 0 {
+			return nil
+		}
+		sets = append(sets, grant.Conditions)
+	}
+	return sets
+}
+
+// DocumentToConditionMap chuyển document (struct/map) thành map theo tên field MongoDB (bson) để so khớp điều kiện
+// Struct được chuyển bằng utility.ToMap (chạy struct tag `extract`) nên giống dữ liệu thực sự được ghi xuống database
+func DocumentToConditionMap(doc interface{}) (map[string]interface{}, error) {
+	if m, ok := doc.(map[string]interface{}); ok {
+		return NormalizeConditions(m), nil
+	}
+	result, err := utility.ToMap(doc)
+	if err != nil {
+		return nil, common.ErrInvalidFormat
+	}
+	return NormalizeConditions(result), nil
+}
+
+// MatchConditions kiểm tra document có thỏa điều kiện không (so khớp trong bộ nhớ, theo ngữ nghĩa filter MongoDB)
+// Field dạng "a.b" được tra theo document lồng nhau; field là mảng thỏa khi có phần tử thỏa (trừ $nin, $exists)
+func MatchConditions(doc map[string]interface{}, conditions map[string]interface{}) bool {
+	for field, clause := range NormalizeConditions(conditions) {
+		value, found := lookupConditionPath(doc, field)
+		if !MatchConditionClause(value, found, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchConditionClause kiểm tra giá trị của một field có thỏa điều kiện của field đó không
+func MatchConditionClause(value interface{}, found bool, clause interface{}) bool {
+	value = normalizeConditionValue(value)
+	operators, ok := clause.(map[string]interface{})
+	if !ok {
+		return matchAny(value, found, func(v interface{}) bool { return conditionEqual(v, clause) })
+	}
+
+	for op, operand := range operators {
+		var matched bool
+		switch op {
+		case "$eq":
+			matched = matchAny(value, found, func(v interface{}) bool { return conditionEqual(v, operand) })
+		case "$gt", "$gte", "$lt", "$lte":
+			matched = matchAny(value, found, func(v interface{}) bool {
+				cmp, ok := conditionCompare(v, operand)
+				if !ok {
+					return false
+				}
+				switch op {
+				case "$gt":
+					return cmp > 0
+				case "$gte":
+					return cmp >= 0
+				case "$lt":
+					return cmp < 0
+				}
+				return cmp <= 0
+			})
+		case "$in":
+			matched = matchAny(value, found, func(v interface{}) bool { return conditionIn(v, operand) })
+		case "$nin":
+			matched = !matchAny(value, found, func(v interface{}) bool { return conditionIn(v, operand) })
+		case "$exists":
+			want, _ := operand.(bool)
+			matched = found == want
+		default:
+			matched = false // Toán tử ngoài whitelist không bao giờ thỏa
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny áp dụng điều kiện cho giá trị; giá trị mảng thỏa khi có phần tử thỏa
+// Field không tồn tại được xem như null
+func matchAny(value interface{}, found bool, match func(interface{}) bool) bool {
+	if !found {
+		return match(nil)
+	}
+	if values, ok := value.([]interface{}); ok {
+		for _, item := range values {
+			if match(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(value)
+}
+
+// conditionIn giá trị có nằm trong mảng operand không
+func conditionIn(value interface{}, operand interface{}) bool {
+	values, _ := normalizeConditionValue(operand).([]interface{})
+	for _, item := range values {
+		if conditionEqual(value, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionEqual so sánh bằng: số so theo giá trị, ObjectID so với chuỗi hex
+func conditionEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if cmp, ok := conditionCompare(a, b); ok {
+		return cmp == 0
+	}
+	if boolA, ok := a.(bool); ok {
+		boolB, ok := b.(bool)
+		return ok && boolA == boolB
+	}
+	return false
+}
+
+// conditionCompare so sánh hai giá trị cùng loại (số hoặc chuỗi); ok = false nếu không so sánh được
+func conditionCompare(a, b interface{}) (int, bool) {
+	if numA, ok := conditionNumber(a); ok {
+		numB, ok := conditionNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case numA < numB:
+			return -1, true
+		case numA > numB:
+			return 1, true
+		}
+		return 0, true
+	}
+	strA, okA := conditionString(a)
+	strB, okB := conditionString(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	return strings.Compare(strA, strB), true
+}
+
+// conditionNumber chuyển giá trị số về float64
+func conditionNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// conditionString chuyển giá trị chuỗi (hoặc ObjectID → hex) về string
+func conditionString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case primitive.ObjectID:
+		return v.Hex(), true
+	}
+	return "", false
+}
+
+// lookupConditionPath lấy giá trị theo đường dẫn "a.b.c" trong document lồng nhau
+func lookupConditionPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// CompatibleConditionSets lọc các bộ điều kiện mà dữ liệu cập nhật ($set) không làm document vi phạm:
+// mọi field của điều kiện có trong $set phải thỏa với giá trị mới. Field của điều kiện là field cha/con
+// của một key trong $set (vd: điều kiện "posData.status", $set "posData") không đánh giá được → bộ điều kiện bị loại.
+// Document khớp một bộ điều kiện tương thích trước khi cập nhật sẽ vẫn thỏa bộ điều kiện đó sau khi cập nhật.
+func CompatibleConditionSets(sets []map[string]interface{}, set map[string]interface{}) []map[string]interface{} {
+	var compatible []map[string]interface{}
+	for _, conditions := range sets {
+		ok := true
+		for field, clause := range NormalizeConditions(conditions) {
+			for key, value := range set {
+				if key == field {
+					ok = MatchConditionClause(value, true, clause)
+				} else if strings.HasPrefix(field, key+".") || strings.HasPrefix(key, field+".") {
+					ok = false
+				}
+				if !ok {
+					break
+				}
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			compatible = append(compatible, conditions)
+		}
+	}
+	return compatible
+}
+
+// DryRunConditionResult kết quả chạy thử điều kiện trên một document mẫu
+type DryRunConditionResult struct {
+	Index   int      `json:"index"`            // Vị trí document trong danh sách mẫu
+	Matched bool     `json:"matched"`          // Document có thỏa điều kiện không
+	Failed  []string `json:"failed,omitempty"` // Các field không thỏa (đã sắp xếp)
+}
+
+// DryRunConditions validate điều kiện rồi so khớp với từng document mẫu
+func DryRunConditions(conditions map[string]interface{}, documents []map[string]interface{}) ([]DryRunConditionResult, error) {
+	if err := ValidatePermissionConditions(conditions); err != nil {
+		return nil, err
+	}
+
+	normalized := NormalizeConditions(conditions)
+	results := make([]DryRunConditionResult, 0, len(documents))
+	for i, document := range documents {
+		doc := NormalizeConditions(document)
+		result := DryRunConditionResult{Index: i, Matched: true}
+		for field, clause := range normalized {
+			value, found := lookupConditionPath(doc, field)
+			if !MatchConditionClause(value, found, clause) {
+				result.Matched = false
+				result.Failed = append(result.Failed, field)
+			}
+		}
+		sort.Strings(result.Failed)
+		results = append(results, result)
+	}
+	return results, nil
+}
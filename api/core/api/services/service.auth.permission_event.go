@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"meta_commerce/core/global"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PermissionChangeEvent mô tả một thay đổi ảnh hưởng tới quyền của user
+// Các service phát event này sau khi ghi vào user_roles, role_permissions, roles
+// để các nơi cache quyền (AuthManager) xóa đúng phần bị ảnh hưởng
+type PermissionChangeEvent struct {
+	UserIDs []primitive.ObjectID // Các user có danh sách role thay đổi
+	RoleIDs []primitive.ObjectID // Các role có danh sách permission thay đổi hoặc bị xóa
+	All     bool                 // Không xác định được phạm vi (vd: update theo filter) → xóa toàn bộ cache quyền
+}
+
+// PermissionChangeListener hàm xử lý khi quyền thay đổi
+type PermissionChangeListener func(event PermissionChangeEvent)
+
+var (
+	permissionChangeMu        sync.RWMutex
+	permissionChangeListeners []PermissionChangeListener
+)
+
+// SubscribePermissionChange đăng ký listener nhận event thay đổi quyền
+func SubscribePermissionChange(listener PermissionChangeListener) {
+	permissionChangeMu.Lock()
+	defer permissionChangeMu.Unlock()
+	permissionChangeListeners = append(permissionChangeListeners, listener)
+}
+
+// PublishPermissionChange phát event thay đổi quyền tới tất cả listener (đồng bộ)
+// Event có role thay đổi được bổ sung các role kế thừa quyền từ role đó (xem withInheritingRoles)
+func PublishPermissionChange(event PermissionChangeEvent) {
+	if !event.All && len(event.UserIDs) == 0 && len(event.RoleIDs) == 0 {
+		return
+	}
+	if !event.All && len(event.RoleIDs) > 0 {
+		event = withInheritingRoles(event)
+	}
+
+	permissionChangeMu.RLock()
+	listeners := make([]PermissionChangeListener, len(permissionChangeListeners))
+	copy(listeners, permissionChangeListeners)
+	permissionChangeMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// withInheritingRoles thêm vào event các role kế thừa quyền (role con, role khởi tạo từ template) từ các role thay đổi,
+// vì quyền của role cha / template thay đổi thì quyền của các role này thay đổi theo.
+// Không đọc được role kế thừa → xóa toàn bộ cache quyền
+func withInheritingRoles(event PermissionChangeEvent) PermissionChangeEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roles, _, err := inheritingRoles(ctx, event.RoleIDs)
+	if err != nil {
+		logrus.WithError(err).Warn("PublishPermissionChange: Không thể lấy role kế thừa, xóa toàn bộ cache quyền")
+		return PermissionChangeEvent{All: true}
+	}
+	for _, role := range roles {
+		event.RoleIDs = append(event.RoleIDs, role.ID)
+	}
+	return event
+}
+
+// publishUserPermissionChange phát event khi danh sách role của các user thay đổi
+func publishUserPermissionChange(userIDs ...primitive.ObjectID) {
+	PublishPermissionChange(PermissionChangeEvent{UserIDs: userIDs})
+}
+
+// publishRolePermissionChange phát event khi danh sách permission của các role thay đổi
+func publishRolePermissionChange(roleIDs ...primitive.ObjectID) {
+	PublishPermissionChange(PermissionChangeEvent{RoleIDs: roleIDs})
+}
+
+// publishAllPermissionChange phát event khi không xác định được user/role bị ảnh hưởng
+func publishAllPermissionChange() {
+	PublishPermissionChange(PermissionChangeEvent{All: true})
+}
+
+// permissionChangeStreamEvent là phần dữ liệu cần dùng của một change event MongoDB
+type permissionChangeStreamEvent struct {
+	OperationType string `bson:"operationType"`
+	Ns            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument bson.M `bson:"fullDocument"`
+}
+
+// WatchPermissionChanges theo dõi change stream của user_roles, role_permissions, roles
+// và phát PermissionChangeEvent, giúp cache quyền của nhiều server instance luôn đồng bộ.
+// Yêu cầu MongoDB chạy replica set. Hàm chạy cho tới khi ctx bị hủy, tự kết nối lại khi lỗi.
+func WatchPermissionChanges(ctx context.Context) {
+	db := global.MongoDB_Session.Database(global.MongoDB_ServerConfig.MongoDB_DBName_Auth)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"ns.coll": bson.M{"$in": []string{
+			global.MongoDB_ColNames.UserRoles,
+			global.MongoDB_ColNames.RolePermissions,
+			global.MongoDB_ColNames.Roles,
+		}}}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	for {
+		stream, err := db.Watch(ctx, pipeline, opts)
+		if err != nil {
+			logrus.WithError(err).Error("WatchPermissionChanges: Không thể mở change stream, thử lại sau 5 giây")
+		} else {
+			logrus.Info("WatchPermissionChanges: Đang theo dõi thay đổi quyền")
+			for stream.Next(ctx) {
+				var event permissionChangeStreamEvent
+				if err := stream.Decode(&event); err != nil {
+					logrus.WithError(err).Warn("WatchPermissionChanges: Không thể decode change event")
+					continue
+				}
+				PublishPermissionChange(permissionChangeFromStream(event))
+			}
+			if err := stream.Err(); err != nil && ctx.Err() == nil {
+				logrus.WithError(err).Error("WatchPermissionChanges: Change stream bị ngắt, thử lại sau 5 giây")
+			}
+			stream.Close(context.Background())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// permissionChangeFromStream chuyển change event của MongoDB thành PermissionChangeEvent
+func permissionChangeFromStream(event permissionChangeStreamEvent) PermissionChangeEvent {
+	switch event.Ns.Coll {
+	case global.MongoDB_ColNames.Roles:
+		return PermissionChangeEvent{RoleIDs: []primitive.ObjectID{event.DocumentKey.ID}}
+	case global.MongoDB_ColNames.RolePermissions:
+		if roleID, ok := event.FullDocument["roleId"].(primitive.ObjectID); ok {
+			return PermissionChangeEvent{RoleIDs: []primitive.ObjectID{roleID}}
+		}
+	case global.MongoDB_ColNames.UserRoles:
+		if userID, ok := event.FullDocument["userId"].(primitive.ObjectID); ok {
+			return PermissionChangeEvent{UserIDs: []primitive.ObjectID{userID}}
+		}
+	}
+	// Delete không có fullDocument → không biết user/role nào bị ảnh hưởng
+	return PermissionChangeEvent{All: true}
+}
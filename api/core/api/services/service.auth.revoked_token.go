@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revokedTokenCache cache cục bộ các jti đã bị thu hồi để tránh truy vấn database cho token đã biết là bị thu hồi
+// Chỉ cache kết quả "đã thu hồi" - kết quả "chưa thu hồi" luôn được kiểm tra lại trong database
+// để việc thu hồi từ instance khác có hiệu lực ngay lập tức
+var revokedTokenCache = utility.NewCache[bool]("revoked_tokens", 5*time.Minute, time.Minute, 50000)
+
+// RevokedTokenService quản lý danh sách jti của JWT đã bị thu hồi (denylist)
+type RevokedTokenService struct {
+	*BaseServiceMongoImpl[models.RevokedToken]
+}
+
+// NewRevokedTokenService tạo mới RevokedTokenService
+func NewRevokedTokenService() (*RevokedTokenService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RevokedTokens)
+	if !exist {
+		return nil, fmt.Errorf("failed to get revoked_tokens collection: %v", common.ErrNotFound)
+	}
+
+	return &RevokedTokenService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.RevokedToken](collection),
+	}, nil
+}
+
+// Revoke thêm jti vào denylist cho đến khi token hết hạn (expiresAt - Unix giây)
+// Gọi nhiều lần với cùng jti không gây lỗi
+func (s *RevokedTokenService) Revoke(ctx context.Context, jti string, userID primitive.ObjectID, expiresAt int64, reason string) error {
+	if jti == "" {
+		return nil
+	}
+
+	// Token đã hết hạn thì không cần đưa vào denylist
+	expireAt := time.Unix(expiresAt, 0)
+	if !expireAt.After(time.Now()) {
+		return nil
+	}
+
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"jti": jti},
+		bson.M{"$setOnInsert": bson.M{
+			"jti":       jti,
+			"userId":    userID,
+			"reason":    reason,
+			"expireAt":  expireAt,
+			"createdAt": time.Now().UnixMilli(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+
+	revokedTokenCache.Set(jti, true)
+	return nil
+}
+
+// RevokeSession thu hồi cả access token và refresh token của một phiên (thiết bị)
+func (s *RevokedTokenService) RevokeSession(ctx context.Context, userID primitive.ObjectID, session models.Token, reason string) error {
+	if err := s.Revoke(ctx, session.AccessJti, userID, session.AccessExpiresAt, reason); err != nil {
+		return err
+	}
+	return s.Revoke(ctx, session.RefreshJti, userID, session.RefreshExpiresAt, reason)
+}
+
+// IsRevoked kiểm tra jti có nằm trong denylist không
+func (s *RevokedTokenService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if _, found := revokedTokenCache.Get(jti); found {
+		return true, nil
+	}
+
+	count, err := s.collection.CountDocuments(ctx, bson.M{"jti": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, common.ConvertMongoError(err)
+	}
+
+	if count > 0 {
+		revokedTokenCache.Set(jti, true)
+		return true, nil
+	}
+	return false, nil
+}
@@ -29,6 +29,138 @@ func NewRoleService() (*RoleService, error) {
 	}, nil
 }
 
+// InsertOne override method InsertOne để kiểm tra liên kết kế thừa (role cha, template) và quyền cấp qua liên kết
+func (s *RoleService) InsertOne(ctx context.Context, data models.Role) (models.Role, error) {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	if err := validateRoleLinks(ctx, data, nil); err != nil {
+		return data, err
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// InsertMany override method InsertMany để kiểm tra liên kết kế thừa của từng role
+func (s *RoleService) InsertMany(ctx context.Context, data []models.Role) ([]models.Role, error) {
+	for i := range data {
+		if data[i].ID.IsZero() {
+			data[i].ID = primitive.NewObjectID()
+		}
+		if err := validateRoleLinks(ctx, data[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return s.BaseServiceMongoImpl.InsertMany(ctx, data)
+}
+
+// prepareRoleUpdate chuẩn hóa dữ liệu update (xem normalizeRoleUpdate) và kiểm tra liên kết kế thừa của các role sau khi update
+// changed = true khi update đổi field liên quan đến kế thừa (quyền của role và các role kế thừa từ nó có thể thay đổi)
+func (s *RoleService) prepareRoleUpdate(ctx context.Context, filter interface{}, update interface{}, upsert bool) (updateData *UpdateData, changed bool, err error) {
+	updateData, err = normalizeRoleUpdate(update)
+	if err != nil {
+		return nil, false, err
+	}
+	if !touchesRoleLinks(updateData, upsert) {
+		return updateData, false, nil
+	}
+
+	if filter == nil {
+		filter = bson.D{}
+	}
+	var roles []models.Role
+	if err := findDelegationDocs(ctx, s.collection, filter, &roles); err != nil {
+		return nil, false, err
+	}
+	if len(roles) == 0 && upsert {
+		role, err := applyRoleLinkUpdate(models.Role{ID: primitive.NewObjectID()}, updateData, true)
+		if err != nil {
+			return nil, false, err
+		}
+		return updateData, true, validateRoleLinks(ctx, role, nil)
+	}
+	for i := range roles {
+		updated, err := applyRoleLinkUpdate(roles[i], updateData, false)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := validateRoleLinks(ctx, updated, &roles[i]); err != nil {
+			return nil, false, err
+		}
+	}
+	return updateData, true, nil
+}
+
+// UpdateOne override method UpdateOne để kiểm tra liên kết kế thừa và invalidate cache quyền khi liên kết thay đổi
+func (s *RoleService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (models.Role, error) {
+	updateData, changed, err := s.prepareRoleUpdate(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if changed {
+		defer publishAllPermissionChange()
+	}
+	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, updateData, opts)
+}
+
+// UpdateMany override method UpdateMany để kiểm tra liên kết kế thừa và invalidate cache quyền khi liên kết thay đổi
+func (s *RoleService) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (int64, error) {
+	updateData, changed, err := s.prepareRoleUpdate(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert)
+	if err != nil {
+		return 0, err
+	}
+	if changed {
+		defer publishAllPermissionChange()
+	}
+	return s.BaseServiceMongoImpl.UpdateMany(ctx, filter, updateData, opts)
+}
+
+// UpdateById override method UpdateById để kiểm tra liên kết kế thừa và invalidate cache quyền khi liên kết thay đổi
+func (s *RoleService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (models.Role, error) {
+	updateData, changed, err := s.prepareRoleUpdate(ctx, bson.M{"_id": id}, data, false)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if changed {
+		defer publishRolePermissionChange(id)
+	}
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, updateData)
+}
+
+// FindOneAndUpdate override method FindOneAndUpdate để kiểm tra liên kết kế thừa và invalidate cache quyền khi liên kết thay đổi
+func (s *RoleService) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.FindOneAndUpdateOptions) (models.Role, error) {
+	updateData, changed, err := s.prepareRoleUpdate(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if changed {
+		defer publishAllPermissionChange()
+	}
+	return s.BaseServiceMongoImpl.FindOneAndUpdate(ctx, filter, updateData, opts)
+}
+
+// Upsert override method Upsert để kiểm tra liên kết kế thừa và invalidate cache quyền khi liên kết thay đổi
+func (s *RoleService) Upsert(ctx context.Context, filter interface{}, data interface{}) (models.Role, error) {
+	updateData, changed, err := s.prepareRoleUpdate(ctx, filter, data, true)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if changed {
+		defer publishAllPermissionChange()
+	}
+	return s.BaseServiceMongoImpl.Upsert(ctx, filter, updateData)
+}
+
+// UpsertMany override method UpsertMany để kiểm tra liên kết kế thừa của từng role và invalidate cache quyền
+func (s *RoleService) UpsertMany(ctx context.Context, filter interface{}, data []models.Role) ([]models.Role, error) {
+	for _, role := range data {
+		if err := validateRoleLinks(ctx, role, nil); err != nil {
+			return nil, err
+		}
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpsertMany(ctx, filter, data)
+}
+
 // validateBeforeDelete kiểm tra các điều kiện trước khi xóa role
 // - Không cho phép xóa role Administrator
 func (s *RoleService) validateBeforeDelete(ctx context.Context, roleID primitive.ObjectID) error {
@@ -79,7 +211,12 @@ func (s *RoleService) DeleteOne(ctx context.Context, filter interface{}) error {
 	}
 
 	// Thực hiện xóa nếu không có ràng buộc
-	return s.BaseServiceMongoImpl.DeleteOne(ctx, filter)
+	if err := s.BaseServiceMongoImpl.DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+
+	publishRolePermissionChange(modelRole.ID)
+	return nil
 }
 
 // DeleteById override method DeleteById để kiểm tra trước khi xóa
@@ -90,7 +227,12 @@ func (s *RoleService) DeleteById(ctx context.Context, id primitive.ObjectID) err
 	}
 
 	// Thực hiện xóa nếu không có ràng buộc
-	return s.BaseServiceMongoImpl.DeleteById(ctx, id)
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+
+	publishRolePermissionChange(id)
+	return nil
 }
 
 // DeleteMany override method DeleteMany để kiểm tra trước khi xóa
@@ -102,6 +244,7 @@ func (s *RoleService) DeleteMany(ctx context.Context, filter interface{}) (int64
 	}
 
 	// Kiểm tra từng role trước khi xóa
+	roleIDs := make([]primitive.ObjectID, 0, len(roles))
 	for _, role := range roles {
 		var modelRole models.Role
 		bsonBytes, _ := bson.Marshal(role)
@@ -113,10 +256,17 @@ func (s *RoleService) DeleteMany(ctx context.Context, filter interface{}) (int64
 		if err := s.validateBeforeDelete(ctx, modelRole.ID); err != nil {
 			return 0, err
 		}
+		roleIDs = append(roleIDs, modelRole.ID)
 	}
 
 	// Thực hiện xóa nếu không có ràng buộc
-	return s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
+	deleted, err := s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
+	if err != nil {
+		return deleted, err
+	}
+
+	publishRolePermissionChange(roleIDs...)
+	return deleted, nil
 }
 
 // FindOneAndDelete override method FindOneAndDelete để kiểm tra trước khi xóa
@@ -142,5 +292,11 @@ func (s *RoleService) FindOneAndDelete(ctx context.Context, filter interface{},
 	}
 
 	// Thực hiện xóa nếu không có ràng buộc
-	return s.BaseServiceMongoImpl.FindOneAndDelete(ctx, filter, opts)
+	deletedRole, err := s.BaseServiceMongoImpl.FindOneAndDelete(ctx, filter, opts)
+	if err != nil {
+		return zero, err
+	}
+
+	publishRolePermissionChange(deletedRole.ID)
+	return deletedRole, nil
 }
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoleElevationService quản lý yêu cầu nâng quyền tạm thời (just-in-time)
+type RoleElevationService struct {
+	*BaseServiceMongoImpl[models.RoleElevationRequest]
+	roleService     *RoleService
+	userRoleService *UserRoleService
+}
+
+// NewRoleElevationService tạo mới RoleElevationService
+func NewRoleElevationService() (*RoleElevationService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RoleElevations)
+	if !exist {
+		return nil, fmt.Errorf("failed to get role_elevation_requests collection: %v", common.ErrNotFound)
+	}
+
+	roleService, err := NewRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role service: %v", err)
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
+	}
+
+	return &RoleElevationService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.RoleElevationRequest](collection),
+		roleService:          roleService,
+		userRoleService:      userRoleService,
+	}, nil
+}
+
+// Request tạo yêu cầu nâng quyền tạm thời cho user
+func (s *RoleElevationService) Request(ctx context.Context, userID primitive.ObjectID, input dto.RoleElevationRequestInput) (*models.RoleElevationRequest, error) {
+	roleID, err := primitive.ObjectIDFromHex(input.RoleID)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeValidationFormat, "roleId không hợp lệ", common.StatusBadRequest, err)
+	}
+	maxHours := global.MongoDB_ServerConfig.RoleElevationMaxHours
+	if input.DurationHours <= 0 || input.DurationHours > maxHours {
+		return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("durationHours phải từ 1 đến %d", maxHours), common.StatusBadRequest, nil)
+	}
+	justification := strings.TrimSpace(input.Justification)
+	if justification == "" {
+		return nil, common.NewError(common.ErrCodeValidationInput, "justification không được để trống", common.StatusBadRequest, nil)
+	}
+
+	role, err := s.roleService.FindOneById(ctx, roleID)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, common.NewError(common.ErrCodeValidationInput, "Role không tồn tại", common.StatusBadRequest, nil)
+		}
+		return nil, err
+	}
+
+	assigned, err := s.userRoleService.IsExist(ctx, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if assigned {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Bạn đã có role này", common.StatusConflict, nil)
+	}
+
+	pending, err := s.collection.CountDocuments(ctx, bson.M{"userId": userID, "roleId": roleID, "status": models.RoleElevationStatusPending})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if pending > 0 {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Đã có yêu cầu nâng quyền cho role này đang chờ duyệt", common.StatusConflict, nil)
+	}
+
+	created, err := s.InsertOne(ctx, models.RoleElevationRequest{
+		UserID:              userID,
+		RoleID:              roleID,
+		OwnerOrganizationID: role.OwnerOrganizationID,
+		DurationHours:       input.DurationHours,
+		Justification:       justification,
+		Status:              models.RoleElevationStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// CanDecide kiểm tra user có quyền UserRole.Update (scope 0/1, không tính scope 2) trong tổ chức của role được xin không
+func (s *RoleElevationService) CanDecide(ctx context.Context, approverID primitive.ObjectID, request *models.RoleElevationRequest) (bool, error) {
+	access, err := GetUserDataAccess(ctx, approverID, "UserRole.Update")
+	if err != nil {
+		return false, err
+	}
+	for _, orgID := range access.OrganizationIDs {
+		if orgID == request.OwnerOrganizationID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findPendingForDecision lấy yêu cầu đang chờ duyệt và kiểm tra quyền của người duyệt
+func (s *RoleElevationService) findPendingForDecision(ctx context.Context, approverID, id primitive.ObjectID) (*models.RoleElevationRequest, error) {
+	request, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != models.RoleElevationStatusPending {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Yêu cầu nâng quyền đã được xử lý", common.StatusConflict, nil)
+	}
+	if request.UserID == approverID {
+		return nil, common.NewError(common.ErrCodeAuthRole, "Không thể tự duyệt yêu cầu nâng quyền của chính mình", common.StatusForbidden, nil)
+	}
+	allowed, err := s.CanDecide(ctx, approverID, &request)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, common.NewError(common.ErrCodeAuthRole, "Bạn không có quyền UserRole.Update trong tổ chức của role này", common.StatusForbidden, nil)
+	}
+	return &request, nil
+}
+
+// decide chuyển yêu cầu từ pending sang trạng thái mới; lỗi nếu yêu cầu đã được xử lý đồng thời
+func (s *RoleElevationService) decide(ctx context.Context, id primitive.ObjectID, set bson.M) (*models.RoleElevationRequest, error) {
+	set["updatedAt"] = utility.CurrentTimeInMilli()
+
+	var updated models.RoleElevationRequest
+	err := s.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": models.RoleElevationStatusPending},
+		bson.M{"$set": set},
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, common.NewError(common.ErrCodeBusinessOperation, "Yêu cầu nâng quyền đã được xử lý", common.StatusConflict, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	return &updated, nil
+}
+
+// Approve duyệt yêu cầu: tạo role assignment có validUntil = lúc duyệt + durationHours
+func (s *RoleElevationService) Approve(ctx context.Context, approverID, id primitive.ObjectID, note string) (*models.RoleElevationRequest, error) {
+	request, err := s.findPendingForDecision(ctx, approverID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utility.CurrentTimeInMilli()
+	validUntil := now + (time.Duration(request.DurationHours) * time.Hour).Milliseconds()
+	approved, err := s.decide(ctx, id, bson.M{
+		"status":       models.RoleElevationStatusApproved,
+		"decidedBy":    approverID,
+		"decidedAt":    now,
+		"decisionNote": note,
+		"validUntil":   validUntil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userRole, err := s.userRoleService.InsertOne(ctx, models.UserRole{
+		UserID:             request.UserID,
+		RoleID:             request.RoleID,
+		ValidFrom:          &now,
+		ValidUntil:         &validUntil,
+		ElevationRequestID: &request.ID,
+	})
+	if err != nil {
+		// Trả yêu cầu về pending để có thể duyệt lại
+		_, _ = s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$set":   bson.M{"status": models.RoleElevationStatusPending},
+			"$unset": bson.M{"decidedBy": "", "decidedAt": "", "decisionNote": "", "validUntil": ""},
+		})
+		return nil, err
+	}
+
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"userRoleId": userRole.ID}}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	approved.UserRoleID = &userRole.ID
+	return approved, nil
+}
+
+// Reject từ chối yêu cầu nâng quyền
+func (s *RoleElevationService) Reject(ctx context.Context, approverID, id primitive.ObjectID, note string) (*models.RoleElevationRequest, error) {
+	if _, err := s.findPendingForDecision(ctx, approverID, id); err != nil {
+		return nil, err
+	}
+	return s.decide(ctx, id, bson.M{
+		"status":       models.RoleElevationStatusRejected,
+		"decidedBy":    approverID,
+		"decidedAt":    utility.CurrentTimeInMilli(),
+		"decisionNote": note,
+	})
+}
+
+// Cancel để người yêu cầu tự hủy yêu cầu đang chờ duyệt
+func (s *RoleElevationService) Cancel(ctx context.Context, userID, id primitive.ObjectID) (*models.RoleElevationRequest, error) {
+	request, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID != userID {
+		return nil, common.ErrNotFound
+	}
+	return s.decide(ctx, id, bson.M{"status": models.RoleElevationStatusCancelled})
+}
+
+// ListPendingForApprover lấy các yêu cầu đang chờ duyệt trong các tổ chức mà user có quyền UserRole.Update (scope 0/1)
+func (s *RoleElevationService) ListPendingForApprover(ctx context.Context, approverID primitive.ObjectID) ([]models.RoleElevationRequest, error) {
+	access, err := GetUserDataAccess(ctx, approverID, "UserRole.Update")
+	if err != nil {
+		return nil, err
+	}
+	if len(access.OrganizationIDs) == 0 {
+		return []models.RoleElevationRequest{}, nil
+	}
+	return s.Find(ctx, bson.M{
+		"status":              models.RoleElevationStatusPending,
+		"ownerOrganizationId": bson.M{"$in": access.OrganizationIDs},
+		"userId":              bson.M{"$ne": approverID},
+	}, mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+// MarkExpired đánh dấu expired cho các yêu cầu đã duyệt có role assignment vừa bị gỡ
+func (s *RoleElevationService) MarkExpired(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": models.RoleElevationStatusApproved},
+		bson.M{"$set": bson.M{"status": models.RoleElevationStatusExpired, "updatedAt": utility.CurrentTimeInMilli()}},
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
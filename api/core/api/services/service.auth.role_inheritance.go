@@ -0,0 +1,697 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ====================================
+// KẾ THỪA QUYỀN GIỮA CÁC ROLE (ROLE CHA, TEMPLATE)
+// ====================================
+//
+// Role kế thừa toàn bộ role permission của role cha (parentRoleId) và của template (templateId), trực tiếp hoặc gián tiếp.
+// Quyền kế thừa áp dụng tại tổ chức của role được gán cho user (không phải tổ chức của role cha/template),
+// nên một template định nghĩa ở tập đoàn được dùng lại cho mọi công ty con mà không cần sao chép role_permissions.
+
+// MaxRoleInheritanceDepth số cấp kế thừa tối đa (role cha, template) tính cả các role kế thừa từ role
+const MaxRoleInheritanceDepth = 5
+
+// InheritedRolePermission là role permission hiệu lực của role: của chính role hoặc kế thừa từ role cha / template
+type InheritedRolePermission struct {
+	models.RolePermission `bson:",inline"`
+	SourceRoleID          primitive.ObjectID `json:"sourceRoleId" bson:"-"`             // Role sở hữu role permission (khác roleId = quyền kế thừa)
+	SourceRoleName        string             `json:"sourceRoleName" bson:"-"`           // Tên role sở hữu role permission
+	PermissionName        string             `json:"permissionName,omitempty" bson:"-"` // Tên permission
+}
+
+// roleSources các role mà role kế thừa quyền trực tiếp (role cha, template)
+func roleSources(role models.Role) []primitive.ObjectID {
+	var sources []primitive.ObjectID
+	if role.ParentRoleID != nil && !role.ParentRoleID.IsZero() {
+		sources = append(sources, *role.ParentRoleID)
+	}
+	if role.TemplateID != nil && !role.TemplateID.IsZero() {
+		sources = append(sources, *role.TemplateID)
+	}
+	return sources
+}
+
+// loadRoles lấy role theo danh sách ID (ID không tồn tại bị bỏ qua)
+func loadRoles(ctx context.Context, roleIDs []primitive.ObjectID) (map[primitive.ObjectID]models.Role, error) {
+	roles := make(map[primitive.ObjectID]models.Role, len(roleIDs))
+	if len(roleIDs) == 0 {
+		return roles, nil
+	}
+	roleCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Roles)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	var roleList []models.Role
+	if err := findDelegationDocs(ctx, roleCollection, bson.M{"_id": bson.M{"$in": roleIDs}}, &roleList); err != nil {
+		return nil, err
+	}
+	for _, role := range roleList {
+		roles[role.ID] = role
+	}
+	return roles, nil
+}
+
+// loadOrganizations lấy tổ chức theo danh sách ID (ID không tồn tại bị bỏ qua)
+func loadOrganizations(ctx context.Context, orgIDs []primitive.ObjectID) (map[primitive.ObjectID]models.Organization, error) {
+	organizations := make(map[primitive.ObjectID]models.Organization, len(orgIDs))
+	if len(orgIDs) == 0 {
+		return organizations, nil
+	}
+	organizationCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Organizations)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	var organizationList []models.Organization
+	if err := findDelegationDocs(ctx, organizationCollection, bson.M{"_id": bson.M{"$in": orgIDs}}, &organizationList); err != nil {
+		return nil, err
+	}
+	for _, organization := range organizationList {
+		organizations[organization.ID] = organization
+	}
+	return organizations, nil
+}
+
+// resolveRoleAncestors lấy các role mà mỗi role kế thừa quyền (trực tiếp hoặc gián tiếp, không gồm chính nó)
+// Role tổ tiên được đọc theo từng cấp, tối đa MaxRoleInheritanceDepth cấp; vòng kế thừa (dữ liệu lỗi) được bỏ qua
+func resolveRoleAncestors(ctx context.Context, roles []models.Role) (map[primitive.ObjectID][]models.Role, error) {
+	known := make(map[primitive.ObjectID]models.Role, len(roles))
+	for _, role := range roles {
+		known[role.ID] = role
+	}
+
+	frontier := roles
+	for depth := 0; depth < MaxRoleInheritanceDepth && len(frontier) > 0; depth++ {
+		var missing []primitive.ObjectID
+		pending := make(map[primitive.ObjectID]bool)
+		for _, role := range frontier {
+			for _, sourceID := range roleSources(role) {
+				if _, ok := known[sourceID]; !ok && !pending[sourceID] {
+					pending[sourceID] = true
+					missing = append(missing, sourceID)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		loaded, err := loadRoles(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		frontier = frontier[:0:0]
+		for _, role := range loaded {
+			known[role.ID] = role
+			frontier = append(frontier, role)
+		}
+	}
+
+	type queued struct {
+		role  models.Role
+		depth int
+	}
+	ancestors := make(map[primitive.ObjectID][]models.Role, len(roles))
+	for _, role := range roles {
+		visited := map[primitive.ObjectID]bool{role.ID: true}
+		queue := []queued{{role: role}}
+		for len(queue) > 0 {
+			item := queue[0]
+			queue = queue[1:]
+			if item.depth >= MaxRoleInheritanceDepth {
+				continue
+			}
+			for _, sourceID := range roleSources(item.role) {
+				source, ok := known[sourceID]
+				if !ok || visited[sourceID] {
+					continue
+				}
+				visited[sourceID] = true
+				ancestors[role.ID] = append(ancestors[role.ID], source)
+				queue = append(queue, queued{role: source, depth: item.depth + 1})
+			}
+		}
+	}
+	return ancestors, nil
+}
+
+// inheritingRoles lấy các role kế thừa quyền (trực tiếp hoặc gián tiếp) từ các role, tối đa MaxRoleInheritanceDepth cấp
+// Trả về thêm số cấp kế thừa tìm được (0 = không có role nào kế thừa)
+func inheritingRoles(ctx context.Context, roleIDs []primitive.ObjectID) ([]models.Role, int, error) {
+	if len(roleIDs) == 0 {
+		return nil, 0, nil
+	}
+	roleCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Roles)
+	if !exist {
+		return nil, 0, common.ErrNotFound
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(roleIDs))
+	for _, roleID := range roleIDs {
+		seen[roleID] = true
+	}
+	var result []models.Role
+	levels := 0
+	frontier := roleIDs
+	for levels < MaxRoleInheritanceDepth && len(frontier) > 0 {
+		var roles []models.Role
+		filter := bson.M{"$or": []bson.M{
+			{"parentRoleId": bson.M{"$in": frontier}},
+			{"templateId": bson.M{"$in": frontier}},
+		}}
+		if err := findDelegationDocs(ctx, roleCollection, filter, &roles); err != nil {
+			return nil, 0, err
+		}
+		frontier = nil
+		for _, role := range roles {
+			if seen[role.ID] {
+				continue
+			}
+			seen[role.ID] = true
+			result = append(result, role)
+			frontier = append(frontier, role.ID)
+		}
+		if len(frontier) > 0 {
+			levels++
+		}
+	}
+	return result, levels, nil
+}
+
+// roleInheritedPermissions lấy role permission hiệu lực của các role, gồm cả quyền kế thừa từ role cha / template
+// RoleID của kết quả là role được tính, SourceRoleID là role sở hữu role permission. includeOwn = false: chỉ lấy quyền kế thừa
+func roleInheritedPermissions(ctx context.Context, roles []models.Role, includeOwn bool) ([]InheritedRolePermission, error) {
+	ancestors, err := resolveRoleAncestors(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[primitive.ObjectID][]models.Role, len(roles))
+	var sourceIDs []primitive.ObjectID
+	added := make(map[primitive.ObjectID]bool)
+	for _, role := range roles {
+		list := ancestors[role.ID]
+		if includeOwn {
+			list = append([]models.Role{role}, list...)
+		}
+		sources[role.ID] = list
+		for _, source := range list {
+			if !added[source.ID] {
+				added[source.ID] = true
+				sourceIDs = append(sourceIDs, source.ID)
+			}
+		}
+	}
+	if len(sourceIDs) == 0 {
+		return nil, nil
+	}
+
+	rolePermissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RolePermissions)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	var rolePermissions []models.RolePermission
+	if err := findDelegationDocs(ctx, rolePermissionCollection, bson.M{"roleId": bson.M{"$in": sourceIDs}}, &rolePermissions); err != nil {
+		return nil, err
+	}
+	bySource := make(map[primitive.ObjectID][]models.RolePermission)
+	for _, rolePermission := range rolePermissions {
+		bySource[rolePermission.RoleID] = append(bySource[rolePermission.RoleID], rolePermission)
+	}
+
+	var result []InheritedRolePermission
+	for _, role := range roles {
+		for _, source := range sources[role.ID] {
+			for _, rolePermission := range bySource[source.ID] {
+				item := InheritedRolePermission{
+					RolePermission: rolePermission,
+					SourceRoleID:   source.ID,
+					SourceRoleName: source.Name,
+				}
+				item.RoleID = role.ID
+				result = append(result, item)
+			}
+		}
+	}
+	return result, nil
+}
+
+// roleInheritanceError lỗi liên kết kế thừa (role cha, template) không hợp lệ
+func roleInheritanceError(message string) error {
+	return common.NewError(
+		common.ErrCodeBusinessOperation,
+		message,
+		common.StatusBadRequest,
+		map[string]interface{}{"errorCode": "INVALID_ROLE_INHERITANCE"},
+	)
+}
+
+// isDescendantPath kiểm tra path là tổ chức con (trực tiếp hoặc gián tiếp) của tổ chức có ancestorPath
+func isDescendantPath(ancestorPath, path string) bool {
+	return ancestorPath != "" && strings.HasPrefix(path, ancestorPath+"/")
+}
+
+// validateRoleInheritance kiểm tra liên kết kế thừa của role (dữ liệu sau khi tạo / cập nhật):
+//   - Template chỉ thuộc tổ chức hệ thống hoặc tập đoàn, không khởi tạo từ template khác, role cha (nếu có) cũng là template
+//   - templateId là template thuộc tổ chức cấp trên của tổ chức của role
+//   - parentRoleId là role cùng tổ chức hoặc thuộc tổ chức cấp trên
+//   - Không tạo vòng kế thừa, không vượt quá MaxRoleInheritanceDepth cấp (tính cả các role đang kế thừa từ role này)
+//   - Các role đang kế thừa trực tiếp từ role này vẫn hợp lệ (vd: không bỏ isTemplate khi còn role khởi tạo từ template)
+func validateRoleInheritance(ctx context.Context, role models.Role) error {
+	linked, err := loadRoles(ctx, roleSources(role))
+	if err != nil {
+		return err
+	}
+	var dependents []models.Role
+	dependentLevels := 0
+	if !role.ID.IsZero() {
+		if dependents, dependentLevels, err = inheritingRoles(ctx, []primitive.ObjectID{role.ID}); err != nil {
+			return err
+		}
+	}
+
+	orgIDs := []primitive.ObjectID{role.OwnerOrganizationID}
+	for _, linkedRole := range linked {
+		orgIDs = append(orgIDs, linkedRole.OwnerOrganizationID)
+	}
+	for _, dependent := range dependents {
+		orgIDs = append(orgIDs, dependent.OwnerOrganizationID)
+	}
+	organizations, err := loadOrganizations(ctx, orgIDs)
+	if err != nil {
+		return err
+	}
+	organization := organizations[role.OwnerOrganizationID]
+
+	if role.IsTemplate {
+		if organization.Type != models.OrganizationTypeSystem && organization.Type != models.OrganizationTypeGroup {
+			return roleInheritanceError("Template chỉ được tạo ở tổ chức hệ thống hoặc tập đoàn")
+		}
+		if role.TemplateID != nil {
+			return roleInheritanceError("Template không thể được khởi tạo từ template khác")
+		}
+	}
+
+	if role.TemplateID != nil {
+		template, ok := linked[*role.TemplateID]
+		switch {
+		case *role.TemplateID == role.ID:
+			return roleInheritanceError("Role không thể khởi tạo từ chính nó")
+		case !ok:
+			return roleInheritanceError(fmt.Sprintf("Không tìm thấy template với ID: %s", role.TemplateID.Hex()))
+		case !template.IsTemplate:
+			return roleInheritanceError(fmt.Sprintf("Role '%s' không phải là template", template.Name))
+		case !isDescendantPath(organizations[template.OwnerOrganizationID].Path, organization.Path):
+			return roleInheritanceError("Chỉ khởi tạo được template cho tổ chức con của tổ chức sở hữu template")
+		}
+	}
+
+	if role.ParentRoleID != nil {
+		parent, ok := linked[*role.ParentRoleID]
+		switch {
+		case *role.ParentRoleID == role.ID:
+			return roleInheritanceError("Role không thể kế thừa từ chính nó")
+		case !ok:
+			return roleInheritanceError(fmt.Sprintf("Không tìm thấy role cha với ID: %s", role.ParentRoleID.Hex()))
+		case role.IsTemplate && !parent.IsTemplate:
+			return roleInheritanceError("Role cha của template phải là template")
+		case parent.OwnerOrganizationID != role.OwnerOrganizationID && !isDescendantPath(organizations[parent.OwnerOrganizationID].Path, organization.Path):
+			return roleInheritanceError("Role cha phải thuộc cùng tổ chức hoặc tổ chức cấp trên")
+		}
+	}
+
+	for _, dependent := range dependents {
+		dependentOrganization := organizations[dependent.OwnerOrganizationID]
+		if dependent.TemplateID != nil && *dependent.TemplateID == role.ID {
+			if !role.IsTemplate {
+				return roleInheritanceError(fmt.Sprintf("Role '%s' đang được khởi tạo từ template này, tách role khỏi template trước", dependent.Name))
+			}
+			if !isDescendantPath(organization.Path, dependentOrganization.Path) {
+				return roleInheritanceError(fmt.Sprintf("Role '%s' khởi tạo từ template này không thuộc tổ chức con của tổ chức mới", dependent.Name))
+			}
+		}
+		if dependent.ParentRoleID != nil && *dependent.ParentRoleID == role.ID {
+			if dependent.IsTemplate && !role.IsTemplate {
+				return roleInheritanceError(fmt.Sprintf("Template '%s' đang kế thừa từ role này, role cha của template phải là template", dependent.Name))
+			}
+			if dependent.OwnerOrganizationID != role.OwnerOrganizationID && !isDescendantPath(organization.Path, dependentOrganization.Path) {
+				return roleInheritanceError(fmt.Sprintf("Role '%s' đang kế thừa từ role này không thuộc cùng tổ chức hoặc tổ chức con của tổ chức mới", dependent.Name))
+			}
+		}
+	}
+
+	// Vòng kế thừa và số cấp: duyệt các role tổ tiên theo từng cấp
+	levels := 0
+	visited := map[primitive.ObjectID]bool{}
+	frontier := roleSources(role)
+	for len(frontier) > 0 {
+		levels++
+		if levels+dependentLevels > MaxRoleInheritanceDepth {
+			return roleInheritanceError(fmt.Sprintf("Vượt quá số cấp kế thừa tối đa (%d cấp)", MaxRoleInheritanceDepth))
+		}
+		for _, roleID := range frontier {
+			visited[roleID] = true
+		}
+		loaded, err := loadRoles(ctx, frontier)
+		if err != nil {
+			return err
+		}
+		frontier = nil
+		for _, ancestor := range loaded {
+			for _, sourceID := range roleSources(ancestor) {
+				if sourceID == role.ID {
+					return roleInheritanceError("Liên kết kế thừa tạo thành vòng")
+				}
+				if !visited[sourceID] {
+					visited[sourceID] = true
+					frontier = append(frontier, sourceID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateRoleInheritanceDelegation kiểm tra người thực hiện được phép cấp các quyền role nhận thêm qua liên kết kế thừa mới
+// (gán role cha / template = cấp cho role, và các role đang kế thừa từ role, mọi quyền của role cha / template)
+func validateRoleInheritanceDelegation(ctx context.Context, role models.Role, previous *models.Role) error {
+	if _, _, ok := delegationCaller(ctx); !ok {
+		return nil
+	}
+
+	var previousSources []primitive.ObjectID
+	if previous != nil {
+		previousSources = roleSources(*previous)
+	}
+	linked := role
+	linked.ParentRoleID, linked.TemplateID = nil, nil
+	if role.ParentRoleID != nil && !containsObjectID(previousSources, *role.ParentRoleID) {
+		linked.ParentRoleID = role.ParentRoleID
+	}
+	if role.TemplateID != nil && !containsObjectID(previousSources, *role.TemplateID) {
+		linked.TemplateID = role.TemplateID
+	}
+	if len(roleSources(linked)) == 0 {
+		return nil
+	}
+
+	inherited, err := roleInheritedPermissions(ctx, []models.Role{linked}, false)
+	if err != nil {
+		return err
+	}
+	grants := make([]models.RolePermission, 0, len(inherited))
+	for _, item := range inherited {
+		grants = append(grants, item.RolePermission)
+	}
+	return validateRolePermissionDelegation(ctx, grants, role)
+}
+
+// containsObjectID kiểm tra ID có trong danh sách không
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRoleLinks kiểm tra liên kết kế thừa và quyền cấp khi tạo / cập nhật role (previous = nil khi tạo mới)
+// Role không có liên kết, không phải template và không đổi các field liên quan thì bỏ qua
+func validateRoleLinks(ctx context.Context, role models.Role, previous *models.Role) error {
+	if !role.IsTemplate && len(roleSources(role)) == 0 && previous == nil {
+		return nil
+	}
+	if err := validateRoleInheritance(ctx, role); err != nil {
+		return err
+	}
+	return validateRoleInheritanceDelegation(ctx, role, previous)
+}
+
+// roleLinkFields các field của role ảnh hưởng tới kế thừa quyền
+var roleLinkFields = []string{"parentRoleId", "templateId", "isTemplate", "ownerOrganizationId"}
+
+// normalizeRoleUpdate chuẩn hóa dữ liệu update của role: parentRoleId/templateId dạng chuỗi hex → ObjectID, rỗng/null → $unset
+// (dữ liệu update từ JSON giữ nguyên kiểu chuỗi, lưu chuỗi sẽ làm hỏng liên kết)
+func normalizeRoleUpdate(update interface{}) (*UpdateData, error) {
+	updateData, err := ToUpdateData(update)
+	if err != nil {
+		return nil, common.ErrInvalidFormat
+	}
+	for _, field := range []string{"parentRoleId", "templateId"} {
+		for i, values := range []map[string]interface{}{updateData.Set, updateData.SetOnInsert} {
+			value, ok := values[field]
+			if !ok {
+				continue
+			}
+			if s, isString := value.(string); isString && s != "" {
+				id, err := primitive.ObjectIDFromHex(s)
+				if err != nil {
+					return nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("%s không hợp lệ", field), common.StatusBadRequest, err)
+				}
+				values[field] = id
+				continue
+			}
+			if value != nil && value != "" {
+				continue
+			}
+			// Giá trị rỗng: gỡ liên kết ($set) hoặc bỏ qua ($setOnInsert)
+			delete(values, field)
+			if i == 0 {
+				if updateData.Unset == nil {
+					updateData.Unset = make(map[string]interface{})
+				}
+				updateData.Unset[field] = ""
+			}
+		}
+	}
+	return updateData, nil
+}
+
+// touchesRoleLinks kiểm tra dữ liệu update có đổi field liên quan đến kế thừa quyền không
+func touchesRoleLinks(updateData *UpdateData, upsert bool) bool {
+	for _, field := range roleLinkFields {
+		_, inSet := updateData.Set[field]
+		_, inUnset := updateData.Unset[field]
+		_, inSetOnInsert := updateData.SetOnInsert[field]
+		if inSet || inUnset || (upsert && inSetOnInsert) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRoleLinkUpdate trả về role sau khi áp dụng thay đổi các field kế thừa của update
+func applyRoleLinkUpdate(role models.Role, updateData *UpdateData, inserting bool) (models.Role, error) {
+	doc, err := bson.Marshal(role)
+	if err != nil {
+		return role, common.ErrInvalidFormat
+	}
+	var values bson.M
+	if err := bson.Unmarshal(doc, &values); err != nil {
+		return role, common.ErrInvalidFormat
+	}
+	for _, field := range roleLinkFields {
+		if inserting {
+			if value, ok := updateData.SetOnInsert[field]; ok {
+				values[field] = value
+			}
+		}
+		if value, ok := updateData.Set[field]; ok {
+			values[field] = value
+		}
+		if _, ok := updateData.Unset[field]; ok {
+			delete(values, field)
+		}
+		if value, ok := values[field].(string); ok {
+			if id, err := primitive.ObjectIDFromHex(value); err == nil {
+				values[field] = id
+			}
+		}
+	}
+	data, err := bson.Marshal(values)
+	if err != nil {
+		return role, common.ErrInvalidFormat
+	}
+	var result models.Role
+	if err := bson.Unmarshal(data, &result); err != nil {
+		return role, common.ErrInvalidFormat
+	}
+	return result, nil
+}
+
+// ====================================
+// TEMPLATE
+// ====================================
+
+// InstantiateTemplate khởi tạo role từ template cho các tổ chức con của tổ chức sở hữu template
+// Role mới kế thừa quyền của template (templateId), thay đổi quyền của template áp dụng ngay cho các role này
+// name rỗng → dùng tên template. Tổ chức đã có role cùng tên → lỗi, không tạo role nào.
+func (s *RoleService) InstantiateTemplate(ctx context.Context, templateID primitive.ObjectID, orgIDs []primitive.ObjectID, name string) ([]models.Role, error) {
+	template, err := s.FindOneById(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !template.IsTemplate {
+		return nil, roleInheritanceError(fmt.Sprintf("Role '%s' không phải là template", template.Name))
+	}
+	if name == "" {
+		name = template.Name
+	}
+
+	existing, err := s.BaseServiceMongoImpl.Find(ctx, bson.M{"ownerOrganizationId": bson.M{"$in": orgIDs}, "name": name}, nil)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		conflicts := make([]string, 0, len(existing))
+		for _, role := range existing {
+			conflicts = append(conflicts, role.OwnerOrganizationID.Hex())
+		}
+		return nil, common.NewError(
+			common.ErrCodeBusinessOperation,
+			fmt.Sprintf("Đã có role '%s' trong %d tổ chức", name, len(existing)),
+			common.StatusConflict,
+			map[string]interface{}{"organizationIds": conflicts},
+		)
+	}
+
+	roles := make([]models.Role, 0, len(orgIDs))
+	for _, orgID := range orgIDs {
+		templateRef := templateID
+		roles = append(roles, models.Role{
+			ID:                  primitive.NewObjectID(),
+			Name:                name,
+			Describe:            template.Describe,
+			OwnerOrganizationID: orgID,
+			TemplateID:          &templateRef,
+		})
+	}
+	return s.InsertMany(ctx, roles)
+}
+
+// DetachTemplate tách role khỏi template: sao chép quyền đang kế thừa từ template (kể cả role cha của template) thành quyền riêng
+// của role rồi bỏ liên kết. Quyền hiệu lực của role không đổi, thay đổi sau này của template không còn áp dụng.
+func (s *RoleService) DetachTemplate(ctx context.Context, roleID primitive.ObjectID) (*models.Role, error) {
+	role, err := s.FindOneById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.TemplateID == nil {
+		return nil, roleInheritanceError("Role không được khởi tạo từ template")
+	}
+
+	template, err := s.FindOneById(ctx, *role.TemplateID)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	var inherited []InheritedRolePermission
+	if err == nil {
+		if inherited, err = roleInheritedPermissions(ctx, []models.Role{template}, true); err != nil {
+			return nil, err
+		}
+	}
+
+	rolePermissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.RolePermissions)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	var own []models.RolePermission
+	if err := findDelegationDocs(ctx, rolePermissionCollection, bson.M{"roleId": roleID}, &own); err != nil {
+		return nil, err
+	}
+
+	// Quyền role đã có (cùng permission, scope, điều kiện) không sao chép lại
+	createdByUserID, createdByRoleID, _ := delegationCaller(ctx)
+	now := time.Now().Unix()
+	var copies []interface{}
+	for _, item := range inherited {
+		duplicated := false
+		for _, rolePermission := range own {
+			if rolePermission.PermissionID == item.PermissionID && rolePermission.Scope == item.Scope && SameConditions(rolePermission.Conditions, item.Conditions) {
+				duplicated = true
+				break
+			}
+		}
+		if duplicated {
+			continue
+		}
+		rolePermission := models.RolePermission{
+			ID:              primitive.NewObjectID(),
+			RoleID:          roleID,
+			PermissionID:    item.PermissionID,
+			Scope:           item.Scope,
+			Conditions:      item.Conditions,
+			CreatedByUserID: createdByUserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if createdByRoleID != nil {
+			rolePermission.CreatedByRoleID = *createdByRoleID
+		}
+		own = append(own, rolePermission)
+		copies = append(copies, rolePermission)
+	}
+
+	// Ghi trực tiếp (không qua kiểm tra quyền cấp): role đã có các quyền này qua template
+	if len(copies) > 0 {
+		if _, err := rolePermissionCollection.InsertMany(ctx, copies); err != nil {
+			return nil, common.ConvertMongoError(err)
+		}
+	}
+	updated, err := s.BaseServiceMongoImpl.UpdateById(ctx, roleID, &UpdateData{Unset: map[string]interface{}{"templateId": ""}})
+	if err != nil {
+		return nil, err
+	}
+
+	publishRolePermissionChange(roleID)
+	return &updated, nil
+}
+
+// GetRolePermissions lấy quyền hiệu lực của role: quyền riêng và quyền kế thừa từ role cha / template (kèm role sở hữu)
+func (s *RoleService) GetRolePermissions(ctx context.Context, roleID primitive.ObjectID) ([]InheritedRolePermission, error) {
+	role, err := s.FindOneById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := roleInheritedPermissions(ctx, []models.Role{role}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionIDs := make([]primitive.ObjectID, 0, len(result))
+	for _, item := range result {
+		permissionIDs = append(permissionIDs, item.PermissionID)
+	}
+	permissionCollection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Permissions)
+	if !exist {
+		return nil, common.ErrNotFound
+	}
+	var permissions []models.Permission
+	if err := findDelegationDocs(ctx, permissionCollection, bson.M{"_id": bson.M{"$in": permissionIDs}}, &permissions); err != nil {
+		return nil, err
+	}
+	names := make(map[primitive.ObjectID]string, len(permissions))
+	for _, permission := range permissions {
+		names[permission.ID] = permission.Name
+	}
+	for i := range result {
+		result[i].PermissionName = names[result[i].PermissionID]
+	}
+	if result == nil {
+		result = []InheritedRolePermission{}
+	}
+	return result, nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // RolePermissionService là cấu trúc chứa các phương thức liên quan đến quyền của vai trò
@@ -81,12 +82,19 @@ func (s *RolePermissionService) Create(ctx context.Context, input *dto.RolePermi
 		RoleID:       roleObjID,
 		PermissionID: permissionObjID,
 		Scope:        input.Scope,
+		Conditions:   input.Conditions,
 		CreatedAt:    time.Now().Unix(),
 		UpdatedAt:    time.Now().Unix(),
 	}
+	if userID, roleID, ok := delegationCaller(ctx); ok {
+		rolePermission.CreatedByUserID = userID
+		if roleID != nil {
+			rolePermission.CreatedByRoleID = *roleID
+		}
+	}
 
 	// Lưu rolePermission
-	createdRolePermission, err := s.BaseServiceMongoImpl.InsertOne(ctx, *rolePermission)
+	createdRolePermission, err := s.InsertOne(ctx, *rolePermission)
 	if err != nil {
 		return nil, common.ConvertMongoError(err)
 	}
@@ -106,3 +114,271 @@ func (s *RolePermissionService) IsExist(ctx context.Context, roleID, permissionI
 	}
 	return count > 0, nil
 }
+
+// UpdateRolePermissions thay thế toàn bộ danh sách quyền của vai trò
+// Các permissionId không hợp lệ sẽ bị bỏ qua
+// Người thực hiện chỉ được thêm quyền (hoặc mở rộng scope) mà mình có thể cấp (xem validateRolePermissionDelegation)
+func (s *RolePermissionService) UpdateRolePermissions(ctx context.Context, roleID primitive.ObjectID, items []dto.RolePermissionUpdateItem) ([]models.RolePermission, error) {
+	// Tạo danh sách role permission mới
+	var rolePermissions []models.RolePermission
+	now := time.Now().Unix()
+	createdByUserID, createdByRoleID, _ := delegationCaller(ctx)
+
+	for _, item := range items {
+		permissionID, err := primitive.ObjectIDFromHex(item.PermissionID)
+		if err != nil {
+			continue // Bỏ qua các permissionId không hợp lệ
+		}
+		rolePermission := models.RolePermission{
+			ID:              primitive.NewObjectID(),
+			RoleID:          roleID,
+			PermissionID:    permissionID,
+			Scope:           item.Scope,
+			Conditions:      item.Conditions,
+			CreatedByUserID: createdByUserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if createdByRoleID != nil {
+			rolePermission.CreatedByRoleID = *createdByRoleID
+		}
+		rolePermissions = append(rolePermissions, rolePermission)
+	}
+
+	// Kiểm tra trước khi xóa quyền cũ để request bị từ chối không làm mất quyền của role
+	if err := validateRolePermissionConditions(rolePermissions); err != nil {
+		return nil, err
+	}
+	if err := validateRolePermissionDelegation(ctx, rolePermissions); err != nil {
+		return nil, err
+	}
+
+	// Xóa tất cả role permission cũ của role
+	if _, err := s.BaseServiceMongoImpl.DeleteMany(ctx, bson.M{"roleId": roleID}); err != nil {
+		return nil, err
+	}
+	// Quyền cũ đã bị xóa → invalidate ngay cả khi bước thêm mới bên dưới lỗi
+	defer publishRolePermissionChange(roleID)
+
+	if len(rolePermissions) > 0 {
+		if _, err := s.BaseServiceMongoImpl.InsertMany(ctx, rolePermissions); err != nil {
+			return nil, err
+		}
+	}
+
+	return rolePermissions, nil
+}
+
+// validateRolePermissionConditions kiểm tra điều kiện của các role permission (xem ValidatePermissionConditions)
+func validateRolePermissionConditions(rolePermissions []models.RolePermission) error {
+	for _, rolePermission := range rolePermissions {
+		if err := ValidatePermissionConditions(rolePermission.Conditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateUpdateConditions kiểm tra điều kiện mới trong dữ liệu update ($set, $setOnInsert)
+func validateUpdateConditions(update interface{}) error {
+	updateData, err := ToUpdateData(update)
+	if err != nil {
+		return common.ErrInvalidFormat
+	}
+	for _, values := range []map[string]interface{}{updateData.Set, updateData.SetOnInsert} {
+		value, ok := values["conditions"]
+		if !ok || value == nil {
+			continue
+		}
+		conditions, ok := normalizeConditionValue(value).(map[string]interface{})
+		if !ok {
+			return conditionError("Điều kiện phải là một object JSON", "")
+		}
+		if err := ValidatePermissionConditions(conditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateUpdateDelegation kiểm tra update đổi roleId/permissionId/scope/conditions không cấp quyền vượt quá quyền của người thực hiện
+// và điều kiện mới (nếu có) hợp lệ
+func (s *RolePermissionService) validateUpdateDelegation(ctx context.Context, filter interface{}, update interface{}, upsert bool) error {
+	if err := validateUpdateConditions(update); err != nil {
+		return err
+	}
+	updated, err := delegationUpdatedDocs[models.RolePermission](ctx, s.collection, filter, update, upsert, "roleId", "permissionId", "scope", "conditions")
+	if err != nil {
+		return err
+	}
+	return validateRolePermissionDelegation(ctx, updated)
+}
+
+// findAffectedRoleIDs lấy danh sách roleId của các role permission khớp filter (dùng trước khi xóa)
+func (s *RolePermissionService) findAffectedRoleIDs(ctx context.Context, filter interface{}) ([]primitive.ObjectID, error) {
+	rolePermissions, err := s.BaseServiceMongoImpl.Find(ctx, filter, nil)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+
+	roleIDs := make([]primitive.ObjectID, 0, len(rolePermissions))
+	for _, rolePermission := range rolePermissions {
+		roleIDs = append(roleIDs, rolePermission.RoleID)
+	}
+	return roleIDs, nil
+}
+
+// InsertOne override method InsertOne để kiểm tra điều kiện, quyền cấp (chống leo thang đặc quyền) và invalidate cache quyền của role
+func (s *RolePermissionService) InsertOne(ctx context.Context, data models.RolePermission) (models.RolePermission, error) {
+	if err := validateRolePermissionConditions([]models.RolePermission{data}); err != nil {
+		return data, err
+	}
+	if err := validateRolePermissionDelegation(ctx, []models.RolePermission{data}); err != nil {
+		return data, err
+	}
+
+	result, err := s.BaseServiceMongoImpl.InsertOne(ctx, data)
+	if err != nil {
+		return result, err
+	}
+
+	publishRolePermissionChange(result.RoleID)
+	return result, nil
+}
+
+// InsertMany override method InsertMany để kiểm tra quyền cấp và invalidate cache quyền của các role
+func (s *RolePermissionService) InsertMany(ctx context.Context, data []models.RolePermission) ([]models.RolePermission, error) {
+	if err := validateRolePermissionConditions(data); err != nil {
+		return nil, err
+	}
+	if err := validateRolePermissionDelegation(ctx, data); err != nil {
+		return nil, err
+	}
+
+	result, err := s.BaseServiceMongoImpl.InsertMany(ctx, data)
+	if err != nil {
+		return result, err
+	}
+
+	roleIDs := make([]primitive.ObjectID, 0, len(result))
+	for _, rolePermission := range result {
+		roleIDs = append(roleIDs, rolePermission.RoleID)
+	}
+	publishRolePermissionChange(roleIDs...)
+	return result, nil
+}
+
+// UpdateOne override method UpdateOne để invalidate cache quyền
+// Update có thể đổi roleId nên không xác định được phạm vi → invalidate toàn bộ
+func (s *RolePermissionService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (models.RolePermission, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return models.RolePermission{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, update, opts)
+}
+
+// UpdateMany override method UpdateMany để invalidate cache quyền
+func (s *RolePermissionService) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (int64, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return 0, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateMany(ctx, filter, update, opts)
+}
+
+// UpdateById override method UpdateById để invalidate cache quyền
+func (s *RolePermissionService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (models.RolePermission, error) {
+	if err := s.validateUpdateDelegation(ctx, bson.M{"_id": id}, data, false); err != nil {
+		return models.RolePermission{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, data)
+}
+
+// FindOneAndUpdate override method FindOneAndUpdate để invalidate cache quyền
+func (s *RolePermissionService) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.FindOneAndUpdateOptions) (models.RolePermission, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return models.RolePermission{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.FindOneAndUpdate(ctx, filter, update, opts)
+}
+
+// Upsert override method Upsert để invalidate cache quyền
+func (s *RolePermissionService) Upsert(ctx context.Context, filter interface{}, data interface{}) (models.RolePermission, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, data, true); err != nil {
+		return models.RolePermission{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.Upsert(ctx, filter, data)
+}
+
+// UpsertMany override method UpsertMany để invalidate cache quyền
+func (s *RolePermissionService) UpsertMany(ctx context.Context, filter interface{}, data []models.RolePermission) ([]models.RolePermission, error) {
+	if err := validateRolePermissionConditions(data); err != nil {
+		return nil, err
+	}
+	if err := validateRolePermissionDelegation(ctx, data); err != nil {
+		return nil, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpsertMany(ctx, filter, data)
+}
+
+// DeleteOne override method DeleteOne để invalidate cache quyền của role
+func (s *RolePermissionService) DeleteOne(ctx context.Context, filter interface{}) error {
+	roleIDs, err := s.findAffectedRoleIDs(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if err := s.BaseServiceMongoImpl.DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+
+	publishRolePermissionChange(roleIDs...)
+	return nil
+}
+
+// DeleteMany override method DeleteMany để invalidate cache quyền của các role
+func (s *RolePermissionService) DeleteMany(ctx context.Context, filter interface{}) (int64, error) {
+	roleIDs, err := s.findAffectedRoleIDs(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
+	if err != nil {
+		return deleted, err
+	}
+
+	publishRolePermissionChange(roleIDs...)
+	return deleted, nil
+}
+
+// DeleteById override method DeleteById để invalidate cache quyền của role
+func (s *RolePermissionService) DeleteById(ctx context.Context, id primitive.ObjectID) error {
+	rolePermission, err := s.BaseServiceMongoImpl.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+
+	publishRolePermissionChange(rolePermission.RoleID)
+	return nil
+}
+
+// FindOneAndDelete override method FindOneAndDelete để invalidate cache quyền của role
+func (s *RolePermissionService) FindOneAndDelete(ctx context.Context, filter interface{}, opts *mongoopts.FindOneAndDeleteOptions) (models.RolePermission, error) {
+	result, err := s.BaseServiceMongoImpl.FindOneAndDelete(ctx, filter, opts)
+	if err != nil {
+		return result, err
+	}
+
+	publishRolePermissionChange(result.RoleID)
+	return result, nil
+}
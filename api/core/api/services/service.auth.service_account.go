@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+)
+
+// ServiceAccountService là service quản lý service account (principal của máy: bot, dịch vụ nội bộ)
+type ServiceAccountService struct {
+	*BaseServiceMongoImpl[models.ServiceAccount]
+}
+
+// NewServiceAccountService tạo mới ServiceAccountService
+func NewServiceAccountService() (*ServiceAccountService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.ServiceAccounts)
+	if !exist {
+		return nil, fmt.Errorf("failed to get service_accounts collection: %v", common.ErrNotFound)
+	}
+
+	return &ServiceAccountService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.ServiceAccount](collection),
+	}, nil
+}
+
+// InsertOne override method InsertOne để gán người tạo
+func (s *ServiceAccountService) InsertOne(ctx context.Context, data models.ServiceAccount) (models.ServiceAccount, error) {
+	if data.OwnerOrganizationID.IsZero() {
+		return data, common.NewError(common.ErrCodeValidationInput, "Service account phải thuộc một tổ chức (ownerOrganizationId)", common.StatusBadRequest, nil)
+	}
+	if userID, ok := GetUserIDFromContext(ctx); ok {
+		data.CreatedBy = userID
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
@@ -4,14 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"strconv"
 	"time"
 
 	"meta_commerce/core/api/dto"
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
 	"meta_commerce/core/global"
+	"meta_commerce/core/identity"
 	"meta_commerce/core/logger"
 	"meta_commerce/core/utility"
 
@@ -25,8 +24,9 @@ import (
 // UserService là cấu trúc chứa các phương thức liên quan đến người dùng
 type UserService struct {
 	*BaseServiceMongoImpl[models.User]
-	userRoleService *BaseServiceMongoImpl[models.UserRole]
-	collection      *mongo.Collection // Lưu reference để insert trực tiếp với bson.M
+	userRoleService     *BaseServiceMongoImpl[models.UserRole]
+	revokedTokenService *RevokedTokenService
+	collection          *mongo.Collection // Lưu reference để insert trực tiếp với bson.M
 }
 
 // NewUserService tạo mới UserService
@@ -42,28 +42,48 @@ func NewUserService() (*UserService, error) {
 		return nil, fmt.Errorf("failed to get user_roles collection: %v", common.ErrNotFound)
 	}
 
+	revokedTokenService, err := NewRevokedTokenService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revoked token service: %v", err)
+	}
+
 	return &UserService{
 		BaseServiceMongoImpl: NewBaseServiceMongo[models.User](userCollection),
 		userRoleService:      NewBaseServiceMongo[models.UserRole](userRoleCollection),
+		revokedTokenService:  revokedTokenService,
 		collection:           userCollection,
 	}, nil
 }
 
 // Logout đăng xuất người dùng
-func (s *UserService) Logout(ctx context.Context, userID primitive.ObjectID, input *dto.UserLogoutInput) error {
+// Xóa phiên của hwid và thu hồi ngay access/refresh token của phiên đó.
+// currentClaims là claims của access token đang dùng để gọi logout (có thể nil)
+func (s *UserService) Logout(ctx context.Context, userID primitive.ObjectID, input *dto.UserLogoutInput, currentClaims *models.JwtToken) error {
 	// Tìm user
 	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	// Xóa token của hwid
+	// Xóa token của hwid và thu hồi các jti của phiên đó
 	newTokens := make([]models.Token, 0)
 	for _, t := range user.Tokens {
 		if t.Hwid != input.Hwid {
 			newTokens = append(newTokens, t)
+			continue
+		}
+		if err := s.revokedTokenService.RevokeSession(ctx, userID, t, "logout"); err != nil {
+			return err
 		}
 	}
+
+	// Thu hồi cả token đang dùng (trường hợp hwid gửi lên khác với hwid của token)
+	if currentClaims != nil {
+		if err := s.revokedTokenService.Revoke(ctx, currentClaims.Id, userID, currentClaims.ExpiresAt, "logout"); err != nil {
+			return err
+		}
+	}
+
 	user.Tokens = newTokens
 	user.Token = "" // Xóa token hiện tại
 	user.UpdatedAt = time.Now().Unix()
@@ -73,203 +93,511 @@ func (s *UserService) Logout(ctx context.Context, userID primitive.ObjectID, inp
 	return err
 }
 
-// LoginWithFirebase đăng nhập bằng Firebase ID token
-func (s *UserService) LoginWithFirebase(ctx context.Context, input *dto.FirebaseLoginInput) (*models.User, error) {
-	logrus.WithFields(logrus.Fields{
-		"hwid": input.Hwid,
-	}).Debug("LoginWithFirebase: Bắt đầu đăng nhập với Firebase")
+// EndImpersonation kết thúc phiên "login as": chỉ thu hồi token impersonation đang dùng, không đụng tới phiên của user
+func (s *UserService) EndImpersonation(ctx context.Context, claims *models.JwtToken) error {
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return common.ErrTokenInvalid
+	}
+	return s.revokedTokenService.Revoke(ctx, claims.Id, userID, claims.ExpiresAt, "impersonation_end")
+}
 
-	// 1. Verify Firebase ID token
-	token, err := utility.VerifyIDToken(ctx, input.IDToken)
+// RevokeAllSessions thu hồi toàn bộ token (mọi thiết bị) của user và xóa danh sách phiên
+// Dùng khi khóa tài khoản hoặc khi cần buộc user đăng nhập lại
+func (s *UserService) RevokeAllSessions(ctx context.Context, userID primitive.ObjectID, reason string) error {
+	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
 	if err != nil {
-		logrus.WithError(err).Error("LoginWithFirebase: Lỗi verify Firebase ID token")
-		return nil, common.NewError(
-			common.ErrCodeAuthCredentials,
-			"Token không hợp lệ",
-			common.StatusUnauthorized,
-			err,
-		)
+		return err
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"firebase_uid": token.UID,
-	}).Debug("LoginWithFirebase: Firebase token hợp lệ")
+	for _, t := range user.Tokens {
+		if err := s.revokedTokenService.RevokeSession(ctx, userID, t, reason); err != nil {
+			return err
+		}
+	}
 
-	// 2. Lấy thông tin user từ Firebase
-	firebaseUser, err := utility.GetUserByUID(ctx, token.UID)
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"tokens":    []models.Token{},
+		"token":     "",
+		"updatedAt": time.Now().UnixMilli(),
+	}})
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// ListSessions lấy danh sách phiên đăng nhập (mỗi thiết bị một phiên) của user
+func (s *UserService) ListSessions(ctx context.Context, userID primitive.ObjectID) ([]models.Token, error) {
+	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"firebase_uid": token.UID,
-			"error":        err.Error(),
-		}).Error("LoginWithFirebase: Lỗi lấy thông tin user từ Firebase")
 		return nil, err
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"firebase_uid":   token.UID,
-		"email":          firebaseUser.Email,
-		"phone":          firebaseUser.PhoneNumber,
-		"email_verified": firebaseUser.EmailVerified,
-	}).Debug("LoginWithFirebase: Đã lấy thông tin user từ Firebase")
+	sessions := make([]models.Token, 0, len(user.Tokens))
+	for _, t := range user.Tokens {
+		// Không trả token ra ngoài
+		t.JwtToken = ""
+		sessions = append(sessions, t)
+	}
+	return sessions, nil
+}
 
-	// 3. Kiểm tra conflict với email/phone trước khi upsert
-	// (để tránh tạo user mới khi đã có user khác dùng email/phone này)
-	var existingUser *models.User
-	var foundBy string
+// RevokeSession đăng xuất một thiết bị: thu hồi token của phiên và xóa phiên khỏi user
+func (s *UserService) RevokeSession(ctx context.Context, userID primitive.ObjectID, hwid string, reason string) error {
+	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
+	if err != nil {
+		return err
+	}
 
-	// Kiểm tra theo email nếu có
-	if firebaseUser.Email != "" {
-		emailFilter := bson.M{"email": firebaseUser.Email}
-		logrus.WithFields(logrus.Fields{
-			"email": firebaseUser.Email,
-		}).Debug("LoginWithFirebase: Kiểm tra user theo email")
-		if emailUser, emailErr := s.BaseServiceMongoImpl.FindOne(ctx, emailFilter, nil); emailErr == nil {
-			existingUser = &emailUser
-			foundBy = "email"
-			logrus.WithFields(logrus.Fields{
-				"user_id":      emailUser.ID.Hex(),
-				"firebase_uid": emailUser.FirebaseUID,
-			}).Debug("LoginWithFirebase: Tìm thấy user theo email")
-		} else if !errors.Is(emailErr, common.ErrNotFound) {
-			logrus.WithError(emailErr).Error("LoginWithFirebase: Lỗi khi tìm user theo email")
-			return nil, emailErr
+	found := false
+	for _, t := range user.Tokens {
+		if t.Hwid == hwid {
+			found = true
+			if err := s.revokedTokenService.RevokeSession(ctx, userID, t, reason); err != nil {
+				return err
+			}
 		}
 	}
+	if !found {
+		return common.NewError(common.ErrCodeDatabaseQuery, "Không tìm thấy phiên đăng nhập của thiết bị", common.StatusNotFound, nil)
+	}
 
-	// Kiểm tra theo phone nếu có và chưa tìm thấy user
-	if existingUser == nil && firebaseUser.PhoneNumber != "" {
-		phoneFilter := bson.M{"phone": firebaseUser.PhoneNumber}
-		logrus.WithFields(logrus.Fields{
-			"phone": firebaseUser.PhoneNumber,
-		}).Debug("LoginWithFirebase: Kiểm tra user theo phone")
-		if phoneUser, phoneErr := s.BaseServiceMongoImpl.FindOne(ctx, phoneFilter, nil); phoneErr == nil {
-			existingUser = &phoneUser
-			foundBy = "phone"
-			logrus.WithFields(logrus.Fields{
-				"user_id":      phoneUser.ID.Hex(),
-				"firebase_uid": phoneUser.FirebaseUID,
-			}).Debug("LoginWithFirebase: Tìm thấy user theo phone")
-		} else if !errors.Is(phoneErr, common.ErrNotFound) {
-			logrus.WithError(phoneErr).Error("LoginWithFirebase: Lỗi khi tìm user theo phone")
-			return nil, phoneErr
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$pull": bson.M{"tokens": bson.M{"hwid": hwid}},
+		"$set":  bson.M{"updatedAt": time.Now().UnixMilli()},
+	})
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions đăng xuất tất cả thiết bị khác ngoài keepHwid, trả về số phiên đã thu hồi
+func (s *UserService) RevokeOtherSessions(ctx context.Context, userID primitive.ObjectID, keepHwid string, reason string) (int, error) {
+	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, t := range user.Tokens {
+		if t.Hwid == keepHwid {
+			continue
+		}
+		if err := s.revokedTokenService.RevokeSession(ctx, userID, t, reason); err != nil {
+			return revoked, err
 		}
+		revoked++
+	}
+	if revoked == 0 {
+		return 0, nil
 	}
 
-	// 4. Nếu tìm thấy user đã tồn tại với email/phone, kiểm tra conflict
-	if existingUser != nil {
-		// Kiểm tra xem user này đã có firebaseUid chưa
-		if existingUser.FirebaseUID != "" && existingUser.FirebaseUID != token.UID {
-			// User này đã có firebaseUid khác - conflict
-			var conflictField string
-			if foundBy == "email" {
-				conflictField = fmt.Sprintf("Email '%s'", firebaseUser.Email)
-			} else {
-				conflictField = fmt.Sprintf("Số điện thoại '%s'", firebaseUser.PhoneNumber)
-			}
-			logrus.WithFields(logrus.Fields{
-				"existing_firebase_uid": existingUser.FirebaseUID,
-				"new_firebase_uid":      token.UID,
-				"found_by":              foundBy,
-			}).Warn("LoginWithFirebase: Conflict - email/phone đã được sử dụng bởi tài khoản khác")
-			return nil, common.NewError(
-				common.ErrCodeAuthCredentials,
-				fmt.Sprintf("%s đã được sử dụng bởi tài khoản khác. Vui lòng sử dụng %s khác hoặc đăng nhập bằng tài khoản cũ.", conflictField, foundBy),
-				common.StatusConflict,
-				nil,
-			)
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$pull": bson.M{"tokens": bson.M{"hwid": bson.M{"$ne": keepHwid}}},
+		"$set":  bson.M{"updatedAt": time.Now().UnixMilli()},
+	})
+	if err != nil {
+		return revoked, common.ConvertMongoError(err)
+	}
+	return revoked, nil
+}
+
+// TouchSession cập nhật thời điểm hoạt động gần nhất, IP và User-Agent của phiên (thiết bị)
+func (s *UserService) TouchSession(ctx context.Context, userID primitive.ObjectID, hwid string, ip string, userAgent string) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "tokens.hwid": hwid},
+		bson.M{"$set": bson.M{
+			"tokens.$.lastSeenAt": time.Now().UnixMilli(),
+			"tokens.$.ip":         ip,
+			"tokens.$.userAgent":  userAgent,
+		}},
+	)
+	if err != nil {
+		return common.ConvertMongoError(err)
+	}
+	return nil
+}
+
+// RefreshToken đổi refresh token lấy cặp access/refresh token mới (rotation)
+// Refresh token cũ bị thu hồi ngay sau khi dùng. Nếu một refresh token cũ bị dùng lại
+// (không còn khớp với phiên hiện tại của thiết bị), toàn bộ phiên của thiết bị đó bị thu hồi.
+func (s *UserService) RefreshToken(ctx context.Context, input *dto.RefreshTokenInput) (*models.User, error) {
+	claims, err := utility.ParseToken(global.MongoDB_ServerConfig.JwtSecret, input.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != models.JwtTokenTypeRefresh {
+		return nil, common.ErrTokenInvalid
+	}
+
+	// Refresh token đã bị thu hồi (thường là token cũ đã rotate) vẫn phải đi qua bước phát hiện dùng lại bên dưới
+	revoked, err := s.revokedTokenService.IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, common.ErrTokenInvalid
+	}
+
+	user, err := s.BaseServiceMongoImpl.FindOneById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, common.ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	if user.IsBlock {
+		return nil, common.NewError(
+			common.ErrCodeAuth,
+			"Tài khoản đã bị khóa",
+			common.StatusForbidden,
+			nil,
+		)
+	}
+
+	// Tìm phiên của thiết bị
+	var current *models.Token
+	for i := range user.Tokens {
+		if user.Tokens[i].Hwid == claims.Hwid {
+			current = &user.Tokens[i]
+			break
 		}
-		// User này chưa có firebaseUid hoặc firebaseUid trùng, sẽ update bằng upsert
+	}
+
+	if revoked || current == nil || current.RefreshJti != claims.Id {
+		// Refresh token hợp lệ về chữ ký nhưng đã bị thu hồi hoặc không còn là token hiện tại của thiết bị
+		// → có thể đã bị đánh cắp và dùng lại, thu hồi toàn bộ phiên của thiết bị
 		logrus.WithFields(logrus.Fields{
-			"user_id": existingUser.ID.Hex(),
-		}).Debug("LoginWithFirebase: User đã tồn tại, sẽ update bằng upsert")
-	} else {
-		logrus.Debug("LoginWithFirebase: Không tìm thấy user, sẽ tạo mới bằng upsert")
+			"user_id": user.ID.Hex(),
+			"hwid":    claims.Hwid,
+		}).Warn("RefreshToken: Phát hiện refresh token bị dùng lại, thu hồi phiên của thiết bị")
+
+		if current != nil {
+			if err := s.revokedTokenService.RevokeSession(ctx, user.ID, *current, "refresh_reuse"); err != nil {
+				return nil, err
+			}
+			if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+				"$pull": bson.M{"tokens": bson.M{"hwid": claims.Hwid}},
+			}); err != nil {
+				return nil, common.ConvertMongoError(err)
+			}
+		}
+		_ = s.revokedTokenService.Revoke(ctx, claims.Id, user.ID, claims.ExpiresAt, "refresh_reuse")
+		return nil, common.ErrTokenInvalid
+	}
+
+	// Giữ thời điểm xác thực TOTP của phiên: claim MFA vẫn chỉ "mới" trong MFA_STEP_UP_MAX_AGE giây
+	session, refreshToken, err := newSessionTokens(user.ID, claims.Hwid, current.MfaAt)
+	if err != nil {
+		return nil, err
+	}
+	session.RoleID = current.RoleID
+	session.CreatedAt = current.CreatedAt
+	session.LastSeenAt = time.Now().UnixMilli()
+	session.IP = input.IP
+	session.UserAgent = input.UserAgent
+
+	// Chỉ thay phiên nếu refresh token vẫn là token hiện tại (tránh 2 request refresh đồng thời cùng thành công)
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":    user.ID,
+			"tokens": bson.M{"$elemMatch": bson.M{"hwid": claims.Hwid, "refreshJti": claims.Id}},
+		},
+		bson.M{"$set": bson.M{
+			"tokens.$":  session,
+			"token":     session.JwtToken,
+			"updatedAt": time.Now().UnixMilli(),
+		}},
+	)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, common.ErrTokenInvalid
+	}
+
+	// Thu hồi cặp token cũ
+	if err := s.revokedTokenService.RevokeSession(ctx, user.ID, *current, "refresh"); err != nil {
+		return nil, err
+	}
+
+	user.Token = session.JwtToken
+	user.RefreshToken = refreshToken
+	user.TokenExpiresAt = session.AccessExpiresAt
+	return &user, nil
+}
+
+// newSessionTokens tạo cặp access token + refresh token mới cho một thiết bị (hwid)
+// mfaAt là thời điểm xác thực TOTP gần nhất của phiên (0 = chưa), được đưa vào claim của access token
+// Trả về phiên (lưu vào user.Tokens) và refresh token đã ký (chỉ trả về cho client, không lưu DB)
+func newSessionTokens(userID primitive.ObjectID, hwid string, mfaAt int64) (models.Token, string, error) {
+	cfg := global.MongoDB_ServerConfig
+
+	accessClaims, err := utility.NewJwtClaims(userID.Hex(), hwid, models.JwtTokenTypeAccess, time.Duration(cfg.JwtAccessTTL)*time.Second)
+	if err != nil {
+		return models.Token{}, "", err
+	}
+	accessClaims.MfaAt = mfaAt
+	refreshClaims, err := utility.NewJwtClaims(userID.Hex(), hwid, models.JwtTokenTypeRefresh, time.Duration(cfg.JwtRefreshTTL)*time.Second)
+	if err != nil {
+		return models.Token{}, "", err
+	}
+
+	accessToken, err := utility.SignToken(cfg.JwtSecret, accessClaims)
+	if err != nil {
+		return models.Token{}, "", err
+	}
+	refreshToken, err := utility.SignToken(cfg.JwtSecret, refreshClaims)
+	if err != nil {
+		return models.Token{}, "", err
+	}
+
+	return models.Token{
+		Hwid:             hwid,
+		JwtToken:         accessToken,
+		AccessJti:        accessClaims.Id,
+		RefreshJti:       refreshClaims.Id,
+		AccessExpiresAt:  accessClaims.ExpiresAt,
+		RefreshExpiresAt: refreshClaims.ExpiresAt,
+		MfaAt:            mfaAt,
+	}, refreshToken, nil
+}
+
+// MarkSessionMFA đánh dấu phiên của access token hiện tại đã xác thực TOTP (step-up)
+// Cấp access token mới có claim MFA = mfaAt thay cho access token đang dùng (token cũ bị thu hồi), refresh token giữ nguyên
+func (s *UserService) MarkSessionMFA(ctx context.Context, userID primitive.ObjectID, claims *models.JwtToken, mfaAt int64) (*models.Token, error) {
+	cfg := global.MongoDB_ServerConfig
+	accessClaims, err := utility.NewJwtClaims(userID.Hex(), claims.Hwid, models.JwtTokenTypeAccess, time.Duration(cfg.JwtAccessTTL)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	accessClaims.MfaAt = mfaAt
+	accessToken, err := utility.SignToken(cfg.JwtSecret, accessClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chỉ cập nhật nếu access token đang dùng vẫn là token hiện tại của phiên
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":    userID,
+			"tokens": bson.M{"$elemMatch": bson.M{"hwid": claims.Hwid, "accessJti": claims.Id}},
+		},
+		bson.M{"$set": bson.M{
+			"tokens.$.jwtToken":        accessToken,
+			"tokens.$.accessJti":       accessClaims.Id,
+			"tokens.$.accessExpiresAt": accessClaims.ExpiresAt,
+			"tokens.$.mfaAt":           mfaAt,
+			"token":                    accessToken,
+			"updatedAt":                time.Now().UnixMilli(),
+		}},
+	)
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, common.ErrTokenInvalid
+	}
+
+	if err := s.revokedTokenService.Revoke(ctx, claims.Id, userID, claims.ExpiresAt, "mfa_step_up"); err != nil {
+		return nil, err
+	}
+
+	return &models.Token{
+		Hwid:            claims.Hwid,
+		JwtToken:        accessToken,
+		AccessJti:       accessClaims.Id,
+		AccessExpiresAt: accessClaims.ExpiresAt,
+		MfaAt:           mfaAt,
+	}, nil
+}
+
+// LoginWithFirebase đăng nhập bằng Firebase ID token
+// Giữ lại để tương thích với route /auth/login/firebase, tương đương LoginWithIdentity với provider "firebase"
+func (s *UserService) LoginWithFirebase(ctx context.Context, input *dto.FirebaseLoginInput) (*models.User, error) {
+	return s.LoginWithIdentity(ctx, identity.ProviderFirebase, &dto.IdentityLoginInput{
+		IDToken:         input.IDToken,
+		Hwid:            input.Hwid,
+		InvitationToken: input.InvitationToken,
+		IP:              input.IP,
+		UserAgent:       input.UserAgent,
+	})
+}
+
+// LoginWithIdentity đăng nhập bằng token của một identity provider đã đăng ký (firebase, oidc, local...)
+// User được tìm theo danh tính đã liên kết ("provider:subject"), sau đó theo email/số điện thoại.
+// Với provider khác Firebase, chỉ tự liên kết vào user có sẵn khi email/số điện thoại đã được provider xác thực.
+// Nếu có InvitationToken, lời mời phải khớp email/số điện thoại của danh tính và được chấp nhận (tạo user_roles) trước khi cấp phiên.
+func (s *UserService) LoginWithIdentity(ctx context.Context, providerName string, input *dto.IdentityLoginInput) (*models.User, error) {
+	logrus.WithFields(logrus.Fields{
+		"provider": providerName,
+		"hwid":     input.Hwid,
+	}).Debug("LoginWithIdentity: Bắt đầu đăng nhập")
+
+	// 1. Verify token qua identity provider
+	provider, err := identity.Get(providerName)
+	if err != nil {
+		return nil, common.NewError(
+			common.ErrCodeValidationInput,
+			fmt.Sprintf("Identity provider '%s' không được hỗ trợ", providerName),
+			common.StatusBadRequest,
+			err,
+		)
+	}
+
+	id, err := provider.Verify(ctx, input.IDToken)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", providerName).Error("LoginWithIdentity: Lỗi verify token")
+		return nil, common.NewError(
+			common.ErrCodeAuthCredentials,
+			"Token không hợp lệ",
+			common.StatusUnauthorized,
+			err,
+		)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"provider":       id.Provider,
+		"subject":        id.Subject,
+		"email":          id.Email,
+		"phone":          id.Phone,
+		"email_verified": id.EmailVerified,
+	}).Debug("LoginWithIdentity: Token hợp lệ")
+
+	// Kiểm tra lời mời trước khi tạo user để link sai/hết hạn không tạo ra user thừa
+	var invitation *models.OrganizationInvitation
+	var invitationService *OrganizationInvitationService
+	if input.InvitationToken != "" {
+		invitationService, err = NewOrganizationInvitationService()
+		if err != nil {
+			return nil, err
+		}
+		invitation, err = invitationService.CheckForRecipient(ctx, input.InvitationToken, InvitationRecipient{
+			Email:         id.Email,
+			EmailVerified: id.EmailVerified,
+			Phone:         id.Phone,
+			PhoneVerified: id.PhoneVerified,
+			Trusted:       id.Provider == identity.ProviderFirebase,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Tìm user đã tồn tại (theo danh tính, rồi email/phone)
+	existingUser, err := s.findUserForIdentity(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// 5. Chuẩn bị dữ liệu để upsert
-	// Tạo update data với chỉ các field có dữ liệu (không set email/phone nếu rỗng)
+	// 3. Chuẩn bị dữ liệu để upsert
+	identityKey := id.Key()
+	newIdentity := models.UserIdentity{
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		Email:    id.Email,
+		LinkedAt: time.Now().UnixMilli(),
+	}
 	updateData := &UpdateData{
 		Set: make(map[string]interface{}),
+		// Chỉ khởi tạo tokens và trạng thái khóa khi tạo mới user
+		// - không xóa phiên của các thiết bị khác và không tự mở khóa user khi login lại
+		SetOnInsert: map[string]interface{}{
+			"tokens":  []models.Token{},
+			"token":   "", // Set token rỗng ban đầu, sẽ được cập nhật sau
+			"isBlock": false,
+		},
 	}
 
-	// Luôn set firebaseUid và các field bắt buộc
-	updateData.Set["firebaseUid"] = token.UID
-	updateData.Set["emailVerified"] = firebaseUser.EmailVerified
-	updateData.Set["phoneVerified"] = firebaseUser.PhoneNumber != ""
-	updateData.Set["isBlock"] = false
-	updateData.Set["tokens"] = []models.Token{}
-	updateData.Set["token"] = "" // Set token rỗng ban đầu, sẽ được cập nhật sau
-
-	// Chỉ set các field không rỗng
-	if firebaseUser.DisplayName != "" {
-		updateData.Set["name"] = firebaseUser.DisplayName
+	// User đã có: chỉ ghi email/phone của provider khi provider xác nhận đã xác thực hoặc user chưa có giá trị này
+	// (provider trả về giá trị chưa xác thực không được đổi email/phone đang dùng để khớp lời mời, gộp tài khoản...)
+	email, phone := "", ""
+	if id.Email != "" && (existingUser == nil || id.EmailVerified || existingUser.Email == "") {
+		email = id.Email
+		updateData.Set["emailVerified"] = id.EmailVerified
 	}
-	if firebaseUser.PhotoURL != "" {
-		updateData.Set["avatarUrl"] = firebaseUser.PhotoURL
+	if id.Phone != "" && (existingUser == nil || id.PhoneVerified || existingUser.Phone == "") {
+		phone = id.Phone
+		updateData.Set["phoneVerified"] = id.PhoneVerified
 	}
-	// Chỉ set email nếu không rỗng (quan trọng cho sparse unique index)
-	if firebaseUser.Email != "" {
-		updateData.Set["email"] = firebaseUser.Email
+	if id.Provider == identity.ProviderFirebase {
+		updateData.Set["firebaseUid"] = id.Subject
 	}
-	// Chỉ set phone nếu không rỗng (quan trọng cho sparse unique index)
-	if firebaseUser.PhoneNumber != "" {
-		updateData.Set["phone"] = firebaseUser.PhoneNumber
+	if id.Name != "" {
+		updateData.Set["name"] = id.Name
+	}
+	if id.AvatarURL != "" {
+		updateData.Set["avatarUrl"] = id.AvatarURL
 	}
 
-	// 6. Upsert với filter firebaseUid (hoặc _id nếu đã tìm thấy user)
 	var filter bson.M
-	var user models.User
 	if existingUser != nil {
-		// Nếu đã tìm thấy user, upsert với _id để update user đó
 		filter = bson.M{"_id": existingUser.ID}
-		logrus.WithFields(logrus.Fields{
-			"filter":  filter,
-			"user_id": existingUser.ID.Hex(),
-		}).Debug("LoginWithFirebase: Upsert với filter _id")
+		// Giữ email/phone hiện có nếu không ghi giá trị của provider (Upsert sẽ $unset email/phone không có trong $set)
+		if email == "" {
+			email = existingUser.Email
+		}
+		if phone == "" {
+			phone = existingUser.Phone
+		}
+		if !userHasIdentityKey(existingUser, identityKey) {
+			updateData.AddToSet = map[string]interface{}{
+				"identityKeys": identityKey,
+				"identities":   newIdentity,
+			}
+		}
 	} else {
-		// Nếu chưa tìm thấy, upsert với firebaseUid để tạo mới hoặc update
-		filter = bson.M{"firebaseUid": token.UID}
-		logrus.WithFields(logrus.Fields{
-			"filter":       filter,
-			"firebase_uid": token.UID,
-		}).Debug("LoginWithFirebase: Upsert với filter firebaseUid")
+		// Tạo user mới với _id sinh sẵn; unique index identityKeys chặn tạo trùng khi login đồng thời
+		filter = bson.M{"_id": primitive.NewObjectID()}
+		updateData.Set["identityKeys"] = []string{identityKey}
+		updateData.Set["identities"] = []models.UserIdentity{newIdentity}
+		if id.Email == "" {
+			updateData.Set["emailVerified"] = false
+		}
+		if id.Phone == "" {
+			updateData.Set["phoneVerified"] = false
+		}
+	}
+	// Chỉ set email/phone nếu không rỗng (quan trọng cho sparse unique index)
+	if email != "" {
+		updateData.Set["email"] = email
+	}
+	if phone != "" {
+		updateData.Set["phone"] = phone
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"filter":      filter,
 		"update_keys": getUpdateDataKeys(updateData),
-	}).Debug("LoginWithFirebase: Bắt đầu gọi Upsert")
+	}).Debug("LoginWithIdentity: Bắt đầu gọi Upsert")
 
-	user, err = s.BaseServiceMongoImpl.Upsert(ctx, filter, updateData)
+	user, err := s.BaseServiceMongoImpl.Upsert(ctx, filter, updateData)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"filter": filter,
 			"error":  err.Error(),
-		}).Error("LoginWithFirebase: Lỗi khi gọi Upsert")
-		// Nếu bị lỗi duplicate (có thể do race condition), thử tìm lại user
-		if errors.Is(err, common.ErrMongoDuplicate) {
-			logrus.Warn("LoginWithFirebase: Lỗi duplicate, thử tìm lại user theo firebaseUid")
-			// Thử tìm lại user theo firebaseUid
-			firebaseFilter := bson.M{"firebaseUid": token.UID}
-			if found, findErr := s.BaseServiceMongoImpl.FindOne(ctx, firebaseFilter, nil); findErr == nil {
-				user = found
-				logrus.WithFields(logrus.Fields{
-					"user_id": user.ID.Hex(),
-				}).Debug("LoginWithFirebase: Đã tìm lại user sau lỗi duplicate")
-			} else {
-				logrus.WithError(findErr).Error("LoginWithFirebase: Không tìm thấy user sau lỗi duplicate")
-				return nil, err
-			}
-		} else {
+		}).Error("LoginWithIdentity: Lỗi khi gọi Upsert")
+		// Nếu bị lỗi duplicate (có thể do race condition), thử tìm lại user theo danh tính
+		if !errors.Is(err, common.ErrMongoDuplicate) {
+			return nil, err
+		}
+		found, findErr := s.BaseServiceMongoImpl.FindOne(ctx, bson.M{"identityKeys": identityKey}, nil)
+		if findErr != nil {
+			logrus.WithError(findErr).Error("LoginWithIdentity: Không tìm thấy user sau lỗi duplicate")
 			return nil, err
 		}
+		user = found
+		logrus.WithField("user_id", user.ID.Hex()).Debug("LoginWithIdentity: Đã tìm lại user sau lỗi duplicate")
 	} else {
-		logrus.WithFields(logrus.Fields{
-			"user_id": user.ID.Hex(),
-		}).Debug("LoginWithFirebase: Upsert thành công")
+		logrus.WithField("user_id", user.ID.Hex()).Debug("LoginWithIdentity: Upsert thành công")
 	}
 
-	// 6. Kiểm tra user bị block
+	// 4. Kiểm tra user bị block
 	if user.IsBlock {
 		return nil, common.NewError(
 			common.ErrCodeAuth,
@@ -279,149 +607,247 @@ func (s *UserService) LoginWithFirebase(ctx context.Context, input *dto.Firebase
 		)
 	}
 
-	// 7. Tạo JWT token cho backend
-	rdNumber := rand.Intn(100)
-	currentTime := time.Now().Unix()
+	// Chấp nhận lời mời: gắn vào user và tạo user_roles
+	if invitation != nil {
+		if _, err := invitationService.Accept(ctx, invitation, user.ID); err != nil {
+			return nil, err
+		}
+		logrus.WithFields(logrus.Fields{
+			"user_id":         user.ID.Hex(),
+			"invitation_id":   invitation.ID.Hex(),
+			"organization_id": invitation.OwnerOrganizationID.Hex(),
+		}).Info("LoginWithIdentity: Đã chấp nhận lời mời vào tổ chức")
+	}
 
-	tokenMap, err := utility.CreateToken(
-		global.MongoDB_ServerConfig.JwtSecret,
-		user.ID.Hex(),
-		strconv.FormatInt(currentTime, 16),
-		strconv.Itoa(rdNumber),
-	)
+	// 5. Cấp phiên đăng nhập cho thiết bị
+	updatedUser, err := s.issueLoginSession(ctx, user, input.Hwid, input.IP, input.UserAgent)
 	if err != nil {
 		return nil, err
 	}
 
-	// 8. Cập nhật token vào user
-	user.Token = tokenMap["token"]
+	// 6. Nếu chưa có admin nào, tự động set user đầu tiên làm admin
+	// Đây là phương án phổ biến: "First user becomes admin"
+	initService, err := NewInitService()
+	if err == nil {
+		hasAdmin, err := initService.HasAnyAdministrator()
+		if err == nil && !hasAdmin {
+			// Chưa có admin, tự động set user này làm admin
+			logrus.WithFields(logrus.Fields{
+				"user_id": updatedUser.ID.Hex(),
+			}).Info("LoginWithIdentity: Tự động set user đầu tiên làm admin")
+			_, err = initService.SetAdministrator(updatedUser.ID)
+			if err != nil && err != common.ErrUserAlreadyAdmin {
+				logrus.WithError(err).Warn("LoginWithIdentity: Lỗi khi set admin, nhưng không fail login")
+				// Log warning nhưng không fail login
+				// User vẫn có thể login, chỉ là chưa có quyền admin
+				// Có thể set admin sau bằng cách khác
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  updatedUser.ID.Hex(),
+		"email":    updatedUser.Email,
+		"provider": id.Provider,
+	}).Info("LoginWithIdentity: Đăng nhập thành công")
+
+	return updatedUser, nil
+}
+
+// findUserForIdentity tìm user ứng với danh tính: theo identityKeys, firebaseUid (dữ liệu cũ), rồi email/phone
+// Trả về nil nếu chưa có user; trả về lỗi conflict nếu email/phone thuộc user đã liên kết danh tính khác của cùng provider
+func (s *UserService) findUserForIdentity(ctx context.Context, id *identity.Identity) (*models.User, error) {
+	filters := []bson.M{{"identityKeys": id.Key()}}
+	if id.Provider == identity.ProviderFirebase {
+		// User tạo trước khi có identityKeys chỉ có firebaseUid
+		filters = append(filters, bson.M{"firebaseUid": id.Subject})
+	}
+	for _, filter := range filters {
+		user, err := s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+		if err == nil {
+			logrus.WithField("user_id", user.ID.Hex()).Debug("LoginWithIdentity: Tìm thấy user theo danh tính đã liên kết")
+			return &user, nil
+		}
+		if !errors.Is(err, common.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	// Kiểm tra conflict với email/phone trước khi upsert
+	// (để tránh tạo user mới khi đã có user khác dùng email/phone này)
+	candidates := []struct {
+		field    string
+		value    string
+		verified bool
+		label    string
+	}{
+		{"email", id.Email, id.EmailVerified, fmt.Sprintf("Email '%s'", id.Email)},
+		{"phone", id.Phone, id.PhoneVerified, fmt.Sprintf("Số điện thoại '%s'", id.Phone)},
+	}
+	for _, candidate := range candidates {
+		if candidate.value == "" {
+			continue
+		}
+		user, err := s.BaseServiceMongoImpl.FindOne(ctx, bson.M{candidate.field: candidate.value}, nil)
+		if errors.Is(err, common.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("LoginWithIdentity: Lỗi khi tìm user theo %s", candidate.field)
+			return nil, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"user_id":  user.ID.Hex(),
+			"found_by": candidate.field,
+		}).Debug("LoginWithIdentity: Tìm thấy user theo email/phone")
+
+		// User này đã liên kết một danh tính khác của cùng provider - conflict
+		if userHasOtherSubject(&user, id.Provider, id.Subject) {
+			logrus.WithFields(logrus.Fields{
+				"provider": id.Provider,
+				"subject":  id.Subject,
+				"found_by": candidate.field,
+			}).Warn("LoginWithIdentity: Conflict - email/phone đã được sử dụng bởi tài khoản khác")
+			return nil, common.NewError(
+				common.ErrCodeAuthCredentials,
+				fmt.Sprintf("%s đã được sử dụng bởi tài khoản khác. Vui lòng sử dụng %s khác hoặc đăng nhập bằng tài khoản cũ.", candidate.label, candidate.field),
+				common.StatusConflict,
+				nil,
+			)
+		}
+
+		// Provider local (dev/test) không bao giờ tự liên kết vào tài khoản có sẵn: ai có secret đều ký được email/phone bất kỳ
+		if id.Provider == identity.ProviderLocal {
+			return nil, common.NewError(
+				common.ErrCodeAuthCredentials,
+				fmt.Sprintf("%s đã được sử dụng bởi tài khoản khác. Provider local không liên kết vào tài khoản có sẵn.", candidate.label),
+				common.StatusConflict,
+				nil,
+			)
+		}
+
+		// Chỉ tự liên kết khi provider đã xác thực email/phone (Firebase giữ hành vi cũ: luôn liên kết)
+		if !candidate.verified && id.Provider != identity.ProviderFirebase {
+			return nil, common.NewError(
+				common.ErrCodeAuthCredentials,
+				fmt.Sprintf("%s đã được sử dụng bởi tài khoản khác và chưa được %s xác thực. Vui lòng đăng nhập bằng tài khoản cũ.", candidate.label, id.Provider),
+				common.StatusConflict,
+				nil,
+			)
+		}
+		return &user, nil
+	}
+
+	logrus.Debug("LoginWithIdentity: Không tìm thấy user, sẽ tạo mới bằng upsert")
+	return nil, nil
+}
+
+// userHasIdentityKey kiểm tra user đã liên kết danh tính "provider:subject" chưa
+func userHasIdentityKey(user *models.User, key string) bool {
+	for _, k := range user.IdentityKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// userHasOtherSubject kiểm tra user đã liên kết danh tính khác (subject khác) của cùng provider chưa
+func userHasOtherSubject(user *models.User, provider, subject string) bool {
+	if provider == identity.ProviderFirebase && user.FirebaseUID != "" && user.FirebaseUID != subject {
+		return true
+	}
+	for _, linked := range user.Identities {
+		if linked.Provider == provider && linked.Subject != subject {
+			return true
+		}
+	}
+	return false
+}
+
+// issueLoginSession tạo cặp access token + refresh token cho thiết bị (hwid) và lưu vào user
+// Đăng nhập lại trên cùng thiết bị sẽ thu hồi cặp token cũ của thiết bị đó
+func (s *UserService) issueLoginSession(ctx context.Context, user models.User, hwid string, ip string, userAgent string) (*models.User, error) {
+	session, refreshToken, err := newSessionTokens(user.ID, hwid, 0)
+	if err != nil {
+		return nil, err
+	}
+	session.CreatedAt = time.Now().UnixMilli()
+	session.LastSeenAt = session.CreatedAt
+	session.IP = ip
+	session.UserAgent = userAgent
+
+	// Cập nhật token vào user
+	user.Token = session.JwtToken
 
 	// Cập nhật hoặc thêm token vào tokens array (theo hwid)
 	var idTokenExist int = -1
 	for i, _token := range user.Tokens {
-		if _token.Hwid == input.Hwid {
+		if _token.Hwid == hwid {
 			idTokenExist = i
 			break
 		}
 	}
 
 	if idTokenExist == -1 {
-		user.Tokens = append(user.Tokens, models.Token{
-			Hwid:     input.Hwid,
-			JwtToken: tokenMap["token"],
-		})
+		user.Tokens = append(user.Tokens, session)
 		logrus.WithFields(logrus.Fields{
 			"user_id": user.ID.Hex(),
-			"hwid":    input.Hwid,
-			"token":   tokenMap["token"][:20] + "...",
-		}).Debug("LoginWithFirebase: Thêm token mới vào tokens array")
+			"hwid":    hwid,
+		}).Debug("issueLoginSession: Thêm token mới vào tokens array")
 	} else {
-		user.Tokens[idTokenExist].JwtToken = tokenMap["token"]
+		// Đăng nhập lại trên cùng thiết bị: thu hồi cặp token cũ của thiết bị
+		if err := s.revokedTokenService.RevokeSession(ctx, user.ID, user.Tokens[idTokenExist], "relogin"); err != nil {
+			return nil, err
+		}
+		session.RoleID = user.Tokens[idTokenExist].RoleID
+		user.Tokens[idTokenExist] = session
 		logrus.WithFields(logrus.Fields{
 			"user_id": user.ID.Hex(),
-			"hwid":    input.Hwid,
-			"token":   tokenMap["token"][:20] + "...",
-		}).Debug("LoginWithFirebase: Cập nhật token trong tokens array")
+			"hwid":    hwid,
+		}).Debug("issueLoginSession: Cập nhật token trong tokens array")
 	}
 
-	// Log số lượng tokens trước khi lưu
-	logrus.WithFields(logrus.Fields{
-		"user_id":      user.ID.Hex(),
-		"tokens_count": len(user.Tokens),
-		"hwid":         input.Hwid,
-	}).Debug("LoginWithFirebase: Số lượng tokens trước khi lưu")
-
-	// 9. Lưu user - Sử dụng UpdateData để đảm bảo update đúng các field
-	logrus.WithFields(logrus.Fields{
-		"user_id":      user.ID.Hex(),
-		"token_length": len(user.Token),
-		"tokens_count": len(user.Tokens),
-	}).Debug("LoginWithFirebase: Bắt đầu cập nhật token vào user")
-
-	// Sử dụng UpdateData để update chỉ các field cần thiết
+	// Lưu user - Sử dụng UpdateData để update chỉ các field cần thiết
 	tokenUpdateData := &UpdateData{
 		Set: map[string]interface{}{
 			"token":  user.Token,
 			"tokens": user.Tokens,
 		},
 	}
-	
-	// Log trước khi update để debug - dùng GetAppLogger để ghi vào file
+
 	logger.GetAppLogger().WithFields(logrus.Fields{
 		"user_id":      user.ID.Hex(),
 		"token_length": len(user.Token),
 		"tokens_count": len(user.Tokens),
-		"update_data_set_keys": []string{"token", "tokens"},
-	}).Error("🔄 [LOGIN] LoginWithFirebase: Chuẩn bị update token với UpdateData - FORCE LOG")
-	
+	}).Debug("issueLoginSession: Chuẩn bị update token")
+
 	updatedUser, err := s.BaseServiceMongoImpl.UpdateById(ctx, user.ID, tokenUpdateData)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"user_id": user.ID.Hex(),
 			"error":   err.Error(),
-		}).Error("LoginWithFirebase: Lỗi khi cập nhật token vào user")
+		}).Error("issueLoginSession: Lỗi khi cập nhật token vào user")
 		return nil, err
 	}
 
-	// Verify token đã được lưu - dùng GetAppLogger để ghi vào file
-	tokenMatch := updatedUser.Token == user.Token
-	logger.GetAppLogger().WithFields(logrus.Fields{
-		"user_id":      updatedUser.ID.Hex(),
-		"token_length": len(updatedUser.Token),
-		"tokens_count": len(updatedUser.Tokens),
-		"token_match":  tokenMatch,
-	}).Error("✅ [LOGIN] LoginWithFirebase: Đã cập nhật token vào user - verify - FORCE LOG")
-	
-	if !tokenMatch {
+	if updatedUser.Token != user.Token {
 		logger.GetAppLogger().WithFields(logrus.Fields{
-			"user_id":         updatedUser.ID.Hex(),
-			"expected_token":  user.Token[:min(50, len(user.Token))] + "...",
-			"actual_token":    updatedUser.Token[:min(50, len(updatedUser.Token))] + "...",
-		}).Error("❌ [LOGIN] LoginWithFirebase: Token không khớp sau khi update!")
+			"user_id":        updatedUser.ID.Hex(),
+			"expected_token": user.Token[:min(50, len(user.Token))] + "...",
+			"actual_token":   updatedUser.Token[:min(50, len(updatedUser.Token))] + "...",
+		}).Error("❌ [LOGIN] issueLoginSession: Token không khớp sau khi update!")
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"user_id":      updatedUser.ID.Hex(),
 		"tokens_count": len(updatedUser.Tokens),
-		"hwid":         input.Hwid,
-	}).Debug("LoginWithFirebase: Đã cập nhật token vào user")
-
-	// Log token cuối cùng trong tokens array để verify
-	if len(updatedUser.Tokens) > 0 {
-		lastToken := updatedUser.Tokens[len(updatedUser.Tokens)-1]
-		logrus.WithFields(logrus.Fields{
-			"user_id":      updatedUser.ID.Hex(),
-			"last_hwid":    lastToken.Hwid,
-			"last_token":   lastToken.JwtToken[:20] + "...",
-			"tokens_count": len(updatedUser.Tokens),
-		}).Debug("LoginWithFirebase: Token cuối cùng trong tokens array")
-	}
-
-	// 10. Nếu chưa có admin nào, tự động set user đầu tiên làm admin
-	// Đây là phương án phổ biến: "First user becomes admin"
-	initService, err := NewInitService()
-	if err == nil {
-		hasAdmin, err := initService.HasAnyAdministrator()
-		if err == nil && !hasAdmin {
-			// Chưa có admin, tự động set user này làm admin
-			logrus.WithFields(logrus.Fields{
-				"user_id": updatedUser.ID.Hex(),
-			}).Info("LoginWithFirebase: Tự động set user đầu tiên làm admin")
-			_, err = initService.SetAdministrator(updatedUser.ID)
-			if err != nil && err != common.ErrUserAlreadyAdmin {
-				logrus.WithError(err).Warn("LoginWithFirebase: Lỗi khi set admin, nhưng không fail login")
-				// Log warning nhưng không fail login
-				// User vẫn có thể login, chỉ là chưa có quyền admin
-				// Có thể set admin sau bằng cách khác
-			}
-		}
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"user_id": updatedUser.ID.Hex(),
-		"email":   updatedUser.Email,
-	}).Info("LoginWithFirebase: Đăng nhập thành công")
+		"hwid":         hwid,
+	}).Debug("issueLoginSession: Đã cập nhật token vào user")
 
+	updatedUser.RefreshToken = refreshToken
+	updatedUser.TokenExpiresAt = session.AccessExpiresAt
 	return &updatedUser, nil
 }
 
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userReference là một field tham chiếu tới user trong một collection
+type userReference struct {
+	collection string
+	field      string
+}
+
+// userReferences các field tham chiếu tới user (ngoài user_roles và assignedUsers) được trỏ sang user giữ lại khi gộp tài khoản
+// Lịch sử (revoked token, log) giữ nguyên user cũ
+func userReferences() []userReference {
+	names := global.MongoDB_ColNames
+	references := []userReference{
+		{names.ApiKeys, "createdBy"},
+		{names.ApiKeys, "revokedBy"},
+		{names.ServiceAccounts, "createdBy"},
+		{names.OAuthClients, "createdBy"},
+		{names.Organizations, "archivedBy"},
+		{"auth_organization_shares", "createdBy"},
+		{"auth_organization_shares", "revokedBy"},
+		{names.Invitations, "invitedBy"},
+		{names.Invitations, "acceptedBy"},
+		{names.Invitations, "revokedBy"},
+		{names.RolePermissions, "createdByUserId"},
+		{names.RoleElevations, "userId"},
+		{names.RoleElevations, "decidedBy"},
+	}
+	// Người tạo / người phụ trách bản ghi (phân quyền scope 2): user giữ lại phải thấy bản ghi của user bị gộp
+	for _, collection := range []string{names.Customers, names.FbCustomers, names.PcPosCustomers, names.FbConvesations, names.PcPosOrders} {
+		references = append(references, userReference{collection, "createdBy"}, userReference{collection, "assignedTo"})
+	}
+	return references
+}
+
+// UserMergeService phát hiện và gộp tài khoản trùng (cùng email/số điện thoại đã xác thực)
+type UserMergeService struct {
+	userService     *UserService
+	userRoleService *UserRoleService
+}
+
+// NewUserMergeService tạo mới UserMergeService
+func NewUserMergeService() (*UserMergeService, error) {
+	userService, err := NewUserService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user service: %v", err)
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
+	}
+
+	return &UserMergeService{
+		userService:     userService,
+		userRoleService: userRoleService,
+	}, nil
+}
+
+// normalizeEmail chuẩn hóa email để so sánh (unique index của users phân biệt hoa thường)
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone chuẩn hóa số điện thoại để so sánh: chỉ giữ chữ số, số bắt đầu bằng 0 coi là số Việt Nam (84)
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	switch {
+	case strings.HasPrefix(digits, "00"):
+		return digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		return "84" + digits[1:]
+	}
+	return digits
+}
+
+// FindDuplicateCandidates tìm các nhóm user có cùng email hoặc số điện thoại đã xác thực (sau khi chuẩn hóa)
+// và khác firebaseUid - ứng viên cần gộp tài khoản
+func (s *UserMergeService) FindDuplicateCandidates(ctx context.Context) ([]dto.DuplicateUserGroup, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"emailVerified": true, "email": bson.M{"$nin": bson.A{nil, ""}}},
+		bson.M{"phoneVerified": true, "phone": bson.M{"$nin": bson.A{nil, ""}}},
+	}}
+	projection := bson.M{"name": 1, "email": 1, "phone": 1, "firebaseUid": 1, "emailVerified": 1, "phoneVerified": 1, "createdAt": 1}
+	cursor, err := s.userService.collection.Find(ctx, filter, mongoopts.Find().SetProjection(projection).SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	type groupKey struct{ matchType, value string }
+	groups := make(map[groupKey][]models.User)
+	var keys []groupKey
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, common.ConvertMongoError(err)
+		}
+		var userKeys []groupKey
+		if user.EmailVerified && user.Email != "" {
+			userKeys = append(userKeys, groupKey{"email", normalizeEmail(user.Email)})
+		}
+		if user.PhoneVerified && user.Phone != "" {
+			userKeys = append(userKeys, groupKey{"phone", normalizePhone(user.Phone)})
+		}
+		for _, key := range userKeys {
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], user)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	result := make([]dto.DuplicateUserGroup, 0)
+	for _, key := range keys {
+		users := groups[key]
+		if len(users) < 2 || !hasDistinctFirebaseUID(users) {
+			continue
+		}
+		group := dto.DuplicateUserGroup{MatchType: key.matchType, MatchValue: key.value}
+		for _, user := range users {
+			roleCount, err := s.userRoleService.CountDocuments(ctx, bson.M{"userId": user.ID})
+			if err != nil {
+				return nil, err
+			}
+			group.Users = append(group.Users, dto.DuplicateUserInfo{
+				ID:            user.ID.Hex(),
+				Name:          user.Name,
+				Email:         user.Email,
+				Phone:         user.Phone,
+				FirebaseUID:   user.FirebaseUID,
+				EmailVerified: user.EmailVerified,
+				PhoneVerified: user.PhoneVerified,
+				RoleCount:     roleCount,
+				CreatedAt:     user.CreatedAt,
+			})
+		}
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// hasDistinctFirebaseUID kiểm tra trong nhóm có ít nhất 2 firebaseUid khác nhau (user chưa có firebaseUid tính là khác)
+func hasDistinctFirebaseUID(users []models.User) bool {
+	seen := make(map[string]bool)
+	for _, user := range users {
+		if user.FirebaseUID == "" {
+			return true
+		}
+		seen[user.FirebaseUID] = true
+	}
+	return len(seen) > 1
+}
+
+// sharesVerifiedContact kiểm tra 2 user có cùng email hoặc số điện thoại đã xác thực
+func sharesVerifiedContact(a, b models.User) bool {
+	if a.EmailVerified && b.EmailVerified && a.Email != "" && normalizeEmail(a.Email) == normalizeEmail(b.Email) {
+		return true
+	}
+	return a.PhoneVerified && b.PhoneVerified && a.Phone != "" && normalizePhone(a.Phone) == normalizePhone(b.Phone)
+}
+
+// Merge gộp user nguồn vào user đích trong một transaction:
+// role assignment, assignedUsers của agent/access token và các tham chiếu createdBy/invitedBy/... được trỏ sang user đích,
+// danh tính (identities), email/số điện thoại còn thiếu được chuyển sang user đích, sau đó user nguồn bị xóa
+// Chỉ gộp được 2 user có cùng email hoặc số điện thoại đã xác thực
+func (s *UserMergeService) Merge(ctx context.Context, sourceID, targetID primitive.ObjectID) (*dto.UserMergeResult, error) {
+	if sourceID == targetID {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Không thể gộp một tài khoản vào chính nó", common.StatusBadRequest, nil)
+	}
+	source, err := s.userService.FindOneById(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.userService.FindOneById(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !sharesVerifiedContact(source, target) {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Hai tài khoản không có cùng email hoặc số điện thoại đã xác thực", common.StatusBadRequest, nil)
+	}
+
+	targetUpdate := mergedUserFields(source, target)
+	names := global.MongoDB_ColNames
+
+	session, err := s.userService.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer session.EndSession(ctx)
+
+	result := &dto.UserMergeResult{TargetUserID: targetID.Hex(), SourceUserID: sourceID.Hex()}
+	// Audit của transaction chỉ được ghi sau khi commit
+	txCtx, audits := withAuditBuffer(ctx)
+	_, err = session.WithTransaction(txCtx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		*result = dto.UserMergeResult{TargetUserID: targetID.Hex(), SourceUserID: sourceID.Hex()}
+		audits.reset()
+
+		moved, err := s.mergeUserRoles(sessCtx, sourceID, targetID)
+		if err != nil {
+			return nil, err
+		}
+		result.UserRoles = moved
+
+		for _, name := range []string{names.Agents, names.AccessTokens} {
+			count, err := replaceAssignedUser(sessCtx, name, sourceID, targetID)
+			if err != nil {
+				return nil, err
+			}
+			if name == names.Agents {
+				result.Agents = count
+			} else {
+				result.AccessTokens = count
+			}
+		}
+
+		for _, ref := range userReferences() {
+			collection, exist := global.RegistryCollections.Get(ref.collection)
+			if !exist {
+				continue
+			}
+			res, err := collection.UpdateMany(sessCtx, bson.M{ref.field: sourceID}, bson.M{"$set": bson.M{ref.field: targetID}})
+			if err != nil {
+				return nil, err
+			}
+			result.References += res.ModifiedCount
+		}
+
+		// MFA và authorization code gắn với thiết bị/phiên của user nguồn, không chuyển sang
+		for _, name := range []string{names.UserMFA, names.OAuthCodes} {
+			if collection, exist := global.RegistryCollections.Get(name); exist {
+				if _, err := collection.DeleteMany(sessCtx, bson.M{"userId": sourceID}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Xóa user nguồn trước để email/số điện thoại/danh tính (unique) chuyển được sang user đích
+		if _, err := s.userService.collection.DeleteOne(sessCtx, bson.M{"_id": sourceID}); err != nil {
+			return nil, err
+		}
+		targetUpdate["updatedAt"] = time.Now().UnixMilli()
+		update := bson.M{"$set": targetUpdate}
+		if len(source.Identities) > 0 {
+			update["$addToSet"] = bson.M{
+				"identities":   bson.M{"$each": source.Identities},
+				"identityKeys": bson.M{"$each": source.IdentityKeys},
+			}
+		}
+		if _, err := s.userService.collection.UpdateOne(sessCtx, bson.M{"_id": targetID}, update); err != nil {
+			return nil, err
+		}
+		recordAuditEntry(sessCtx, models.AuthLog{
+			Collection: s.userService.collection.Name(),
+			DocumentID: targetID,
+			Action:     "merge",
+			Describe:   fmt.Sprintf("Gộp tài khoản %s vào %s", sourceID.Hex(), targetID.Hex()),
+			OldData:    auditJSON(auditDataMap(source)),
+		})
+		return nil, nil
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	audits.flush(ctx)
+
+	publishUserPermissionChange(sourceID, targetID)
+	return result, nil
+}
+
+// mergedUserFields trả về các field của user đích được bổ sung từ user nguồn (chỉ khi user đích còn thiếu)
+func mergedUserFields(source, target models.User) bson.M {
+	set := bson.M{}
+	if target.Email == "" && source.Email != "" {
+		set["email"] = source.Email
+		set["emailVerified"] = source.EmailVerified
+	} else if !target.EmailVerified && source.EmailVerified && normalizeEmail(target.Email) == normalizeEmail(source.Email) {
+		set["emailVerified"] = true
+	}
+	if target.Phone == "" && source.Phone != "" {
+		set["phone"] = source.Phone
+		set["phoneVerified"] = source.PhoneVerified
+	} else if !target.PhoneVerified && source.PhoneVerified && normalizePhone(target.Phone) == normalizePhone(source.Phone) {
+		set["phoneVerified"] = true
+	}
+	if target.FirebaseUID == "" && source.FirebaseUID != "" {
+		set["firebaseUid"] = source.FirebaseUID
+	}
+	if target.Name == "" && source.Name != "" {
+		set["name"] = source.Name
+	}
+	if target.AvatarURL == "" && source.AvatarURL != "" {
+		set["avatarUrl"] = source.AvatarURL
+	}
+	return set
+}
+
+// mergeUserRoles chuyển role assignment của user nguồn sang user đích
+// Role đã gán cho cả 2 user chỉ giữ một assignment: vĩnh viễn nếu có, ngược lại assignment hết hạn muộn nhất
+func (s *UserMergeService) mergeUserRoles(ctx mongo.SessionContext, sourceID, targetID primitive.ObjectID) (int64, error) {
+	cursor, err := s.userRoleService.collection.Find(ctx, bson.M{"userId": bson.M{"$in": bson.A{sourceID, targetID}}})
+	if err != nil {
+		return 0, err
+	}
+	var assignments []models.UserRole
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return 0, err
+	}
+
+	byRole := make(map[primitive.ObjectID][]models.UserRole)
+	for _, assignment := range assignments {
+		byRole[assignment.RoleID] = append(byRole[assignment.RoleID], assignment)
+	}
+
+	var moved int64
+	for _, group := range byRole {
+		sort.SliceStable(group, func(i, j int) bool { return outlasts(group[i], group[j]) })
+		keep := group[0]
+		for _, duplicate := range group[1:] {
+			if _, err := s.userRoleService.collection.DeleteOne(ctx, bson.M{"_id": duplicate.ID}); err != nil {
+				return 0, err
+			}
+			recordAudit(ctx, s.userRoleService.collection.Name(), AuditActionDelete, duplicate, nil)
+		}
+		if keep.UserID == sourceID {
+			if _, err := s.userRoleService.collection.UpdateOne(ctx, bson.M{"_id": keep.ID}, bson.M{"$set": bson.M{"userId": targetID}}); err != nil {
+				return 0, err
+			}
+			reassigned := keep
+			reassigned.UserID = targetID
+			recordAudit(ctx, s.userRoleService.collection.Name(), AuditActionUpdate, keep, reassigned)
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// outlasts kiểm tra assignment a có hiệu lực lâu hơn b (vĩnh viễn > hết hạn muộn hơn)
+func outlasts(a, b models.UserRole) bool {
+	if a.ValidUntil == nil || b.ValidUntil == nil {
+		return a.ValidUntil == nil && b.ValidUntil != nil
+	}
+	return *a.ValidUntil > *b.ValidUntil
+}
+
+// replaceAssignedUser thay user nguồn bằng user đích trong assignedUsers (không tạo phần tử trùng)
+func replaceAssignedUser(ctx mongo.SessionContext, collectionName string, sourceID, targetID primitive.ObjectID) (int64, error) {
+	collection, exist := global.RegistryCollections.Get(collectionName)
+	if !exist {
+		return 0, nil
+	}
+	// Document đã có user đích: chỉ bỏ user nguồn
+	pulled, err := collection.UpdateMany(ctx,
+		bson.M{"assignedUsers": bson.M{"$all": bson.A{sourceID, targetID}}},
+		bson.M{"$pull": bson.M{"assignedUsers": sourceID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	replaced, err := collection.UpdateMany(ctx,
+		bson.M{"assignedUsers": sourceID},
+		bson.M{"$set": bson.M{"assignedUsers.$": targetID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return pulled.ModifiedCount + replaced.ModifiedCount, nil
+}
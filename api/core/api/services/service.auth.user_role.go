@@ -9,16 +9,19 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
 	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRoleService là cấu trúc chứa các phương thức liên quan đến vai trò của người dùng
 type UserRoleService struct {
 	*BaseServiceMongoImpl[models.UserRole]
-	userService *UserService
-	roleService *RoleService
+	userService           *UserService
+	roleService           *RoleService
+	serviceAccountService *ServiceAccountService
 }
 
 // NewUserRoleService tạo mới UserRoleService
@@ -38,13 +41,53 @@ func NewUserRoleService() (*UserRoleService, error) {
 		return nil, fmt.Errorf("failed to create role service: %v", err)
 	}
 
+	serviceAccountService, err := NewServiceAccountService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account service: %v", err)
+	}
+
 	return &UserRoleService{
-		BaseServiceMongoImpl: NewBaseServiceMongo[models.UserRole](userRoleCollection),
-		userService:          userService,
-		roleService:          roleService,
+		BaseServiceMongoImpl:  NewBaseServiceMongo[models.UserRole](userRoleCollection),
+		userService:           userService,
+		roleService:           roleService,
+		serviceAccountService: serviceAccountService,
 	}, nil
 }
 
+// ActiveUserRoleFilter trả về điều kiện role assignment đang có hiệu lực: đã tới validFrom và chưa quá validUntil
+// (assignment cũ không có validFrom/validUntil được coi là vĩnh viễn)
+func ActiveUserRoleFilter() bson.M {
+	now := utility.CurrentTimeInMilli()
+	return bson.M{"$and": []bson.M{
+		{"$or": []bson.M{
+			{"validFrom": bson.M{"$exists": false}},
+			{"validFrom": bson.M{"$lte": now}},
+		}},
+		{"$or": []bson.M{
+			{"validUntil": bson.M{"$exists": false}},
+			{"validUntil": bson.M{"$gt": now}},
+		}},
+	}}
+}
+
+// permanentUserRoleFilter là điều kiện role assignment vĩnh viễn (không có validUntil)
+// Dùng cho kiểm tra "ít nhất một Administrator" vì assignment có thời hạn sẽ tự hết hiệu lực
+var permanentUserRoleFilter = bson.M{"validUntil": bson.M{"$exists": false}}
+
+// validateValidity kiểm tra khoảng hiệu lực của role assignment
+func validateValidity(validFrom, validUntil *int64) error {
+	if validUntil == nil {
+		return nil
+	}
+	if *validUntil <= utility.CurrentTimeInMilli() {
+		return common.NewError(common.ErrCodeValidationInput, "validUntil phải ở tương lai", common.StatusBadRequest, nil)
+	}
+	if validFrom != nil && *validFrom >= *validUntil {
+		return common.NewError(common.ErrCodeValidationInput, "validFrom phải nhỏ hơn validUntil", common.StatusBadRequest, nil)
+	}
+	return nil
+}
+
 // Create tạo mới một vai trò người dùng
 func (s *UserRoleService) Create(ctx context.Context, input *dto.UserRoleCreateInput) (*models.UserRole, error) {
 	userObjID, err := primitive.ObjectIDFromHex(input.UserID)
@@ -56,9 +99,11 @@ func (s *UserRoleService) Create(ctx context.Context, input *dto.UserRoleCreateI
 		return nil, common.ErrInvalidInput
 	}
 
-	// Kiểm tra User có tồn tại không
+	// Kiểm tra User (hoặc service account) có tồn tại không
 	if _, err := s.userService.FindOneById(ctx, userObjID); err != nil {
-		return nil, common.ErrNotFound
+		if _, err := s.serviceAccountService.FindOneById(ctx, userObjID); err != nil {
+			return nil, common.ErrNotFound
+		}
 	}
 
 	// Kiểm tra Role có tồn tại không
@@ -66,26 +111,28 @@ func (s *UserRoleService) Create(ctx context.Context, input *dto.UserRoleCreateI
 		return nil, common.ErrNotFound
 	}
 
-	// Kiểm tra UserRole đã tồn tại chưa
-	exists, err := s.IsExist(ctx, userObjID, roleObjID)
+	// Kiểm tra UserRole đã tồn tại chưa (kể cả assignment chưa tới hạn hoặc chờ job gỡ)
+	count, err := s.collection.CountDocuments(ctx, bson.M{"userId": userObjID, "roleId": roleObjID})
 	if err != nil {
-		return nil, err
+		return nil, common.ConvertMongoError(err)
 	}
-	if exists {
+	if count > 0 {
 		return nil, common.ErrInvalidInput
 	}
 
 	// Tạo userRole mới
 	userRole := &models.UserRole{
-		ID:        primitive.NewObjectID(),
-		UserID:    userObjID,
-		RoleID:    roleObjID,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
+		ID:         primitive.NewObjectID(),
+		UserID:     userObjID,
+		RoleID:     roleObjID,
+		ValidFrom:  input.ValidFrom,
+		ValidUntil: input.ValidUntil,
+		CreatedAt:  time.Now().Unix(),
+		UpdatedAt:  time.Now().Unix(),
 	}
 
 	// Lưu userRole
-	createdUserRole, err := s.BaseServiceMongoImpl.InsertOne(ctx, *userRole)
+	createdUserRole, err := s.InsertOne(ctx, *userRole)
 	if err != nil {
 		return nil, common.ConvertMongoError(err)
 	}
@@ -94,19 +141,30 @@ func (s *UserRoleService) Create(ctx context.Context, input *dto.UserRoleCreateI
 }
 
 // UpdateUserRoles cập nhật danh sách roles cho một user
-// Xóa tất cả roles cũ và thêm roles mới
-// Tự động kiểm tra logic bảo vệ role Administrator
+// Xóa tất cả roles vĩnh viễn cũ và thêm roles mới (assignment có thời hạn được giữ nguyên đến khi hết hạn)
+// Tự động kiểm tra logic bảo vệ role Administrator và quyền gán role của người thực hiện
 func (s *UserRoleService) UpdateUserRoles(ctx context.Context, userID primitive.ObjectID, newRoleIDs []primitive.ObjectID) ([]models.UserRole, error) {
 	// Kiểm tra xem có thể xóa user khỏi role Administrator không
 	if err := s.validateCanRemoveAdministratorRole(ctx, userID, newRoleIDs); err != nil {
 		return nil, err
 	}
 
-	// Xóa tất cả user role cũ của user (dùng base service để tránh kiểm tra trùng lặp)
-	filter := bson.M{"userId": userID}
+	// Người thực hiện chỉ được gán thêm role mà mình cấp được toàn bộ quyền (role user đã có được bỏ qua)
+	assignments := make([]models.UserRole, 0, len(newRoleIDs))
+	for _, roleID := range newRoleIDs {
+		assignments = append(assignments, models.UserRole{UserID: userID, RoleID: roleID})
+	}
+	if err := validateUserRoleDelegation(ctx, assignments); err != nil {
+		return nil, err
+	}
+
+	// Xóa tất cả user role vĩnh viễn cũ của user (dùng base service để tránh kiểm tra trùng lặp)
+	filter := bson.M{"userId": userID, "validUntil": permanentUserRoleFilter["validUntil"]}
 	if _, err := s.BaseServiceMongoImpl.DeleteMany(ctx, filter); err != nil {
 		return nil, err
 	}
+	// Role cũ đã bị xóa → invalidate ngay cả khi bước thêm mới bên dưới lỗi
+	defer publishUserPermissionChange(userID)
 
 	// Tạo danh sách user role mới
 	var userRoles []models.UserRole
@@ -125,7 +183,7 @@ func (s *UserRoleService) UpdateUserRoles(ctx context.Context, userID primitive.
 
 	// Thêm các user role mới
 	if len(userRoles) > 0 {
-		_, err := s.InsertMany(ctx, userRoles)
+		_, err := s.BaseServiceMongoImpl.InsertMany(ctx, userRoles)
 		if err != nil {
 			return nil, err
 		}
@@ -150,8 +208,8 @@ func (s *UserRoleService) validateCanRemoveAdministratorRole(ctx context.Context
 		return nil // Bỏ qua nếu không parse được
 	}
 
-	// Kiểm tra user hiện tại có role Administrator không
-	oldUserRoles, err := s.Find(ctx, bson.M{"userId": userID, "roleId": modelAdminRole.ID}, nil)
+	// Kiểm tra user hiện tại có role Administrator vĩnh viễn không
+	oldUserRoles, err := s.Find(ctx, bson.M{"userId": userID, "roleId": modelAdminRole.ID, "validUntil": permanentUserRoleFilter["validUntil"]}, nil)
 	if err != nil && err != common.ErrNotFound {
 		return err
 	}
@@ -169,8 +227,8 @@ func (s *UserRoleService) validateCanRemoveAdministratorRole(ctx context.Context
 
 	// Nếu user đang có role Administrator và sẽ bị xóa khỏi role Administrator
 	if hasAdminRoleInOldRoles && !hasAdminRoleInNewRoles {
-		// Kiểm tra xem đây có phải là user cuối cùng có role Administrator không
-		allAdminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelAdminRole.ID}, nil)
+		// Kiểm tra xem đây có phải là user cuối cùng có role Administrator (vĩnh viễn) không
+		allAdminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelAdminRole.ID, "validUntil": permanentUserRoleFilter["validUntil"]}, nil)
 		if err != nil && err != common.ErrNotFound {
 			return err
 		}
@@ -189,12 +247,15 @@ func (s *UserRoleService) validateCanRemoveAdministratorRole(ctx context.Context
 	return nil
 }
 
-// IsExist kiểm tra xem một UserRole đã tồn tại chưa
+// IsExist kiểm tra xem user có đang được gán role (assignment còn hiệu lực) không
 func (s *UserRoleService) IsExist(ctx context.Context, userID, roleID primitive.ObjectID) (bool, error) {
 	filter := bson.M{
 		"userId": userID,
 		"roleId": roleID,
 	}
+	for key, value := range ActiveUserRoleFilter() {
+		filter[key] = value
+	}
 	count, err := s.collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return false, common.ConvertMongoError(err)
@@ -202,6 +263,58 @@ func (s *UserRoleService) IsExist(ctx context.Context, userID, roleID primitive.
 	return count > 0, nil
 }
 
+// ExpireUserRoles gỡ các role assignment đã quá validUntil (dùng cho job định kỳ) và invalidate cache quyền của user
+// Không áp dụng kiểm tra "ít nhất một Administrator" vì chỉ assignment vĩnh viễn được tính
+func (s *UserRoleService) ExpireUserRoles(ctx context.Context) ([]models.UserRole, error) {
+	now := utility.CurrentTimeInMilli()
+	filter := bson.M{"validUntil": bson.M{"$lte": now}}
+
+	userRoles, err := s.BaseServiceMongoImpl.Find(ctx, filter, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return []models.UserRole{}, nil
+		}
+		return nil, err
+	}
+	if len(userRoles) == 0 {
+		return userRoles, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(userRoles))
+	userIDs := make([]primitive.ObjectID, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		ids = append(ids, userRole.ID)
+		userIDs = append(userIDs, userRole.UserID)
+	}
+	if _, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "validUntil": filter["validUntil"]}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	for _, userRole := range userRoles {
+		recordAudit(ctx, s.collection.Name(), AuditActionDelete, userRole, nil)
+	}
+
+	publishUserPermissionChange(userIDs...)
+	return userRoles, nil
+}
+
+// RefreshActivatedUserRoles invalidate cache quyền của user có role assignment bắt đầu hiệu lực trong (since, until]
+// Assignment có validFrom ở tương lai không phát sinh thay đổi dữ liệu khi tới hạn nên job phải chủ động báo
+func (s *UserRoleService) RefreshActivatedUserRoles(ctx context.Context, since, until int64) error {
+	userRoles, err := s.BaseServiceMongoImpl.Find(ctx, bson.M{"validFrom": bson.M{"$gt": since, "$lte": until}}, nil)
+	if err != nil && err != common.ErrNotFound {
+		return err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		userIDs = append(userIDs, userRole.UserID)
+	}
+	if len(userIDs) > 0 {
+		publishUserPermissionChange(userIDs...)
+	}
+	return nil
+}
+
 // validateBeforeDeleteAdministratorRole kiểm tra xem có thể xóa user khỏi role Administrator không
 // Không cho phép xóa nếu đây là user cuối cùng có role Administrator
 func (s *UserRoleService) validateBeforeDeleteAdministratorRole(ctx context.Context, userRoleID primitive.ObjectID) error {
@@ -231,10 +344,10 @@ func (s *UserRoleService) validateBeforeDeleteAdministratorRole(ctx context.Cont
 		return common.ErrInvalidFormat
 	}
 
-	// Kiểm tra nếu role là Administrator
-	if modelRole.Name == "Administrator" {
-		// Đếm số lượng user có role Administrator
-		adminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelRole.ID}, nil)
+	// Kiểm tra nếu role là Administrator (assignment có thời hạn không được tính nên xóa thoải mái)
+	if modelRole.Name == "Administrator" && modelUserRole.ValidUntil == nil {
+		// Đếm số lượng user có role Administrator vĩnh viễn
+		adminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelRole.ID, "validUntil": permanentUserRoleFilter["validUntil"]}, nil)
 		if err != nil && err != common.ErrNotFound {
 			return err
 		}
@@ -275,8 +388,8 @@ func (s *UserRoleService) validateBeforeDeleteAdministratorRoleByFilter(ctx cont
 		return nil // Bỏ qua nếu không parse được
 	}
 
-	// Đếm số lượng user có role Administrator hiện tại
-	allAdminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelAdminRole.ID}, nil)
+	// Đếm số lượng user có role Administrator vĩnh viễn hiện tại
+	allAdminUserRoles, err := s.Find(ctx, bson.M{"roleId": modelAdminRole.ID, "validUntil": permanentUserRoleFilter["validUntil"]}, nil)
 	if err != nil && err != common.ErrNotFound {
 		return err
 	}
@@ -289,13 +402,13 @@ func (s *UserRoleService) validateBeforeDeleteAdministratorRoleByFilter(ctx cont
 		if err := bson.Unmarshal(bsonBytes, &modelUserRole); err != nil {
 			continue
 		}
-		if modelUserRole.RoleID == modelAdminRole.ID {
+		if modelUserRole.RoleID == modelAdminRole.ID && modelUserRole.ValidUntil == nil {
 			adminUserRolesToDelete++
 		}
 	}
 
 	// Nếu sau khi xóa, không còn user nào có role Administrator
-	if err == nil && len(allAdminUserRoles) <= adminUserRolesToDelete {
+	if err == nil && adminUserRolesToDelete > 0 && len(allAdminUserRoles) <= adminUserRolesToDelete {
 		return common.NewError(
 			common.ErrCodeBusinessOperation,
 			"Không thể xóa user khỏi role Administrator. Role Administrator phải có ít nhất một user.",
@@ -324,6 +437,7 @@ func (s *UserRoleService) DeleteOne(ctx context.Context, filter interface{}) err
 			filterMap = temp
 		} else {
 			// Nếu không convert được, bỏ qua validation
+			defer publishAllPermissionChange()
 			return s.BaseServiceMongoImpl.DeleteOne(ctx, filter)
 		}
 	}
@@ -333,8 +447,18 @@ func (s *UserRoleService) DeleteOne(ctx context.Context, filter interface{}) err
 		return err
 	}
 
+	userIDs, err := s.findAffectedUserIDs(ctx, filterMap)
+	if err != nil {
+		return err
+	}
+
 	// Thực hiện xóa
-	return s.BaseServiceMongoImpl.DeleteOne(ctx, filter)
+	if err := s.BaseServiceMongoImpl.DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+
+	publishUserPermissionChange(userIDs...)
+	return nil
 }
 
 // DeleteById override method DeleteById để kiểm tra trước khi xóa
@@ -344,8 +468,18 @@ func (s *UserRoleService) DeleteById(ctx context.Context, id primitive.ObjectID)
 		return err
 	}
 
+	userRole, err := s.BaseServiceMongoImpl.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	// Thực hiện xóa
-	return s.BaseServiceMongoImpl.DeleteById(ctx, id)
+	if err := s.BaseServiceMongoImpl.DeleteById(ctx, id); err != nil {
+		return err
+	}
+
+	publishUserPermissionChange(userRole.UserID)
+	return nil
 }
 
 // DeleteMany override method DeleteMany để kiểm tra trước khi xóa
@@ -365,6 +499,7 @@ func (s *UserRoleService) DeleteMany(ctx context.Context, filter interface{}) (i
 			filterMap = temp
 		} else {
 			// Nếu không convert được, bỏ qua validation
+			defer publishAllPermissionChange()
 			return s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
 		}
 	}
@@ -374,6 +509,155 @@ func (s *UserRoleService) DeleteMany(ctx context.Context, filter interface{}) (i
 		return 0, err
 	}
 
+	userIDs, err := s.findAffectedUserIDs(ctx, filterMap)
+	if err != nil {
+		return 0, err
+	}
+
 	// Thực hiện xóa
-	return s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
+	deleted, err := s.BaseServiceMongoImpl.DeleteMany(ctx, filter)
+	if err != nil {
+		return deleted, err
+	}
+
+	publishUserPermissionChange(userIDs...)
+	return deleted, nil
+}
+
+// validateUpdateDelegation kiểm tra update đổi userId/roleId không gán role vượt quá quyền của người thực hiện
+func (s *UserRoleService) validateUpdateDelegation(ctx context.Context, filter interface{}, update interface{}, upsert bool) error {
+	updated, err := delegationUpdatedDocs[models.UserRole](ctx, s.collection, filter, update, upsert, "userId", "roleId")
+	if err != nil {
+		return err
+	}
+	return validateUserRoleDelegation(ctx, updated)
+}
+
+// findAffectedUserIDs lấy danh sách userId của các user role khớp filter (dùng trước khi xóa)
+func (s *UserRoleService) findAffectedUserIDs(ctx context.Context, filter interface{}) ([]primitive.ObjectID, error) {
+	userRoles, err := s.BaseServiceMongoImpl.Find(ctx, filter, nil)
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		userIDs = append(userIDs, userRole.UserID)
+	}
+	return userIDs, nil
+}
+
+// FindOneAndDelete override method FindOneAndDelete để kiểm tra trước khi xóa và invalidate cache quyền của user
+func (s *UserRoleService) FindOneAndDelete(ctx context.Context, filter interface{}, opts *mongoopts.FindOneAndDeleteOptions) (models.UserRole, error) {
+	var zero models.UserRole
+
+	userRole, err := s.BaseServiceMongoImpl.FindOne(ctx, filter, nil)
+	if err != nil {
+		return zero, err
+	}
+
+	// Kiểm tra trước khi xóa
+	if err := s.validateBeforeDeleteAdministratorRole(ctx, userRole.ID); err != nil {
+		return zero, err
+	}
+
+	result, err := s.BaseServiceMongoImpl.FindOneAndDelete(ctx, bson.M{"_id": userRole.ID}, opts)
+	if err != nil {
+		return zero, err
+	}
+
+	publishUserPermissionChange(result.UserID)
+	return result, nil
+}
+
+// InsertOne override method InsertOne để kiểm tra khoảng hiệu lực, quyền gán role (chống leo thang đặc quyền) và invalidate cache quyền của user
+func (s *UserRoleService) InsertOne(ctx context.Context, data models.UserRole) (models.UserRole, error) {
+	if err := validateValidity(data.ValidFrom, data.ValidUntil); err != nil {
+		return data, err
+	}
+	if err := validateUserRoleDelegation(ctx, []models.UserRole{data}); err != nil {
+		return data, err
+	}
+
+	result, err := s.BaseServiceMongoImpl.InsertOne(ctx, data)
+	if err != nil {
+		return result, err
+	}
+
+	publishUserPermissionChange(result.UserID)
+	return result, nil
+}
+
+// InsertMany override method InsertMany để kiểm tra quyền gán role và invalidate cache quyền của các user
+func (s *UserRoleService) InsertMany(ctx context.Context, data []models.UserRole) ([]models.UserRole, error) {
+	if err := validateUserRoleDelegation(ctx, data); err != nil {
+		return nil, err
+	}
+
+	result, err := s.BaseServiceMongoImpl.InsertMany(ctx, data)
+	if err != nil {
+		return result, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(result))
+	for _, userRole := range result {
+		userIDs = append(userIDs, userRole.UserID)
+	}
+	publishUserPermissionChange(userIDs...)
+	return result, nil
+}
+
+// UpdateOne override method UpdateOne để invalidate cache quyền
+// Update có thể đổi userId/roleId nên không xác định được phạm vi → invalidate toàn bộ
+func (s *UserRoleService) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (models.UserRole, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return models.UserRole{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateOne(ctx, filter, update, opts)
+}
+
+// UpdateMany override method UpdateMany để invalidate cache quyền
+func (s *UserRoleService) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.UpdateOptions) (int64, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return 0, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateMany(ctx, filter, update, opts)
+}
+
+// UpdateById override method UpdateById để invalidate cache quyền
+func (s *UserRoleService) UpdateById(ctx context.Context, id primitive.ObjectID, data interface{}) (models.UserRole, error) {
+	if err := s.validateUpdateDelegation(ctx, bson.M{"_id": id}, data, false); err != nil {
+		return models.UserRole{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpdateById(ctx, id, data)
+}
+
+// FindOneAndUpdate override method FindOneAndUpdate để invalidate cache quyền
+func (s *UserRoleService) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts *mongoopts.FindOneAndUpdateOptions) (models.UserRole, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, update, opts != nil && opts.Upsert != nil && *opts.Upsert); err != nil {
+		return models.UserRole{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.FindOneAndUpdate(ctx, filter, update, opts)
+}
+
+// Upsert override method Upsert để invalidate cache quyền
+func (s *UserRoleService) Upsert(ctx context.Context, filter interface{}, data interface{}) (models.UserRole, error) {
+	if err := s.validateUpdateDelegation(ctx, filter, data, true); err != nil {
+		return models.UserRole{}, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.Upsert(ctx, filter, data)
+}
+
+// UpsertMany override method UpsertMany để invalidate cache quyền
+func (s *UserRoleService) UpsertMany(ctx context.Context, filter interface{}, data []models.UserRole) ([]models.UserRole, error) {
+	if err := validateUserRoleDelegation(ctx, data); err != nil {
+		return nil, err
+	}
+	defer publishAllPermissionChange()
+	return s.BaseServiceMongoImpl.UpsertMany(ctx, filter, data)
 }
@@ -11,28 +11,38 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GetUserAllowedOrganizationIDs lấy danh sách organization IDs mà user được phép truy cập
-// Dựa trên permissions và scope của user
-func GetUserAllowedOrganizationIDs(ctx context.Context, userID primitive.ObjectID, permissionName string) ([]primitive.ObjectID, error) {
-	// Lấy các services cần thiết
-	userRoleService, err := NewUserRoleService()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user role service: %v", err)
-	}
+// UserDataAccess là phạm vi dữ liệu của user theo tổ chức
+type UserDataAccess struct {
+	OrganizationIDs    []primitive.ObjectID // Tổ chức xem được toàn bộ dữ liệu (scope 0, 1)
+	OwnOrganizationIDs []primitive.ObjectID // Tổ chức chỉ xem được bản ghi của mình (scope 2), không gồm tổ chức đã có trong OrganizationIDs
+}
 
-	roleService, err := NewRoleService()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create role service: %v", err)
-	}
+// AllOrganizationIDs trả về tất cả tổ chức trong phạm vi (toàn bộ dữ liệu + chỉ bản ghi của mình)
+func (a *UserDataAccess) AllOrganizationIDs() []primitive.ObjectID {
+	result := make([]primitive.ObjectID, 0, len(a.OrganizationIDs)+len(a.OwnOrganizationIDs))
+	result = append(result, a.OrganizationIDs...)
+	return append(result, a.OwnOrganizationIDs...)
+}
 
-	rolePermissionService, err := NewRolePermissionService()
+// GetUserAllowedOrganizationIDs lấy danh sách organization IDs mà user được phép truy cập toàn bộ (scope 0, 1)
+// Dựa trên permissions và scope của user (permissionName rỗng = tất cả permissions)
+// KHÔNG gồm tổ chức chỉ có scope 2 (bản ghi của mình): scope 2 không cho quyền quản lý tổ chức (mời, duyệt, impersonate...)
+// Dùng GetUserDataAccess cho phạm vi dữ liệu CRUD (lọc và kiểm tra bản ghi của mình)
+func GetUserAllowedOrganizationIDs(ctx context.Context, userID primitive.ObjectID, permissionName string) ([]primitive.ObjectID, error) {
+	access, err := GetUserDataAccess(ctx, userID, permissionName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create role permission service: %v", err)
+		return nil, err
 	}
+	return access.OrganizationIDs, nil
+}
 
-	permissionService, err := NewPermissionService()
+// GetUserDataAccess lấy phạm vi dữ liệu của user: tổ chức xem toàn bộ và tổ chức chỉ xem bản ghi của mình
+// Dựa trên permissions và scope của user (permissionName rỗng = tất cả permissions)
+// Tổ chức có cả grant scope 0/1 và scope 2 được tính là xem toàn bộ
+func GetUserDataAccess(ctx context.Context, userID primitive.ObjectID, permissionName string) (*UserDataAccess, error) {
+	userRoleService, err := NewUserRoleService()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create permission service: %v", err)
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
 	}
 
 	organizationService, err := NewOrganizationService()
@@ -40,68 +50,70 @@ func GetUserAllowedOrganizationIDs(ctx context.Context, userID primitive.ObjectI
 		return nil, fmt.Errorf("failed to create organization service: %v", err)
 	}
 
-	// 1. Lấy UserRoles của user
-	userRoles, err := userRoleService.Find(ctx, bson.M{"userId": userID}, nil)
+	// 1. Lấy permission hiệu lực (kèm role và tổ chức nguồn) bằng một aggregation
+	permissions, err := userRoleService.GetEffectivePermissions(ctx, userID, nil, permissionName)
 	if err != nil {
 		return nil, err
 	}
 
+	// 2. Tính toán allowed organization IDs dựa trên scope của từng grant
 	allowedOrgIDsMap := make(map[primitive.ObjectID]bool)
-
-	// 2. Duyệt qua từng role
-	for _, userRole := range userRoles {
-		// Lấy role
-		role, err := roleService.FindOneById(ctx, userRole.RoleID)
-		if err != nil {
-			continue
+	ownOrgIDsMap := make(map[primitive.ObjectID]bool)
+	subtreePaths := make(map[string]bool)
+	for _, permission := range permissions {
+		for _, grant := range permission.Grants {
+			switch grant.Scope {
+			case models.PermissionScopeOwn:
+				// Scope 2: Chỉ bản ghi của mình trong organization của role
+				ownOrgIDsMap[grant.OrganizationID] = true
+			case models.PermissionScopeSubtree:
+				// Scope 1: Organization + children
+				allowedOrgIDsMap[grant.OrganizationID] = true
+				subtreePaths[grant.OrganizationPath] = true
+			default:
+				// Scope 0: Chỉ organization của role
+				allowedOrgIDsMap[grant.OrganizationID] = true
+			}
 		}
+	}
 
-		orgID := role.OwnerOrganizationID
-
-		// 3. Lấy RolePermissions của role
-		rolePermissions, err := rolePermissionService.Find(ctx, bson.M{"roleId": role.ID}, nil)
-		if err != nil {
-			continue
+	// 3. Lấy children của tất cả organization có scope 1 trong một truy vấn
+	if len(subtreePaths) > 0 {
+		paths := make([]string, 0, len(subtreePaths))
+		for path := range subtreePaths {
+			paths = append(paths, path)
 		}
-
-		// 4. Kiểm tra permission cụ thể
-		for _, rp := range rolePermissions {
-			permission, err := permissionService.FindOneById(ctx, rp.PermissionID)
-			if err != nil {
-				continue
-			}
-
-			// Chỉ xử lý nếu permission name khớp (hoặc permissionName rỗng = tất cả permissions)
-			if permissionName != "" && permission.Name != permissionName {
-				continue
-			}
-
-			// 5. Tính toán allowed organization IDs dựa trên scope
-			if rp.Scope == 0 {
-				// Scope 0: Chỉ organization của role
-				allowedOrgIDsMap[orgID] = true
-			} else if rp.Scope == 1 {
-				// Scope 1: Organization + children
-				allowedOrgIDsMap[orgID] = true
-
-				// Lấy children IDs
-				childrenIDs, err := organizationService.GetChildrenIDs(ctx, orgID)
-				if err == nil {
-					for _, childID := range childrenIDs {
-						allowedOrgIDsMap[childID] = true
-					}
-				}
+		childrenIDs, err := organizationService.GetChildrenIDsByPaths(ctx, paths)
+		if err == nil {
+			for _, childID := range childrenIDs {
+				allowedOrgIDsMap[childID] = true
 			}
 		}
 	}
 
-	// 6. Convert map thành slice (KHÔNG tự động thêm parents)
-	result := make([]primitive.ObjectID, 0, len(allowedOrgIDsMap))
+	// 4. Convert map thành slice (KHÔNG tự động thêm parents)
+	access := &UserDataAccess{
+		OrganizationIDs:    make([]primitive.ObjectID, 0, len(allowedOrgIDsMap)),
+		OwnOrganizationIDs: make([]primitive.ObjectID, 0, len(ownOrgIDsMap)),
+	}
 	for orgID := range allowedOrgIDsMap {
-		result = append(result, orgID)
+		access.OrganizationIDs = append(access.OrganizationIDs, orgID)
+	}
+	for orgID := range ownOrgIDsMap {
+		if !allowedOrgIDsMap[orgID] {
+			access.OwnOrganizationIDs = append(access.OwnOrganizationIDs, orgID)
+		}
 	}
 
-	return result, nil
+	return access, nil
+}
+
+// OwnRecordFilter là điều kiện "bản ghi của user": do user tạo (createdBy) hoặc được giao cho user (assignedTo)
+func OwnRecordFilter(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"createdBy": userID},
+		{"assignedTo": userID},
+	}}
 }
 
 // IsUserAdministrator kiểm tra xem user có phải Administrator không
@@ -134,20 +146,8 @@ func IsUserAdministrator(ctx context.Context, userID primitive.ObjectID) (bool,
 		return false, err
 	}
 
-	// Kiểm tra user có role Administrator không
-	_, err = userRoleService.FindOne(ctx, bson.M{
-		"userId": userID,
-		"roleId": modelRole.ID,
-	}, nil)
-
-	if err != nil {
-		if err == common.ErrNotFound {
-			return false, nil // User không có role Administrator
-		}
-		return false, err
-	}
-
-	return true, nil // User có role Administrator
+	// Kiểm tra user có role Administrator (assignment còn hiệu lực) không
+	return userRoleService.IsExist(ctx, userID, modelRole.ID)
 }
 
 // Context key type để tránh conflict
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"meta_commerce/core/api/dto"
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
+	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvitationInvalid là lỗi chung khi token mời không hợp lệ, đã dùng, bị thu hồi hoặc hết hạn
+// (không phân biệt để tránh dò trạng thái lời mời qua token)
+var ErrInvitationInvalid = common.NewError(common.ErrCodeValidationInput, "Link mời không hợp lệ hoặc đã hết hạn", common.StatusBadRequest, nil)
+
+// InvitationRecipient là danh tính đang chấp nhận lời mời (lấy từ identity provider hoặc user đã đăng nhập)
+type InvitationRecipient struct {
+	Email         string
+	EmailVerified bool
+	Phone         string
+	PhoneVerified bool
+	Trusted       bool // true = tin email/phone dù chưa xác thực (Firebase - giữ quy tắc liên kết tài khoản hiện có)
+}
+
+// OrganizationInvitationService quản lý lời mời người dùng vào tổ chức
+type OrganizationInvitationService struct {
+	*BaseServiceMongoImpl[models.OrganizationInvitation]
+	organizationService *OrganizationService
+	roleService         *RoleService
+	userRoleService     *UserRoleService
+}
+
+// NewOrganizationInvitationService tạo mới OrganizationInvitationService
+func NewOrganizationInvitationService() (*OrganizationInvitationService, error) {
+	collection, exist := global.RegistryCollections.Get(global.MongoDB_ColNames.Invitations)
+	if !exist {
+		return nil, fmt.Errorf("failed to get organization_invitations collection: %v", common.ErrNotFound)
+	}
+
+	organizationService, err := NewOrganizationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization service: %v", err)
+	}
+
+	roleService, err := NewRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role service: %v", err)
+	}
+
+	userRoleService, err := NewUserRoleService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user role service: %v", err)
+	}
+
+	return &OrganizationInvitationService{
+		BaseServiceMongoImpl: NewBaseServiceMongo[models.OrganizationInvitation](collection),
+		organizationService:  organizationService,
+		roleService:          roleService,
+		userRoleService:      userRoleService,
+	}, nil
+}
+
+// signInvitation tính chữ ký HMAC-SHA256 (bằng JWT_SECRET) cho "invitationId.nonce"
+func signInvitation(invitationID, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(global.MongoDB_ServerConfig.JwtSecret))
+	mac.Write([]byte("invitation:" + invitationID + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newInvitationToken sinh token mời dạng "invitationId.nonce.signature" và hash của nonce để lưu DB
+func newInvitationToken(invitationID primitive.ObjectID) (string, string, error) {
+	nonceBytes := make([]byte, 24)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	token := invitationID.Hex() + "." + nonce + "." + signInvitation(invitationID.Hex(), nonce)
+	return token, hashAPIKey(nonce), nil
+}
+
+// parseInvitationToken kiểm tra chữ ký và tách invitationId, hash của nonce từ token
+func parseInvitationToken(token string) (primitive.ObjectID, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+	if !hmac.Equal([]byte(signInvitation(parts[0], parts[1])), []byte(parts[2])) {
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+	invitationID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+	return invitationID, hashAPIKey(parts[1]), nil
+}
+
+// invitationLink tạo link mời trên frontend chứa token
+func invitationLink(token string) string {
+	return strings.TrimRight(global.MongoDB_ServerConfig.FrontendURL, "/") + "/invitations/accept?token=" + url.QueryEscape(token)
+}
+
+// defaultInvitationExpiry trả về thời điểm hết hạn mặc định của link mời (milliseconds)
+func defaultInvitationExpiry() int64 {
+	ttl := time.Duration(global.MongoDB_ServerConfig.OrganizationInvitationTTLHours) * time.Hour
+	return time.Now().Add(ttl).UnixMilli()
+}
+
+// Create tạo lời mời vào tổ chức và trả về lời mời kèm InviteLink (token chỉ có trong link, không lưu DB)
+func (s *OrganizationInvitationService) Create(ctx context.Context, orgID, inviterID primitive.ObjectID, input dto.OrganizationInvitationCreateInput) (*models.OrganizationInvitation, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	phone := strings.TrimSpace(input.Phone)
+	if email == "" && phone == "" {
+		return nil, common.NewError(common.ErrCodeValidationInput, "Cần email hoặc số điện thoại của người được mời", common.StatusBadRequest, nil)
+	}
+
+	org, err := s.organizationService.FindOneById(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ArchivedAt != nil {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Không thể mời vào tổ chức đã lưu trữ", common.StatusBadRequest, nil)
+	}
+
+	roleIDs := make([]primitive.ObjectID, 0, len(input.RoleIDs))
+	for _, roleIDStr := range input.RoleIDs {
+		roleID, err := primitive.ObjectIDFromHex(roleIDStr)
+		if err != nil {
+			return nil, common.NewError(common.ErrCodeValidationFormat, fmt.Sprintf("roleId '%s' không hợp lệ", roleIDStr), common.StatusBadRequest, err)
+		}
+		role, err := s.roleService.FindOneById(ctx, roleID)
+		if err != nil || role.OwnerOrganizationID != orgID {
+			return nil, common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("Role '%s' không thuộc tổ chức này", roleIDStr), common.StatusBadRequest, nil)
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+	// Người mời chỉ được mời với role mà mình cấp được (role được gán khi người nhận chấp nhận, lúc đó người thực hiện là người nhận nên không kiểm tra lại)
+	if err := validateRoleDelegation(ctx, roleIDs); err != nil {
+		return nil, err
+	}
+
+	expiresAt := defaultInvitationExpiry()
+	if input.ExpiresAt != nil {
+		if *input.ExpiresAt <= utility.CurrentTimeInMilli() {
+			return nil, common.NewError(common.ErrCodeValidationInput, "expiresAt phải ở tương lai", common.StatusBadRequest, nil)
+		}
+		expiresAt = *input.ExpiresAt
+	}
+
+	// Mỗi người chỉ có một lời mời đang chờ trong một tổ chức - dùng gửi lại thay vì tạo mới
+	recipientFilter := []bson.M{}
+	if email != "" {
+		recipientFilter = append(recipientFilter, bson.M{"email": email})
+	}
+	if phone != "" {
+		recipientFilter = append(recipientFilter, bson.M{"phone": phone})
+	}
+	pending, err := s.collection.CountDocuments(ctx, bson.M{
+		"ownerOrganizationId": orgID,
+		"status":              models.OrganizationInvitationStatusPending,
+		"expiresAt":           bson.M{"$gt": utility.CurrentTimeInMilli()},
+		"$or":                 recipientFilter,
+	})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	if pending > 0 {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Người này đã có lời mời đang chờ vào tổ chức. Hãy gửi lại lời mời cũ", common.StatusConflict, nil)
+	}
+
+	invitationID := primitive.NewObjectID()
+	token, tokenHash, err := newInvitationToken(invitationID)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể sinh link mời", common.StatusInternalServerError, err)
+	}
+
+	created, err := s.InsertOne(ctx, models.OrganizationInvitation{
+		ID:                  invitationID,
+		OwnerOrganizationID: orgID,
+		Email:               email,
+		Phone:               phone,
+		RoleIDs:             roleIDs,
+		Status:              models.OrganizationInvitationStatusPending,
+		TokenHash:           tokenHash,
+		ExpiresAt:           expiresAt,
+		InvitedBy:           inviterID,
+		SendCount:           1,
+		LastSentAt:          utility.CurrentTimeInMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	created.InviteLink = invitationLink(token)
+	return &created, nil
+}
+
+// Resend sinh link mới cho lời mời đang chờ (link cũ hết hiệu lực); lời mời đã quá hạn được gia hạn theo thời hạn mặc định
+func (s *OrganizationInvitationService) Resend(ctx context.Context, orgID, id primitive.ObjectID) (*models.OrganizationInvitation, error) {
+	invitation, err := s.findInOrganization(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != models.OrganizationInvitationStatusPending {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, "Chỉ gửi lại được lời mời đang chờ", common.StatusConflict, nil)
+	}
+
+	token, tokenHash, err := newInvitationToken(invitation.ID)
+	if err != nil {
+		return nil, common.NewError(common.ErrCodeInternalServer, "Không thể sinh link mời", common.StatusInternalServerError, err)
+	}
+	now := utility.CurrentTimeInMilli()
+	set := bson.M{"tokenHash": tokenHash, "lastSentAt": now, "updatedAt": now}
+	if invitation.ExpiresAt <= now {
+		set["expiresAt"] = defaultInvitationExpiry()
+	}
+
+	var updated models.OrganizationInvitation
+	err = s.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": invitation.ID, "status": models.OrganizationInvitationStatusPending},
+		bson.M{"$set": set, "$inc": bson.M{"sendCount": 1}},
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, common.NewError(common.ErrCodeBusinessOperation, "Chỉ gửi lại được lời mời đang chờ", common.StatusConflict, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	updated.InviteLink = invitationLink(token)
+	return &updated, nil
+}
+
+// Revoke thu hồi lời mời đang chờ
+func (s *OrganizationInvitationService) Revoke(ctx context.Context, orgID, id, revokedBy primitive.ObjectID) (*models.OrganizationInvitation, error) {
+	invitation, err := s.findInOrganization(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utility.CurrentTimeInMilli()
+	var updated models.OrganizationInvitation
+	err = s.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": invitation.ID, "status": models.OrganizationInvitationStatusPending},
+		bson.M{"$set": bson.M{
+			"status":    models.OrganizationInvitationStatusRevoked,
+			"revokedBy": revokedBy,
+			"revokedAt": now,
+			"updatedAt": now,
+		}},
+		mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, common.NewError(common.ErrCodeBusinessOperation, "Chỉ thu hồi được lời mời đang chờ", common.StatusConflict, nil)
+		}
+		return nil, common.ConvertMongoError(err)
+	}
+	return &updated, nil
+}
+
+// ListByOrganization lấy các lời mời của tổ chức (mới nhất trước), lọc theo status nếu có
+func (s *OrganizationInvitationService) ListByOrganization(ctx context.Context, orgID primitive.ObjectID, status string) ([]models.OrganizationInvitation, error) {
+	filter := bson.M{"ownerOrganizationId": orgID}
+	if status != "" {
+		filter["status"] = status
+	}
+	return s.Find(ctx, filter, mongoopts.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+}
+
+// findInOrganization lấy lời mời và kiểm tra thuộc tổ chức trong URL
+func (s *OrganizationInvitationService) findInOrganization(ctx context.Context, orgID, id primitive.ObjectID) (*models.OrganizationInvitation, error) {
+	invitation, err := s.FindOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.OwnerOrganizationID != orgID {
+		return nil, common.ErrNotFound
+	}
+	return &invitation, nil
+}
+
+// CheckForRecipient kiểm tra token mời còn hiệu lực và khớp với email/số điện thoại của danh tính đang chấp nhận
+// Không thay đổi dữ liệu - dùng trước khi tạo user khi đăng nhập để lỗi lời mời không tạo ra user thừa
+func (s *OrganizationInvitationService) CheckForRecipient(ctx context.Context, token string, recipient InvitationRecipient) (*models.OrganizationInvitation, error) {
+	invitationID, tokenHash, err := parseInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.FindOneById(ctx, invitationID)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if invitation.Status != models.OrganizationInvitationStatusPending ||
+		invitation.TokenHash != tokenHash ||
+		invitation.ExpiresAt <= utility.CurrentTimeInMilli() {
+		return nil, ErrInvitationInvalid
+	}
+
+	emailMatched := invitation.Email != "" && strings.EqualFold(invitation.Email, strings.TrimSpace(recipient.Email)) && (recipient.EmailVerified || recipient.Trusted)
+	phoneMatched := invitation.Phone != "" && invitation.Phone == strings.TrimSpace(recipient.Phone) && (recipient.PhoneVerified || recipient.Trusted)
+	if !emailMatched && !phoneMatched {
+		return nil, common.NewError(common.ErrCodeAuth, "Lời mời không dành cho tài khoản này (email/số điện thoại không khớp hoặc chưa xác thực)", common.StatusForbidden, nil)
+	}
+	return &invitation, nil
+}
+
+// Accept gắn lời mời vào user và tạo user_roles cho các role của lời mời
+// Lời mời chỉ được chấp nhận một lần (cập nhật có điều kiện status = pending); cập nhật lời mời và gán role trong cùng transaction
+// để lỗi gán role không làm mất lời mời. Quyền gán role đã được kiểm tra với người mời lúc tạo lời mời nên không kiểm tra lại với người nhận
+func (s *OrganizationInvitationService) Accept(ctx context.Context, invitation *models.OrganizationInvitation, userID primitive.ObjectID) (*models.OrganizationInvitation, error) {
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+	defer session.EndSession(ctx)
+
+	var accepted models.OrganizationInvitation
+	// Audit của transaction chỉ được ghi sau khi commit
+	txCtx, audits := withAuditBuffer(withoutDelegationCaller(ctx))
+	_, err = session.WithTransaction(txCtx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		audits.reset()
+
+		now := utility.CurrentTimeInMilli()
+		err := s.collection.FindOneAndUpdate(sessCtx,
+			bson.M{
+				"_id":       invitation.ID,
+				"status":    models.OrganizationInvitationStatusPending,
+				"tokenHash": invitation.TokenHash,
+				"expiresAt": bson.M{"$gt": now},
+			},
+			bson.M{"$set": bson.M{
+				"status":     models.OrganizationInvitationStatusAccepted,
+				"acceptedBy": userID,
+				"acceptedAt": now,
+				"updatedAt":  now,
+			}},
+			mongoopts.FindOneAndUpdate().SetReturnDocument(mongoopts.After),
+		).Decode(&accepted)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrInvitationInvalid
+			}
+			return nil, common.ConvertMongoError(err)
+		}
+
+		for _, roleID := range accepted.RoleIDs {
+			// Role có thể đã bị xóa/chuyển tổ chức sau khi mời
+			role, err := s.roleService.FindOneById(sessCtx, roleID)
+			if err != nil || role.OwnerOrganizationID != accepted.OwnerOrganizationID {
+				logrus.WithFields(logrus.Fields{
+					"invitation_id": accepted.ID.Hex(),
+					"role_id":       roleID.Hex(),
+				}).Warn("Invitation: Role không còn thuộc tổ chức, bỏ qua")
+				continue
+			}
+			assigned, err := s.userRoleService.IsExist(sessCtx, userID, roleID)
+			if err != nil {
+				return nil, err
+			}
+			if assigned {
+				continue
+			}
+			if _, err := s.userRoleService.InsertOne(sessCtx, models.UserRole{UserID: userID, RoleID: roleID}); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	audits.flush(ctx)
+	// Cache quyền có thể đã được tính lại trong lúc transaction chưa commit
+	publishUserPermissionChange(userID)
+	return &accepted, nil
+}
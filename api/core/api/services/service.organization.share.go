@@ -6,11 +6,27 @@ import (
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/common"
 	"meta_commerce/core/global"
+	"meta_commerce/core/utility"
+	"regexp"
+	"sort"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoopts "go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// SharedOrganizationScope là phạm vi dữ liệu nhận được từ share
+// DocumentFilter = nil: toàn bộ dữ liệu của OrganizationID
+type SharedOrganizationScope struct {
+	OrganizationID primitive.ObjectID     `json:"organizationId"`
+	ShareID        primitive.ObjectID     `json:"shareId"`
+	DocumentFilter map[string]interface{} `json:"documentFilter,omitempty"`
+}
+
+// documentFilterFieldPattern giới hạn tên field trong DocumentFilter (không cho phép operator hoặc field lồng)
+var documentFilterFieldPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
 // OrganizationShareService là service quản lý sharing giữa các organizations
 type OrganizationShareService struct {
 	*BaseServiceMongoImpl[models.OrganizationShare]
@@ -46,22 +62,100 @@ func NewOrganizationShareService() (*OrganizationShareService, error) {
 	}, nil
 }
 
-// GetSharedOrganizationIDs lấy organizations được share với user's organizations
-// userOrgIDs: Danh sách organization IDs của user (từ scope)
+// ActiveShareFilter trả về điều kiện share còn hiệu lực: chưa thu hồi/hết hạn và chưa quá expiresAt
+// (share cũ không có status được coi là active)
+func ActiveShareFilter() bson.M {
+	return bson.M{
+		"status": bson.M{"$nin": []string{models.OrganizationShareStatusRevoked, models.OrganizationShareStatusExpired}},
+		"$or": []bson.M{
+			{"expiresAt": bson.M{"$exists": false}},
+			{"expiresAt": bson.M{"$gt": utility.CurrentTimeInMilli()}},
+		},
+	}
+}
+
+// ValidateDocumentFilter kiểm tra DocumentFilter của share: chỉ so sánh bằng trên field đơn giản
+func ValidateDocumentFilter(filter map[string]interface{}) error {
+	for field, value := range filter {
+		if !documentFilterFieldPattern.MatchString(field) {
+			return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("documentFilter: tên field '%s' không hợp lệ", field), common.StatusBadRequest, nil)
+		}
+		switch value.(type) {
+		case string, bool, int, int32, int64, float64:
+		default:
+			return common.NewError(common.ErrCodeValidationInput, fmt.Sprintf("documentFilter: giá trị của '%s' phải là string, number hoặc bool", field), common.StatusBadRequest, nil)
+		}
+	}
+	return nil
+}
+
+// ResolvedShare là share còn hiệu lực kèm các tổ chức cụ thể ở hai phía (đã mở rộng theo cây tổ chức)
+type ResolvedShare struct {
+	Share                    models.OrganizationShare
+	OwnerOrganizationIDs     []primitive.ObjectID // OwnerOrganizationID (+ tổ chức con đang hoạt động nếu IncludeOwnerSubtree)
+	RecipientOrganizationIDs []primitive.ObjectID // Các tổ chức trong recipientOrgIDs nhận share (ToOrgID hoặc tổ chức con nếu IncludeRecipientSubtree)
+}
+
+// ResolveActiveShares lấy các share còn hiệu lực tới recipientOrgIDs
+// - Share tới chính tổ chức trong recipientOrgIDs
+// - Share tới tổ chức cha (theo Path) có IncludeRecipientSubtree
+// Bỏ share từ tổ chức đã lưu trữ; share toàn bộ dữ liệu đứng trước share giới hạn theo DocumentFilter
 // permissionName: Permission name cụ thể (nếu rỗng = tất cả permissions)
-func GetSharedOrganizationIDs(ctx context.Context, userOrgIDs []primitive.ObjectID, permissionName string) ([]primitive.ObjectID, error) {
+func ResolveActiveShares(ctx context.Context, recipientOrgIDs []primitive.ObjectID, permissionName string) ([]ResolvedShare, error) {
+	if len(recipientOrgIDs) == 0 {
+		return []ResolvedShare{}, nil
+	}
+
 	shareService, err := NewOrganizationShareService()
 	if err != nil {
 		return nil, err
 	}
+	organizationService, err := NewOrganizationService()
+	if err != nil {
+		return nil, err
+	}
 
-	if len(userOrgIDs) == 0 {
-		return []primitive.ObjectID{}, nil
+	// 1. Tổ chức cha của các tổ chức nhận (để tìm share có IncludeRecipientSubtree)
+	recipientSet := make(map[primitive.ObjectID]bool, len(recipientOrgIDs))
+	for _, id := range recipientOrgIDs {
+		recipientSet[id] = true
+	}
+	recipients, err := organizationService.Find(ctx, bson.M{"_id": bson.M{"$in": recipientOrgIDs}}, mongoopts.Find().SetProjection(bson.M{"_id": 1, "path": 1}))
+	if err != nil && err != common.ErrNotFound {
+		return nil, err
+	}
+	ancestorPathSet := make(map[string]bool)
+	for _, org := range recipients {
+		for i := 1; i < len(org.Path); i++ {
+			if org.Path[i] == '/' {
+				ancestorPathSet[org.Path[:i]] = true
+			}
+		}
+	}
+	ancestorPaths := make([]string, 0, len(ancestorPathSet))
+	for path := range ancestorPathSet {
+		ancestorPaths = append(ancestorPaths, path)
+	}
+	ancestors := []models.Organization{}
+	if len(ancestorPaths) > 0 {
+		ancestors, err = organizationService.Find(ctx, bson.M{"path": bson.M{"$in": ancestorPaths}}, mongoopts.Find().SetProjection(bson.M{"_id": 1, "path": 1}))
+		if err != nil && err != common.ErrNotFound {
+			return nil, err
+		}
+	}
+	ancestorIDs := make([]primitive.ObjectID, 0, len(ancestors))
+	for _, org := range ancestors {
+		ancestorIDs = append(ancestorIDs, org.ID)
 	}
 
-	// Query: toOrgId trong userOrgIDs (dùng tên field trong bson tag)
-	filter := bson.M{
-		"toOrgId": bson.M{"$in": userOrgIDs},
+	// 2. Share còn hiệu lực tới tổ chức nhận hoặc tổ chức cha có IncludeRecipientSubtree
+	toConditions := []bson.M{{"toOrgId": bson.M{"$in": recipientOrgIDs}}}
+	if len(ancestorIDs) > 0 {
+		toConditions = append(toConditions, bson.M{"toOrgId": bson.M{"$in": ancestorIDs}, "includeRecipientSubtree": true})
+	}
+	conditions := []bson.M{
+		{"$or": toConditions},
+		ActiveShareFilter(),
 	}
 
 	// Nếu có permissionName, filter thêm
@@ -69,49 +163,225 @@ func GetSharedOrganizationIDs(ctx context.Context, userOrgIDs []primitive.Object
 		// Share nếu:
 		// 1. PermissionNames rỗng/nil (share tất cả permissions)
 		// 2. PermissionNames chứa permissionName cụ thể
-		filter["$or"] = []bson.M{
+		conditions = append(conditions, bson.M{"$or": []bson.M{
 			{"permissionNames": bson.M{"$exists": false}},                // Không có field
 			{"permissionNames": bson.M{"$size": 0}},                      // Array rỗng
 			{"permissionNames": bson.M{"$in": []string{permissionName}}}, // Chứa permissionName
-		}
+		}})
 	}
 
-	shares, err := shareService.Find(ctx, filter, nil)
+	shares, err := shareService.Find(ctx, bson.M{"$and": conditions}, nil)
 	if err != nil {
 		if err == common.ErrNotFound {
-			return []primitive.ObjectID{}, nil
+			return []ResolvedShare{}, nil
 		}
 		return nil, err
 	}
 
-	// Lấy fromOrgIDs (organizations share data với user)
-	sharedOrgIDsMap := make(map[primitive.ObjectID]bool)
+	// 3. Bỏ share từ tổ chức đã lưu trữ
+	ownerOrgIDs := make([]primitive.ObjectID, 0, len(shares))
+	for _, share := range shares {
+		ownerOrgIDs = append(ownerOrgIDs, share.OwnerOrganizationID)
+	}
+	archived, err := organizationService.GetArchivedIDs(ctx, ownerOrgIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Mở rộng phía chủ sở hữu theo cây tổ chức (IncludeOwnerSubtree)
+	subtreeOwnerIDs := make([]primitive.ObjectID, 0)
+	for _, share := range shares {
+		if share.IncludeOwnerSubtree && !archived[share.OwnerOrganizationID] {
+			subtreeOwnerIDs = append(subtreeOwnerIDs, share.OwnerOrganizationID)
+		}
+	}
+	ownerPaths := make(map[primitive.ObjectID]string)
+	ownerSubtrees := make(map[string][]primitive.ObjectID)
+	if len(subtreeOwnerIDs) > 0 {
+		owners, err := organizationService.Find(ctx, bson.M{"_id": bson.M{"$in": subtreeOwnerIDs}}, mongoopts.Find().SetProjection(bson.M{"_id": 1, "path": 1}))
+		if err != nil && err != common.ErrNotFound {
+			return nil, err
+		}
+		paths := make([]string, 0, len(owners))
+		for _, owner := range owners {
+			ownerPaths[owner.ID] = owner.Path
+			paths = append(paths, owner.Path)
+		}
+		ownerSubtrees, err = organizationService.GetChildrenIDsGroupedByPath(ctx, paths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Tổ chức nhận nằm dưới mỗi tổ chức cha (IncludeRecipientSubtree)
+	ancestorRecipients := make(map[primitive.ObjectID][]primitive.ObjectID, len(ancestors))
+	for _, ancestor := range ancestors {
+		for _, org := range recipients {
+			if strings.HasPrefix(org.Path, ancestor.Path+"/") {
+				ancestorRecipients[ancestor.ID] = append(ancestorRecipients[ancestor.ID], org.ID)
+			}
+		}
+	}
+
+	// Share toàn bộ trước, share giới hạn sau
+	sort.SliceStable(shares, func(i, j int) bool {
+		return len(shares[i].DocumentFilter) == 0 && len(shares[j].DocumentFilter) > 0
+	})
+
+	result := make([]ResolvedShare, 0, len(shares))
 	for _, share := range shares {
-		// Nếu có permissionName, kiểm tra kỹ hơn
-		if permissionName != "" {
-			// Nếu PermissionNames không rỗng và không chứa permissionName → skip
-			if len(share.PermissionNames) > 0 {
-				hasPermission := false
-				for _, pn := range share.PermissionNames {
-					if pn == permissionName {
-						hasPermission = true
-						break
+		if archived[share.OwnerOrganizationID] {
+			continue
+		}
+
+		resolved := ResolvedShare{Share: share, OwnerOrganizationIDs: []primitive.ObjectID{share.OwnerOrganizationID}}
+		if share.IncludeOwnerSubtree {
+			if path, ok := ownerPaths[share.OwnerOrganizationID]; ok {
+				for _, orgID := range ownerSubtrees[path] {
+					if orgID != share.OwnerOrganizationID {
+						resolved.OwnerOrganizationIDs = append(resolved.OwnerOrganizationIDs, orgID)
 					}
 				}
-				if !hasPermission {
-					continue // Skip share này
-				}
 			}
 		}
 
-		sharedOrgIDsMap[share.OwnerOrganizationID] = true
+		if recipientSet[share.ToOrgID] {
+			resolved.RecipientOrganizationIDs = append(resolved.RecipientOrganizationIDs, share.ToOrgID)
+		}
+		if share.IncludeRecipientSubtree {
+			resolved.RecipientOrganizationIDs = append(resolved.RecipientOrganizationIDs, ancestorRecipients[share.ToOrgID]...)
+		}
+		if len(resolved.RecipientOrganizationIDs) == 0 {
+			continue
+		}
+		result = append(result, resolved)
+	}
+	return result, nil
+}
+
+// GetSharedOrganizationScopes lấy phạm vi dữ liệu được share với user's organizations (chỉ share còn hiệu lực)
+// userOrgIDs: Danh sách organization IDs của user (từ scope)
+// permissionName: Permission name cụ thể (nếu rỗng = tất cả permissions)
+// Tổ chức đã có share toàn bộ thì bỏ qua các share giới hạn theo DocumentFilter của tổ chức đó
+func GetSharedOrganizationScopes(ctx context.Context, userOrgIDs []primitive.ObjectID, permissionName string) ([]SharedOrganizationScope, error) {
+	shares, err := ResolveActiveShares(ctx, userOrgIDs, permissionName)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert to slice
-	result := make([]primitive.ObjectID, 0, len(sharedOrgIDsMap))
-	for orgID := range sharedOrgIDsMap {
-		result = append(result, orgID)
+	fullShared := make(map[primitive.ObjectID]bool)
+	result := make([]SharedOrganizationScope, 0, len(shares))
+	for _, resolved := range shares {
+		for _, orgID := range resolved.OwnerOrganizationIDs {
+			if fullShared[orgID] {
+				continue
+			}
+			if len(resolved.Share.DocumentFilter) == 0 {
+				fullShared[orgID] = true
+			}
+			result = append(result, SharedOrganizationScope{
+				OrganizationID: orgID,
+				ShareID:        resolved.Share.ID,
+				DocumentFilter: resolved.Share.DocumentFilter,
+			})
+		}
 	}
 
 	return result, nil
 }
+
+// GetSharedOrganizationIDs lấy organizations share toàn bộ dữ liệu với user's organizations
+// (không gồm share giới hạn theo DocumentFilter - dùng GetSharedOrganizationScopes nếu cần)
+// userOrgIDs: Danh sách organization IDs của user (từ scope)
+// permissionName: Permission name cụ thể (nếu rỗng = tất cả permissions)
+func GetSharedOrganizationIDs(ctx context.Context, userOrgIDs []primitive.ObjectID, permissionName string) ([]primitive.ObjectID, error) {
+	scopes, err := GetSharedOrganizationScopes(ctx, userOrgIDs, permissionName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]primitive.ObjectID, 0, len(scopes))
+	for _, scope := range scopes {
+		if len(scope.DocumentFilter) == 0 {
+			result = append(result, scope.OrganizationID)
+		}
+	}
+	return result, nil
+}
+
+// InsertOne override để đặt status mặc định và validate DocumentFilter
+func (s *OrganizationShareService) InsertOne(ctx context.Context, data models.OrganizationShare) (models.OrganizationShare, error) {
+	if err := ValidateDocumentFilter(data.DocumentFilter); err != nil {
+		return data, err
+	}
+	if data.Status == "" {
+		data.Status = models.OrganizationShareStatusActive
+	}
+	return s.BaseServiceMongoImpl.InsertOne(ctx, data)
+}
+
+// RevokeShare thu hồi share còn hiệu lực
+// note: Lý do thu hồi (rỗng = giữ note cũ)
+func (s *OrganizationShareService) RevokeShare(ctx context.Context, shareID, revokedBy primitive.ObjectID, note string) (*models.OrganizationShare, error) {
+	share, err := s.FindOneById(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.Status == models.OrganizationShareStatusRevoked || share.Status == models.OrganizationShareStatusExpired {
+		return nil, common.NewError(common.ErrCodeBusinessOperation, fmt.Sprintf("Share đang ở trạng thái %s, không thể thu hồi", share.Status), common.StatusConflict, nil)
+	}
+
+	now := utility.CurrentTimeInMilli()
+	set := bson.M{
+		"status":    models.OrganizationShareStatusRevoked,
+		"revokedAt": now,
+		"revokedBy": revokedBy,
+	}
+	if note != "" {
+		set["note"] = note
+		share.Note = note
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": shareID}, bson.M{"$set": set}); err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	share.Status = models.OrganizationShareStatusRevoked
+	share.RevokedAt = &now
+	share.RevokedBy = &revokedBy
+	return &share, nil
+}
+
+// ExpireShares đánh dấu expired cho các share active đã quá expiresAt, trả về các share vừa được đánh dấu
+func (s *OrganizationShareService) ExpireShares(ctx context.Context) ([]models.OrganizationShare, error) {
+	now := utility.CurrentTimeInMilli()
+	filter := bson.M{
+		"status":    bson.M{"$nin": []string{models.OrganizationShareStatusRevoked, models.OrganizationShareStatusExpired}},
+		"expiresAt": bson.M{"$lte": now},
+	}
+
+	shares, err := s.Find(ctx, filter, nil)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return []models.OrganizationShare{}, nil
+		}
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return shares, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(shares))
+	for _, share := range shares {
+		ids = append(ids, share.ID)
+	}
+	// Giữ điều kiện status để không ghi đè share bị thu hồi trong lúc xử lý
+	_, err = s.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": filter["status"]}, bson.M{"$set": bson.M{"status": models.OrganizationShareStatusExpired}})
+	if err != nil {
+		return nil, common.ConvertMongoError(err)
+	}
+
+	for i := range shares {
+		shares[i].Status = models.OrganizationShareStatusExpired
+	}
+	return shares, nil
+}
@@ -176,6 +176,16 @@ func ValidateBeforeDeleteRole(ctx context.Context, roleID primitive.ObjectID) er
 			FieldName:      "roleId",
 			ErrorMessage:   "Không thể xóa role vì có %d permission đang được gán cho role này. Vui lòng gỡ các permission trước.",
 		},
+		{
+			CollectionName: global.MongoDB_ColNames.Roles,
+			FieldName:      "parentRoleId",
+			ErrorMessage:   "Không thể xóa role vì có %d role đang kế thừa quyền từ role này. Vui lòng gỡ liên kết role cha trước.",
+		},
+		{
+			CollectionName: global.MongoDB_ColNames.Roles,
+			FieldName:      "templateId",
+			ErrorMessage:   "Không thể xóa template vì có %d role đang được khởi tạo từ template này. Vui lòng tách các role khỏi template trước.",
+		},
 	}
 
 	return CheckRelationshipExists(ctx, roleID, checks)
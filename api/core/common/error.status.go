@@ -115,6 +115,13 @@ var (
 		Description: "Lỗi liên quan đến vai trò người dùng",
 	}
 
+	ErrCodeAuthMFA = ErrorCode{
+		Code:        "AUTH_004",
+		Category:    "Authentication",
+		SubCategory: "MFA",
+		Description: "Cần xác thực 2 lớp (TOTP) cho thao tác nhạy cảm",
+	}
+
 	// Validation Errors (VAL_xxx)
 	ErrCodeValidation = ErrorCode{
 		Code:        "VAL",
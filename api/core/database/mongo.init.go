@@ -165,6 +165,12 @@ func compareIndex(existingIndex bson.M, keys bson.D, options *options.IndexOptio
 		return false
 	}
 
+	// So sánh sparse (index cũ không sparse nhưng index mới sparse hoặc ngược lại => mismatch)
+	existingSparse, _ := existingIndex["sparse"].(bool)
+	if existingSparse != (options.Sparse != nil && *options.Sparse) {
+		return false
+	}
+
 	// So sánh TTL
 	if ttl, ok := existingIndex["expireAfterSeconds"].(int32); ok && options.ExpireAfterSeconds != nil {
 		if ttl != *options.ExpireAfterSeconds {
@@ -246,7 +252,8 @@ func CreateIndexes(ctx context.Context, collection *mongo.Collection, model inte
 			continue
 		}
 
-		bsonField := field.Tag.Get("bson")
+		// Bỏ các tùy chọn của bson tag (vd: "email,omitempty" -> "email")
+		bsonField, _, _ := strings.Cut(field.Tag.Get("bson"), ",")
 		if bsonField == "" || bsonField == "-" {
 			continue
 		}
@@ -333,7 +340,8 @@ func CreateIndexes(ctx context.Context, collection *mongo.Collection, model inte
 			continue
 		}
 
-		bsonField := field.Tag.Get("bson")
+		// Bỏ các tùy chọn của bson tag (vd: "email,omitempty" -> "email")
+		bsonField, _, _ := strings.Cut(field.Tag.Get("bson"), ",")
 		if bsonField == "" || bsonField == "-" {
 			continue
 		}
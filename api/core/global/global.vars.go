@@ -18,6 +18,18 @@ type MongoDB_Auth_CollectionName struct {
 	RolePermissions string // Tên collection cho vai trò và quyền
 	UserRoles       string // Tên collection cho người dùng và vai trò
 	Organizations   string // Tên collection cho tổ chức
+	RevokedTokens   string // Tên collection cho danh sách jti token đã bị thu hồi
+	ServiceAccounts string // Tên collection cho service account (principal của máy)
+	ApiKeys         string // Tên collection cho API key của service account
+	OAuthClients    string // Tên collection cho OAuth client (ứng dụng nhận token do hệ thống phát hành)
+	OAuthKeys       string // Tên collection cho khóa ký token OAuth/OIDC (RS256)
+	OAuthCodes      string // Tên collection cho authorization code OAuth
+	RoleElevations  string // Tên collection cho yêu cầu nâng quyền tạm thời (JIT)
+	Invitations     string // Tên collection cho lời mời tham gia tổ chức
+	UserMFA         string // Tên collection cho cấu hình xác thực 2 lớp (TOTP) của user
+	AuthLogs        string // Tên collection cho audit trail (lịch sử thay đổi dữ liệu)
+	LoginAttempts   string // Tên collection cho bộ đếm đăng nhập thất bại (theo danh tính/IP) và trạng thái khóa
+	LoginEvents     string // Tên collection cho lịch sử đăng nhập (IP, thiết bị, kết quả)
 	Agents          string // Tên collection cho bot
 	AccessTokens    string // Tên collection cho token
 	FbPages         string // Tên collection cho trang Facebook
@@ -0,0 +1,93 @@
+// Package identity định nghĩa lớp trừu tượng cho các nhà cung cấp danh tính (identity provider).
+//
+// Mỗi provider nhận một token do client gửi lên (Firebase ID token, OIDC id_token, token dev cục bộ...),
+// xác thực token và trả về Identity chuẩn hóa. UserService dựa vào Identity để tìm/tạo user
+// mà không phụ thuộc vào provider cụ thể.
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"meta_commerce/core/registry"
+)
+
+// Tên các provider có sẵn
+const (
+	ProviderFirebase = "firebase"
+	ProviderOIDC     = "oidc"
+	ProviderLocal    = "local"
+)
+
+// ErrProviderNotFound trả về khi provider chưa được đăng ký (chưa cấu hình)
+var ErrProviderNotFound = errors.New("identity provider not found")
+
+// Identity là thông tin danh tính đã được provider xác thực
+type Identity struct {
+	Provider      string // Tên provider đã xác thực (firebase, oidc, local...)
+	Subject       string // ID duy nhất của user trong provider (Firebase UID, claim "sub"...)
+	Email         string // Email (có thể rỗng)
+	EmailVerified bool   // Email đã được provider xác thực
+	Phone         string // Số điện thoại (có thể rỗng)
+	PhoneVerified bool   // Số điện thoại đã được provider xác thực
+	Name          string // Tên hiển thị
+	AvatarURL     string // URL ảnh đại diện
+}
+
+// Key trả về khóa định danh duy nhất "provider:subject" dùng để liên kết với user
+func (i *Identity) Key() string {
+	return IdentityKey(i.Provider, i.Subject)
+}
+
+// IdentityKey ghép provider và subject thành khóa "provider:subject"
+func IdentityKey(provider, subject string) string {
+	return provider + ":" + subject
+}
+
+// Provider là nhà cung cấp danh tính
+type Provider interface {
+	// Name trả về tên provider (dùng trong route /auth/login/:provider)
+	Name() string
+	// Verify xác thực token và trả về danh tính tương ứng
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+var (
+	providers = registry.NewRegistry[Provider]()
+	namesMu   sync.RWMutex
+	names     []string
+)
+
+// Register đăng ký provider; đăng ký lại cùng tên sẽ ghi đè provider cũ
+func Register(p Provider) error {
+	isNew, err := providers.Register(p.Name(), p)
+	if err != nil {
+		return fmt.Errorf("failed to register identity provider %s: %v", p.Name(), err)
+	}
+	if isNew {
+		namesMu.Lock()
+		names = append(names, p.Name())
+		sort.Strings(names)
+		namesMu.Unlock()
+	}
+	return nil
+}
+
+// Get lấy provider theo tên
+func Get(name string) (Provider, error) {
+	p, ok := providers.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	return p, nil
+}
+
+// Names trả về danh sách tên các provider đã đăng ký
+func Names() []string {
+	namesMu.RLock()
+	defer namesMu.RUnlock()
+	return append([]string(nil), names...)
+}
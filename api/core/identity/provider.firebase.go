@@ -0,0 +1,46 @@
+package identity
+
+import (
+	"context"
+
+	"meta_commerce/core/utility"
+)
+
+// FirebaseProvider xác thực Firebase ID token qua Firebase Admin SDK
+// Yêu cầu utility.InitFirebase đã được gọi khi khởi động server
+type FirebaseProvider struct{}
+
+// NewFirebaseProvider tạo mới FirebaseProvider
+func NewFirebaseProvider() *FirebaseProvider {
+	return &FirebaseProvider{}
+}
+
+// Name trả về tên provider
+func (p *FirebaseProvider) Name() string {
+	return ProviderFirebase
+}
+
+// Verify xác thực Firebase ID token và lấy thông tin user từ Firebase
+func (p *FirebaseProvider) Verify(ctx context.Context, token string) (*Identity, error) {
+	idToken, err := utility.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	firebaseUser, err := utility.GetUserByUID(ctx, idToken.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:      ProviderFirebase,
+		Subject:       idToken.UID,
+		Email:         firebaseUser.Email,
+		EmailVerified: firebaseUser.EmailVerified,
+		Phone:         firebaseUser.PhoneNumber,
+		// Firebase chỉ gắn số điện thoại sau khi xác thực OTP
+		PhoneVerified: firebaseUser.PhoneNumber != "",
+		Name:          firebaseUser.DisplayName,
+		AvatarURL:     firebaseUser.PhotoURL,
+	}, nil
+}
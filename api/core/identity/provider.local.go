@@ -0,0 +1,68 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// localIssuer là giá trị claim "iss" của token do LocalProvider phát hành
+const localIssuer = "meta_commerce-local"
+
+// LocalClaims là claims của token dev cục bộ
+type LocalClaims struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified,omitempty"`
+	Phone         string `json:"phone_number,omitempty"`
+	Name          string `json:"name,omitempty"`
+	AvatarURL     string `json:"picture,omitempty"`
+	jwt.StandardClaims
+}
+
+// LocalProvider là provider cục bộ dùng cho môi trường dev/test offline
+// Token được ký HS256 bằng secret cấu hình (LOCAL_IDENTITY_SECRET) ngay tại test harness, server không phát hành token này
+// KHÔNG bật provider này ở production: ai có secret đều ký được token cho bất kỳ danh tính nào
+type LocalProvider struct {
+	secret []byte
+}
+
+// NewLocalProvider tạo mới LocalProvider với secret ký token
+func NewLocalProvider(secret string) *LocalProvider {
+	return &LocalProvider{secret: []byte(secret)}
+}
+
+// Name trả về tên provider
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// Verify xác thực token dev (claims LocalClaims, iss "meta_commerce-local", bắt buộc sub và exp)
+func (p *LocalProvider) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := &LocalClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return p.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify local token: %v", err)
+	}
+	if claims.Issuer != localIssuer || claims.Subject == "" || claims.ExpiresAt == 0 {
+		return nil, errors.New("invalid local token claims")
+	}
+
+	return &Identity{
+		Provider:      ProviderLocal,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Phone:         claims.Phone,
+		// Không có bước xác thực số điện thoại nào phía sau token dev
+		PhoneVerified: false,
+		Name:          claims.Name,
+		AvatarURL:     claims.AvatarURL,
+	}, nil
+}
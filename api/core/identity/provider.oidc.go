@@ -0,0 +1,301 @@
+package identity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// jwksCacheTTL là thời gian cache bộ khóa JWKS trước khi tải lại
+	jwksCacheTTL = time.Hour
+	// jwksMinRefreshInterval giới hạn tần suất tải lại JWKS khi gặp kid lạ (tránh bị spam token giả)
+	jwksMinRefreshInterval = time.Minute
+)
+
+// OIDCProvider xác thực id_token của một OpenID Connect issuer bất kỳ (Google, Keycloak, Azure AD...)
+// Chữ ký được kiểm tra bằng bộ khóa công khai JWKS của issuer; claim iss/aud/exp được kiểm tra theo cấu hình
+type OIDCProvider struct {
+	name       string
+	issuer     string
+	clientID   string
+	jwksURL    string
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]interface{} // kid -> *rsa.PublicKey | *ecdsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewOIDCProvider tạo mới OIDCProvider
+// jwksURL có thể rỗng: khi đó sẽ lấy jwks_uri từ {issuer}/.well-known/openid-configuration
+func NewOIDCProvider(name, issuer, clientID, jwksURL string) *OIDCProvider {
+	if name == "" {
+		name = ProviderOIDC
+	}
+	return &OIDCProvider{
+		name:       name,
+		issuer:     strings.TrimSuffix(issuer, "/"),
+		clientID:   clientID,
+		jwksURL:    jwksURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name trả về tên provider
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// Verify xác thực id_token và trả về danh tính từ các claim chuẩn OIDC
+func (p *OIDCProvider) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %v", err)
+	}
+
+	// MapClaims.Valid chỉ kiểm tra exp/iat/nbf nếu có - id_token bắt buộc phải có exp
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id token missing exp claim")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.issuer {
+		return nil, fmt.Errorf("unexpected issuer: %s", iss)
+	}
+	if !audienceContains(claims["aud"], p.clientID) {
+		return nil, errors.New("id token audience mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token missing sub claim")
+	}
+
+	id := &Identity{Provider: p.name, Subject: subject}
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified, _ = claims["email_verified"].(bool)
+	id.Phone, _ = claims["phone_number"].(string)
+	id.PhoneVerified, _ = claims["phone_number_verified"].(bool)
+	id.Name, _ = claims["name"].(string)
+	id.AvatarURL, _ = claims["picture"].(string)
+	return id, nil
+}
+
+// audienceContains kiểm tra claim aud (string hoặc mảng string) có chứa clientID
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getKey lấy khóa công khai theo kid, tải lại JWKS khi cache hết hạn hoặc gặp kid chưa biết
+func (p *OIDCProvider) getKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	key, ok := p.lookupKey(kid)
+	stale := time.Since(p.fetchedAt) > jwksCacheTTL
+	canRefresh := time.Since(p.fetchedAt) > jwksMinRefreshInterval
+	p.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && !canRefresh {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		if ok {
+			// Không tải được JWKS mới nhưng vẫn còn khóa cũ - dùng tạm khóa cũ
+			return key, nil
+		}
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+// lookupKey tìm khóa theo kid; nếu token không có kid và JWKS chỉ có một khóa thì dùng khóa đó
+// Caller phải giữ p.mu
+func (p *OIDCProvider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey là một khóa trong JWKS (RFC 7517), chỉ hỗ trợ RSA và EC
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// refreshKeys tải lại JWKS của issuer
+func (p *OIDCProvider) refreshKeys(ctx context.Context) error {
+	jwksURL, err := p.resolveJWKSURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch jwks: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Bỏ qua khóa không hỗ trợ thay vì làm hỏng cả bộ khóa
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks contains no usable signing keys")
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// resolveJWKSURL trả về JWKS URL đã cấu hình, hoặc lấy từ discovery document của issuer
+func (p *OIDCProvider) resolveJWKSURL(ctx context.Context) (string, error) {
+	p.mu.RLock()
+	jwksURL := p.jwksURL
+	p.mu.RUnlock()
+	if jwksURL != "" {
+		return jwksURL, nil
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", fmt.Errorf("failed to fetch openid configuration: %v", err)
+	}
+	if discovery.JWKSURI == "" {
+		return "", errors.New("openid configuration missing jwks_uri")
+	}
+
+	p.mu.Lock()
+	p.jwksURL = discovery.JWKSURI
+	p.mu.Unlock()
+	return discovery.JWKSURI, nil
+}
+
+// getJSON gửi GET request và decode JSON response
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// publicKey chuyển JWK thành *rsa.PublicKey hoặc *ecdsa.PublicKey
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// decodeBase64URLInt decode số nguyên lớn mã hóa base64url (không padding)
+func decodeBase64URLInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("empty key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
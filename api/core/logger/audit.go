@@ -11,6 +11,7 @@ import (
 type AuditAction struct {
 	Action      string                 `json:"action"`       // Tên hành động (ví dụ: "user_create", "user_delete")
 	UserID      string                 `json:"user_id"`      // ID người dùng thực hiện
+	ImpersonatorID string              `json:"impersonator_id,omitempty"` // ID quản trị viên đang đăng nhập thay UserID (nếu có)
 	ResourceID  string                 `json:"resource_id"`  // ID tài nguyên bị ảnh hưởng
 	ResourceType string                `json:"resource_type"` // Loại tài nguyên (ví dụ: "user", "organization")
 	IP          string                 `json:"ip"`           // IP address
@@ -31,13 +32,22 @@ func LogAction(action string, c fiber.Ctx, details map[string]interface{}) {
 		Timestamp:   time.Now(),
 	}
 
-	// Lấy user ID từ context nếu có
-	if userID := c.Locals("userID"); userID != nil {
+	if audit.Details == nil {
+		audit.Details = make(map[string]interface{})
+	}
+
+	// Lấy user ID từ context nếu có (AuthMiddleware lưu với key "user_id")
+	if userID := c.Locals("user_id"); userID != nil {
 		if uid, ok := userID.(string); ok {
 			audit.UserID = uid
 		}
 	}
 
+	// Request thực hiện bằng token "login as": ghi lại quản trị viên thật
+	if impersonatorID, ok := c.Locals("impersonator_id").(string); ok && impersonatorID != "" {
+		audit.ImpersonatorID = impersonatorID
+	}
+
 	// Lấy organization ID từ context nếu có
 	if orgID := c.Locals("organizationID"); orgID != nil {
 		if oid, ok := orgID.(string); ok {
@@ -53,6 +63,7 @@ func LogAction(action string, c fiber.Ctx, details map[string]interface{}) {
 	auditLogger.WithFields(logrus.Fields{
 		"action":       audit.Action,
 		"user_id":      audit.UserID,
+		"impersonator_id": audit.ImpersonatorID,
 		"resource_id":  audit.ResourceID,
 		"resource_type": audit.ResourceType,
 		"ip":           audit.IP,
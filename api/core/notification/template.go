@@ -5,16 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	models "meta_commerce/core/api/models/mongodb"
 	"meta_commerce/core/api/services"
 	"meta_commerce/core/common"
 	"meta_commerce/core/notification/channels"
+	"meta_commerce/core/utility"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// templateCache cache kết quả FindTemplate theo (eventType, channelType, organizationId)
+// TTL ngắn nên template vừa sửa sẽ có hiệu lực sau tối đa 1 phút
+var templateCache = utility.NewCache[*models.NotificationTemplate]("notification_templates", time.Minute, time.Minute, 1000)
+
 // Template xử lý việc tìm và render template
 type Template struct {
 	templateService *services.NotificationTemplateService
@@ -35,6 +41,14 @@ func NewTemplate() (*Template, error) {
 // FindTemplate tìm template theo EventType, ChannelType, và OrganizationID
 // Logic: Tìm team-specific trước, nếu không có → tìm global
 func (t *Template) FindTemplate(ctx context.Context, eventType string, channelType string, organizationID primitive.ObjectID) (*models.NotificationTemplate, error) {
+	cacheKey := eventType + ":" + channelType + ":" + organizationID.Hex()
+	return templateCache.GetOrLoad(cacheKey, func() (*models.NotificationTemplate, error) {
+		return t.findTemplate(ctx, eventType, channelType, organizationID)
+	})
+}
+
+// findTemplate tìm template trong database (không qua cache)
+func (t *Template) findTemplate(ctx context.Context, eventType string, channelType string, organizationID primitive.ObjectID) (*models.NotificationTemplate, error) {
 	// 1. Tìm team-specific template
 	filter := bson.M{
 		"eventType":   eventType,
@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/api/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BuildQueueItems tạo queue item cho mỗi recipient của mỗi route
+// Route có channel không tìm thấy hoặc không hỗ trợ bị bỏ qua
+func BuildQueueItems(ctx context.Context, routes []Route, eventType string, payload map[string]interface{}) ([]*models.NotificationQueueItem, error) {
+	channelService, err := services.NewNotificationChannelService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel service: %w", err)
+	}
+
+	queueItems := make([]*models.NotificationQueueItem, 0)
+	for _, route := range routes {
+		// Lấy channel để biết recipients
+		channel, err := channelService.FindOneById(ctx, route.ChannelID)
+		if err != nil {
+			// Log error nhưng tiếp tục với route khác
+			continue
+		}
+
+		// Xác định recipients dựa trên channel type
+		var recipients []string
+		switch channel.ChannelType {
+		case "email":
+			recipients = channel.Recipients
+		case "telegram":
+			recipients = channel.ChatIDs
+		case "webhook":
+			if channel.WebhookURL != "" {
+				recipients = []string{channel.WebhookURL}
+			}
+		default:
+			continue
+		}
+
+		// Tạo queue item cho mỗi recipient
+		for _, recipient := range recipients {
+			queueItems = append(queueItems, &models.NotificationQueueItem{
+				ID:                  primitive.NewObjectID(),
+				EventType:           eventType,
+				OwnerOrganizationID: route.OrganizationID, // Phân quyền dữ liệu - queue item thuộc về organization này
+				ChannelID:           route.ChannelID,
+				Recipient:           recipient,
+				Payload:             payload,
+				Status:              "pending",
+				RetryCount:          0,
+				MaxRetries:          3,
+				CreatedAt:           time.Now().Unix(),
+				UpdatedAt:           time.Now().Unix(),
+			})
+		}
+	}
+	return queueItems, nil
+}
+
+// Trigger tìm routes cho eventType và thêm notification vào queue (dùng cho event phát sinh từ server, vd: job)
+// Returns: số item đã thêm vào queue
+func Trigger(ctx context.Context, eventType string, payload map[string]interface{}) (int, error) {
+	router, err := NewRouter()
+	if err != nil {
+		return 0, err
+	}
+	queue, err := NewQueue()
+	if err != nil {
+		return 0, err
+	}
+
+	routes, err := router.FindRoutes(ctx, eventType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find routes for event type '%s': %w", eventType, err)
+	}
+	if len(routes) == 0 {
+		return 0, nil
+	}
+
+	queueItems, err := BuildQueueItems(ctx, routes, eventType, payload)
+	if err != nil {
+		return 0, err
+	}
+	if len(queueItems) == 0 {
+		return 0, nil
+	}
+	if err := queue.Enqueue(ctx, queueItems); err != nil {
+		return 0, err
+	}
+	return len(queueItems), nil
+}
+
+// TriggerForRecipient giống Trigger nhưng gửi thêm cho một người nhận cụ thể (vd: email của user bị tác động)
+// Người nhận được thêm vào mỗi route có channel email, dùng cấu hình gửi của channel đó
+// Returns: số item đã thêm vào queue
+func TriggerForRecipient(ctx context.Context, eventType string, recipientEmail string, payload map[string]interface{}) (int, error) {
+	return triggerRecipient(ctx, eventType, recipientEmail, payload, true)
+}
+
+// SendToRecipient chỉ gửi cho một người nhận cụ thể qua các route email của eventType, không gửi cho recipients cố định của channel
+// Dùng khi payload chứa dữ liệu riêng của người nhận (vd: link mời có token)
+// Returns: số item đã thêm vào queue
+func SendToRecipient(ctx context.Context, eventType string, recipientEmail string, payload map[string]interface{}) (int, error) {
+	if recipientEmail == "" {
+		return 0, nil
+	}
+	return triggerRecipient(ctx, eventType, recipientEmail, payload, false)
+}
+
+// triggerRecipient thêm queue item cho recipientEmail trên mỗi route email (và cho recipients của route nếu includeRoutes)
+func triggerRecipient(ctx context.Context, eventType string, recipientEmail string, payload map[string]interface{}, includeRoutes bool) (int, error) {
+	router, err := NewRouter()
+	if err != nil {
+		return 0, err
+	}
+	queue, err := NewQueue()
+	if err != nil {
+		return 0, err
+	}
+	channelService, err := services.NewNotificationChannelService()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create channel service: %w", err)
+	}
+
+	routes, err := router.FindRoutes(ctx, eventType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find routes for event type '%s': %w", eventType, err)
+	}
+	if len(routes) == 0 {
+		return 0, nil
+	}
+
+	queueItems := make([]*models.NotificationQueueItem, 0)
+	if includeRoutes {
+		queueItems, err = BuildQueueItems(ctx, routes, eventType, payload)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if recipientEmail != "" {
+		for _, route := range routes {
+			channel, err := channelService.FindOneById(ctx, route.ChannelID)
+			if err != nil || channel.ChannelType != "email" {
+				continue
+			}
+			alreadyIncluded := false
+			if includeRoutes {
+				for _, recipient := range channel.Recipients {
+					if recipient == recipientEmail {
+						alreadyIncluded = true
+						break
+					}
+				}
+			}
+			if alreadyIncluded {
+				continue
+			}
+			queueItems = append(queueItems, &models.NotificationQueueItem{
+				ID:                  primitive.NewObjectID(),
+				EventType:           eventType,
+				OwnerOrganizationID: route.OrganizationID,
+				ChannelID:           route.ChannelID,
+				Recipient:           recipientEmail,
+				Payload:             payload,
+				Status:              "pending",
+				RetryCount:          0,
+				MaxRetries:          3,
+				CreatedAt:           time.Now().Unix(),
+				UpdatedAt:           time.Now().Unix(),
+			})
+		}
+	}
+
+	if len(queueItems) == 0 {
+		return 0, nil
+	}
+	if err := queue.Enqueue(ctx, queueItems); err != nil {
+		return 0, err
+	}
+	return len(queueItems), nil
+}
@@ -1,59 +1,324 @@
 package utility
 
 import (
+	"container/list"
+	"errors"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Cache là struct để quản lý cache với thời gian sống và thời gian dọn dẹp
-type Cache struct {
-	items    map[string]interface{}
-	mu       sync.RWMutex
-	ttl      time.Duration
-	cleanup  time.Duration
-	stopChan chan struct{}
+// Cache là cache trong bộ nhớ với TTL theo từng entry, giới hạn số phần tử (LRU) và thống kê hit/miss
+// - Entry hết hạn được coi như không tồn tại và bị xóa khi đọc hoặc khi dọn dẹp định kỳ
+// - Khi vượt quá maxSize, entry ít được dùng gần đây nhất bị loại bỏ
+// - GetOrLoad gộp các lần load đồng thời cùng key thành một (singleflight)
+type Cache[V any] struct {
+	name       string
+	ttl        time.Duration
+	maxSize    int
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List // Front = dùng gần đây nhất
+	calls      map[string]*cacheCall[V]
+	generation uint64 // Tăng mỗi khi xóa key, dùng để bỏ kết quả load đã cũ
+	stopChan   chan struct{}
+	stopOnce   sync.Once
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// cacheEntry là một phần tử trong cache
+type cacheEntry[V any] struct {
+	key      string
+	value    V
+	expireAt time.Time // Zero = không hết hạn
 }
 
+// cacheCall là một lần load đang chạy trong GetOrLoad
+type cacheCall[V any] struct {
+	wg    sync.WaitGroup
+	value V
+	err   error
+}
+
+// CacheStats là thống kê của một cache
+type CacheStats struct {
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	MaxSize     int    `json:"maxSize"`
+	TTLSeconds  int64  `json:"ttlSeconds"`
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+}
+
+// cacheStatsProvider là phần chung của Cache[V] dùng cho registry thống kê
+type cacheStatsProvider interface {
+	Stats() CacheStats
+}
+
+// errCacheLoaderPanic trả về cho các lần gọi GetOrLoad đang chờ khi loader bị panic
+var errCacheLoaderPanic = errors.New("cache loader panicked")
+
+var (
+	cacheRegistryMu sync.RWMutex
+	cacheRegistry   = make(map[string]cacheStatsProvider)
+)
+
 // NewCache tạo một instance mới của Cache
-func NewCache(ttl, cleanup time.Duration) *Cache {
-	cache := &Cache{
-		items:    make(map[string]interface{}),
+// Parameters:
+//   - name: Tên cache, dùng để xem thống kê qua AllCacheStats (rỗng = không đăng ký)
+//   - ttl: Thời gian sống mặc định của entry (0 = không hết hạn)
+//   - cleanup: Chu kỳ dọn entry hết hạn (0 = không chạy dọn dẹp định kỳ)
+//   - maxSize: Số entry tối đa (0 = không giới hạn)
+func NewCache[V any](name string, ttl, cleanup time.Duration, maxSize int) *Cache[V] {
+	cache := &Cache[V]{
+		name:     name,
 		ttl:      ttl,
-		cleanup:  cleanup,
+		maxSize:  maxSize,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		calls:    make(map[string]*cacheCall[V]),
 		stopChan: make(chan struct{}),
 	}
-	go cache.cleanupLoop()
+	if cleanup > 0 {
+		go cache.cleanupLoop(cleanup)
+	}
+
+	if name != "" {
+		cacheRegistryMu.Lock()
+		cacheRegistry[name] = cache
+		cacheRegistryMu.Unlock()
+	}
 	return cache
 }
 
-// Set lưu giá trị vào cache
-func (c *Cache) Set(key string, value interface{}) {
+// AllCacheStats trả về thống kê của tất cả cache đã đăng ký, sắp xếp theo tên
+func AllCacheStats() []CacheStats {
+	cacheRegistryMu.RLock()
+	stats := make([]CacheStats, 0, len(cacheRegistry))
+	for _, cache := range cacheRegistry {
+		stats = append(stats, cache.Stats())
+	}
+	cacheRegistryMu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Set lưu giá trị vào cache với TTL mặc định
+func (c *Cache[V]) Set(key string, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL lưu giá trị vào cache với TTL riêng (0 = không hết hạn)
+func (c *Cache[V]) SetWithTTL(key string, value V, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.items[key] = value
+	c.setLocked(key, value, ttl)
 }
 
-// Get lấy giá trị từ cache
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	value, exists := c.items[key]
-	return value, exists
+// setLocked lưu giá trị, caller phải giữ c.mu
+func (c *Cache[V]) setLocked(key string, value V, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry[V])
+		entry.value = value
+		entry.expireAt = expireAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&cacheEntry[V]{key: key, value: value, expireAt: expireAt})
+
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
+	}
 }
 
-// cleanupLoop dọn dẹp cache định kỳ
-func (c *Cache) cleanupLoop() {
-	ticker := time.NewTicker(c.cleanup)
+// Get lấy giá trị từ cache, entry hết hạn được coi là không tồn tại
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.getLocked(key)
+}
+
+// getLocked lấy giá trị và cập nhật thống kê, caller phải giữ c.mu
+func (c *Cache[V]) getLocked(key string) (V, bool) {
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return zero, false
+	}
+
+	entry := elem.Value.(*cacheEntry[V])
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.removeElement(elem)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return zero, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.hits.Add(1)
+	return entry.value, true
+}
+
+// GetOrLoad lấy giá trị từ cache, nếu không có thì gọi loader và lưu kết quả với TTL mặc định
+// Các lần gọi đồng thời cùng key chỉ chạy loader một lần và dùng chung kết quả
+// Lỗi từ loader không được cache
+func (c *Cache[V]) GetOrLoad(key string, loader func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if value, ok := c.getLocked(key); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := &cacheCall[V]{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	generation := c.generation
+	c.mu.Unlock()
+
+	// Nếu loader panic, các goroutine đang chờ nhận lỗi thay vì bị treo
+	call.err = errCacheLoaderPanic
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		// Chỉ lưu nếu không có lần xóa nào xảy ra trong lúc load (tránh lưu lại dữ liệu cũ)
+		if call.err == nil && generation == c.generation {
+			c.setLocked(key, call.value, c.ttl)
+		}
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.value, call.err = loader()
+	return call.value, call.err
+}
+
+// Delete xóa một key khỏi cache
+func (c *Cache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// DeleteByPrefix xóa tất cả key bắt đầu bằng prefix, trả về số key đã xóa
+func (c *Cache[V]) DeleteByPrefix(prefix string) int {
+	return c.DeleteFunc(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// DeleteFunc xóa tất cả key thỏa mãn điều kiện match, trả về số key đã xóa
+func (c *Cache[V]) DeleteFunc(match func(key string) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	deleted := 0
+	for key, elem := range c.items {
+		if match(key) {
+			c.removeElement(elem)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Clear xóa toàn bộ cache
+func (c *Cache[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len trả về số entry hiện có (bao gồm cả entry hết hạn chưa được dọn)
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats trả về thống kê hiện tại của cache
+func (c *Cache[V]) Stats() CacheStats {
+	return CacheStats{
+		Name:        c.name,
+		Size:        c.Len(),
+		MaxSize:     c.maxSize,
+		TTLSeconds:  int64(c.ttl / time.Second),
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
+
+// Stop dừng goroutine dọn dẹp và hủy đăng ký thống kê, gọi nhiều lần không gây lỗi
+func (c *Cache[V]) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+		if c.name != "" {
+			cacheRegistryMu.Lock()
+			if cacheRegistry[c.name] == cacheStatsProvider(c) {
+				delete(cacheRegistry, c.name)
+			}
+			cacheRegistryMu.Unlock()
+		}
+	})
+}
+
+// removeElement xóa một phần tử, caller phải giữ c.mu
+func (c *Cache[V]) removeElement(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry[V])
+	delete(c.items, entry.key)
+}
+
+// removeExpired xóa các entry đã hết hạn
+func (c *Cache[V]) removeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, elem := range c.items {
+		entry := elem.Value.(*cacheEntry[V])
+		if !entry.expireAt.IsZero() && now.After(entry.expireAt) {
+			c.removeElement(elem)
+			c.expirations.Add(1)
+		}
+	}
+}
+
+// cleanupLoop dọn dẹp các entry hết hạn định kỳ
+func (c *Cache[V]) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			c.mu.Lock()
-			for k := range c.items {
-				delete(c.items, k)
-			}
-			c.mu.Unlock()
+			c.removeExpired()
 		case <-c.stopChan:
 			return
 		}
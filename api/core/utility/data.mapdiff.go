@@ -25,7 +25,7 @@ func MyMapDiff(newMap, oldMap map[string]interface{}) (resultDiff []interface{})
 					// fmt.Println(test.String())
 
 					// Nếu giá trị là một bản đồ khác
-					if test.String() == "map[string]interface {}" {
+					if test != nil && test.String() == "map[string]interface {}" {
 						old, err := ToMap(oldVal)
 						if err != nil {
 							continue
@@ -0,0 +1,27 @@
+package utility
+
+import "strings"
+
+// MaskString che phần giữa chuỗi bằng "*", giữ lại keepStart ký tự đầu và keepEnd ký tự cuối
+// Chuỗi quá ngắn (không còn ký tự nào để che) sẽ bị che toàn bộ
+func MaskString(value string, keepStart, keepEnd int) string {
+	runes := []rune(value)
+	if len(runes) <= keepStart+keepEnd {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:keepStart]) + strings.Repeat("*", len(runes)-keepStart-keepEnd) + string(runes[len(runes)-keepEnd:])
+}
+
+// MaskPhone che số điện thoại, giữ 2 số đầu và 2 số cuối (0912345689 → 09******89)
+func MaskPhone(phone string) string {
+	return MaskString(phone, 2, 2)
+}
+
+// MaskEmail che phần tên của email, giữ 2 ký tự đầu và tên miền (nguyenvana@gmail.com → ng********@gmail.com)
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return MaskString(email, 2, 2)
+	}
+	return MaskString(email[:at], 2, 0) + email[at:]
+}
@@ -1,7 +1,16 @@
 package utility
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"strconv"
+	"time"
+
 	models "meta_commerce/core/api/models/mongodb"
+	"meta_commerce/core/common"
 
 	"github.com/dgrijalva/jwt-go"
 )
@@ -51,3 +60,88 @@ func CreateToken(secretKey string, id string, time string, randomNum string) (ma
 	m["token"] = tokenString // thiết lập dữ liệu phản hồi
 	return m, nil
 }
+
+// SignToken ký một JwtToken đã có đầy đủ claims (jti, exp, nbf, iat...) bằng HS256
+//
+// Tham số:
+// - secretKey: Chuỗi bí mật dùng để ký token.
+// - claims: Các claims của token.
+//
+// Trả về:
+// - string: Token đã được ký.
+// - error: Lỗi nếu có trong quá trình ký token.
+func SignToken(secretKey string, claims models.JwtToken) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secretKey))
+}
+
+// ParseToken xác thực chữ ký HS256 và các claims thời gian (exp, nbf, iat) của token ngay tại server,
+// không cần truy vấn database.
+//
+// Token bắt buộc phải có exp và jti, token cũ không có thời hạn sẽ bị từ chối.
+//
+// Trả về:
+// - *models.JwtToken: Claims của token nếu hợp lệ.
+// - error: common.ErrTokenExpired nếu token đã hết hạn, common.ErrTokenInvalid nếu token không hợp lệ.
+func ParseToken(secretKey string, tokenString string) (*models.JwtToken, error) {
+	claims := &models.JwtToken{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		// Chỉ chấp nhận HS256, tránh tấn công đổi thuật toán (alg=none, RS256...)
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, common.ErrTokenExpired
+		}
+		return nil, common.ErrTokenInvalid
+	}
+
+	if !token.Valid || claims.ExpiresAt == 0 || claims.Id == "" || claims.UserID == "" {
+		return nil, common.ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// NewTokenID tạo jti ngẫu nhiên cho JWT
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewJwtClaims tạo claims cho một JWT mới với jti ngẫu nhiên, iat/nbf là thời điểm hiện tại và exp = hiện tại + ttl
+//
+// Tham số:
+// - userID: ID của người dùng.
+// - hwid: ID phần cứng của thiết bị.
+// - tokenType: Loại token (models.JwtTokenTypeAccess hoặc models.JwtTokenTypeRefresh).
+// - ttl: Thời gian sống của token.
+func NewJwtClaims(userID string, hwid string, tokenType string, ttl time.Duration) (models.JwtToken, error) {
+	jti, err := NewTokenID()
+	if err != nil {
+		return models.JwtToken{}, err
+	}
+
+	now := time.Now()
+	return models.JwtToken{
+		UserID:       userID,
+		Time:         strconv.FormatInt(now.Unix(), 16),
+		RandomNumber: strconv.Itoa(mathrand.Intn(100)),
+		TokenType:    tokenType,
+		Hwid:         hwid,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   userID,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	}, nil
+}